@@ -0,0 +1,158 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"math/rand"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Anonymizer replaces the values of selected items with placeholders, so
+// that inputs causing problems can be shared without leaking secrets or
+// proprietary names.
+//
+// Placeholders preserve the structure of the input.  A value is always
+// replaced by the same placeholder and distinct values by distinct
+// placeholders where possible.  A placeholder has the length in bytes of
+// the value it replaces, so the positions of items do not change, and
+// replaces only letters and digits, keeping punctuation, space, and the
+// rune following each backslash, so string literals remain well formed.
+//
+//	a := lexer.NewAnonymizer(1)
+//	a.Type(itemString)
+//	a.Match(itemIdent, regexp.MustCompile(`(?i)secret|token`).MatchString)
+//	shared := a.Source(lex)
+type Anonymizer struct {
+	rnd   *rand.Rand
+	sel   map[ItemType][]func(string) bool
+	repl  map[string]string
+	taken map[string]bool
+}
+
+// NewAnonymizer returns an anonymizer selecting no items, which generates
+// placeholders from seed.  Anonymizers with the same seed and selections
+// replace the values of the same inputs identically.
+func NewAnonymizer(seed int64) *Anonymizer {
+	return &Anonymizer{
+		rnd:   rand.New(rand.NewSource(seed)),
+		sel:   make(map[ItemType][]func(string) bool),
+		repl:  make(map[string]string),
+		taken: make(map[string]bool),
+	}
+}
+
+// Type selects all items of type t.
+func (a *Anonymizer) Type(t ItemType) {
+	a.Match(t, nil)
+}
+
+// Match selects the items of type t whose values match, such as the
+// MatchString method of a regular expression.  A nil match selects all
+// items of type t.
+func (a *Anonymizer) Match(t ItemType, match func(value string) bool) {
+	a.sel[t] = append(a.sel[t], match)
+}
+
+// Selects returns true if a replaces the value of item.
+func (a *Anonymizer) Selects(item *Item) bool {
+	for _, match := range a.sel[item.Type] {
+		if match == nil || match(item.Value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Item returns item if it is not selected and otherwise a copy of item
+// whose value is replaced by its placeholder.  The Payload of the copy is
+// nil, since it may hold the decoded value.  Item can be given to
+// Pipeline.Map.
+func (a *Anonymizer) Item(item *Item) *Item {
+	if !a.Selects(item) {
+		return item
+	}
+	cp := *item
+	cp.Value = a.Placeholder(item.Value)
+	cp.Payload = nil
+	return &cp
+}
+
+// Placeholder returns the placeholder replacing value.
+func (a *Anonymizer) Placeholder(value string) string {
+	if p, ok := a.repl[value]; ok {
+		return p
+	}
+	var p string
+	for try := 0; try < 10; try++ {
+		p = a.scramble(value)
+		if p == value || !a.taken[p] {
+			break
+		}
+	}
+	a.repl[value] = p
+	a.taken[p] = true
+	return p
+}
+
+// scramble replaces the letters and digits of value with random ones of
+// the same kind, using as many ASCII letters as a non-ASCII letter or digit
+// has bytes.
+func (a *Anonymizer) scramble(value string) string {
+	const (
+		lower = "abcdefghijklmnopqrstuvwxyz"
+		upper = "ABCDEFGHIJKLMNOPQRSTUVWXYZ"
+		digit = "0123456789"
+	)
+	var b strings.Builder
+	escaped := false
+	for _, r := range value {
+		switch {
+		case escaped:
+			b.WriteRune(r)
+			escaped = false
+		case r == '\\':
+			b.WriteRune(r)
+			escaped = true
+		case r >= '0' && r <= '9':
+			b.WriteByte(digit[a.rnd.Intn(len(digit))])
+		case r >= 'A' && r <= 'Z':
+			b.WriteByte(upper[a.rnd.Intn(len(upper))])
+		case r >= 'a' && r <= 'z' || r >= utf8.RuneSelf && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			for n := utf8.RuneLen(r); n > 0; n-- {
+				b.WriteByte(lower[a.rnd.Intn(len(lower))])
+			}
+		default:
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+// Source returns the input of lex with the values of the selected items
+// replaced by their placeholders.  The items of lex are consumed.  Items
+// whose values are not the text at their positions, such as values decoded
+// by EmitValue, are left in place.
+func (a *Anonymizer) Source(lex *Lexer) string {
+	input := lex.Input()
+	var b strings.Builder
+	last := 0
+	for {
+		item := lex.Next()
+		if item.Type == ItemEOF {
+			break
+		}
+		end := item.Pos + len(item.Value)
+		if !a.Selects(item) || item.Pos < last || end > len(input) || input[item.Pos:end] != item.Value {
+			continue
+		}
+		b.WriteString(input[last:item.Pos])
+		b.WriteString(a.Placeholder(item.Value))
+		last = end
+	}
+	b.WriteString(input[last:])
+	return b.String()
+}
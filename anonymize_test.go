@@ -0,0 +1,54 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+// anonymize replaces the words of input beginning with "se".
+func anonymize(seed int64, input string) string {
+	a := NewAnonymizer(seed)
+	a.Match(1, func(v string) bool { return strings.HasPrefix(v, "se") })
+	return a.Source(New(lexWords, input, WithErrorPolicy(ErrorResume)))
+}
+
+func TestAnonymizer(t *testing.T) {
+	input := "see a secret see é"
+	out := anonymize(1, input)
+	if len(out) != len(input) {
+		t.Fatalf("%q: length changed", out)
+	}
+	words := strings.Fields(out)
+	if words[1] != "a" || words[4] != "é" {
+		t.Errorf("unselected words replaced: %q", out)
+	}
+	if words[0] == "see" || words[0] != words[3] || words[0] == words[2][:3] {
+		t.Errorf("placeholders %q", out)
+	}
+	if anonymize(1, input) != out {
+		t.Errorf("anonymization is not deterministic")
+	}
+}
+
+func TestAnonymizerItem(t *testing.T) {
+	a := NewAnonymizer(2)
+	a.Type(2)
+	item := &Item{Type: 2, Value: `"pass\nwörd9"`, Payload: "pass\nwörd9"}
+	out := a.Item(item)
+	if out == item || out.Payload != nil || len(out.Value) != len(item.Value) {
+		t.Fatalf("item %#v", out)
+	}
+	if out.Value[0] != '"' || out.Value[5:7] != `\n` || out.Value[len(out.Value)-1] != '"' {
+		t.Errorf("structure lost: %s", out.Value)
+	}
+	if c := out.Value[len(out.Value)-2]; c < '0' || c > '9' {
+		t.Errorf("digit replaced by %q", c)
+	}
+	if other := (&Item{Type: 1, Value: "x"}); a.Item(other) != other {
+		t.Errorf("unselected item copied")
+	}
+}
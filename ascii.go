@@ -0,0 +1,88 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"unicode/utf8"
+)
+
+// The run-accepting methods skip over runs of ASCII bytes without decoding
+// runes when the set of valid runes is ASCII only, which is the case for the
+// most common classes, such as white space and decimal digits.
+
+// asciiSet is a set of ASCII bytes, as a bitmap.
+type asciiSet [2]uint64
+
+// makeASCIISet returns the set of bytes in chars, and false if chars contains
+// bytes which are not ASCII.
+func makeASCIISet(chars string) (as asciiSet, ok bool) {
+	for i := 0; i < len(chars); i++ {
+		c := chars[i]
+		if c >= utf8.RuneSelf {
+			return as, false
+		}
+		as[c>>6] |= 1 << (c & 63)
+	}
+	return as, true
+}
+
+func (as *asciiSet) contains(c byte) bool {
+	return c < utf8.RuneSelf && as[c>>6]&(1<<(c&63)) != 0
+}
+
+// span returns the length of the prefix of s made of bytes in as.
+func (as *asciiSet) span(s string) int {
+	for i := 0; i < len(s); i++ {
+		if !as.contains(s[i]) {
+			return i
+		}
+	}
+	return len(s)
+}
+
+// digitSpan returns the length of the prefix of s made of decimal digits.
+// It tests eight bytes at a time, adding six to each byte so that a byte is
+// a digit if both it and the sum have a high nibble of three.  A carry
+// between bytes only occurs past a byte which is not a digit.
+func digitSpan(s string) int {
+	i := 0
+	for ; i+8 <= len(s); i += 8 {
+		x := uint64(s[i]) | uint64(s[i+1])<<8 | uint64(s[i+2])<<16 | uint64(s[i+3])<<24 |
+			uint64(s[i+4])<<32 | uint64(s[i+5])<<40 | uint64(s[i+6])<<48 | uint64(s[i+7])<<56
+		if x&0xf0f0f0f0f0f0f0f0|(x+0x0606060606060606)&0xf0f0f0f0f0f0f0f0>>4 != 0x3333333333333333 {
+			break
+		}
+	}
+	for ; i < len(s); i++ {
+		if s[i] < '0' || s[i] > '9' {
+			break
+		}
+	}
+	return i
+}
+
+// skipASCII advances l's position over the longest run of bytes in valid if
+// valid is ASCII only, as a run of calls to Accept would, and returns the
+// number of bytes skipped.  The rune following the run is left for Accept,
+// so that the lexer finishes in the state Accept leaves it in.  Nothing is
+// skipped while l records its calls or its coverage, so that each call to
+// Accept is observed.
+func (l *Lexer) skipASCII(valid string) int {
+	if l.rec != nil || l.cover != nil || l.pos >= len(l.input) {
+		return 0
+	}
+	var n int
+	if valid == digits {
+		n = digitSpan(l.input[l.pos:])
+	} else if as, ok := makeASCIISet(valid); ok {
+		n = as.span(l.input[l.pos:])
+	}
+	if n > 0 {
+		l.pos += n
+		l.last, l.width = rune(l.input[l.pos-1]), 1
+		l.unread, l.backed = false, false
+	}
+	return n
+}
@@ -0,0 +1,55 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDigitSpan(t *testing.T) {
+	naive := func(s string) int {
+		return len(s) - len(strings.TrimLeft(s, digits))
+	}
+	for c := 0; c < 256; c++ {
+		for i := 0; i < 10; i++ {
+			b := []byte("0123456789012345")
+			b[i] = byte(c)
+			s := string(b)
+			if got, want := digitSpan(s), naive(s); got != want {
+				t.Fatalf("digitSpan(%q) = %d, want %d", s, got, want)
+			}
+		}
+	}
+}
+
+func TestAcceptRunASCII(t *testing.T) {
+	for _, test := range []struct {
+		input, valid string
+	}{
+		{"  \t\nx", " \t\n"},
+		{"1234567890123456789é", digits},
+		{"12345678\xff", digits},
+		{"", digits},
+		{"abc", digits},
+		{"aaaa", "a"},
+		{"ééa", "é"},
+	} {
+		var rec Recording
+		fast, slow := New(nilState, test.input), New(nilState, test.input, WithRecording(&rec))
+		n, m := fast.AcceptRun(test.valid), slow.AcceptRun(test.valid)
+		r1, w1 := fast.Last()
+		r2, w2 := slow.Last()
+		if n != m || fast.Pos() != slow.Pos() || r1 != r2 || w1 != w2 || fast.backed != slow.backed || fast.unread != slow.unread {
+			t.Errorf("AcceptRun(%q) on %q: %d at %d last %q, want %d at %d last %q",
+				test.valid, test.input, n, fast.Pos(), r1, m, slow.Pos(), r2)
+		}
+		fast, slow = New(nilState, test.input), New(nilState, test.input, WithRecording(&rec))
+		n, m = fast.AcceptByteRun(test.valid), slow.AcceptByteRun(test.valid)
+		if n != m || fast.Pos() != slow.Pos() {
+			t.Errorf("AcceptByteRun(%q) on %q: %d at %d, want %d at %d", test.valid, test.input, n, fast.Pos(), m, slow.Pos())
+		}
+	}
+}
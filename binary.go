@@ -0,0 +1,336 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// The binary format of item streams written by ItemEncoder begins with the
+// magic string "LEXI" and a version byte.  A sequence of records follows,
+// each a kind byte, the uvarint length of its body and the body.  Decoders
+// skip records of unknown kinds, so later versions may add records without
+// breaking earlier decoders.  The records of version 1 are
+//
+//	type    uvarint ItemType, string name
+//	string  string
+//	item    uvarint type index, varint offset from the end of the previous
+//	        item, string value, flags byte, then varint line delta and
+//	        uvarint column if flags&1, uvarint string index of Lang if
+//	        flags&2
+//
+// where a string is a uvarint length followed by its bytes.  Types and
+// strings are numbered in the order they are defined, from zero, and are
+// defined before the first item using them.  Payloads are not encoded.
+const (
+	binaryMagic   = "LEXI"
+	BinaryVersion = 1
+)
+
+const (
+	recType   = 1
+	recString = 2
+	recItem   = 3
+)
+
+// ErrBinaryFormat is returned when decoding data which is not an item stream
+// written by ItemEncoder.
+var ErrBinaryFormat = errors.New("lexer: invalid binary item stream")
+
+// ItemEncoder writes items in a compact binary format, for persisting the
+// results of lexing far more cheaply than JSON.  An ItemEncoder is a Sink.
+//
+//	enc := lexer.NewItemEncoder(w)
+//	err := lex.Drain(enc)
+type ItemEncoder struct {
+	w       *bufio.Writer
+	types   map[ItemType]int
+	strings map[string]int
+	started bool
+	end     int
+	line    int
+	buf     []byte
+	err     error
+}
+
+// NewItemEncoder returns an encoder writing to w.  The header is written
+// with the first item, or by Flush.
+func NewItemEncoder(w io.Writer) *ItemEncoder {
+	return &ItemEncoder{
+		w:       bufio.NewWriter(w),
+		types:   make(map[ItemType]int),
+		strings: make(map[string]int),
+	}
+}
+
+// Write encodes item.  An item of type ItemEOF is not encoded.
+func (enc *ItemEncoder) Write(item Item) error {
+	if enc.err != nil || item.Type == ItemEOF {
+		return enc.err
+	}
+	enc.start()
+	t, ok := enc.types[item.Type]
+	if !ok {
+		t = len(enc.types)
+		enc.types[item.Type] = t
+		b := binary.AppendUvarint(enc.buf[:0], uint64(item.Type))
+		enc.record(recType, appendString(b, item.Type.String()))
+	}
+	lang := -1
+	if item.Lang != "" {
+		if lang, ok = enc.strings[item.Lang]; !ok {
+			lang = len(enc.strings)
+			enc.strings[item.Lang] = lang
+			enc.record(recString, appendString(enc.buf[:0], item.Lang))
+		}
+	}
+	b := binary.AppendUvarint(enc.buf[:0], uint64(t))
+	b = binary.AppendVarint(b, int64(item.Pos-enc.end))
+	b = appendString(b, item.Value)
+	var flags byte
+	if item.Line > 0 {
+		flags |= 1
+	}
+	if lang >= 0 {
+		flags |= 2
+	}
+	b = append(b, flags)
+	if item.Line > 0 {
+		b = binary.AppendVarint(b, int64(item.Line-enc.line))
+		b = binary.AppendUvarint(b, uint64(item.Column))
+		enc.line = item.Line
+	}
+	if lang >= 0 {
+		b = binary.AppendUvarint(b, uint64(lang))
+	}
+	enc.record(recItem, b)
+	enc.buf = b[:0]
+	enc.end = item.Pos + len(item.Value)
+	return enc.err
+}
+
+// Flush writes buffered data to the underlying writer.
+func (enc *ItemEncoder) Flush() error {
+	if enc.err != nil {
+		return enc.err
+	}
+	enc.start()
+	enc.err = enc.w.Flush()
+	return enc.err
+}
+
+// start writes the header if it has not been written.
+func (enc *ItemEncoder) start() {
+	if !enc.started {
+		enc.w.WriteString(binaryMagic)
+		enc.w.WriteByte(BinaryVersion)
+		enc.started = true
+	}
+}
+
+func (enc *ItemEncoder) record(kind byte, body []byte) {
+	var hdr [binary.MaxVarintLen64 + 1]byte
+	hdr[0] = kind
+	n := binary.PutUvarint(hdr[1:], uint64(len(body)))
+	enc.w.Write(hdr[:n+1])
+	if _, err := enc.w.Write(body); err != nil && enc.err == nil {
+		enc.err = err
+	}
+}
+
+func appendString(b []byte, s string) []byte {
+	b = binary.AppendUvarint(b, uint64(len(s)))
+	return append(b, s...)
+}
+
+// ItemDecoder reads items written by an ItemEncoder.  An ItemDecoder is a
+// Stream, so the items can be given to a Pipeline or to Tokens.
+type ItemDecoder struct {
+	r       *bufio.Reader
+	header  bool
+	types   []ItemType
+	strings []string
+	end     int
+	line    int
+	err     error
+}
+
+// NewItemDecoder returns a decoder reading from r.
+func NewItemDecoder(r io.Reader) *ItemDecoder {
+	return &ItemDecoder{r: bufio.NewReader(r)}
+}
+
+// Decode returns the next item.  At the end of the stream it returns io.EOF.
+// Data which is not a valid item stream produces an error wrapping
+// ErrBinaryFormat.
+func (dec *ItemDecoder) Decode() (*Item, error) {
+	if dec.err != nil {
+		return nil, dec.err
+	}
+	item, err := dec.decode()
+	if err != nil {
+		if err == io.ErrUnexpectedEOF {
+			err = fmt.Errorf("%w: truncated", ErrBinaryFormat)
+		}
+		dec.err = err
+	}
+	return item, err
+}
+
+func (dec *ItemDecoder) decode() (*Item, error) {
+	if !dec.header {
+		var hdr [len(binaryMagic) + 1]byte
+		if _, err := io.ReadFull(dec.r, hdr[:]); err != nil {
+			if err == io.EOF {
+				return nil, err
+			}
+			return nil, fmt.Errorf("%w: missing header", ErrBinaryFormat)
+		}
+		if string(hdr[:len(binaryMagic)]) != binaryMagic {
+			return nil, fmt.Errorf("%w: bad magic", ErrBinaryFormat)
+		}
+		if v := hdr[len(binaryMagic)]; v < 1 {
+			return nil, fmt.Errorf("%w: unsupported version %d", ErrBinaryFormat, v)
+		}
+		dec.header = true
+	}
+	for {
+		kind, err := dec.r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		n, err := binary.ReadUvarint(dec.r)
+		if err != nil {
+			return nil, unexpected(err)
+		}
+		if n > uint64(maxOffset) {
+			return nil, fmt.Errorf("%w: record length %d too large", ErrBinaryFormat, n)
+		}
+		// The body is read as it arrives rather than allocated up front, so
+		// that a corrupt length cannot allocate more than the data left.
+		body, err := io.ReadAll(io.LimitReader(dec.r, int64(n)))
+		if err != nil {
+			return nil, err
+		}
+		if uint64(len(body)) < n {
+			return nil, io.ErrUnexpectedEOF
+		}
+		r := &bodyReader{b: body}
+		switch kind {
+		case recType:
+			dec.types = append(dec.types, ItemType(r.uvarint()))
+			r.string()
+		case recString:
+			dec.strings = append(dec.strings, r.string())
+		case recItem:
+			item := new(Item)
+			t := r.uvarint()
+			pos := dec.end + int(r.varint())
+			item.Value = r.string()
+			flags := r.byte()
+			if flags&1 != 0 {
+				dec.line += int(r.varint())
+				item.Line, item.Column = dec.line, int(r.uvarint())
+			}
+			lang := -1
+			if flags&2 != 0 {
+				lang = int(r.uvarint())
+			}
+			switch {
+			case r.bad:
+				return nil, fmt.Errorf("%w: malformed record", ErrBinaryFormat)
+			case t >= uint64(len(dec.types)):
+				return nil, fmt.Errorf("%w: undefined type %d", ErrBinaryFormat, t)
+			case lang >= len(dec.strings):
+				return nil, fmt.Errorf("%w: undefined string %d", ErrBinaryFormat, lang)
+			case lang >= 0:
+				item.Lang = dec.strings[lang]
+			}
+			item.Type, item.Pos = dec.types[t], pos
+			dec.end = pos + len(item.Value)
+			return item, nil
+		}
+		if r.bad {
+			return nil, fmt.Errorf("%w: malformed record", ErrBinaryFormat)
+		}
+	}
+}
+
+func unexpected(err error) error {
+	if err == io.EOF {
+		return io.ErrUnexpectedEOF
+	}
+	return err
+}
+
+// Next returns the next item, or an item of type ItemEOF after the stream
+// ends or an error occurs.
+func (dec *ItemDecoder) Next() *Item {
+	item, err := dec.Decode()
+	if err != nil {
+		return &Item{Type: ItemEOF, Pos: dec.end}
+	}
+	return item
+}
+
+// Err returns the error which stopped the decoder, other than io.EOF.
+func (dec *ItemDecoder) Err() error {
+	if dec.err == io.EOF {
+		return nil
+	}
+	return dec.err
+}
+
+// bodyReader decodes the fields of a record, setting bad if the record is
+// too short.
+type bodyReader struct {
+	b   []byte
+	bad bool
+}
+
+func (r *bodyReader) uvarint() uint64 {
+	v, n := binary.Uvarint(r.b)
+	if n <= 0 {
+		r.bad = true
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *bodyReader) varint() int64 {
+	v, n := binary.Varint(r.b)
+	if n <= 0 {
+		r.bad = true
+		return 0
+	}
+	r.b = r.b[n:]
+	return v
+}
+
+func (r *bodyReader) byte() byte {
+	if len(r.b) == 0 {
+		r.bad = true
+		return 0
+	}
+	c := r.b[0]
+	r.b = r.b[1:]
+	return c
+}
+
+func (r *bodyReader) string() string {
+	n := r.uvarint()
+	if n > uint64(len(r.b)) {
+		r.bad = true
+		return ""
+	}
+	s := string(r.b[:n])
+	r.b = r.b[n:]
+	return s
+}
@@ -0,0 +1,88 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"io"
+	"reflect"
+	"testing"
+)
+
+func TestItemEncoder(t *testing.T) {
+	want := []Item{
+		{Type: 1, Pos: 2, Value: "ab", Line: 1, Column: 3},
+		{Type: 7, Pos: 10, Value: "", Line: 3, Column: 1, Lang: "css"},
+		{Type: ItemError, Pos: 4, Value: "bad", Line: 2, Column: 1},
+		{Type: 1, Pos: 11, Value: "cd", Lang: "css"},
+	}
+	var buf bytes.Buffer
+	enc := NewItemEncoder(&buf)
+	for _, item := range want {
+		if err := enc.Write(item); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := enc.Flush(); err != nil {
+		t.Fatal(err)
+	}
+	dec := NewItemDecoder(bytes.NewReader(buf.Bytes()))
+	var got []Item
+	for {
+		item, err := dec.Decode()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, *item)
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("decoded %+v", got)
+	}
+	js, _ := json.Marshal(want)
+	if buf.Len() >= len(js)/2 {
+		t.Errorf("%d bytes encoded, %d bytes of JSON", buf.Len(), len(js))
+	}
+}
+
+func TestItemDecoderErrors(t *testing.T) {
+	var buf bytes.Buffer
+	enc := NewItemEncoder(&buf)
+	enc.Write(Item{Type: 1, Value: "abc"})
+	enc.Flush()
+	data := buf.Bytes()
+	for _, bad := range [][]byte{
+		[]byte("LEXJ\x01"),
+		data[:3],
+		data[:len(data)-1],
+		append(append([]byte(nil), data[:5]...), recItem, 3, 0, 0, 0),
+		[]byte("LEXI\x01\x03\xff\xff\xff\xff\xff\xff\xff\xff\x7f"),
+		[]byte("LEXI\x01\x03\xff\xff\xff\xff\xff\xff\xff\xff\x01"),
+	} {
+		dec := NewItemDecoder(bytes.NewReader(bad))
+		_, err := dec.Decode()
+		if !errors.Is(err, ErrBinaryFormat) {
+			t.Errorf("%q: %v", bad, err)
+		}
+		if dec.Next().Type != ItemEOF || dec.Err() == nil {
+			t.Errorf("%q: stream error %v", bad, dec.Err())
+		}
+	}
+	future := append([]byte("LEXI\x02"), 99, 2, 'x', 'y')
+	future = append(future, data[5:]...)
+	dec := NewItemDecoder(bytes.NewReader(future))
+	if item := dec.Next(); item.Value != "abc" || dec.Next().Type != ItemEOF || dec.Err() != nil {
+		t.Errorf("unknown record not skipped: %+v %v", item, dec.Err())
+	}
+	buf.Reset()
+	NewItemEncoder(&buf).Flush()
+	if _, err := NewItemDecoder(&buf).Decode(); err != io.EOF {
+		t.Errorf("empty stream: %v", err)
+	}
+}
@@ -0,0 +1,61 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+)
+
+// The byte-oriented scanner API treats input as raw bytes, without UTF-8
+// decoding.  It is used for protocols which mix text with binary data, such
+// as length-prefixed frames following a textual header.
+
+// AdvanceByte adds one byte of input to the current lexeme and returns it.
+// AdvanceByte returns false if there is no input.  Backup may be called
+// after AdvanceByte to return the byte to the input.
+func (l *Lexer) AdvanceByte() (byte, bool) {
+	if l.pos >= len(l.input) {
+		l.width = 0
+		return 0, false
+	}
+	c := l.input[l.pos]
+	l.record(Call{Op: "advancebyte"})
+	l.pos++
+	l.last, l.width = rune(c), 1
+	l.unread, l.backed = false, false
+	return c, true
+}
+
+// AcceptByte advances the lexer if the next byte is in valid.
+func (l *Lexer) AcceptByte(valid string) (ok bool) {
+	if l.pos < len(l.input) && strings.IndexByte(valid, l.input[l.pos]) >= 0 {
+		l.AdvanceByte()
+		ok = true
+	}
+	if l.cover != nil {
+		l.cover.branch(l, "AcceptByte", valid, ok)
+	}
+	return
+}
+
+// AcceptByteRun advances l's position as long as the next byte is in valid
+// and returns the number of bytes accepted.
+func (l *Lexer) AcceptByteRun(valid string) (n int) {
+	n = l.skipASCII(valid)
+	for l.AcceptByte(valid) {
+		n++
+	}
+	return n
+}
+
+// AcceptBytes advances l's position over the next n bytes, regardless of
+// their value.  If fewer than n bytes remain AcceptBytes returns false and
+// l's position is unchanged.
+func (l *Lexer) AcceptBytes(n int) bool {
+	if n < 0 || len(l.input)-l.pos < n {
+		return false
+	}
+	return l.acceptString(l.input[l.pos : l.pos+n])
+}
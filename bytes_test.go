@@ -0,0 +1,60 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strconv"
+	"testing"
+)
+
+func TestByteScanning(t *testing.T) {
+	const (
+		itemLength ItemType = iota + 1
+		itemData
+	)
+	var lexFrame StateFn
+	lexFrame = func(l *Lexer) StateFn {
+		if l.AcceptByteRun("0123456789") == 0 {
+			return nil
+		}
+		n, _ := strconv.Atoi(l.Current())
+		l.Emit(itemLength)
+		if !l.AcceptByte(":") {
+			return l.Errorf("expected ':'")
+		}
+		l.Ignore()
+		if !l.AcceptBytes(n) {
+			return l.Errorf("short frame")
+		}
+		l.Emit(itemData)
+		return lexFrame
+	}
+	lex := New(lexFrame, "3:\xff\xfe\x002:é7:ab")
+	var values []string
+	for item := lex.Next(); item.Type != ItemEOF; item = lex.Next() {
+		values = append(values, item.Value)
+	}
+	want := []string{"3", "\xff\xfe\x00", "2", "é", "7", "short frame"}
+	if len(values) != len(want) {
+		t.Fatalf("items %q", values)
+	}
+	for i := range want {
+		if values[i] != want[i] {
+			t.Errorf("item %d %q (expected %q)", i, values[i], want[i])
+		}
+	}
+
+	lex = New(nilState, "\xc3")
+	if c, ok := lex.AdvanceByte(); !ok || c != 0xc3 || lex.Pos() != 1 {
+		t.Errorf("advanced %x %v to %d", c, ok, lex.Pos())
+	}
+	lex.Backup()
+	if _, ok := lex.AdvanceByte(); !ok || lex.Pos() != 1 {
+		t.Errorf("position %d", lex.Pos())
+	}
+	if _, ok := lex.AdvanceByte(); ok {
+		t.Errorf("advanced past the end of input")
+	}
+}
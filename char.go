@@ -0,0 +1,97 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+	"unicode/utf8"
+)
+
+// ScanCharLiteral advances the lexer over a character literal delimited by
+// quote, such as 'a' or '\n', which may contain escape sequences described by
+// escapes, and returns the character it denotes.  Octal and hexadecimal
+// escapes denote the character with the value of their byte, as in Go.  The
+// literal must denote exactly one character and may not contain a newline.
+//
+// If the input does not begin with a valid literal the lexer does not
+// advance and a *LexError is returned whose Pos and End span the offending
+// text: the invalid escape sequence, the characters following the first in
+// a literal with too many, or the literal itself if it is empty or
+// unterminated.  The error can be emitted with ErrorAt.
+//
+//	r, err := l.ScanCharLiteral('\'', lexer.GoEscapes)
+//	if err != nil {
+//		return l.ErrorAt(err.(*lexer.LexError))
+//	}
+//	l.EmitValue(itemChar, string(r))
+func (l *Lexer) ScanCharLiteral(quote rune, escapes EscapeRules) (rune, error) {
+	c := l.Checkpoint()
+	open := l.pos
+	if !l.AcceptString(string(quote)) {
+		return 0, l.charError(c, "expected character literal", open, open)
+	}
+	var v rune
+	n, extra := 0, 0
+	for {
+		at := l.pos
+		if s := l.input[at:]; strings.HasPrefix(s, `\`) {
+			m, msg := escapes.decode(s, func(r rune, byteval bool) { v = r })
+			if msg != "" {
+				return 0, l.charError(c, msg, at, at+escapeExtent(s))
+			}
+			l.acceptString(s[:m])
+		} else {
+			r, w := l.Advance()
+			switch {
+			case IsEOF(r, w) || r == '\n':
+				return 0, l.charError(c, "unterminated character literal", open, at)
+			case IsInvalid(r, w):
+				return 0, l.charError(c, "invalid utf-8 rune in character literal", at, at+w)
+			case r == quote:
+				switch {
+				case n == 0:
+					return 0, l.charError(c, "empty character literal", open, l.pos)
+				case n > 1:
+					return 0, l.charError(c, "too many characters in character literal", extra, at)
+				}
+				return v, nil
+			}
+			v = r
+		}
+		if n++; n == 2 {
+			extra = at
+		}
+	}
+}
+
+// charError restores l to c and returns the error msg about the input
+// between pos and end.
+func (l *Lexer) charError(c Checkpoint, msg string, pos, end int) error {
+	l.Restore(c)
+	return l.lexError(msg, l.offset(pos), l.offset(end))
+}
+
+// escapeExtent returns the length of the malformed escape sequence at the
+// beginning of s: the backslash, the character following it and the digits
+// following that of a numeric escape.
+func escapeExtent(s string) int {
+	if len(s) < 2 {
+		return len(s)
+	}
+	base, n := 0, 2
+	switch s[1] {
+	case 'x', 'u', 'U':
+		base = 16
+	case '0', '1', '2', '3', '4', '5', '6', '7':
+		base = 8
+	default:
+		_, w := utf8.DecodeRuneInString(s[1:])
+		return 1 + w
+	}
+	for n < len(s) && n < 10 && digitValue(s[n]) < base {
+		n++
+	}
+	return n
+}
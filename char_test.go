@@ -0,0 +1,77 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func TestScanCharLiteral(t *testing.T) {
+	for _, test := range []struct {
+		input     string
+		v         rune
+		pos       int
+		err       string
+		errLexeme string
+	}{
+		{`'a' x`, 'a', 3, "", ""},
+		{`'é'`, 'é', 4, "", ""},
+		{`'\n'`, '\n', 4, "", ""},
+		{`'\''`, '\'', 4, "", ""},
+		{`'\xff'`, 0xff, 6, "", ""},
+		{`'\u00e9'`, 'é', 8, "", ""},
+		{`a`, 0, 0, "expected character literal", ""},
+		{`''`, 0, 0, "empty character literal", `''`},
+		{`'ab\n'`, 0, 0, "too many characters in character literal", `b\n`},
+		{`'\q'`, 0, 0, "unknown escape sequence", `\q`},
+		{`'\x4'`, 0, 0, "invalid hexadecimal escape", `\x4`},
+		{`'\ud800'`, 0, 0, "escape sequence is invalid unicode code point", `\ud800`},
+		{"'a\n'", 0, 0, "unterminated character literal", `'a`},
+		{`'a`, 0, 0, "unterminated character literal", `'a`},
+		{"'\xff'", 0, 0, "invalid utf-8 rune in character literal", "\xff"},
+	} {
+		lex := New(nilState, test.input)
+		v, err := lex.ScanCharLiteral('\'', GoEscapes)
+		if test.err != "" {
+			lerr, ok := err.(*LexError)
+			if !ok || lerr.Msg != test.err || lerr.Lexeme != test.errLexeme || lex.Pos() != 0 {
+				t.Errorf("%s: error %#v at %d (expected %q)", test.input, err, lex.Pos(), test.err)
+			}
+			continue
+		}
+		if err != nil || v != test.v || lex.Pos() != test.pos {
+			t.Errorf("%s: %q %v at %d (expected %q at %d)", test.input, v, err, lex.Pos(), test.v, test.pos)
+		}
+	}
+}
+
+func TestErrorAt(t *testing.T) {
+	var rec Recording
+	lexChar := func(l *Lexer) StateFn {
+		l.AcceptRun(" ")
+		l.Ignore()
+		if _, err := l.ScanCharLiteral('\'', GoEscapes); err != nil {
+			return l.ErrorAt(err.(*LexError))
+		}
+		l.Emit(1)
+		return nil
+	}
+	lex := New(lexChar, ` 'ab'`, WithRecording(&rec))
+	item := lex.Next()
+	span := item.Span()
+	if item.Type != ItemError || item.Pos != 3 || span != (Span{3, 4}) || item.Value != "too many characters in character literal" {
+		t.Fatalf("error %v span %v", item, span)
+	}
+	if lex.Next().Type != ItemEOF {
+		t.Errorf("not stopped")
+	}
+	replay, err := rec.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if item := replay.Next(); item.Type != ItemError || item.Pos != 3 || item.Span() != span {
+		t.Errorf("replayed error %v span %v", item, item.Span())
+	}
+}
@@ -0,0 +1,240 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Command lexrepl lexes lines typed interactively and prints their items, to
+shorten the loop of editing and testing the token definitions of a lexer.
+
+	lexrepl -lang json
+	lexrepl -rules scanner.l
+	lexrepl -rules grammar.ebnf -start Expression
+
+The lexer is a registered language, given by -lang, or is built from a rules
+file, given by -rules, whose format is chosen by its extension: a flex
+scanner (.l or .lex), a TextMate grammar (.json) or an EBNF grammar (.ebnf),
+for which -start names the start production.  A rules file is loaded again
+whenever it changes, so that rules can be edited while lexrepl runs.
+
+Each input line is lexed separately and its items are printed with their
+column, type and value.  Errors are shown with the line underlined.  Lines
+beginning with a colon are commands.
+
+	:lang name   switch to a registered language
+	:rules file  switch to a rules file
+	:reload      load the rules file again
+	:langs       list the registered languages
+	:quit        exit
+*/
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/bmatsuo/go-lexer"
+	_ "github.com/bmatsuo/go-lexer/presets/csvlex"
+	_ "github.com/bmatsuo/go-lexer/presets/exprlex"
+	_ "github.com/bmatsuo/go-lexer/presets/httplex"
+	_ "github.com/bmatsuo/go-lexer/presets/inilex"
+	_ "github.com/bmatsuo/go-lexer/presets/jsonlex"
+	_ "github.com/bmatsuo/go-lexer/presets/mdlex"
+	_ "github.com/bmatsuo/go-lexer/presets/shlex"
+	_ "github.com/bmatsuo/go-lexer/presets/sqllex"
+	_ "github.com/bmatsuo/go-lexer/presets/tmpllex"
+	"github.com/bmatsuo/go-lexer/rules"
+	"github.com/bmatsuo/go-lexer/rules/ebnf"
+	"github.com/bmatsuo/go-lexer/rules/flex"
+	"github.com/bmatsuo/go-lexer/rules/textmate"
+)
+
+func main() {
+	s := new(session)
+	flag.StringVar(&s.lang, "lang", "", "registered language to lex")
+	flag.StringVar(&s.file, "rules", "", "rules file to lex with")
+	flag.StringVar(&s.start, "start", "", "start production of an EBNF grammar")
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("lexrepl: ")
+	if flag.NArg() > 0 || (s.lang == "") == (s.file == "") {
+		fmt.Fprintln(os.Stderr, "usage: lexrepl (-lang name | -rules file [-start production])")
+		os.Exit(2)
+	}
+	if err := s.load(); err != nil {
+		log.Fatal(err)
+	}
+	s.run(os.Stdin, os.Stdout)
+}
+
+// session is the state of the REPL.
+type session struct {
+	lang  string // registered language, or
+	file  string // rules file
+	start string // start production of an EBNF rules file
+
+	newState func() lexer.StateFn
+	names    func(lexer.ItemType) string
+	modTime  time.Time
+}
+
+// load prepares the lexer of the language or rules file of s.
+func (s *session) load() error {
+	if s.file == "" {
+		lang := lexer.Lookup(s.lang)
+		if lang == nil {
+			return fmt.Errorf("unknown language %q", s.lang)
+		}
+		s.newState = lang.Start
+		s.names = lexer.ItemType.String
+		return nil
+	}
+	info, err := os.Stat(s.file)
+	if err != nil {
+		return err
+	}
+	f, err := os.Open(s.file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var b *rules.Builder
+	var names []string
+	switch ext := strings.ToLower(filepath.Ext(s.file)); ext {
+	case ".l", ".lex":
+		spec, err := flex.Parse(f)
+		if err != nil {
+			return err
+		}
+		if b, err = spec.Builder(nil); err != nil {
+			return err
+		}
+		names = spec.Tokens()
+	case ".json":
+		g, err := textmate.Import(f)
+		if err != nil {
+			return err
+		}
+		b, names = g.Builder(), g.Scopes
+	case ".ebnf":
+		if s.start == "" {
+			return fmt.Errorf("%s: -start is required for EBNF grammars", s.file)
+		}
+		g, err := ebnf.Parse(s.file, f)
+		if err != nil {
+			return err
+		}
+		terms, err := g.Terminals(s.start)
+		if err != nil {
+			return err
+		}
+		if b, err = g.Builder(s.start, nil); err != nil {
+			return err
+		}
+		for _, term := range terms {
+			names = append(names, term.Name)
+		}
+	default:
+		return fmt.Errorf("%s: unknown rules format %q", s.file, ext)
+	}
+	start, err := b.Build()
+	if err != nil {
+		return err
+	}
+	s.newState = func() lexer.StateFn { return start }
+	s.names = func(t lexer.ItemType) string {
+		if t >= 1 && int(t) <= len(names) {
+			return names[t-1]
+		}
+		return t.String()
+	}
+	s.modTime = info.ModTime()
+	return nil
+}
+
+// stale reports whether the rules file of s changed since it was loaded.
+func (s *session) stale() bool {
+	if s.file == "" {
+		return false
+	}
+	info, err := os.Stat(s.file)
+	return err == nil && !info.ModTime().Equal(s.modTime)
+}
+
+// run reads lines from r until it ends or the :quit command, writing
+// prompts and output to w.
+func (s *session) run(r io.Reader, w io.Writer) {
+	scan := bufio.NewScanner(r)
+	for fmt.Fprint(w, "> "); scan.Scan(); fmt.Fprint(w, "> ") {
+		line := scan.Text()
+		if strings.HasPrefix(line, ":") {
+			if !s.command(w, strings.Fields(line[1:])) {
+				return
+			}
+			continue
+		}
+		if s.stale() {
+			if err := s.load(); err != nil {
+				fmt.Fprintln(w, err)
+				continue
+			}
+			fmt.Fprintf(w, "reloaded %s\n", s.file)
+		}
+		s.lex(w, line)
+	}
+	fmt.Fprintln(w)
+}
+
+// command executes a command and reports whether the REPL continues.
+func (s *session) command(w io.Writer, args []string) bool {
+	if len(args) == 0 {
+		args = []string{""}
+	}
+	reload := func(lang, file string) {
+		old := *s
+		s.lang, s.file = lang, file
+		if err := s.load(); err != nil {
+			*s = old
+			fmt.Fprintln(w, err)
+		}
+	}
+	switch {
+	case args[0] == "quit" || args[0] == "q":
+		return false
+	case args[0] == "langs":
+		for _, lang := range lexer.Languages() {
+			fmt.Fprintf(w, "%s\t%s\n", lang.Name, strings.Join(lang.Aliases, " "))
+		}
+	case args[0] == "reload" && len(args) == 1 && s.file != "":
+		reload("", s.file)
+	case args[0] == "lang" && len(args) == 2:
+		reload(args[1], "")
+	case args[0] == "rules" && len(args) == 2:
+		reload("", args[1])
+	default:
+		fmt.Fprintln(w, "commands: :lang name, :rules file, :reload, :langs, :quit")
+	}
+	return true
+}
+
+// lex prints the items of line.
+func (s *session) lex(w io.Writer, line string) {
+	lex := lexer.New(s.newState(), line)
+	for item := lex.Next(); item.Type != lexer.ItemEOF; item = lex.Next() {
+		if err, ok := item.Payload.(*lexer.LexError); ok {
+			opts := lexer.RenderOptions{}
+			if item.Type == lexer.ItemWarning {
+				opts.Severity = "warning"
+			}
+			fmt.Fprint(w, lexer.RenderError(err, opts))
+			continue
+		}
+		fmt.Fprintf(w, "%4d  %-16s %q\n", item.Pos+1, s.names(item.Type), item.Value)
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSessionRules(t *testing.T) {
+	dir, err := ioutil.TempDir("", "lexrepl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "scanner.l")
+	spec := "%%\n[0-9]+ return NUMBER;\n[a-z]+ return WORD;\n\" \" ;\n"
+	if err := ioutil.WriteFile(file, []byte(spec), 0644); err != nil {
+		t.Fatal(err)
+	}
+	s := &session{file: file}
+	if err := s.load(); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	s.run(strings.NewReader("ab 12\n:lang json\n[1]\n:lang nope\n:quit\nignored\n"), &out)
+	for _, want := range []string{
+		`   1  WORD             "ab"`,
+		`   4  NUMBER           "12"`,
+		`   1  ItemType(2)      "["`,
+		`unknown language "nope"`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("output lacks %q:\n%s", want, out.String())
+		}
+	}
+	if strings.Contains(out.String(), "ignored") {
+		t.Errorf("input read after :quit:\n%s", out.String())
+	}
+}
+
+func TestSessionError(t *testing.T) {
+	s := &session{lang: "json"}
+	if err := s.load(); err != nil {
+		t.Fatal(err)
+	}
+	var out bytes.Buffer
+	s.lex(&out, "[@]")
+	if !strings.Contains(out.String(), "error: ") || !strings.Contains(out.String(), "1 | [@]") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+}
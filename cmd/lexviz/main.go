@@ -0,0 +1,163 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Command lexviz renders a file lexed by a registered lexer as annotated HTML,
+to help debug lexers visually and to demonstrate languages built on package
+lexer.
+
+	lexviz [-lang name] [-o file.html] [file]
+
+Each item is colored by its type and shows its type, span and value when
+the pointer hovers over it.  Errors are underlined.  Text ignored by the
+lexer is shown uncolored.  Without a file lexviz reads standard input.  The
+language is chosen by -lang or detected as by lexer.Sniff.  The languages
+of the presets are available.
+*/
+package main
+
+import (
+	"flag"
+	"fmt"
+	"html/template"
+	"io"
+	"io/ioutil"
+	"log"
+	"os"
+
+	"github.com/bmatsuo/go-lexer"
+	_ "github.com/bmatsuo/go-lexer/presets/csvlex"
+	_ "github.com/bmatsuo/go-lexer/presets/exprlex"
+	_ "github.com/bmatsuo/go-lexer/presets/httplex"
+	_ "github.com/bmatsuo/go-lexer/presets/inilex"
+	_ "github.com/bmatsuo/go-lexer/presets/jsonlex"
+	_ "github.com/bmatsuo/go-lexer/presets/mdlex"
+	_ "github.com/bmatsuo/go-lexer/presets/shlex"
+	_ "github.com/bmatsuo/go-lexer/presets/sqllex"
+	_ "github.com/bmatsuo/go-lexer/presets/tmpllex"
+)
+
+func main() {
+	langName := flag.String("lang", "", "language of the input")
+	out := flag.String("o", "", "output file (default standard output)")
+	flag.Parse()
+	log.SetFlags(0)
+	log.SetPrefix("lexviz: ")
+
+	name, in := "stdin", io.Reader(os.Stdin)
+	switch flag.NArg() {
+	case 0:
+	case 1:
+		name = flag.Arg(0)
+		f, err := os.Open(name)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		in = f
+	default:
+		flag.Usage()
+		os.Exit(2)
+	}
+	src, err := ioutil.ReadAll(in)
+	if err != nil {
+		log.Fatal(err)
+	}
+	var lang *lexer.Language
+	if *langName != "" {
+		lang = lexer.Lookup(*langName)
+	} else {
+		lang = lexer.Sniff(name, string(src))
+	}
+	if lang == nil {
+		log.Fatal("unknown language; use -lang")
+	}
+	w := io.Writer(os.Stdout)
+	if *out != "" {
+		f, err := os.Create(*out)
+		if err != nil {
+			log.Fatal(err)
+		}
+		defer f.Close()
+		w = f
+	}
+	lex := lang.New(string(src), lexer.WithName(name), lexer.WithLineTracking())
+	if err := render(w, name+" ("+lang.Name+")", lex); err != nil {
+		log.Fatal(err)
+	}
+}
+
+// span is a run of the input, which is the text of an item if Item is not
+// nil.
+type span struct {
+	Text  string
+	Item  *lexer.Item
+	Class string
+	Title string
+}
+
+// render writes the HTML page showing the items of lex.
+func render(w io.Writer, title string, lex *lexer.Lexer) error {
+	src := lex.Source()
+	var spans []span
+	last := 0
+	for item := lex.Next(); item.Type != lexer.ItemEOF; item = lex.Next() {
+		pos, end := item.Pos, item.Pos+len(item.Value)
+		class := fmt.Sprintf("t%d", int(item.Type)%12)
+		if err, ok := item.Payload.(*lexer.LexError); ok {
+			pos, end, class = err.Pos, err.End, "err"
+		}
+		title := fmt.Sprintf("%v [%d:%d]", item.Type, pos, end)
+		if end > len(src) || class != "err" && src[pos:end] != item.Value {
+			end = pos
+		}
+		if pos < last {
+			continue
+		}
+		if pos > last {
+			spans = append(spans, span{Text: src[last:pos]})
+		}
+		if item.Line > 0 {
+			title += fmt.Sprintf(" %d:%d", item.Line, item.Column)
+		}
+		title += fmt.Sprintf(" %q", item.Value)
+		text := src[pos:end]
+		if text == "" && class == "err" {
+			text = "‸"
+		}
+		spans = append(spans, span{Text: text, Item: item, Class: class, Title: title})
+		last = end
+	}
+	if last < len(src) {
+		spans = append(spans, span{Text: src[last:]})
+	}
+	return page.Execute(w, struct {
+		Title string
+		Spans []span
+	}{title, spans})
+}
+
+var page = template.Must(template.New("page").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<style>
+body { font-family: sans-serif; }
+pre { font-family: monospace; line-height: 1.4; }
+pre span { border-radius: 2px; }
+pre span:hover { outline: 1px solid #444; }
+.t0 { background: #fde2e2; } .t1 { background: #e2f0fd; } .t2 { background: #e5fde2; }
+.t3 { background: #fdf6e2; } .t4 { background: #efe2fd; } .t5 { background: #e2fdf8; }
+.t6 { background: #fde2f5; } .t7 { background: #ecfde2; } .t8 { background: #e2e6fd; }
+.t9 { background: #fdeee2; } .t10 { background: #e2fdec; } .t11 { background: #f2f2f2; }
+.err { text-decoration: underline wavy red; background: #ffd0d0; }
+</style>
+</head>
+<body>
+<h1>{{.Title}}</h1>
+<pre>{{range .Spans}}{{if .Item}}<span class="{{.Class}}" title="{{.Title}}">{{.Text}}</span>{{else}}{{.Text}}{{end}}{{end}}</pre>
+</body>
+</html>
+`))
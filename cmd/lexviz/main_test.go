@@ -0,0 +1,33 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+func TestRender(t *testing.T) {
+	lex := lexer.Lookup("json").New(`{"a": <1}`, lexer.WithLineTracking())
+	var buf bytes.Buffer
+	if err := render(&buf, "test", lex); err != nil {
+		t.Fatal(err)
+	}
+	out := buf.String()
+	for _, want := range []string{
+		`<title>test</title>`,
+		`title="`,
+		`&#34;a&#34;</span>`,
+		`class="err"`,
+		` 1:1 &#34;{&#34;"`,
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("output lacks %q:\n%s", want, out)
+		}
+	}
+}
@@ -0,0 +1,220 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package combinator composes small scanning functions into lexer.StateFn values.
+
+A Matcher attempts to match input at the current position of a lexer.  The
+primitive matchers wrap the Accept family of lexer methods and the combinators
+(Seq, Choice, Optional, Repeat and Until) build larger matchers out of smaller
+ones.  Matchers that fail leave the lexer where they found it, so they may be
+freely nested.
+
+Emit, Skip and Loop turn matchers into state functions.
+
+	ident := combinator.Seq(
+		combinator.Range(unicode.Letter),
+		combinator.Repeat(combinator.Func(isIdentRune), 0, -1))
+	start := combinator.Loop(
+		combinator.Token{Type: itemIdent, Match: ident},
+		combinator.Token{Match: combinator.Run(" \t\n"), Skip: true})
+*/
+package combinator
+
+import (
+	"unicode"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// A Matcher advances l over a prefix of its remaining input and returns true.
+// If the input does not match a Matcher returns false and the position of l
+// is unchanged.
+type Matcher func(l *lexer.Lexer) bool
+
+// String matches the literal string s.
+func String(s string) Matcher {
+	return func(l *lexer.Lexer) bool {
+		return l.AcceptString(s)
+	}
+}
+
+// Rune matches a single rune in valid.
+func Rune(valid string) Matcher {
+	return func(l *lexer.Lexer) bool {
+		return l.Accept(valid)
+	}
+}
+
+// Run matches one or more runes in valid.
+func Run(valid string) Matcher {
+	return func(l *lexer.Lexer) bool {
+		return l.AcceptRun(valid) > 0
+	}
+}
+
+// Range matches a single rune in tab.
+func Range(tab *unicode.RangeTable) Matcher {
+	return func(l *lexer.Lexer) bool {
+		return l.AcceptRange(tab)
+	}
+}
+
+// Func matches a single rune for which fn returns true.
+func Func(fn func(rune) bool) Matcher {
+	return func(l *lexer.Lexer) bool {
+		return l.AcceptFunc(fn)
+	}
+}
+
+// Seq matches each of ms in order.  If any of ms fails to match the lexer is
+// restored to the position it had before Seq was called.
+func Seq(ms ...Matcher) Matcher {
+	return func(l *lexer.Lexer) bool {
+		c := l.Checkpoint()
+		for _, m := range ms {
+			if !m(l) {
+				l.Restore(c)
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// Choice matches the first of ms which matches the input.
+func Choice(ms ...Matcher) Matcher {
+	return func(l *lexer.Lexer) bool {
+		for _, m := range ms {
+			if m(l) {
+				return true
+			}
+		}
+		return false
+	}
+}
+
+// Optional matches m zero or one times.  It always succeeds.
+func Optional(m Matcher) Matcher {
+	return func(l *lexer.Lexer) bool {
+		m(l)
+		return true
+	}
+}
+
+// Repeat matches m at least min times and at most max times.  A negative max
+// means there is no upper bound.  Repetition stops early if m succeeds without
+// advancing the lexer.
+func Repeat(m Matcher, min, max int) Matcher {
+	return func(l *lexer.Lexer) bool {
+		c := l.Checkpoint()
+		n := 0
+		for max < 0 || n < max {
+			pos := l.Pos()
+			if !m(l) {
+				break
+			}
+			n++
+			if l.Pos() == pos {
+				break
+			}
+		}
+		if n < min {
+			l.Restore(c)
+			return false
+		}
+		return true
+	}
+}
+
+// Until advances the lexer up to, but not including, the next input that
+// matches m.  Until fails if the input is exhausted before m matches.
+func Until(m Matcher) Matcher {
+	return func(l *lexer.Lexer) bool {
+		c := l.Checkpoint()
+		for {
+			before := l.Checkpoint()
+			if m(l) {
+				l.Restore(before)
+				return true
+			}
+			r, n := l.Advance()
+			if lexer.IsEOF(r, n) || lexer.IsInvalid(r, n) {
+				l.Restore(c)
+				return false
+			}
+		}
+	}
+}
+
+// Emit returns a StateFn which emits an item of type t when m matches and
+// returns next.  If m does not match the StateFn emits an error.
+func Emit(t lexer.ItemType, m Matcher, next lexer.StateFn) lexer.StateFn {
+	return func(l *lexer.Lexer) lexer.StateFn {
+		if !m(l) {
+			return unexpected(l)
+		}
+		l.Emit(t)
+		return next
+	}
+}
+
+// Skip returns a StateFn which ignores input matching m and returns next.  If
+// m does not match the StateFn emits an error.
+func Skip(m Matcher, next lexer.StateFn) lexer.StateFn {
+	return func(l *lexer.Lexer) lexer.StateFn {
+		if !m(l) {
+			return unexpected(l)
+		}
+		l.Ignore()
+		return next
+	}
+}
+
+// Token pairs a Matcher with the type of item emitted when it matches.  When
+// Skip is true matched input is ignored instead of being emitted.
+type Token struct {
+	Type  lexer.ItemType
+	Match Matcher
+	Skip  bool
+}
+
+// Loop returns a StateFn which repeatedly emits the first of tokens matching
+// the input until all input has been consumed.  Input which no token matches
+// causes an error, as does a token which matches the empty string.
+func Loop(tokens ...Token) lexer.StateFn {
+	var loop lexer.StateFn
+	loop = func(l *lexer.Lexer) lexer.StateFn {
+		if r, n := l.Peek(); lexer.IsEOF(r, n) {
+			return nil
+		}
+		for _, tok := range tokens {
+			if !tok.Match(l) {
+				continue
+			}
+			if l.Pos() == l.Start() {
+				return l.Errorf("empty match")
+			}
+			if tok.Skip {
+				l.Ignore()
+			} else {
+				l.Emit(tok.Type)
+			}
+			return loop
+		}
+		return unexpected(l)
+	}
+	return loop
+}
+
+func unexpected(l *lexer.Lexer) lexer.StateFn {
+	switch r, n := l.Peek(); {
+	case lexer.IsEOF(r, n):
+		return l.Errorf("unexpected EOF")
+	case lexer.IsInvalid(r, n):
+		return l.Errorf("invalid utf-8 rune")
+	default:
+		return l.Errorf("unexpected rune %q", r)
+	}
+}
@@ -0,0 +1,109 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package combinator
+
+import (
+	"testing"
+	"unicode"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+const (
+	itemIdent lexer.ItemType = iota
+	itemNumber
+	itemComment
+)
+
+func lexAll(start lexer.StateFn, input string) []*lexer.Item {
+	lex := lexer.New(start, input)
+	var items []*lexer.Item
+	for {
+		item := lex.Next()
+		items = append(items, item)
+		if item.Type == lexer.ItemEOF || item.Type == lexer.ItemError {
+			return items
+		}
+	}
+}
+
+func TestMatchers(t *testing.T) {
+	for i, test := range []struct {
+		m     Matcher
+		input string
+		ok    bool
+		pos   int
+	}{
+		{String("abc"), "abcd", true, 3},
+		{String("abc"), "abd", false, 0},
+		{Seq(String("a"), Run("b")), "abbbc", true, 4},
+		{Seq(String("a"), Run("b")), "ac", false, 0},
+		{Choice(String("x"), String("a")), "ab", true, 1},
+		{Choice(String("x"), String("y")), "ab", false, 0},
+		{Optional(String("x")), "ab", true, 0},
+		{Repeat(String("ab"), 2, 3), "ababababab", true, 6},
+		{Repeat(String("ab"), 2, 3), "abx", false, 0},
+		{Repeat(Optional(String("x")), 0, -1), "ab", true, 0},
+		{Until(String("*/")), "abc*/", true, 3},
+		{Until(String("*/")), "abc*", false, 0},
+		{Seq(Range(unicode.Letter), Rune("1")), "a1", true, 2},
+		{Func(unicode.IsDigit), "\xff", false, 0},
+	} {
+		lex := lexer.New(func(*lexer.Lexer) lexer.StateFn { return nil }, test.input)
+		if ok := test.m(lex); ok != test.ok {
+			t.Errorf("test %d: match %v (expected %v)", i, ok, test.ok)
+		}
+		if lex.Pos() != test.pos {
+			t.Errorf("test %d: position %d (expected %d)", i, lex.Pos(), test.pos)
+		}
+	}
+}
+
+func TestLoop(t *testing.T) {
+	ident := Seq(Range(unicode.Letter), Repeat(Range(unicode.Letter), 0, -1))
+	comment := Seq(String("/*"), Until(String("*/")), String("*/"))
+	start := Loop(
+		Token{Type: itemIdent, Match: ident},
+		Token{Type: itemNumber, Match: Run("0123456789")},
+		Token{Type: itemComment, Match: comment},
+		Token{Match: Run(" \t\n"), Skip: true},
+	)
+	items := lexAll(start, "abc 123 /* x */ d")
+	expect := []struct {
+		typ lexer.ItemType
+		val string
+	}{
+		{itemIdent, "abc"},
+		{itemNumber, "123"},
+		{itemComment, "/* x */"},
+		{itemIdent, "d"},
+		{lexer.ItemEOF, ""},
+	}
+	if len(items) != len(expect) {
+		t.Fatalf("%d items (expected %d)", len(items), len(expect))
+	}
+	for i, item := range items {
+		if item.Type != expect[i].typ || item.Value != expect[i].val {
+			t.Errorf("item %d: %v %q (expected %v %q)", i, item.Type, item.Value, expect[i].typ, expect[i].val)
+		}
+	}
+
+	items = lexAll(start, "abc ?")
+	if last := items[len(items)-1]; last.Type != lexer.ItemError || last.Pos != 4 {
+		t.Errorf("unexpected final item %v at %d", last, last.Pos)
+	}
+}
+
+func TestEmit(t *testing.T) {
+	start := Skip(Run(" "), Emit(itemNumber, Run("0123456789"), nil))
+	items := lexAll(start, "  42")
+	if len(items) != 2 || items[0].Type != itemNumber || items[0].Value != "42" {
+		t.Errorf("unexpected items %v", items)
+	}
+	items = lexAll(start, "  x")
+	if items[0].Type != lexer.ItemError {
+		t.Errorf("expected error %v", items[0])
+	}
+}
@@ -0,0 +1,156 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// CommentMap associates each comment of a sequence of items with the item
+// following it, in the manner of doc comments, so that documentation
+// generators and linters can find the comments of an item directly.
+//
+//	cm := lexer.MapComments(items,
+//		func(item *lexer.Item) bool { return item.Type == itemComment },
+//		func(item *lexer.Item) bool { return item.Type == itemSpace })
+//	doc := cm.CommentsFor(&items[i])
+type CommentMap struct {
+	comments []Item
+	byPos    map[int][]Item
+	trailing []Item
+}
+
+// MapComments returns the comments of items associated with the nearest
+// following item which is neither a comment nor trivia, such as space.
+// Errors and warnings are trivia.  Either function may be nil.
+func MapComments(items []Item, isComment, isTrivia func(*Item) bool) *CommentMap {
+	cm := &CommentMap{byPos: make(map[int][]Item)}
+	var pending []Item
+	for i := range items {
+		item := &items[i]
+		switch {
+		case item.Type == ItemEOF:
+		case isComment != nil && isComment(item):
+			cm.comments = append(cm.comments, *item)
+			pending = append(pending, *item)
+		case item.Type == ItemError || item.Type == ItemWarning:
+		case isTrivia != nil && isTrivia(item):
+		default:
+			if len(pending) > 0 {
+				cm.byPos[item.Pos] = pending
+				pending = nil
+			}
+		}
+	}
+	cm.trailing = pending
+	return cm
+}
+
+// CommentsFor returns the comments preceding item, in order.  Items are
+// identified by their positions.
+func (cm *CommentMap) CommentsFor(item *Item) []Item {
+	return cm.byPos[item.Pos]
+}
+
+// Comments returns all the comments, in order.
+func (cm *CommentMap) Comments() []Item {
+	return cm.comments
+}
+
+// Trailing returns the comments following the last item which is neither a
+// comment nor trivia.
+func (cm *CommentMap) Trailing() []Item {
+	return cm.trailing
+}
+
+// CommentSyntax describes the forms of comment of a language, which may
+// have several, such as the "--", "#" and "/* */" comments of MySQL.
+type CommentSyntax struct {
+	Line   []string    // openers of comments ending at a newline
+	Block  [][2]string // opener and closer pairs of block comments
+	Nested bool        // block comments nest
+}
+
+// Comments returns the syntax of comments opened by the strings in line and
+// ending before the following newline, and of comments delimited by the
+// pairs of strings in block, which nest if nested is true.
+//
+//	mysql := lexer.Comments([]string{"--", "#"}, [][2]string{{"/*", "*/"}}, false)
+//	if ok, err := mysql.Accept(l); ok {
+//		l.Emit(itemComment)
+//	} else if err != nil {
+//		return l.Errorf("%v", err)
+//	}
+func Comments(line []string, block [][2]string, nested bool) *CommentSyntax {
+	return &CommentSyntax{Line: line, Block: block, Nested: nested}
+}
+
+// Comment syntaxes of common languages.
+var (
+	CComments     = Comments([]string{"//"}, [][2]string{{"/*", "*/"}}, false)
+	ShellComments = Comments([]string{"#"}, nil, false)
+	SQLComments   = Comments([]string{"--"}, [][2]string{{"/*", "*/"}}, false)
+)
+
+// Accept advances l over a comment beginning at its current position and
+// returns true.  A line comment does not include the newline ending it.
+// When several openers match the longest is used.  If no comment begins at
+// the current position Accept returns false.  If a block comment is not
+// closed l does not advance and an error is returned.
+func (cs *CommentSyntax) Accept(l *Lexer) (bool, error) {
+	rest := l.input[l.pos:]
+	open, block := "", -1
+	for _, s := range cs.Line {
+		if len(s) > len(open) && strings.HasPrefix(rest, s) {
+			open = s
+		}
+	}
+	for i, b := range cs.Block {
+		if len(b[0]) > len(open) && strings.HasPrefix(rest, b[0]) {
+			open, block = b[0], i
+		}
+	}
+	switch {
+	case open == "":
+		return false, nil
+	case block < 0:
+		n := strings.IndexByte(rest, '\n')
+		if n < 0 {
+			n = len(rest)
+		}
+		l.acceptString(rest[:n])
+		return true, nil
+	}
+	n := cs.blockLen(rest, cs.Block[block])
+	if n < 0 {
+		return false, fmt.Errorf("unterminated comment")
+	}
+	l.acceptString(rest[:n])
+	return true, nil
+}
+
+// blockLen returns the length of the block comment delimited by b at the
+// beginning of s, or -1 if it is not closed.
+func (cs *CommentSyntax) blockLen(s string, b [2]string) int {
+	open, close := b[0], b[1]
+	depth := 0
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], open) && (depth == 0 || cs.Nested):
+			depth++
+			i += len(open)
+		case strings.HasPrefix(s[i:], close):
+			depth--
+			i += len(close)
+			if depth == 0 {
+				return i
+			}
+		default:
+			i++
+		}
+	}
+	return -1
+}
@@ -0,0 +1,79 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMapComments(t *testing.T) {
+	const (
+		word ItemType = iota
+		comment
+		space
+	)
+	its := []Item{
+		{Type: comment, Pos: 0, Value: "#a"},
+		{Type: space, Pos: 2, Value: "\n"},
+		{Type: comment, Pos: 3, Value: "#b"},
+		{Type: space, Pos: 5, Value: "\n"},
+		{Type: word, Pos: 6, Value: "x"},
+		{Type: space, Pos: 7, Value: " "},
+		{Type: word, Pos: 8, Value: "y"},
+		{Type: ItemError, Pos: 9, Value: "oops"},
+		{Type: comment, Pos: 9, Value: "#c"},
+		{Type: ItemError, Pos: 11, Value: "oops"},
+		{Type: word, Pos: 11, Value: "z"},
+		{Type: comment, Pos: 12, Value: "#d"},
+	}
+	cm := MapComments(its,
+		func(item *Item) bool { return item.Type == comment },
+		func(item *Item) bool { return item.Type == space })
+	values := func(items []Item) (vs []string) {
+		for _, item := range items {
+			vs = append(vs, item.Value)
+		}
+		return vs
+	}
+	for i, want := range map[int][]string{4: {"#a", "#b"}, 6: nil, 10: {"#c"}} {
+		if got := values(cm.CommentsFor(&its[i])); !reflect.DeepEqual(got, want) {
+			t.Errorf("comments for %q: %q", its[i].Value, got)
+		}
+	}
+	if got := values(cm.Comments()); !reflect.DeepEqual(got, []string{"#a", "#b", "#c", "#d"}) {
+		t.Errorf("comments %q", got)
+	}
+	if got := values(cm.Trailing()); !reflect.DeepEqual(got, []string{"#d"}) {
+		t.Errorf("trailing %q", got)
+	}
+}
+
+func TestComments(t *testing.T) {
+	haskell := Comments([]string{"--"}, [][2]string{{"{-", "-}"}}, true)
+	mysql := Comments([]string{"--", "#"}, [][2]string{{"/*", "*/"}}, false)
+	for _, test := range []struct {
+		syntax  *CommentSyntax
+		input   string
+		comment string
+		err     bool
+	}{
+		{mysql, "-- x\ny", "-- x", false},
+		{mysql, "# x", "# x", false},
+		{mysql, "/* a /* b */ c */", "/* a /* b */", false},
+		{mysql, "/* a", "", true},
+		{mysql, "x -- y", "", false},
+		{haskell, "{- a {- b -} c -} d", "{- a {- b -} c -}", false},
+		{haskell, "{- a {- b -}", "", true},
+		{haskell, "--}", "--}", false},
+		{Comments([]string{"/"}, [][2]string{{"//", "\\\\"}}, false), "// x \\\\", "// x \\\\", false},
+	} {
+		l := New(nilState, test.input)
+		ok, err := test.syntax.Accept(l)
+		if ok != (test.comment != "") || (err != nil) != test.err || l.Current() != test.comment {
+			t.Errorf("%q: %v %v %q", test.input, ok, err, l.Current())
+		}
+	}
+}
@@ -0,0 +1,282 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"strconv"
+	"text/tabwriter"
+)
+
+// Coverage records which parts of a lexer are exercised while lexing, so
+// that the authors of a lexer can find the token paths a test corpus never
+// takes, the way code coverage finds untested statements.  Three kinds of
+// points are recorded: the states entered, the outcomes of the Accept calls
+// made in each state, and the labels passed to Mark.  A Coverage is given to
+// a lexer with WithCoverage and may be shared by several lexers, one at a
+// time, to accumulate the coverage of a corpus.
+//
+//	cov := new(lexer.Coverage)
+//	cov.Declare(lexText, lexNumber, lexString)
+//	for _, input := range corpus {
+//		lex := lexer.New(lexText, input, lexer.WithCoverage(cov))
+//		for lex.Next().Type != lexer.ItemEOF {
+//		}
+//	}
+//	fmt.Print(cov.Report())
+//
+// A branch is a call site of Accept, AcceptByte, AcceptString, AcceptFunc,
+// AcceptRange or AcceptRanges, identified by its state, the method and its
+// argument, which is covered once it has both accepted and rejected input.
+// The methods built on them, such as AcceptRun, record the branches of the
+// calls they make.  AdvanceWhile and the methods reading text of the input
+// itself, such as AcceptBytes, record no branches.
+type Coverage struct {
+	states map[string]*stateCoverage
+	order  []string
+	marks  map[string]int
+	labels []string
+	names  funcNames
+}
+
+type stateCoverage struct {
+	entries  int
+	branches map[string]*BranchCoverage
+	order    []string
+}
+
+// WithCoverage records the coverage of the lexer in c.
+func WithCoverage(c *Coverage) Option {
+	return func(l *Lexer) { l.cover = c }
+}
+
+// Declare adds states to c, so that the report lists them even if no lexer
+// enters them.
+func (c *Coverage) Declare(states ...StateFn) {
+	for _, fn := range states {
+		c.state(c.names.name(fn))
+	}
+}
+
+// DeclareMarks adds labels to c, so that the report lists them even if no
+// lexer marks them.
+func (c *Coverage) DeclareMarks(labels ...string) {
+	for _, label := range labels {
+		c.mark(label, 0)
+	}
+}
+
+// Mark records that the lexer reached the point named label, such as a case
+// of a switch in a state function or a rule of a rule-based lexer.  Mark
+// does nothing unless the lexer was created with WithCoverage.
+func (l *Lexer) Mark(label string) {
+	if l.cover != nil {
+		l.cover.mark(label, 1)
+	}
+}
+
+func (c *Coverage) mark(label string, n int) {
+	if c.marks == nil {
+		c.marks = make(map[string]int)
+	}
+	if _, ok := c.marks[label]; !ok {
+		c.labels = append(c.labels, label)
+	}
+	c.marks[label] += n
+}
+
+func (c *Coverage) state(name string) *stateCoverage {
+	if c.states == nil {
+		c.states = make(map[string]*stateCoverage)
+	}
+	s := c.states[name]
+	if s == nil {
+		s = &stateCoverage{branches: make(map[string]*BranchCoverage)}
+		c.states[name] = s
+		c.order = append(c.order, name)
+	}
+	return s
+}
+
+func (c *Coverage) enter(fn StateFn) {
+	c.state(c.names.name(fn)).entries++
+}
+
+// branch records the outcome of a call to method with argument arg made in
+// the current state of l.
+func (c *Coverage) branch(l *Lexer, method, arg string, ok bool) {
+	s := c.state(c.names.name(l.state))
+	call := method
+	if method != "AcceptFunc" && arg != "" {
+		call += "(" + strconv.Quote(arg) + ")"
+	} else if arg != "" {
+		call += "(" + arg + ")"
+	}
+	b := s.branches[call]
+	if b == nil {
+		b = &BranchCoverage{Call: call}
+		s.branches[call] = b
+		s.order = append(s.order, call)
+	}
+	if ok {
+		b.Accepted++
+	} else {
+		b.Rejected++
+	}
+}
+
+// StateCoverage describes the coverage of one state.
+type StateCoverage struct {
+	Name     string
+	Entries  int // number of times the state was entered
+	Branches []BranchCoverage
+}
+
+// BranchCoverage counts the outcomes of the calls made at one branch.
+type BranchCoverage struct {
+	Call     string // method and argument, such as Accept("+-")
+	Accepted int
+	Rejected int
+}
+
+// MarkCoverage counts the calls to Mark with one label.
+type MarkCoverage struct {
+	Label string
+	Hits  int
+}
+
+// CoverageReport is a summary of the coverage recorded by a Coverage.
+type CoverageReport struct {
+	States []StateCoverage // in order of first appearance
+	Marks  []MarkCoverage  // in order of first appearance
+}
+
+// Report returns the coverage recorded in c so far.
+func (c *Coverage) Report() *CoverageReport {
+	rep := new(CoverageReport)
+	for _, name := range c.order {
+		s := c.states[name]
+		sc := StateCoverage{Name: name, Entries: s.entries}
+		for _, call := range s.order {
+			sc.Branches = append(sc.Branches, *s.branches[call])
+		}
+		rep.States = append(rep.States, sc)
+	}
+	for _, label := range c.labels {
+		rep.Marks = append(rep.Marks, MarkCoverage{label, c.marks[label]})
+	}
+	return rep
+}
+
+// Ratio returns the fraction of the points of rep that are covered, between
+// 0 and 1.  Each state and each label is a point, covered once entered or
+// marked, and each branch counts as two points, one for each outcome.  A
+// report without points has a ratio of 1.
+func (rep *CoverageReport) Ratio() float64 {
+	covered, total := rep.count()
+	if total == 0 {
+		return 1
+	}
+	return float64(covered) / float64(total)
+}
+
+func (rep *CoverageReport) count() (covered, total int) {
+	add := func(n int) {
+		total++
+		if n > 0 {
+			covered++
+		}
+	}
+	for _, s := range rep.States {
+		add(s.Entries)
+		for _, b := range s.Branches {
+			add(b.Accepted)
+			add(b.Rejected)
+		}
+	}
+	for _, m := range rep.Marks {
+		add(m.Hits)
+	}
+	return covered, total
+}
+
+// Uncovered describes the points of rep which are not covered, in the order
+// of the report.
+//
+//	state main.lexString never entered
+//	main.lexNumber: Accept("eE") never accepted
+//	mark "rule 3 (literal \"else\")" never reached
+func (rep *CoverageReport) Uncovered() []string {
+	var missed []string
+	for _, s := range rep.States {
+		if s.Entries == 0 {
+			missed = append(missed, fmt.Sprintf("state %s never entered", s.Name))
+		}
+		for _, b := range s.Branches {
+			if b.Accepted == 0 {
+				missed = append(missed, fmt.Sprintf("%s: %s never accepted", s.Name, b.Call))
+			}
+			if b.Rejected == 0 {
+				missed = append(missed, fmt.Sprintf("%s: %s never rejected", s.Name, b.Call))
+			}
+		}
+	}
+	for _, m := range rep.Marks {
+		if m.Hits == 0 {
+			missed = append(missed, fmt.Sprintf("mark %q never reached", m.Label))
+		}
+	}
+	return missed
+}
+
+// String returns the report as tables for display, sorted by name, followed
+// by the points which are not covered.
+//
+//	coverage 83.3% (10 of 12 points)
+//
+//	STATE           ENTRIES  BRANCHES
+//	main.lexNumber  12       3/4
+//	...
+func (rep *CoverageReport) String() string {
+	var buf bytes.Buffer
+	covered, total := rep.count()
+	fmt.Fprintf(&buf, "coverage %.1f%% (%d of %d points)\n\n", 100*rep.Ratio(), covered, total)
+	states := append([]StateCoverage(nil), rep.States...)
+	sort.Slice(states, func(i, j int) bool { return states[i].Name < states[j].Name })
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "STATE\tENTRIES\tBRANCHES\n")
+	for _, s := range states {
+		n := 0
+		for _, b := range s.Branches {
+			if b.Accepted > 0 {
+				n++
+			}
+			if b.Rejected > 0 {
+				n++
+			}
+		}
+		fmt.Fprintf(w, "%s\t%d\t%d/%d\n", s.Name, s.Entries, n, 2*len(s.Branches))
+	}
+	w.Flush()
+	if len(rep.Marks) > 0 {
+		marks := append([]MarkCoverage(nil), rep.Marks...)
+		sort.Slice(marks, func(i, j int) bool { return marks[i].Label < marks[j].Label })
+		fmt.Fprintf(&buf, "\n")
+		fmt.Fprintf(w, "MARK\tHITS\n")
+		for _, m := range marks {
+			fmt.Fprintf(w, "%s\t%d\n", m.Label, m.Hits)
+		}
+		w.Flush()
+	}
+	if missed := rep.Uncovered(); len(missed) > 0 {
+		fmt.Fprintf(&buf, "\nUNCOVERED\n")
+		for _, m := range missed {
+			fmt.Fprintf(&buf, "\t%s\n", m)
+		}
+	}
+	return buf.String()
+}
@@ -0,0 +1,96 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCoverage(t *testing.T) {
+	c := new(Coverage)
+	c.Declare(lexWords, nilState)
+	c.DeclareMarks("never")
+	collect(New(lexWords, "ab", WithCoverage(c)))
+	rep := c.Report()
+	if len(rep.States) != 2 {
+		t.Fatalf("states %v", rep.States)
+	}
+	words := rep.States[0]
+	if !strings.HasSuffix(words.Name, ".lexWords") || words.Entries != 2 {
+		t.Errorf("state %v", words)
+	}
+	alpha := `Accept("abcdefghijklmnopqrstuvwxyz")`
+	expect := []BranchCoverage{{`Accept(" ")`, 0, 2}, {alpha, 2, 2}}
+	if !reflect.DeepEqual(words.Branches, expect) {
+		t.Errorf("branches %v (expected %v)", words.Branches, expect)
+	}
+	if rep.States[1].Entries != 0 {
+		t.Errorf("state %v entered", rep.States[1])
+	}
+	missed := rep.Uncovered()
+	if len(missed) != 3 ||
+		!strings.Contains(missed[0], `Accept(" ") never accepted`) ||
+		!strings.HasSuffix(missed[1], ".nilState never entered") ||
+		missed[2] != `mark "never" never reached` {
+		t.Errorf("uncovered %q", missed)
+	}
+	if r := rep.Ratio(); r != 4.0/7 {
+		t.Errorf("ratio %v", r)
+	}
+
+	collect(New(lexWords, " a", WithCoverage(c)))
+	collect(New(nilState, "", WithCoverage(c)))
+	rep = c.Report()
+	if missed := rep.Uncovered(); len(missed) != 1 {
+		t.Errorf("uncovered %q", missed)
+	}
+	s := rep.String()
+	for _, want := range []string{"coverage 85.7% (6 of 7 points)", "STATE", "4/4", "MARK", "UNCOVERED"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("report does not contain %q:\n%s", want, s)
+		}
+	}
+}
+
+func TestCoverageMark(t *testing.T) {
+	c := new(Coverage)
+	mark := func(l *Lexer) StateFn {
+		l.Mark("start")
+		if l.AcceptString("x") {
+			l.Mark("x")
+			l.Emit(1)
+		}
+		return nil
+	}
+	collect(New(mark, "x", WithCoverage(c)))
+	collect(New(mark, "y", WithCoverage(c)))
+	New(mark, "x").Next()
+	rep := c.Report()
+	expect := []MarkCoverage{{"start", 2}, {"x", 1}}
+	if !reflect.DeepEqual(rep.Marks, expect) {
+		t.Errorf("marks %v (expected %v)", rep.Marks, expect)
+	}
+	expectb := []BranchCoverage{{`AcceptString("x")`, 1, 1}}
+	if len(rep.States) != 1 || !reflect.DeepEqual(rep.States[0].Branches, expectb) {
+		t.Errorf("states %v", rep.States)
+	}
+}
+
+// TestCoverageAcceptRun checks that runs over ASCII still record the branch
+// of each rune when coverage is recorded.
+func TestCoverageAcceptRun(t *testing.T) {
+	c := new(Coverage)
+	lex := New(func(l *Lexer) StateFn {
+		l.AcceptRun(digits)
+		return nil
+	}, "123x", WithCoverage(c))
+	lex.Next()
+	b := c.Report().States[0].Branches
+	if len(b) != 1 || b[0].Accepted != 3 || b[0].Rejected != 1 {
+		t.Errorf("branches %v", b)
+	}
+}
@@ -0,0 +1,207 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+const digits = "0123456789"
+
+// AcceptDate advances the lexer over a calendar date in the format
+// YYYY-MM-DD.  If the input does not begin with a valid date the lexer's
+// position is unchanged and false is returned.
+func (l *Lexer) AcceptDate() bool {
+	c := l.Checkpoint()
+	if !l.acceptDate() {
+		l.Restore(c)
+		return false
+	}
+	return true
+}
+
+// AcceptTimeOfDay advances the lexer over a time of day in the format
+// hh:mm[:ss[.fraction]].  If the input does not begin with a valid time the
+// lexer's position is unchanged and false is returned.
+func (l *Lexer) AcceptTimeOfDay() bool {
+	c := l.Checkpoint()
+	if !l.acceptClock(false) {
+		l.Restore(c)
+		return false
+	}
+	return true
+}
+
+// AcceptRFC3339 advances the lexer over a timestamp in the format defined by
+// RFC 3339, as produced by the time package's RFC3339 and RFC3339Nano
+// layouts.  The date and time may be separated by 'T', 't' or a space.  If
+// the input does not begin with a valid timestamp the lexer's position is
+// unchanged and false is returned.
+func (l *Lexer) AcceptRFC3339() bool {
+	c := l.Checkpoint()
+	ok := l.acceptDate() &&
+		l.Accept("Tt ") &&
+		l.acceptClock(true) &&
+		(l.Accept("Zz") || l.Accept("+-") && l.acceptNumber(2, 23) && l.Accept(":") && l.acceptNumber(2, 59))
+	if !ok {
+		l.Restore(c)
+	}
+	return ok
+}
+
+// AcceptDuration advances the lexer over a duration in the format accepted by
+// time.ParseDuration, such as "300ms", "-1.5h" or "2h45m".  If the input does
+// not begin with a valid duration the lexer's position is unchanged and false
+// is returned.
+func (l *Lexer) AcceptDuration() bool {
+	c := l.Checkpoint()
+	l.Accept("+-")
+	if l.AcceptString("0") && !l.peekAny(digits+".") && !l.peekUnit() {
+		return true
+	}
+	l.Restore(c)
+	l.Accept("+-")
+	n := 0
+	for {
+		field := l.Checkpoint()
+		whole := l.AcceptRun(digits)
+		frac := 0
+		if l.Accept(".") {
+			frac = l.AcceptRun(digits)
+		}
+		if whole+frac == 0 || !l.acceptUnit() {
+			l.Restore(field)
+			break
+		}
+		n++
+	}
+	if n == 0 {
+		l.Restore(c)
+		return false
+	}
+	return true
+}
+
+// AcceptISODuration advances the lexer over a duration in the ISO 8601
+// format PnYnMnDTnHnMnS (or PnW), such as "P3DT12H" or "PT0.5S".  If the
+// input does not begin with a valid duration the lexer's position is
+// unchanged and false is returned.
+func (l *Lexer) AcceptISODuration() bool {
+	c := l.Checkpoint()
+	if !l.Accept("P") {
+		return false
+	}
+	if l.acceptISOFields("W") {
+		return true
+	}
+	date := l.acceptISOFields("YMD")
+	timed := false
+	if l.Accept("T") {
+		timed = l.acceptISOFields("HMS")
+		if !timed {
+			l.Restore(c)
+			return false
+		}
+	}
+	if !date && !timed {
+		l.Restore(c)
+		return false
+	}
+	return true
+}
+
+// acceptISOFields accepts a sequence of numeric fields each followed by one
+// of designators, in order.
+func (l *Lexer) acceptISOFields(designators string) bool {
+	n := 0
+	for i := 0; i < len(designators); {
+		c := l.Checkpoint()
+		if l.AcceptRun(digits) == 0 {
+			break
+		}
+		if l.Accept(".,") && l.AcceptRun(digits) == 0 {
+			l.Restore(c)
+			break
+		}
+		j := i
+		for j < len(designators) && !l.AcceptString(designators[j:j+1]) {
+			j++
+		}
+		if j == len(designators) {
+			l.Restore(c)
+			break
+		}
+		i = j + 1
+		n++
+	}
+	return n > 0
+}
+
+func (l *Lexer) acceptDate() bool {
+	return l.acceptNumber(4, 9999) &&
+		l.Accept("-") &&
+		l.acceptRange(2, 1, 12) &&
+		l.Accept("-") &&
+		l.acceptRange(2, 1, 31)
+}
+
+// acceptClock accepts hh:mm:ss[.fraction].  Seconds are optional unless
+// seconds is true.
+func (l *Lexer) acceptClock(seconds bool) bool {
+	if !(l.acceptNumber(2, 23) && l.Accept(":") && l.acceptNumber(2, 59)) {
+		return false
+	}
+	c := l.Checkpoint()
+	if !(l.Accept(":") && l.acceptNumber(2, 60)) {
+		l.Restore(c)
+		return !seconds
+	}
+	c = l.Checkpoint()
+	if l.Accept(".") && l.AcceptRun(digits) == 0 {
+		l.Restore(c)
+	}
+	return true
+}
+
+// acceptNumber accepts exactly n digits with a value no greater than max.
+func (l *Lexer) acceptNumber(n, max int) bool {
+	return l.acceptRange(n, 0, max)
+}
+
+// acceptRange accepts exactly n digits with a value in the range [min, max].
+func (l *Lexer) acceptRange(n, min, max int) bool {
+	v := 0
+	for i := 0; i < n; i++ {
+		if !l.Accept(digits) {
+			return false
+		}
+		r, _ := l.Last()
+		v = 10*v + int(r-'0')
+	}
+	return min <= v && v <= max
+}
+
+// durationUnits lists "ms" before "m" and "s" so that the longest unit is
+// accepted.
+var durationUnits = []string{"ns", "us", "µs", "μs", "ms", "s", "m", "h"}
+
+func (l *Lexer) acceptUnit() bool {
+	for _, u := range durationUnits {
+		if l.AcceptString(u) {
+			return true
+		}
+	}
+	return false
+}
+
+func (l *Lexer) peekUnit() bool {
+	c := l.Checkpoint()
+	ok := l.acceptUnit()
+	l.Restore(c)
+	return ok
+}
+
+func (l *Lexer) peekAny(valid string) bool {
+	c := l.Checkpoint()
+	ok := l.Accept(valid)
+	l.Restore(c)
+	return ok
+}
@@ -0,0 +1,52 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func nilState(*Lexer) StateFn { return nil }
+
+func TestAcceptDateTime(t *testing.T) {
+	for i, test := range []struct {
+		accept func(*Lexer) bool
+		input  string
+		pos    int
+	}{
+		{(*Lexer).AcceptDate, "2012-11-02 x", 10},
+		{(*Lexer).AcceptDate, "2012-13-02", 0},
+		{(*Lexer).AcceptDate, "2012-1-02", 0},
+		{(*Lexer).AcceptTimeOfDay, "22:10", 5},
+		{(*Lexer).AcceptTimeOfDay, "22:10:59.782356 PDT", 15},
+		{(*Lexer).AcceptTimeOfDay, "22:10:", 5},
+		{(*Lexer).AcceptTimeOfDay, "24:00", 0},
+		{(*Lexer).AcceptRFC3339, "2012-11-02T22:10:59Z", 20},
+		{(*Lexer).AcceptRFC3339, "2012-11-02 22:10:59.78-07:00 msg", 28},
+		{(*Lexer).AcceptRFC3339, "2012-11-02T22:10:59", 0},
+		{(*Lexer).AcceptRFC3339, "2012-11-02T22:10Z", 0},
+		{(*Lexer).AcceptDuration, "0", 1},
+		{(*Lexer).AcceptDuration, "0s", 2},
+		{(*Lexer).AcceptDuration, "300ms", 5},
+		{(*Lexer).AcceptDuration, "-1.5h ", 5},
+		{(*Lexer).AcceptDuration, "2h45m30.5s", 10},
+		{(*Lexer).AcceptDuration, "1µs", 4},
+		{(*Lexer).AcceptDuration, "12", 0},
+		{(*Lexer).AcceptDuration, "1h30", 2},
+		{(*Lexer).AcceptDuration, "h", 0},
+		{(*Lexer).AcceptISODuration, "P3DT12H", 7},
+		{(*Lexer).AcceptISODuration, "PT0.5S", 6},
+		{(*Lexer).AcceptISODuration, "P2W", 3},
+		{(*Lexer).AcceptISODuration, "P1Y2M", 5},
+		{(*Lexer).AcceptISODuration, "PT", 0},
+		{(*Lexer).AcceptISODuration, "P", 0},
+	} {
+		lex := New(nilState, test.input)
+		ok := test.accept(lex)
+		if ok != (test.pos > 0) || lex.Pos() != test.pos {
+			t.Errorf("test %d: %q accepted %v at %d (expected %d)", i, test.input, ok, lex.Pos(), test.pos)
+		}
+	}
+}
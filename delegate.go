@@ -0,0 +1,154 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+)
+
+// Delegate lexes the input from the current position to the offset end with
+// the state machine of an embedded language, such as the CSS of an HTML
+// style element, starting at start.  The items of the embedded lexer are
+// emitted by l with their Lang set to lang, so that parsers can tell the
+// ItemType constants of the two languages apart, and with positions in the
+// input of l.  Errors and warnings of the embedded lexer are handled as
+// those of l.  The current lexeme is ignored and lexing continues at end.
+//
+//	end := strings.Index(l.Input()[l.Pos():], "</style>")
+//	l.Delegate("css", csslex.Start, l.Pos()+end)
+//
+// The embedded lexer has the EOF sentinel, limits and error policy of l.
+// Its items are not recorded by WithRecording.
+func (l *Lexer) Delegate(lang string, start StateFn, end int) {
+	if end < l.pos || end > len(l.input) {
+		panic("lexer: Delegate end outside the remaining input")
+	}
+	l.Ignore()
+	child := New(start, l.input[l.pos:end], l.childOptions()...)
+	for {
+		item := child.Next()
+		if item.Type == ItemEOF {
+			break
+		}
+		l.adopt(lang, l.pos, item)
+	}
+	l.pos, l.start, l.width = end, end, 0
+}
+
+// childOptions returns the options of lexers delegated to by l.
+func (l *Lexer) childOptions() []Option {
+	return []Option{WithEOF(l.eof), WithLimits(l.limits), WithErrorPolicy(l.policy)}
+}
+
+// adopt emits item, produced by a lexer delegated to by l over the input of
+// l from offset base, as an item of language lang.  Items of lexers to which
+// the delegated lexer itself delegated keep their language.
+func (l *Lexer) adopt(lang string, base int, item *Item) {
+	cp := *item
+	cp.Pos = l.offset(base + item.Pos)
+	cp.Line, cp.Column = 0, 0
+	if cp.Lang == "" {
+		cp.Lang = lang
+	}
+	if cp.Type != ItemError && cp.Type != ItemWarning {
+		l.enqueue(&cp)
+		return
+	}
+	if err, ok := item.Payload.(*LexError); ok {
+		pos, end := cp.Pos, cp.Pos
+		if err.End > err.Pos {
+			end = l.offset(base + err.End)
+		}
+		cp.Payload = l.lexError(err.Msg, pos, end)
+	}
+	if cp.Type == ItemError {
+		l.errors++
+	}
+	l.enqueue(&cp)
+}
+
+// SwitchRegion lexes the input from the current position with the state
+// machine of an embedded language, starting at child, up to the first
+// occurrence of the delimiter until which is not inside an item of the
+// embedded lexer, such as the "</style>" ending the CSS of an HTML style
+// element but not one in a CSS string.  The items of the embedded lexer are
+// emitted as by Delegate.  SwitchRegion leaves l before the delimiter, with
+// an empty lexeme, and returns true.  If the delimiter is not found the
+// whole input is lexed and SwitchRegion returns false.
+//
+//	if !l.SwitchRegion("css", "</style>", csslex.Start) {
+//		return l.Errorf("unclosed style element")
+//	}
+//	l.AcceptString("</style>")
+//
+// An occurrence of the delimiter in the current lexeme of the embedded
+// lexer is only decided when the lexeme is emitted or ignored.
+func (l *Lexer) SwitchRegion(lang, until string, child StateFn) bool {
+	if until == "" {
+		panic("lexer: SwitchRegion with an empty delimiter")
+	}
+	l.Ignore()
+	base, rest := l.pos, l.input[l.pos:]
+	c := New(child, rest, l.childOptions()...)
+	var items []*Item
+	from, end := 0, -1
+	for end < 0 {
+		done := c.state == nil
+		if !done {
+			c.step()
+		}
+		for item := c.dequeue(); item != nil; item = c.dequeue() {
+			items = append(items, item)
+		}
+		limit := c.start
+		if c.state == nil {
+			limit = len(rest)
+		}
+		end, from = topLevel(rest, until, from, limit, items)
+		if done {
+			break
+		}
+	}
+	found := end >= 0
+	if !found {
+		end = len(rest)
+	}
+	for _, item := range items {
+		if item.Pos < end {
+			l.adopt(lang, base, item)
+		}
+	}
+	l.pos, l.start, l.width = base+end, base+end, 0
+	return found
+}
+
+// topLevel returns the offset of the first occurrence of until in s at or
+// after from and before limit which is not inside one of items, or -1, and
+// the offset from which to continue searching.
+func topLevel(s, until string, from, limit int, items []*Item) (int, int) {
+	for from < limit {
+		i := strings.Index(s[from:], until)
+		if i < 0 || from+i >= limit {
+			break
+		}
+		d := from + i
+		inside := false
+		for _, item := range items {
+			pos, end := item.Pos, item.Pos+len(item.Value)
+			if err, ok := item.Payload.(*LexError); ok && (item.Type == ItemError || item.Type == ItemWarning) {
+				pos, end = err.Pos, err.End
+			}
+			if pos < d && d < end {
+				inside = true
+				break
+			}
+		}
+		if !inside {
+			return d, d
+		}
+		from = d + 1
+	}
+	return -1, from
+}
@@ -0,0 +1,111 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// lexTags emits the text of <w>...</w> elements with lexWords as "words",
+// and other runs of text with type 2.
+func lexTags(l *Lexer) StateFn {
+	if l.AcceptString("<w>") {
+		l.Ignore()
+		end := strings.Index(l.Input()[l.Pos():], "</w>")
+		if end < 0 {
+			return l.Errorf("unclosed element")
+		}
+		l.Delegate("words", lexWords, l.Pos()+end)
+		l.AcceptString("</w>")
+		l.Ignore()
+		return lexTags
+	}
+	if l.AcceptRunFunc(func(r rune) bool { return r != '<' }) > 0 {
+		l.Emit(2)
+		return lexTags
+	}
+	return nil
+}
+
+func TestDelegate(t *testing.T) {
+	l := New(lexTags, "x <w>ab cd</w>y<w>e 1</w>", WithLineTracking(), WithErrorPolicy(ErrorResume))
+	var got []string
+	for item := l.Next(); item.Type != ItemEOF; item = l.Next() {
+		got = append(got, fmt.Sprintf("%+v", item))
+	}
+	want := []string{
+		`ItemType(2)@1:1 "x "`,
+		`words.ItemType(1)@1:6 "ab"`,
+		`words.ItemType(1)@1:9 "cd"`,
+		`ItemType(2)@1:15 "y"`,
+		`words.ItemType(1)@1:19 "e"`,
+		`words.Error@1:21 "unexpected \"1\""`,
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("items\n%s\nwant\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestDelegateError(t *testing.T) {
+	var diags DiagnosticList
+	l := New(lexTags, "<w>a 1</w>", WithDiagnostics(&diags))
+	for l.Next().Type != ItemEOF {
+	}
+	if len(diags) != 1 || diags[0].Err.Pos != 5 || diags[0].Err.Lexeme != "1" || diags[0].Position.Column != 6 {
+		t.Errorf("diagnostics %+v", diags)
+	}
+}
+
+// lexQuoted emits words with type 1 and quoted strings with type 3.
+func lexQuoted(l *Lexer) StateFn {
+	l.AcceptRun(" ")
+	l.Ignore()
+	switch {
+	case l.AcceptRun("abcdefghijklmnopqrstuvwxyz") > 0:
+		l.Emit(1)
+	case l.AcceptQuoted('"', GoEscapes) == nil:
+		l.Emit(3)
+	default:
+		if _, n := l.Advance(); n == 0 {
+			return nil
+		}
+		return l.Errorf("unexpected %q", l.Current())
+	}
+	return lexQuoted
+}
+
+func TestSwitchRegion(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		items string
+		found bool
+		rest  string
+	}{
+		{`ab "x</w>y" cd</w>rest`, `ab "x</w>y" cd`, true, "</w>rest"},
+		{`ab</w>`, `ab`, true, "</w>"},
+		{`</w>`, ``, true, "</w>"},
+		{`ab "x</w>`, `ab unexpected "\""`, true, "</w>"},
+		{`a<</w>`, `a unexpected "<"`, true, "</w>"},
+		{`ab "x"`, `ab "x"`, false, ""},
+	} {
+		var found bool
+		l := New(func(l *Lexer) StateFn {
+			found = l.SwitchRegion("q", "</w>", lexQuoted)
+			return nil
+		}, test.input)
+		var items []string
+		for item := l.Next(); item.Type != ItemEOF; item = l.Next() {
+			if item.Lang != "q" {
+				t.Errorf("%q: item %+v", test.input, item)
+			}
+			items = append(items, item.Value)
+		}
+		if strings.Join(items, " ") != test.items || found != test.found || l.Input()[l.Pos():] != test.rest {
+			t.Errorf("%q: items %q found %v rest %q", test.input, items, found, l.Input()[l.Pos():])
+		}
+	}
+}
@@ -0,0 +1,97 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+// Severity classifies a Diagnostic.
+type Severity int
+
+// Severities of diagnostics.
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	if s == SeverityWarning {
+		return "warning"
+	}
+	return "error"
+}
+
+// Diagnostic is an error or warning reported by a lexer.
+type Diagnostic struct {
+	Severity Severity
+	Name     string   // name of the input, see WithName
+	Position Position // position of the start of the offending text
+	Err      *LexError
+}
+
+// DiagnosticSink receives the diagnostics of a lexer.
+type DiagnosticSink interface {
+	Report(d Diagnostic)
+}
+
+// DiagnosticFunc is a DiagnosticSink which calls itself.
+type DiagnosticFunc func(d Diagnostic)
+
+// Report calls fn(d).
+func (fn DiagnosticFunc) Report(d Diagnostic) {
+	fn(d)
+}
+
+// DiagnosticList is a DiagnosticSink which collects diagnostics.
+type DiagnosticList []Diagnostic
+
+// Report appends d to the list.
+func (list *DiagnosticList) Report(d Diagnostic) {
+	*list = append(*list, d)
+}
+
+// WithDiagnostics reports errors and warnings to sink as they reach the
+// front of the item queue, so that errors discarded by Restore or Try are
+// not reported.  The lexer continues to emit error and warning items unless
+// the option WithoutDiagnosticItems is also given.
+func WithDiagnostics(sink DiagnosticSink) Option {
+	return func(l *Lexer) { l.sink = sink }
+}
+
+// WithoutDiagnosticItems stops the lexer from emitting error and warning
+// items when a DiagnosticSink is given to WithDiagnostics.  The parser then
+// receives only the items of the language being lexed.
+func WithoutDiagnosticItems() Option {
+	return func(l *Lexer) { l.quiet = true }
+}
+
+// report sends a diagnostic for i to l's sink if i is an error or warning,
+// and returns true if i should also be returned by Next.
+func (l *Lexer) report(i *Item) bool {
+	if l.sink == nil || (i.Type != ItemError && i.Type != ItemWarning) {
+		return true
+	}
+	d := Diagnostic{
+		Name:     l.name,
+		Position: l.Position(i.Pos),
+		Err:      l.itemError(i),
+	}
+	if i.Type == ItemWarning {
+		d.Severity = SeverityWarning
+	}
+	l.sink.Report(d)
+	return !l.quiet
+}
+
+// itemError returns the *LexError of the error or warning item i.  Items
+// whose payload is not a *LexError, such as errors given to EmitValue, are
+// described by their value and span.
+func (l *Lexer) itemError(i *Item) *LexError {
+	if err, ok := i.Payload.(*LexError); ok {
+		return err
+	}
+	span := i.Span()
+	if _, ok := l.Text(span.Start, span.End); !ok {
+		return &LexError{Msg: i.Value, Pos: i.Pos, End: i.Pos}
+	}
+	return l.lexError(i.Value, span.Start, span.End)
+}
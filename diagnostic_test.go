@@ -0,0 +1,56 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDiagnostics(t *testing.T) {
+	lexWarn := func(l *Lexer) StateFn {
+		if l.Pos() == 0 {
+			l.Warnf("starting")
+		}
+		return lexWords
+	}
+	for _, test := range []struct {
+		opts  []Option
+		items string
+	}{
+		{nil, `starting|ab|unexpected "1"|cd`},
+		{[]Option{WithoutDiagnosticItems()}, "ab|cd"},
+	} {
+		var diags DiagnosticList
+		opts := append(test.opts, WithName("in"), WithDiagnostics(&diags), WithErrorPolicy(ErrorResume))
+		items := collect(New(lexWarn, "ab 1cd", opts...))
+		if s := strings.Join(items, "|"); s != test.items {
+			t.Errorf("items %s", s)
+		}
+		var reports []string
+		for _, d := range diags {
+			reports = append(reports, fmt.Sprintf("%s %s:%v %s %q", d.Severity, d.Name, d.Position, d.Err.Msg, d.Err.Lexeme))
+		}
+		if s := strings.Join(reports, "|"); s != `warning in:1:1 starting ""|error in:1:4 unexpected "1" "1"` {
+			t.Errorf("diagnostics %s", s)
+		}
+	}
+}
+
+func TestDiagnosticsEmitValue(t *testing.T) {
+	var diags DiagnosticList
+	lex := New(func(l *Lexer) StateFn {
+		l.AcceptString("ab")
+		l.EmitValue(ItemError, "bad ab")
+		return nil
+	}, "ab", WithDiagnostics(&diags))
+	if item := lex.Next(); item.Type != ItemError || item.Value != "bad ab" {
+		t.Errorf("item %v", item)
+	}
+	if len(diags) != 1 || diags[0].Err.Msg != "bad ab" || diags[0].Err.Lexeme != "ab" || diags[0].Position.Column != 1 {
+		t.Errorf("diagnostics %+v", diags)
+	}
+}
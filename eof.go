@@ -0,0 +1,99 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+// EOFPolicy determines how often a Lexer returns its item of type ItemEOF.
+type EOFPolicy int
+
+const (
+	// EOFRepeat returns an item of type ItemEOF from every call to Next
+	// after the lexer stops.
+	EOFRepeat EOFPolicy = iota
+	// EOFOnce returns a single item of type ItemEOF when the lexer stops.
+	// Later calls to Next return nil until the lexer is restarted by
+	// Continue or AppendRecord.  A SyncLexer returns an item of type
+	// ItemEOF to every caller regardless.
+	EOFOnce
+)
+
+// WithEOFPolicy sets the lexer's EOF policy.  The default is EOFRepeat.
+func WithEOFPolicy(p EOFPolicy) Option {
+	return func(l *Lexer) { l.eofs = p }
+}
+
+// EndRecord emits an item of type ItemEOR marking the end of a logical
+// record, such as a message of a framed protocol or a statement of a
+// line-oriented format.  The value of the item is the current lexeme, which
+// is usually empty or the record terminator.
+//
+//	if l.AcceptString("\r\n") {
+//		l.EndRecord()
+//		return lexHeader
+//	}
+func (l *Lexer) EndRecord() {
+	l.Emit(ItemEOR)
+}
+
+// AppendRecord adds s to l's input as a record, so that one lexer can read a
+// sequence of framed messages as they arrive.  Each record is lexed from
+// the start state of l and ends when its states stop, where Next returns
+// an item of type ItemEOR instead of one of type ItemEOF.  Records appended
+// before the lexer reaches the end of the current record are queued, so
+// that the states never see past the end of a record, and the lexer
+// continues with the next record after returning the ItemEOR of the
+// previous one.  With no record pending the lexer stops as usual and Next
+// returns an item of type ItemEOF until the next call to AppendRecord.
+//
+//	lex := lexer.New(lexMessage, "", lexer.WithEOFPolicy(lexer.EOFOnce))
+//	for frame := range frames {
+//		lex.AppendRecord(frame)
+//		for item := lex.Next(); item != nil && item.Type != lexer.ItemEOF; item = lex.Next() {
+//			...
+//		}
+//	}
+//
+// A record appended to a lexer which has not finished its plain input, that
+// is input given to New or Append, is lexed as its continuation.  A lexer
+// which was halted by an error or a limit reads no more records.
+func (l *Lexer) AppendRecord(s string) {
+	if l.framed || len(l.frames) > 0 {
+		l.frames = append(l.frames, s)
+		return
+	}
+	l.startRecord(s)
+}
+
+// startRecord appends the record s to l's input and restarts l if it is
+// stopped.
+func (l *Lexer) startRecord(s string) {
+	l.Append(s)
+	if l.state == nil && !l.halted {
+		l.Continue(l.begin)
+	}
+	l.framed, l.eofSent = true, false
+}
+
+// stopped returns the item Next returns when l is stopped and its queue is
+// empty, or nil under EOFOnce.
+func (l *Lexer) stopped() *Item {
+	if l.framed && !l.halted {
+		l.framed = false
+		i := l.newItem()
+		i.Type, i.Pos = ItemEOR, l.offset(l.pos)
+		if len(l.frames) > 0 {
+			s := l.frames[0]
+			l.frames = l.frames[1:]
+			l.startRecord(s)
+		}
+		return l.stamp(i)
+	}
+	if l.eofs == EOFOnce && l.eofSent {
+		return nil
+	}
+	l.eofSent = true
+	i := l.newItem()
+	i.Type, i.Pos = ItemEOF, l.offset(l.pos)
+	return l.stamp(i)
+}
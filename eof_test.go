@@ -0,0 +1,82 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestEOFPolicy(t *testing.T) {
+	lex := New(lexWords, "a")
+	lex.Next()
+	for i := 0; i < 3; i++ {
+		if item := lex.Next(); item == nil || item.Type != ItemEOF {
+			t.Fatalf("EOFRepeat: item %d %v", i, item)
+		}
+	}
+
+	lex = New(lexWords, "a", WithEOFPolicy(EOFOnce))
+	lex.Next()
+	if item := lex.Next(); item == nil || item.Type != ItemEOF || item.Pos != 1 {
+		t.Fatalf("EOFOnce: first item %v", item)
+	}
+	if item := lex.Next(); item != nil {
+		t.Errorf("EOFOnce: second item %v", item)
+	}
+	lex.Append(" b")
+	lex.Continue(lexWords)
+	if items := collect(lex); !reflect.DeepEqual(items, []string{"b"}) {
+		t.Errorf("items after Continue %q", items)
+	}
+	if item := lex.Next(); item != nil {
+		t.Errorf("EOFOnce after Continue: item %v", item)
+	}
+}
+
+// lexMessage lexes words and ends a record at each newline.
+func lexMessage(l *Lexer) StateFn {
+	l.AcceptRun(" ")
+	l.Ignore()
+	switch {
+	case l.AcceptRun("abcdefghijklmnopqrstuvwxyz") > 0:
+		l.Emit(1)
+	case l.AcceptString("\n"):
+		l.EndRecord()
+	default:
+		return nil
+	}
+	return lexMessage
+}
+
+func TestAppendRecord(t *testing.T) {
+	lex := New(lexMessage, "", WithEOFPolicy(EOFOnce))
+	next := func() string {
+		item := lex.Next()
+		if item == nil {
+			return "nil"
+		}
+		return item.Type.String() + ":" + item.Value
+	}
+	var items []string
+	lex.AppendRecord("ab cd")
+	lex.AppendRecord("ef\ngh")
+	for i := 0; i < 9; i++ {
+		items = append(items, next())
+	}
+	lex.AppendRecord("ij")
+	for i := 0; i < 3; i++ {
+		items = append(items, next())
+	}
+	expect := []string{
+		"ItemType(1):ab", "ItemType(1):cd", "EOR:",
+		"ItemType(1):ef", "EOR:\n", "ItemType(1):gh", "EOR:",
+		"EOF:", "nil",
+		"ItemType(1):ij", "EOR:", "EOF:",
+	}
+	if !reflect.DeepEqual(items, expect) {
+		t.Errorf("items\n\t%q\n(expected)\n\t%q", items, expect)
+	}
+}
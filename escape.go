@@ -0,0 +1,248 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+// EscapeRules describes the backslash escape sequences of a language.
+type EscapeRules struct {
+	// Simple maps the rune following a backslash to the rune it denotes
+	// (e.g. 'n' to '\n').
+	Simple map[rune]rune
+
+	// MinOctal and MaxOctal bound the number of digits in an octal escape
+	// (\ooo), which denotes a byte.  Octal escapes are disabled when
+	// MaxOctal is zero.
+	MinOctal, MaxOctal int
+
+	// MinHex and MaxHex bound the number of digits in a hexadecimal escape
+	// (\xhh), which denotes a byte.  Hexadecimal escapes are disabled when
+	// MaxHex is zero.
+	MinHex, MaxHex int
+
+	// Unicode enables escapes of the form \uXXXX.
+	Unicode bool
+
+	// LongUnicode enables escapes of the form \UXXXXXXXX.
+	LongUnicode bool
+
+	// Surrogates combines \u escapes of UTF-16 surrogate pairs into a single
+	// rune.  An unpaired surrogate decodes as utf8.RuneError.  Without
+	// Surrogates an escaped surrogate is an error.
+	Surrogates bool
+}
+
+// Escape rules for common languages.
+var (
+	GoEscapes = EscapeRules{
+		Simple: map[rune]rune{
+			'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t',
+			'v': '\v', '\\': '\\', '\'': '\'', '"': '"',
+		},
+		MinOctal: 3, MaxOctal: 3,
+		MinHex: 2, MaxHex: 2,
+		Unicode:     true,
+		LongUnicode: true,
+	}
+	JSONEscapes = EscapeRules{
+		Simple: map[rune]rune{
+			'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t', '\\': '\\',
+			'/': '/', '"': '"',
+		},
+		Unicode:    true,
+		Surrogates: true,
+	}
+	CEscapes = EscapeRules{
+		Simple: map[rune]rune{
+			'a': '\a', 'b': '\b', 'f': '\f', 'n': '\n', 'r': '\r', 't': '\t',
+			'v': '\v', '\\': '\\', '\'': '\'', '"': '"', '?': '?',
+		},
+		MinOctal: 1, MaxOctal: 3,
+		MinHex: 1, MaxHex: 2,
+		Unicode:     true,
+		LongUnicode: true,
+	}
+)
+
+// EscapeError describes an invalid escape sequence.
+type EscapeError struct {
+	Offset int    // byte offset of the backslash beginning the escape
+	Msg    string // description of the problem
+}
+
+func (err *EscapeError) Error() string {
+	return fmt.Sprintf("%s at offset %d", err.Msg, err.Offset)
+}
+
+// DecodeEscapes returns s with each escape sequence described by rules
+// replaced by the text it denotes.
+func DecodeEscapes(s string, rules EscapeRules) (string, error) {
+	if strings.IndexByte(s, '\\') < 0 {
+		return s, nil
+	}
+	buf := make([]byte, 0, len(s))
+	for i := 0; i < len(s); {
+		if s[i] != '\\' {
+			buf = append(buf, s[i])
+			i++
+			continue
+		}
+		n, err := rules.decode(s[i:], func(r rune, byteval bool) {
+			if byteval {
+				buf = append(buf, byte(r))
+			} else {
+				buf = utf8.AppendRune(buf, r)
+			}
+		})
+		if err != "" {
+			return "", &EscapeError{i, err}
+		}
+		i += n
+	}
+	return string(buf), nil
+}
+
+// decode decodes the escape sequence at the beginning of s, which must begin
+// with a backslash.  Each decoded value is passed to fn, which is told
+// whether the value is a byte (octal and hexadecimal escapes) or a rune.
+// decode returns the length of the escape sequence or a message describing
+// why it is invalid.
+func (rules *EscapeRules) decode(s string, fn func(v rune, byteval bool)) (n int, err string) {
+	if len(s) < 2 {
+		return 0, "incomplete escape sequence"
+	}
+	c, size := utf8.DecodeRuneInString(s[1:])
+	if r, ok := rules.Simple[c]; ok {
+		fn(r, false)
+		return 1 + size, ""
+	}
+	switch {
+	case '0' <= c && c <= '7' && rules.MaxOctal > 0:
+		v, n := digitsValue(s[1:], 8, rules.MaxOctal)
+		if n < rules.MinOctal {
+			return 0, "invalid octal escape"
+		}
+		if v > 255 {
+			return 0, "octal escape value > 255"
+		}
+		fn(v, true)
+		return 1 + n, ""
+	case c == 'x' && rules.MaxHex > 0:
+		v, n := digitsValue(s[2:], 16, rules.MaxHex)
+		if n < rules.MinHex || n == 0 {
+			return 0, "invalid hexadecimal escape"
+		}
+		fn(v, true)
+		return 2 + n, ""
+	case c == 'u' && rules.Unicode, c == 'U' && rules.LongUnicode:
+		width := 4
+		if c == 'U' {
+			width = 8
+		}
+		v, n := digitsValue(s[2:], 16, width)
+		if n < width {
+			return 0, "invalid unicode escape"
+		}
+		n += 2
+		if utf16.IsSurrogate(v) {
+			if !rules.Surrogates {
+				return 0, "escape sequence is invalid unicode code point"
+			}
+			if strings.HasPrefix(s[n:], `\u`) {
+				if v2, m := digitsValue(s[n+2:], 16, 4); m == 4 {
+					if r := utf16.DecodeRune(v, v2); r != utf8.RuneError {
+						fn(r, false)
+						return n + 6, ""
+					}
+				}
+			}
+			fn(utf8.RuneError, false)
+			return n, ""
+		}
+		if !utf8.ValidRune(v) {
+			return 0, "escape sequence is invalid unicode code point"
+		}
+		fn(v, false)
+		return n, ""
+	}
+	return 0, "unknown escape sequence"
+}
+
+// digitsValue returns the value of the up to max digits in the given base at
+// the beginning of s and the number of digits.
+func digitsValue(s string, base, max int) (v rune, n int) {
+	for n < max && n < len(s) {
+		d := digitValue(s[n])
+		if d >= base {
+			break
+		}
+		v = v*rune(base) + rune(d)
+		n++
+	}
+	return v, n
+}
+
+func digitValue(c byte) int {
+	switch {
+	case '0' <= c && c <= '9':
+		return int(c - '0')
+	case 'a' <= c && c <= 'f':
+		return int(c-'a') + 10
+	case 'A' <= c && c <= 'F':
+		return int(c-'A') + 10
+	}
+	return 16
+}
+
+// AcceptEscape advances the lexer over the escape sequence described by rules
+// beginning at the current position.  If the input does not begin with a
+// valid escape sequence the lexer does not advance and an *EscapeError is
+// returned.
+func (l *Lexer) AcceptEscape(rules EscapeRules) error {
+	n, err := rules.decode(l.input[l.pos:], func(rune, bool) {})
+	if err != "" {
+		return &EscapeError{l.pos, err}
+	}
+	l.acceptString(l.input[l.pos : l.pos+n])
+	return nil
+}
+
+// AcceptQuoted advances the lexer over a string literal delimited by quote
+// which may contain escape sequences described by rules.  The literal may
+// not contain a newline.  If the input does not begin with a valid literal
+// the lexer does not advance and an error is returned.  Escape sequences
+// in the literal can be decoded by passing the text between the quotes to
+// DecodeEscapes with the same rules.
+func (l *Lexer) AcceptQuoted(quote rune, rules EscapeRules) error {
+	c := l.Checkpoint()
+	if !l.AcceptString(string(quote)) {
+		return fmt.Errorf("expected %q", quote)
+	}
+	for {
+		if strings.HasPrefix(l.input[l.pos:], `\`) {
+			if err := l.AcceptEscape(rules); err != nil {
+				l.Restore(c)
+				return err
+			}
+			continue
+		}
+		r, n := l.Advance()
+		switch {
+		case IsEOF(r, n) || r == '\n':
+			l.Restore(c)
+			return fmt.Errorf("unterminated literal")
+		case IsInvalid(r, n):
+			l.Restore(c)
+			return fmt.Errorf("invalid utf-8 rune in literal")
+		case r == quote:
+			return nil
+		}
+	}
+}
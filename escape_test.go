@@ -0,0 +1,65 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func TestDecodeEscapes(t *testing.T) {
+	for _, test := range []struct {
+		in    string
+		rules EscapeRules
+		out   string
+		err   string
+	}{
+		{`plain`, GoEscapes, "plain", ""},
+		{`a\tb\n\\\"`, GoEscapes, "a\tb\n\\\"", ""},
+		{`\101\x41é\U0001F600`, GoEscapes, "AAé\U0001F600", ""},
+		{`\xff`, GoEscapes, "\xff", ""},
+		{`\0`, GoEscapes, "", "invalid octal escape at offset 0"},
+		{`ab\x4`, GoEscapes, "", "invalid hexadecimal escape at offset 2"},
+		{`\q`, GoEscapes, "", "unknown escape sequence at offset 0"},
+		{`\ud800`, GoEscapes, "", "escape sequence is invalid unicode code point at offset 0"},
+		{`x\`, GoEscapes, "", "incomplete escape sequence at offset 1"},
+		{`\/😀`, JSONEscapes, "/\U0001F600", ""},
+		{`\ud83dx`, JSONEscapes, "�x", ""},
+		{`\x41`, JSONEscapes, "", "unknown escape sequence at offset 0"},
+		{`\0\12\x4\?`, CEscapes, "\x00\n\x04?", ""},
+		{`\400`, CEscapes, "", "octal escape value > 255 at offset 0"},
+	} {
+		out, err := DecodeEscapes(test.in, test.rules)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: error %v (expected %q)", test.in, err, test.err)
+			}
+			continue
+		}
+		if err != nil || out != test.out {
+			t.Errorf("%s: %q %v (expected %q)", test.in, out, err, test.out)
+		}
+	}
+}
+
+func TestAcceptQuoted(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		pos   int
+		ok    bool
+	}{
+		{`"abc" x`, 5, true},
+		{`"a\"bé"`, 8, true},
+		{`'\''`, 0, false},
+		{`"a\qb"`, 0, false},
+		{`"abc`, 0, false},
+		{"\"a\nb\"", 0, false},
+	} {
+		lex := New(nilState, test.input)
+		err := lex.AcceptQuoted('"', GoEscapes)
+		if (err == nil) != test.ok || lex.Pos() != test.pos {
+			t.Errorf("%s: position %d error %v", test.input, lex.Pos(), err)
+		}
+	}
+}
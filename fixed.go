@@ -0,0 +1,86 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"unicode/utf8"
+)
+
+// AcceptColumns advances l's position over the next width runes of the
+// current line and returns the number of runes accepted, which is less than
+// width if the line ends first.  Invalid UTF-8 bytes count as one rune.
+func (l *Lexer) AcceptColumns(width int) int {
+	end := l.lineEnd()
+	n := 0
+	for ; n < width && l.pos < end; n++ {
+		_, size := utf8.DecodeRuneInString(l.input[l.pos:end])
+		l.pos += size
+	}
+	l.width, l.unread, l.backed = 0, false, false
+	return n
+}
+
+// Field describes a column of a fixed-width record.
+type Field struct {
+	Type  ItemType // type of the emitted item
+	Width int      // width of the field in runes
+	Trim  bool     // exclude leading and trailing spaces from the item
+	Skip  bool     // discard the field instead of emitting it
+}
+
+// FixedWidth returns a state which lexes lines of fixed-width records, as
+// found in mainframe exports and FORTRAN data files.  The fields of each line
+// are emitted in order.  A line that ends early is emitted as a short record
+// and a line longer than the sum of the field widths is an error.  Line
+// terminators are discarded.
+func FixedWidth(fields ...Field) StateFn {
+	total := 0
+	for _, f := range fields {
+		total += f.Width
+	}
+	var state StateFn
+	state = func(l *Lexer) StateFn {
+		if l.pos >= len(l.input) {
+			return nil
+		}
+		for _, f := range fields {
+			if l.pos >= l.lineEnd() {
+				break
+			}
+			l.AcceptColumns(f.Width)
+			switch {
+			case f.Skip:
+				l.Ignore()
+			case f.Trim:
+				l.emitTrimmed(f.Type)
+			default:
+				l.Emit(f.Type)
+			}
+		}
+		if l.pos < l.lineEnd() {
+			l.AcceptLine()
+			return l.Errorf("record longer than %d columns", total)
+		}
+		l.acceptNewline()
+		l.Ignore()
+		return state
+	}
+	return state
+}
+
+// emitTrimmed emits the current lexeme without its leading and trailing
+// spaces.
+func (l *Lexer) emitTrimmed(t ItemType) {
+	end := l.pos
+	for l.start < end && l.input[l.start] == ' ' {
+		l.start++
+	}
+	for l.pos > l.start && l.input[l.pos-1] == ' ' {
+		l.pos--
+	}
+	l.Emit(t)
+	l.pos = end
+	l.Ignore()
+}
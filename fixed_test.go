@@ -0,0 +1,46 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestAcceptColumns(t *testing.T) {
+	lex := New(nilState, "aé\xffb\ncd")
+	if n := lex.AcceptColumns(3); n != 3 || lex.Pos() != 4 {
+		t.Errorf("accepted %d columns to %d", n, lex.Pos())
+	}
+	if n := lex.AcceptColumns(3); n != 1 || lex.Pos() != 5 {
+		t.Errorf("accepted %d columns to %d", n, lex.Pos())
+	}
+}
+
+func TestFixedWidth(t *testing.T) {
+	start := FixedWidth(
+		Field{Type: 1, Width: 4},
+		Field{Width: 1, Skip: true},
+		Field{Type: 2, Width: 6, Trim: true},
+		Field{Type: 3, Width: 3})
+	for _, test := range []struct {
+		input string
+		items string
+	}{
+		{"0001| ab   007\r\n0002|cdé   010\n", "1:0:0001|2:6:ab|3:11:007|1:16:0002|2:21:cdé|3:28:010"},
+		{"0001|      007\n0002| x\n", "1:0:0001|2:11:|3:11:007|1:15:0002|2:21:x"},
+		{"0001| ab   0071\n", "1:0:0001|2:6:ab|3:11:007|65534:14:record longer than 14 columns"},
+	} {
+		var items []string
+		lex := New(start, test.input)
+		for item := lex.Next(); item.Type != ItemEOF; item = lex.Next() {
+			items = append(items, fmt.Sprintf("%d:%d:%s", item.Type, item.Pos, item.Value))
+		}
+		if s := strings.Join(items, "|"); s != test.items {
+			t.Errorf("%q: items %s", test.input, s)
+		}
+	}
+}
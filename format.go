@@ -0,0 +1,130 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"strconv"
+	"sync"
+)
+
+// ItemFormat controls how the items of a lexer are displayed by
+// Item.String and Item.Format.
+type ItemFormat struct {
+	// Limit is the length in runes of the longest value Item.String
+	// returns unquoted.  Longer values are quoted and truncated to Limit
+	// runes.  A limit less than one disables truncation.
+	Limit int
+
+	// Redact, if not nil, is called to obtain the text displayed in place
+	// of an item's value.  It allows sensitive values, such as passwords
+	// in configuration files, to be masked in logs and error messages.
+	// Redact must return i.Value for items that need no masking.  Item
+	// values themselves are never modified.
+	Redact func(i *Item) string
+}
+
+// defaultFormat is the format of items not emitted by a lexer given
+// WithItemFormat.
+var defaultFormat = &ItemFormat{Limit: 10}
+
+// WithItemFormat causes the items emitted by the lexer to be displayed as
+// described by f.  Items of other lexers, and items not emitted by a lexer,
+// have their values truncated to 10 runes and are not redacted.
+//
+//	lex := lexer.New(lexConfig, input, lexer.WithItemFormat(lexer.ItemFormat{
+//		Limit: 20,
+//		Redact: func(i *lexer.Item) string {
+//			if i.Type == itemSecret {
+//				return "****"
+//			}
+//			return i.Value
+//		},
+//	}))
+func WithItemFormat(f ItemFormat) Option {
+	return func(l *Lexer) { l.format = &f }
+}
+
+// itemFormat returns the format of i.
+func (i *Item) itemFormat() *ItemFormat {
+	if i.format == nil {
+		return defaultFormat
+	}
+	return i.format
+}
+
+// display returns the value of i as it should be displayed.
+func (i *Item) display() string {
+	i.checkLive()
+	if redact := i.itemFormat().Redact; redact != nil {
+		return redact(i)
+	}
+	return i.Value
+}
+
+var typeNames = struct {
+	sync.RWMutex
+	m map[ItemType]string
+}{m: map[ItemType]string{
+	ItemEOF:       "EOF",
+	ItemError:     "Error",
+	ItemWarning:   "Warning",
+	ItemShebang:   "Shebang",
+	ItemDirective: "Directive",
+	ItemEOR:       "EOR",
+}}
+
+// RegisterItemType names the item type t.  The name is used when formatting
+// t and items of type t.  Item types are typically registered in an init
+// function of the package defining them.  Because the item types of separate
+// lexers overlap, a program should only register the types of lexers it uses.
+func RegisterItemType(t ItemType, name string) {
+	typeNames.Lock()
+	typeNames.m[t] = name
+	typeNames.Unlock()
+}
+
+// String returns the name registered for t, or a numeric representation if
+// no name was registered.
+func (t ItemType) String() string {
+	typeNames.RLock()
+	name, ok := typeNames.m[t]
+	typeNames.RUnlock()
+	if ok {
+		return name
+	}
+	return "ItemType(" + strconv.Itoa(int(t)) + ")"
+}
+
+// Format implements fmt.Formatter.  The verbs are
+//
+//	%s	the raw value of i, subject to ItemFormat.Redact
+//	%q	the value of i as a quoted string
+//	%v	the result of i.String()
+//	%+v	the type, position and quoted value of i
+//
+// Width and precision apply as they do to strings.  The position of an item
+// is its byte offset or, when it has one, its line and column.  The type of
+// an item with a Lang is prefixed by the language and a period.
+func (i *Item) Format(f fmt.State, verb rune) {
+	switch {
+	case verb == 's' || verb == 'q':
+		fmt.Fprintf(f, fmt.FormatString(f, verb), i.display())
+	case verb == 'v' && f.Flag('+'):
+		pos := strconv.Itoa(i.Pos)
+		if i.Line > 0 {
+			pos = strconv.Itoa(i.Line) + ":" + strconv.Itoa(i.Column)
+		}
+		lang := ""
+		if i.Lang != "" {
+			lang = i.Lang + "."
+		}
+		fmt.Fprintf(f, "%s%v@%s %q", lang, i.Type, pos, i.display())
+	case verb == 'v':
+		fmt.Fprintf(f, fmt.FormatString(f, 's'), i.String())
+	default:
+		fmt.Fprintf(f, "%%!%c(*lexer.Item=%s)", verb, i.String())
+	}
+}
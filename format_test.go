@@ -0,0 +1,82 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"testing"
+)
+
+func TestItemFormat(t *testing.T) {
+	const itemWord ItemType = 101
+	RegisterItemType(itemWord, "Word")
+	long := &Item{Type: itemWord, Pos: 3, Value: "abcdefghijkl"}
+	tracked := &Item{Type: 102, Pos: 3, Value: "a\tb", Line: 2, Column: 1}
+	for _, test := range []struct {
+		format string
+		item   *Item
+		out    string
+	}{
+		{"%s", long, "abcdefghijkl"},
+		{"%.3s", long, "abc"},
+		{"%q", tracked, `"a\tb"`},
+		{"%v", long, `"abcdefghij"...`},
+		{"%6v", tracked, "   a\tb"},
+		{"%+v", long, `Word@3 "abcdefghijkl"`},
+		{"%+v", tracked, `ItemType(102)@2:1 "a\tb"`},
+		{"%+v", &Item{Type: ItemEOF, Pos: 7}, `EOF@7 ""`},
+		{"%d", tracked, "%!d(*lexer.Item=a\tb)"},
+	} {
+		if out := fmt.Sprintf(test.format, test.item); out != test.out {
+			t.Errorf("%s: %q (expected %q)", test.format, out, test.out)
+		}
+	}
+}
+
+func TestItemRedact(t *testing.T) {
+	const itemSecret ItemType = 103
+	format := WithItemFormat(ItemFormat{
+		Limit: 3,
+		Redact: func(i *Item) string {
+			if i.Type == itemSecret {
+				return "****"
+			}
+			return i.Value
+		},
+	})
+	emit := func(t ItemType, value string) *Item {
+		return New(func(l *Lexer) StateFn {
+			l.AcceptString(value)
+			l.Emit(t)
+			return nil
+		}, value, format).Next()
+	}
+	secret := emit(itemSecret, "hunter2")
+	for _, test := range []struct {
+		format string
+		item   *Item
+		out    string
+	}{
+		{"%s", secret, "****"},
+		{"%+v", secret, `ItemType(103)@0 "****"`},
+		{"%v", secret, `"***"...`},
+		{"%v", emit(1, "abcd"), `"abc"...`},
+		{"%v", emit(1, "abc"), "abc"},
+		{"%v", emit(1, "éèê"), "éèê"},
+		{"%v", &Item{Type: itemSecret, Value: "hunter2"}, "hunter2"},
+	} {
+		if out := fmt.Sprintf(test.format, test.item); out != test.out {
+			t.Errorf("%s: %q (expected %q)", test.format, out, test.out)
+		}
+	}
+	lex := New(func(l *Lexer) StateFn {
+		l.AcceptRun("abcdefghijklmnop")
+		l.Emit(1)
+		return nil
+	}, "abcdefghijklmnop", WithItemFormat(ItemFormat{}))
+	if s := lex.Next().String(); s != "abcdefghijklmnop" {
+		t.Errorf("untruncated string %q", s)
+	}
+}
@@ -0,0 +1,133 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"sort"
+)
+
+// StateGraph records the transitions between states observed while lexing.
+// A StateGraph is given to a lexer with WithStateGraph and may be shared by
+// several lexers to accumulate the transitions made over many inputs.
+type StateGraph struct {
+	edges map[[2]string]int
+	order []string
+	seen  map[string]bool
+}
+
+// WithStateGraph records the state transitions made by the lexer in g.
+func WithStateGraph(g *StateGraph) Option {
+	return func(l *Lexer) { l.graph = g }
+}
+
+// Transitions returns the number of times the lexer moved from state from to
+// state to.  The terminal nil state is named "nil".
+func (g *StateGraph) Transitions(from, to string) int {
+	return g.edges[[2]string{from, to}]
+}
+
+func (g *StateGraph) add(from, to StateFn) {
+	if g.edges == nil {
+		g.edges = make(map[[2]string]int)
+		g.seen = make(map[string]bool)
+	}
+	f, t := graphName(from), graphName(to)
+	for _, n := range [...]string{f, t} {
+		if !g.seen[n] {
+			g.seen[n] = true
+			g.order = append(g.order, n)
+		}
+	}
+	g.edges[[2]string{f, t}]++
+}
+
+// WriteDOT writes g to w as a Graphviz digraph.  Nodes are named after state
+// functions and edges are labeled with the number of times they were taken.
+func (g *StateGraph) WriteDOT(w io.Writer) error {
+	edges := make([][2]string, 0, len(g.edges))
+	for e := range g.edges {
+		edges = append(edges, e)
+	}
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i][0] != edges[j][0] {
+			return edges[i][0] < edges[j][0]
+		}
+		return edges[i][1] < edges[j][1]
+	})
+	b := bufio.NewWriter(w)
+	fmt.Fprintln(b, "digraph lexer {")
+	for _, n := range g.order {
+		shape := "box"
+		if n == "nil" {
+			shape = "doublecircle"
+		}
+		fmt.Fprintf(b, "\t%q [shape=%s];\n", n, shape)
+	}
+	for _, e := range edges {
+		fmt.Fprintf(b, "\t%q -> %q [label=\"%d\"];\n", e[0], e[1], g.edges[e])
+	}
+	fmt.Fprintln(b, "}")
+	return b.Flush()
+}
+
+// graphName returns the name given to fn by Named or the name of the
+// function fn without its package path.
+func graphName(fn StateFn) string {
+	if fn == nil {
+		return "nil"
+	}
+	if name, ok := namedState(fn); ok {
+		return name
+	}
+	name := stateName(fn)
+	for i := len(name) - 1; i >= 0; i-- {
+		if name[i] == '/' {
+			name = name[i+1:]
+			break
+		}
+	}
+	return name
+}
+
+// funcNames caches the names of functions by their code pointers.
+type funcNames map[uintptr]string
+
+// name returns the name of the function fn as graphName does, caching the
+// names of the functions seen other than those of named states.
+func (names *funcNames) name(fn interface{}) string {
+	v := reflect.ValueOf(fn)
+	if v.IsNil() {
+		return "nil"
+	}
+	if state, ok := fn.(StateFn); ok {
+		if name, ok := namedState(state); ok {
+			return name
+		}
+	}
+	pc := v.Pointer()
+	if name, ok := (*names)[pc]; ok {
+		return name
+	}
+	if *names == nil {
+		*names = make(funcNames)
+	}
+	name := "?"
+	if f := runtime.FuncForPC(pc); f != nil {
+		name = f.Name()
+		for i := len(name) - 1; i >= 0; i-- {
+			if name[i] == '/' {
+				name = name[i+1:]
+				break
+			}
+		}
+	}
+	(*names)[pc] = name
+	return name
+}
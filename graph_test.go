@@ -0,0 +1,40 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+func TestStateGraph(t *testing.T) {
+	g := new(StateGraph)
+	collect(New(lexWords, "ab cd", WithStateGraph(g)))
+	collect(New(lexWords, "x", WithStateGraph(g)))
+	self := graphName(lexWords)
+	if !strings.HasSuffix(self, ".lexWords") || strings.Contains(self, "/") {
+		t.Errorf("node name %q", self)
+	}
+	if n := g.Transitions(self, self); n != 3 {
+		t.Errorf("%d transitions %s -> %s", n, self, self)
+	}
+	if n := g.Transitions(self, "nil"); n != 2 {
+		t.Errorf("%d transitions %s -> nil", n, self)
+	}
+	var buf bytes.Buffer
+	if err := g.WriteDOT(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		`"` + self + `" [shape=box];`,
+		`"` + self + `" -> "` + self + `" [label="3"];`,
+		`"` + self + `" -> "nil" [label="2"];`,
+	} {
+		if !strings.Contains(buf.String(), "\t"+line+"\n") {
+			t.Errorf("missing %q in\n%s", line, buf.String())
+		}
+	}
+}
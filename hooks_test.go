@@ -0,0 +1,36 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestStateHooks(t *testing.T) {
+	var calls []string
+	enter := func(name string, l *Lexer) {
+		calls = append(calls, "enter "+name+" "+l.input[l.pos:])
+	}
+	exit := func(name string, l *Lexer) {
+		calls = append(calls, "exit "+name+" "+l.input[l.pos:])
+	}
+	words := Named("words", lexWords)
+	collect(New(words, "ab cd", WithStateHooks(enter, exit)))
+	expect := []string{
+		"enter words ab cd", "exit words  cd",
+		"enter go-lexer.lexWords  cd", "exit go-lexer.lexWords ",
+		"enter go-lexer.lexWords ", "exit go-lexer.lexWords ",
+	}
+	if !reflect.DeepEqual(calls, expect) {
+		t.Errorf("calls\n\t%q\n(expected)\n\t%q", calls, expect)
+	}
+
+	calls = nil
+	collect(New(words, "ab", WithStateHooks(nil, exit)))
+	if len(calls) != 2 || calls[0] != "exit words " {
+		t.Errorf("exit calls %q", calls)
+	}
+}
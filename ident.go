@@ -0,0 +1,111 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// IdentSpec describes the identifiers of a language.  It is used by
+// ScanIdentifier, and by the Ident rules of package rules, so that a
+// language defines its identifiers once.
+type IdentSpec struct {
+	// Start holds the runes which may begin an identifier.
+	Start *unicode.RangeTable
+
+	// Continue holds the runes which may follow the first rune of an
+	// identifier.  If Continue is nil they are the runes of Start.
+	Continue *unicode.RangeTable
+
+	// Connectors lists runes which may join two runs of Continue runes,
+	// such as "-" in the kebab-case names of CSS or Lisp.  A connector
+	// cannot begin or end an identifier, or follow another connector, so
+	// that "a-b" is an identifier but "a-" and "a--b" are not.
+	Connectors string
+
+	// Normalize maps the text of an identifier to its value, such as
+	// strings.ToLower for languages whose identifiers are not case
+	// sensitive.  If Normalize is nil the value is the text.
+	Normalize func(string) string
+}
+
+// Identifier specs for common languages.
+var (
+	GoIdent = IdentSpec{
+		Start:    MergeRanges(unicode.Letter, RunesTable("_")),
+		Continue: MergeRanges(unicode.Letter, unicode.Digit, RunesTable("_")),
+	}
+	ASCIIIdent = IdentSpec{
+		Start:    RunesTable("ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz"),
+		Continue: RunesTable("0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZ_abcdefghijklmnopqrstuvwxyz"),
+	}
+)
+
+// continues returns the runes which may follow the first of an identifier.
+func (spec *IdentSpec) continues() *unicode.RangeTable {
+	if spec.Continue == nil {
+		return spec.Start
+	}
+	return spec.Continue
+}
+
+// Valid returns true if s is an identifier.
+func (spec *IdentSpec) Valid(s string) bool {
+	r, n := utf8.DecodeRuneInString(s)
+	if n == 0 || !unicode.Is(spec.Start, r) {
+		return false
+	}
+	cont, joined := spec.continues(), true
+	for _, r := range s[n:] {
+		switch {
+		case unicode.Is(cont, r):
+			joined = true
+		case joined && strings.ContainsRune(spec.Connectors, r):
+			joined = false
+		default:
+			return false
+		}
+	}
+	return joined
+}
+
+// Value returns the value of the identifier s, normalized by spec.
+func (spec *IdentSpec) Value(s string) string {
+	if spec.Normalize == nil {
+		return s
+	}
+	return spec.Normalize(s)
+}
+
+// ScanIdentifier advances the lexer over an identifier described by spec and
+// returns its value.  If the input does not begin with an identifier the
+// lexer's position is not changed and ScanIdentifier returns false.  A
+// connector which is not followed by a Continue rune is not accepted, so
+// that "a-" scans as the identifier "a".
+//
+//	if v, ok := l.ScanIdentifier(&lexer.GoIdent); ok {
+//		l.EmitValue(itemIdent, v)
+//	}
+func (l *Lexer) ScanIdentifier(spec *IdentSpec) (string, bool) {
+	pos := l.pos
+	if !l.AcceptRange(spec.Start) {
+		return "", false
+	}
+	cont := spec.continues()
+	for {
+		l.AcceptRunRange(cont)
+		if spec.Connectors == "" {
+			break
+		}
+		c := l.Checkpoint()
+		if !l.Accept(spec.Connectors) || !l.AcceptRange(cont) {
+			l.Restore(c)
+			break
+		}
+	}
+	return spec.Value(l.input[pos:l.pos]), true
+}
@@ -0,0 +1,65 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+var kebabIdent = IdentSpec{
+	Start:      RunesTable("abcdefghijklmnopqrstuvwxyz"),
+	Continue:   RunesTable("abcdefghijklmnopqrstuvwxyz0123456789"),
+	Connectors: "-",
+	Normalize:  strings.ToUpper,
+}
+
+func TestScanIdentifier(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		spec  *IdentSpec
+		value string
+		ok    bool
+	}{
+		{"héllo_1 x", &GoIdent, "héllo_1", true},
+		{"_x+", &GoIdent, "_x", true},
+		{"1x", &GoIdent, "", false},
+		{"héllo", &ASCIIIdent, "h", true},
+		{"", &ASCIIIdent, "", false},
+		{"ab-c2-d e", &kebabIdent, "AB-C2-D", true},
+		{"ab- c", &kebabIdent, "AB", true},
+		{"ab--c", &kebabIdent, "AB", true},
+		{"-ab", &kebabIdent, "", false},
+	} {
+		lex := New(nilState, test.input)
+		v, ok := lex.ScanIdentifier(test.spec)
+		if ok != test.ok || v != test.value || lex.Pos() != len(lex.Current()) || strings.ToUpper(lex.Current()) != strings.ToUpper(test.value) {
+			t.Errorf("%q: %q %v at %d (expected %q)", test.input, v, ok, lex.Pos(), test.value)
+		}
+	}
+}
+
+func TestIdentSpecValid(t *testing.T) {
+	for _, test := range []struct {
+		s     string
+		valid bool
+	}{
+		{"a", true},
+		{"a-b-c1", true},
+		{"a-", false},
+		{"a--b", false},
+		{"-a", false},
+		{"1a", false},
+		{"A", false},
+		{"", false},
+	} {
+		if valid := kebabIdent.Valid(test.s); valid != test.valid {
+			t.Errorf("%q: valid %v", test.s, valid)
+		}
+	}
+	if !GoIdent.Valid("x_ñ1") || GoIdent.Valid("x-y") {
+		t.Errorf("go identifiers")
+	}
+}
@@ -0,0 +1,169 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package index converts the items of a lexer into the postings of an inverted
+index, for feeding code search or log search engines.
+
+An Emitter is a lexer.Sink writing a posting for each term of each item it
+receives.
+
+	ix := make(index.Index)
+	e := index.NewEmitter("main.go", ix.Add)
+	e.Terms = index.Words
+	err := lex.Drain(e)
+
+The terms of an item are chosen by a function, so that programs may index
+only identifiers, split them into words, or stem them.
+*/
+package index
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Posting records an occurrence of a term in a document.
+type Posting struct {
+	Term     string
+	Doc      string // name of the document
+	Offset   int    // byte offset of the item containing the term
+	Position int    // number of items indexed in the document before it
+}
+
+// Emitter is a lexer.Sink converting the items of a document into postings.
+// All the terms of an item share its position, so that consecutive indexed
+// items have consecutive positions and phrase queries can be answered.
+type Emitter struct {
+	// Terms returns the terms of an item, which are not indexed if there
+	// are none.  If Terms is nil, Lower is used.
+	Terms func(item lexer.Item) []string
+
+	doc string
+	out func(Posting) error
+	pos int
+}
+
+// NewEmitter returns an emitter passing the postings of document doc to out.
+// An error returned by out is returned by Write.
+func NewEmitter(doc string, out func(Posting) error) *Emitter {
+	return &Emitter{doc: doc, out: out}
+}
+
+// Reset prepares e to index document doc, starting again at position zero.
+func (e *Emitter) Reset(doc string) {
+	e.doc = doc
+	e.pos = 0
+}
+
+// Write writes the postings of the terms of item.
+func (e *Emitter) Write(item lexer.Item) error {
+	terms := e.Terms
+	if terms == nil {
+		terms = Lower
+	}
+	ts := terms(item)
+	if len(ts) == 0 {
+		return nil
+	}
+	for _, t := range ts {
+		p := Posting{Term: t, Doc: e.doc, Offset: item.Pos, Position: e.pos}
+		if err := e.out(p); err != nil {
+			return err
+		}
+	}
+	e.pos++
+	return nil
+}
+
+// Lower returns the value of item in lower case, with surrounding space
+// removed.  Errors, warnings and items of space have no terms.
+func Lower(item lexer.Item) []string {
+	switch item.Type {
+	case lexer.ItemError, lexer.ItemWarning, lexer.ItemEOF:
+		return nil
+	}
+	v := strings.TrimSpace(item.Value)
+	if v == "" {
+		return nil
+	}
+	return []string{strings.ToLower(v)}
+}
+
+// Words returns the terms of Lower followed, when the value of item is made
+// of several words, by each word in lower case.  Words are runs of letters
+// and digits, further split where camel case changes from lower to upper
+// case, so that "parseHTTPRequest" has the terms "parsehttprequest",
+// "parse", "http" and "request".
+func Words(item lexer.Item) []string {
+	terms := Lower(item)
+	if terms == nil {
+		return nil
+	}
+	words := splitWords(item.Value)
+	if len(words) > 1 || len(words) == 1 && strings.ToLower(words[0]) != terms[0] {
+		for _, w := range words {
+			terms = append(terms, strings.ToLower(w))
+		}
+	}
+	return terms
+}
+
+func splitWords(s string) []string {
+	var words []string
+	rs := []rune(s)
+	start := -1
+	for i, r := range rs {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if start >= 0 {
+				words = append(words, string(rs[start:i]))
+				start = -1
+			}
+			continue
+		}
+		if start >= 0 && unicode.IsUpper(r) {
+			prev := rs[i-1]
+			nextLower := i+1 < len(rs) && unicode.IsLower(rs[i+1])
+			if !unicode.IsUpper(prev) || nextLower {
+				words = append(words, string(rs[start:i]))
+				start = i
+			}
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		words = append(words, string(rs[start:]))
+	}
+	return words
+}
+
+// Index is an inverted index held in memory, mapping each term to its
+// postings in the order they were added.
+type Index map[string][]Posting
+
+// Add adds p to ix.  It never returns an error, so that it can be given to
+// NewEmitter.
+func (ix Index) Add(p Posting) error {
+	ix[p.Term] = append(ix[p.Term], p)
+	return nil
+}
+
+// Docs returns the sorted names of the documents containing term.
+func (ix Index) Docs(term string) []string {
+	var docs []string
+	seen := make(map[string]bool)
+	for _, p := range ix[term] {
+		if !seen[p.Doc] {
+			seen[p.Doc] = true
+			docs = append(docs, p.Doc)
+		}
+	}
+	sort.Strings(docs)
+	return docs
+}
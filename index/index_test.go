@@ -0,0 +1,84 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package index
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// lexWords emits runs of letters, digits and underscores, ignoring the rest.
+func lexWords(l *lexer.Lexer) lexer.StateFn {
+	const word = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789_"
+	if l.AcceptRun(word) > 0 {
+		l.Emit(1)
+	} else if r, _ := l.Advance(); r == lexer.EOF {
+		return nil
+	}
+	l.Ignore()
+	return lexWords
+}
+
+func TestEmitter(t *testing.T) {
+	ix := make(Index)
+	e := NewEmitter("a.go", ix.Add)
+	if err := lexer.New(lexWords, "Foo bar(foo)").Drain(e); err != nil {
+		t.Fatal(err)
+	}
+	e.Reset("b.go")
+	if err := lexer.New(lexWords, "BAR").Drain(e); err != nil {
+		t.Fatal(err)
+	}
+	want := []Posting{{"foo", "a.go", 0, 0}, {"foo", "a.go", 8, 2}}
+	if !reflect.DeepEqual(ix["foo"], want) {
+		t.Errorf("foo: %v", ix["foo"])
+	}
+	want = []Posting{{"bar", "a.go", 4, 1}, {"bar", "b.go", 0, 0}}
+	if !reflect.DeepEqual(ix["bar"], want) {
+		t.Errorf("bar: %v", ix["bar"])
+	}
+	if docs := ix.Docs("bar"); !reflect.DeepEqual(docs, []string{"a.go", "b.go"}) {
+		t.Errorf("docs %q", docs)
+	}
+}
+
+func TestEmitterError(t *testing.T) {
+	stop := errors.New("stop")
+	n := 0
+	e := NewEmitter("x", func(p Posting) error {
+		n++
+		return stop
+	})
+	if err := lexer.New(lexWords, "a b c").Drain(e); err != stop {
+		t.Errorf("error %v", err)
+	}
+	if n != 1 {
+		t.Errorf("%d postings", n)
+	}
+}
+
+func TestWords(t *testing.T) {
+	for _, test := range []struct {
+		value string
+		terms []string
+	}{
+		{"parseHTTPRequest", []string{"parsehttprequest", "parse", "http", "request"}},
+		{"max_len2", []string{"max_len2", "max", "len2"}},
+		{"lower", []string{"lower"}},
+		{"Upper", []string{"upper"}},
+		{"  ", nil},
+	} {
+		terms := Words(lexer.Item{Type: 1, Value: test.value})
+		if !reflect.DeepEqual(terms, test.terms) {
+			t.Errorf("%q: %q", test.value, terms)
+		}
+	}
+	if terms := Words(lexer.Item{Type: lexer.ItemError, Value: "bad"}); terms != nil {
+		t.Errorf("error terms %q", terms)
+	}
+}
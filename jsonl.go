@@ -0,0 +1,84 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+// JSONLinesWriter writes items as JSON Lines, one object per line, for
+// piping into browser-based visualizers or processing with jq.
+//
+//	{"type":"Ident","id":3,"start":0,"end":3,"line":1,"col":1,"value":"foo"}
+//
+// Errors and warnings have the span of the offending text and the message
+// as their value.  Line and column are omitted when unknown, as is the
+// language of items which have none.  A JSONLinesWriter is a Sink.
+//
+//	w := lexer.NewJSONLinesWriter(os.Stdout)
+//	w.LineBuffered = true
+//	err := lex.Drain(w)
+type JSONLinesWriter struct {
+	// LineBuffered causes each line to be flushed when it is written, so
+	// that readers of long-running streams see items as they are lexed.
+	LineBuffered bool
+
+	w   *bufio.Writer
+	enc *json.Encoder
+	err error
+}
+
+type jsonLine struct {
+	Type   string `json:"type"`
+	ID     int    `json:"id"`
+	Start  int    `json:"start"`
+	End    int    `json:"end"`
+	Line   int    `json:"line,omitempty"`
+	Column int    `json:"col,omitempty"`
+	Value  string `json:"value"`
+	Lang   string `json:"lang,omitempty"`
+}
+
+// NewJSONLinesWriter returns a writer to w.
+func NewJSONLinesWriter(w io.Writer) *JSONLinesWriter {
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	enc.SetEscapeHTML(false)
+	return &JSONLinesWriter{w: bw, enc: enc}
+}
+
+// Write writes item as a line.  An item of type ItemEOF is not written.
+func (w *JSONLinesWriter) Write(item Item) error {
+	if w.err != nil || item.Type == ItemEOF {
+		return w.err
+	}
+	line := jsonLine{
+		Type:   item.Type.String(),
+		ID:     int(item.Type),
+		Start:  item.Pos,
+		End:    item.Pos + len(item.Value),
+		Line:   item.Line,
+		Column: item.Column,
+		Value:  item.display(),
+		Lang:   item.Lang,
+	}
+	if err, ok := item.Payload.(*LexError); ok && (item.Type == ItemError || item.Type == ItemWarning) {
+		line.Start, line.End = err.Pos, err.End
+	}
+	if w.err = w.enc.Encode(&line); w.err == nil && w.LineBuffered {
+		w.err = w.w.Flush()
+	}
+	return w.err
+}
+
+// Flush writes buffered lines to the underlying writer.
+func (w *JSONLinesWriter) Flush() error {
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	return w.err
+}
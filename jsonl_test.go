@@ -0,0 +1,39 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestJSONLinesWriter(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewJSONLinesWriter(&buf)
+	if err := New(lexWords, "ab <", WithLineTracking()).Drain(w); err != nil {
+		t.Fatal(err)
+	}
+	want := `{"type":"ItemType(1)","id":1,"start":0,"end":2,"line":1,"col":1,"value":"ab"}
+{"type":"Error","id":65534,"start":3,"end":4,"line":1,"col":4,"value":"unexpected \"<\""}
+`
+	if buf.String() != want {
+		t.Errorf("output\n%s", buf.String())
+	}
+}
+
+func TestJSONLinesWriterLineBuffered(t *testing.T) {
+	var buf bytes.Buffer
+	w := NewJSONLinesWriter(&buf)
+	w.Write(Item{Type: 1, Value: "a", Lang: "x"})
+	if buf.Len() != 0 {
+		t.Errorf("line written before Flush")
+	}
+	w.LineBuffered = true
+	w.Write(Item{Type: 1, Pos: 1, Value: "b"})
+	if want := `{"type":"ItemType(1)","id":1,"start":0,"end":1,"value":"a","lang":"x"}` + "\n" +
+		`{"type":"ItemType(1)","id":1,"start":1,"end":2,"value":"b"}` + "\n"; buf.String() != want {
+		t.Errorf("output\n%s", buf.String())
+	}
+}
@@ -0,0 +1,253 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package lexbench measures the performance of registered lexers over corpora
+of input files, so that the speed of a lexer, and the effect of changes to
+it or to package lexer, can be quantified in a standard way.
+
+	corpus, err := lexbench.Load("testdata/json")
+	res := lexbench.Run(lexer.Lookup("json"), "testdata/json", corpus)
+	fmt.Println(res) // tokens/sec, allocs/token, ...
+
+Results can be saved with WriteResults and compared with those of another
+build by Compare, which reports the relative change of each measurement.
+
+	old, err := lexbench.ReadResults(oldFile)
+	for _, d := range lexbench.Compare(old, results) {
+		fmt.Println(d)
+	}
+*/
+package lexbench
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+	"time"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Input is a file of a corpus.
+type Input struct {
+	Name string
+	Text string
+}
+
+// Load reads the files named by paths into a corpus.  Directories are
+// walked, and the regular files in them are read in lexical order.
+func Load(paths ...string) ([]Input, error) {
+	var corpus []Input
+	for _, path := range paths {
+		err := filepath.Walk(path, func(name string, info os.FileInfo, err error) error {
+			if err != nil || !info.Mode().IsRegular() {
+				return err
+			}
+			text, err := ioutil.ReadFile(name)
+			if err != nil {
+				return err
+			}
+			corpus = append(corpus, Input{name, string(text)})
+			return nil
+		})
+		if err != nil {
+			return nil, err
+		}
+	}
+	return corpus, nil
+}
+
+// Result is the measured performance of a lexer over a corpus.
+type Result struct {
+	Lang   string
+	Corpus string
+
+	N      int   // times the corpus was lexed
+	Bytes  int64 // bytes in the corpus
+	Tokens int64 // items lexed from the corpus, including errors
+
+	T         time.Duration // total time of the N passes
+	MemAllocs uint64        // total allocations of the N passes
+	MemBytes  uint64        // total bytes allocated by the N passes
+}
+
+// TokensPerSec returns the number of items lexed per second.
+func (r *Result) TokensPerSec() float64 {
+	if r.T <= 0 {
+		return 0
+	}
+	return float64(r.Tokens) * float64(r.N) / r.T.Seconds()
+}
+
+// BytesPerSec returns the number of input bytes lexed per second.
+func (r *Result) BytesPerSec() float64 {
+	if r.T <= 0 {
+		return 0
+	}
+	return float64(r.Bytes) * float64(r.N) / r.T.Seconds()
+}
+
+// AllocsPerToken returns the number of allocations per item lexed.
+func (r *Result) AllocsPerToken() float64 {
+	if r.Tokens == 0 || r.N == 0 {
+		return 0
+	}
+	return float64(r.MemAllocs) / float64(r.Tokens) / float64(r.N)
+}
+
+// BytesPerToken returns the number of bytes allocated per item lexed.
+func (r *Result) BytesPerToken() float64 {
+	if r.Tokens == 0 || r.N == 0 {
+		return 0
+	}
+	return float64(r.MemBytes) / float64(r.Tokens) / float64(r.N)
+}
+
+func (r *Result) String() string {
+	return fmt.Sprintf("%s/%s\t%d tokens\t%.0f tokens/sec\t%.2f MB/s\t%.2f allocs/token\t%.1f B/token",
+		r.Lang, r.Corpus, r.Tokens, r.TokensPerSec(), r.BytesPerSec()/1e6, r.AllocsPerToken(), r.BytesPerToken())
+}
+
+// Run lexes corpus with lang repeatedly, for about a second as go test
+// benchmarks do, and returns the measurements labeled with the name of the
+// corpus.
+func Run(lang *lexer.Language, name string, corpus []Input) *Result {
+	res := &Result{Lang: lang.Name, Corpus: name}
+	for _, in := range corpus {
+		res.Bytes += int64(len(in.Text))
+		res.Tokens += int64(count(lang, in.Text))
+	}
+	b := testing.Benchmark(func(b *testing.B) {
+		b.ReportAllocs()
+		b.SetBytes(res.Bytes)
+		for i := 0; i < b.N; i++ {
+			for _, in := range corpus {
+				count(lang, in.Text)
+			}
+		}
+	})
+	res.N, res.T = b.N, b.T
+	res.MemAllocs, res.MemBytes = b.MemAllocs, b.MemBytes
+	return res
+}
+
+// count lexes text with lang and returns the number of items.
+func count(lang *lexer.Language, text string) int {
+	lex := lang.New(text)
+	n := 0
+	for lex.Next().Type != lexer.ItemEOF {
+		n++
+	}
+	return n
+}
+
+// RunSniffed runs each registered language over the inputs of corpus which
+// lexer.Sniff attributes to it, in the order of lexer.Languages.  Inputs of
+// no known language are skipped.
+func RunSniffed(name string, corpus []Input) []*Result {
+	byLang := make(map[*lexer.Language][]Input)
+	for _, in := range corpus {
+		if lang := lexer.Sniff(in.Name, in.Text); lang != nil {
+			byLang[lang] = append(byLang[lang], in)
+		}
+	}
+	var results []*Result
+	for _, lang := range lexer.Languages() {
+		if inputs := byLang[lang]; len(inputs) > 0 {
+			results = append(results, Run(lang, name, inputs))
+		}
+	}
+	return results
+}
+
+// WriteResults writes results to w as JSON, for comparison with those of
+// another build.
+func WriteResults(w io.Writer, results []*Result) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(results)
+}
+
+// ReadResults reads results written by WriteResults.
+func ReadResults(r io.Reader) ([]*Result, error) {
+	var results []*Result
+	err := json.NewDecoder(r).Decode(&results)
+	return results, err
+}
+
+// Delta is the change in performance of a lexer over a corpus between two
+// builds.  Changes are relative, so that 0.1 is an increase of 10%.
+type Delta struct {
+	Lang, Corpus string
+	Old, New     *Result
+
+	TokensPerSec   float64
+	AllocsPerToken float64
+	BytesPerToken  float64
+}
+
+// Regressed reports whether the lexer became slower, or allocates more,
+// by more than the fraction tolerance.
+func (d *Delta) Regressed(tolerance float64) bool {
+	return d.TokensPerSec < -tolerance || d.AllocsPerToken > tolerance || d.BytesPerToken > tolerance
+}
+
+func (d *Delta) String() string {
+	return fmt.Sprintf("%s/%s\ttokens/sec %.0f -> %.0f (%+.1f%%)\tallocs/token %.2f -> %.2f (%+.1f%%)\tB/token %.1f -> %.1f (%+.1f%%)",
+		d.Lang, d.Corpus,
+		d.Old.TokensPerSec(), d.New.TokensPerSec(), 100*d.TokensPerSec,
+		d.Old.AllocsPerToken(), d.New.AllocsPerToken(), 100*d.AllocsPerToken,
+		d.Old.BytesPerToken(), d.New.BytesPerToken(), 100*d.BytesPerToken)
+}
+
+// Compare pairs the results of two builds by language and corpus and
+// returns the changes from old to new, sorted by language and corpus.
+// Results without a counterpart are omitted.
+func Compare(old, new []*Result) []*Delta {
+	type key struct{ lang, corpus string }
+	prev := make(map[key]*Result)
+	for _, r := range old {
+		prev[key{r.Lang, r.Corpus}] = r
+	}
+	var deltas []*Delta
+	for _, r := range new {
+		o := prev[key{r.Lang, r.Corpus}]
+		if o == nil {
+			continue
+		}
+		deltas = append(deltas, &Delta{
+			Lang:           r.Lang,
+			Corpus:         r.Corpus,
+			Old:            o,
+			New:            r,
+			TokensPerSec:   change(o.TokensPerSec(), r.TokensPerSec()),
+			AllocsPerToken: change(o.AllocsPerToken(), r.AllocsPerToken()),
+			BytesPerToken:  change(o.BytesPerToken(), r.BytesPerToken()),
+		})
+	}
+	sort.Slice(deltas, func(i, j int) bool {
+		if deltas[i].Lang != deltas[j].Lang {
+			return deltas[i].Lang < deltas[j].Lang
+		}
+		return deltas[i].Corpus < deltas[j].Corpus
+	})
+	return deltas
+}
+
+// change returns the relative change from a to b.
+func change(a, b float64) float64 {
+	if a == 0 {
+		if b == 0 {
+			return 0
+		}
+		return 1
+	}
+	return (b - a) / a
+}
@@ -0,0 +1,86 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexbench
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	_ "github.com/bmatsuo/go-lexer/presets/jsonlex"
+)
+
+func TestRunSniffed(t *testing.T) {
+	if testing.Short() {
+		t.Skip("the benchmark runs for about a second")
+	}
+	dir, err := ioutil.TempDir("", "lexbench")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	files := map[string]string{"a.json": `{"a": [1, 2]}`, "b.json": `[true]`, "c.unknown": "?"}
+	for name, text := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(text), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	corpus, err := Load(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(corpus) != 3 || corpus[0].Name != filepath.Join(dir, "a.json") {
+		t.Fatalf("corpus %v", corpus)
+	}
+	results := RunSniffed("test", corpus)
+	if len(results) != 1 {
+		t.Fatalf("%d results", len(results))
+	}
+	r := results[0]
+	if r.Lang != "json" || r.Tokens != 12 || r.Bytes != 19 || r.N == 0 || r.TokensPerSec() <= 0 {
+		t.Errorf("result %+v", r)
+	}
+}
+
+func TestCompare(t *testing.T) {
+	old := []*Result{
+		{Lang: "json", Corpus: "c", N: 1, Tokens: 100, T: time.Second, MemAllocs: 100},
+		{Lang: "ini", Corpus: "c", N: 1, Tokens: 100, T: time.Second},
+	}
+	var buf bytes.Buffer
+	if err := WriteResults(&buf, old); err != nil {
+		t.Fatal(err)
+	}
+	old, err := ReadResults(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	new := []*Result{
+		{Lang: "json", Corpus: "c", N: 2, Tokens: 100, T: time.Second, MemAllocs: 300},
+		{Lang: "sql", Corpus: "c", N: 1, Tokens: 100, T: time.Second},
+	}
+	deltas := Compare(old, new)
+	if len(deltas) != 1 {
+		t.Fatalf("%d deltas", len(deltas))
+	}
+	d := deltas[0]
+	if d.TokensPerSec != 1 || d.AllocsPerToken != 0.5 || d.BytesPerToken != 0 {
+		t.Errorf("delta %+v", d)
+	}
+	if !d.Regressed(0.1) || d.Regressed(0.6) {
+		t.Errorf("Regressed wrong")
+	}
+	want := "json/c\ttokens/sec 100 -> 200 (+100.0%)\tallocs/token 1.00 -> 1.50 (+50.0%)\tB/token 0.0 -> 0.0 (+0.0%)"
+	if s := d.String(); s != want {
+		t.Errorf("String() = %q", s)
+	}
+	if !reflect.DeepEqual(d.Old, old[0]) {
+		t.Errorf("old result %+v", d.Old)
+	}
+}
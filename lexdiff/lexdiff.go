@@ -0,0 +1,310 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package lexdiff compares the tokens of a lexer built with package lexer to
+those of a reference implementation over the same input, which helps when
+porting an existing language to package lexer.  A Harness reports each
+place where the boundaries or the types of the tokens differ.
+
+	h := &lexdiff.Harness{
+		Lexer:     lexdiff.Lexer(lexGo, nil),
+		Reference: lexdiff.GoScanner,
+		Types:     map[string]string{"itemIdent": "IDENT", "itemInt": "INT"},
+	}
+	divs, err := h.Diff(src)
+
+Tokens are compared by their position in the input and by the names of
+their types, which Types translates from the lexer to the reference.
+References are provided for the scanners of packages go/scanner and
+text/scanner.
+*/
+package lexdiff
+
+import (
+	"bytes"
+	"fmt"
+	goscanner "go/scanner"
+	"go/token"
+	"strings"
+	"testing"
+	"text/scanner"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Token is a token of an input, found by a lexer or a reference.
+type Token struct {
+	Pos, End int    // byte offsets of the token in the input
+	Type     string // name of the token type
+	Text     string // input from Pos to End
+}
+
+func (t Token) String() string {
+	return fmt.Sprintf("%s %q", t.Type, t.Text)
+}
+
+// Tokenizer returns the tokens of src in order.
+type Tokenizer func(src string) ([]Token, error)
+
+// Lexer returns a Tokenizer lexing with a new lexer started at start.  The
+// type of each item is named by name, or by the String method of its type if
+// name is nil.  Name receives the item, rather than its type, so that
+// punctuation may be named by its value as references often do.  The error
+// is that of the first error item, if any.
+func Lexer(start lexer.StateFn, name func(*lexer.Item) string, opts ...lexer.Option) Tokenizer {
+	if name == nil {
+		name = func(item *lexer.Item) string { return item.Type.String() }
+	}
+	return func(src string) ([]Token, error) {
+		lex := lexer.New(start, src, opts...)
+		var toks []Token
+		var err error
+		for item := lex.Next(); item.Type != lexer.ItemEOF; item = lex.Next() {
+			switch item.Type {
+			case lexer.ItemError:
+				if err == nil {
+					err = item.Err()
+				}
+			case lexer.ItemWarning:
+			default:
+				end := item.Pos + len(item.Value)
+				if end > len(src) {
+					end = len(src)
+				}
+				toks = append(toks, Token{item.Pos, end, name(item), src[item.Pos:end]})
+			}
+		}
+		return toks, err
+	}
+}
+
+// GoScanner is a Tokenizer using package go/scanner, including comments.
+// Types are named by the String method of token.Token, such as "IDENT",
+// "+" or "func".  Semicolons inserted automatically are omitted.
+func GoScanner(src string) ([]Token, error) {
+	fset := token.NewFileSet()
+	file := fset.AddFile("", fset.Base(), len(src))
+	var s goscanner.Scanner
+	var errs goscanner.ErrorList
+	s.Init(file, []byte(src), errs.Add, goscanner.ScanComments)
+	var toks []Token
+	for {
+		pos, tok, lit := s.Scan()
+		if tok == token.EOF {
+			break
+		}
+		if tok == token.SEMICOLON && lit != ";" {
+			continue
+		}
+		off := file.Offset(pos)
+		n := len(lit)
+		if lit == "" {
+			n = len(tok.String())
+		}
+		if tok == token.COMMENT {
+			n = commentLen(src[off:])
+		}
+		toks = append(toks, Token{off, off + n, tok.String(), src[off : off+n]})
+	}
+	return toks, errs.Err()
+}
+
+// commentLen returns the length of the comment beginning src, whose literal
+// lacks the carriage returns removed by go/scanner.
+func commentLen(src string) int {
+	if strings.HasPrefix(src, "/*") {
+		if i := strings.Index(src[2:], "*/"); i >= 0 {
+			return i + 4
+		}
+		return len(src)
+	}
+	n := strings.IndexByte(src, '\n')
+	if n < 0 {
+		n = len(src)
+	}
+	if n > 0 && src[n-1] == '\r' {
+		n--
+	}
+	return n
+}
+
+// TextScanner returns a Tokenizer using package text/scanner with the given
+// mode, such as scanner.GoTokens.  Types are named by scanner.TokenString,
+// such as "Ident" or `"+"`.
+func TextScanner(mode uint) Tokenizer {
+	return func(src string) ([]Token, error) {
+		var s scanner.Scanner
+		s.Init(strings.NewReader(src))
+		s.Mode = mode
+		var err error
+		s.Error = func(s *scanner.Scanner, msg string) {
+			if err == nil {
+				err = fmt.Errorf("%s: %s", s.Position, msg)
+			}
+		}
+		var toks []Token
+		for tok := s.Scan(); tok != scanner.EOF; tok = s.Scan() {
+			text := s.TokenText()
+			off := s.Position.Offset
+			toks = append(toks, Token{off, off + len(text), scanner.TokenString(tok), text})
+		}
+		return toks, err
+	}
+}
+
+// Kind is the kind of a divergence.
+type Kind int
+
+const (
+	Boundary Kind = iota // overlapping tokens with different boundaries
+	Type                 // tokens with the same boundaries and different types
+	Extra                // a token found only by the lexer
+	Missing              // a token found only by the reference
+)
+
+var kindNames = [...]string{"boundary", "type", "extra", "missing"}
+
+func (k Kind) String() string {
+	if k < 0 || int(k) >= len(kindNames) {
+		return fmt.Sprintf("Kind(%d)", int(k))
+	}
+	return kindNames[k]
+}
+
+// Divergence is a region of the input where the tokens of a lexer differ
+// from those of the reference.
+type Divergence struct {
+	Kind     Kind
+	Pos, End int
+	Got      []Token // tokens of the lexer in the region
+	Want     []Token // tokens of the reference in the region
+}
+
+func (d *Divergence) String() string {
+	return fmt.Sprintf("%d-%d: %v: got %s, want %s", d.Pos, d.End, d.Kind, tokenList(d.Got), tokenList(d.Want))
+}
+
+func tokenList(toks []Token) string {
+	if len(toks) == 0 {
+		return "nothing"
+	}
+	var buf bytes.Buffer
+	for i, t := range toks {
+		if i > 0 {
+			buf.WriteString(", ")
+		}
+		buf.WriteString(t.String())
+	}
+	return buf.String()
+}
+
+// Compare returns the divergences of the tokens got from the tokens want,
+// in order.  The type names of got are translated by types, if they are in
+// it, before they are compared.  Overlapping tokens with different
+// boundaries are grouped until both sequences reach a common boundary, so
+// that a single divergence covers, for example, a token the reference
+// splits in two.
+func Compare(got, want []Token, types map[string]string) []Divergence {
+	var divs []Divergence
+	i, j := 0, 0
+	for i < len(got) || j < len(want) {
+		switch {
+		case j == len(want) || i < len(got) && got[i].End <= want[j].Pos && !sameSpan(got[i], want[j]):
+			divs = append(divs, Divergence{Extra, got[i].Pos, got[i].End, got[i : i+1], nil})
+			i++
+		case i == len(got) || want[j].End <= got[i].Pos && !sameSpan(got[i], want[j]):
+			divs = append(divs, Divergence{Missing, want[j].Pos, want[j].End, nil, want[j : j+1]})
+			j++
+		case sameSpan(got[i], want[j]):
+			typ := got[i].Type
+			if t, ok := types[typ]; ok {
+				typ = t
+			}
+			if typ != want[j].Type {
+				divs = append(divs, Divergence{Type, got[i].Pos, got[i].End, got[i : i+1], want[j : j+1]})
+			}
+			i++
+			j++
+		default:
+			gi, wj := i+1, j+1
+			gEnd, wEnd := got[i].End, want[j].End
+			for gEnd != wEnd {
+				if gEnd < wEnd && gi < len(got) && got[gi].Pos < wEnd {
+					gEnd = max(gEnd, got[gi].End)
+					gi++
+				} else if wEnd < gEnd && wj < len(want) && want[wj].Pos < gEnd {
+					wEnd = max(wEnd, want[wj].End)
+					wj++
+				} else {
+					break
+				}
+			}
+			divs = append(divs, Divergence{Boundary, min(got[i].Pos, want[j].Pos), max(gEnd, wEnd), got[i:gi], want[j:wj]})
+			i, j = gi, wj
+		}
+	}
+	return divs
+}
+
+func sameSpan(a, b Token) bool {
+	return a.Pos == b.Pos && a.End == b.End
+}
+
+// Harness compares a lexer to a reference.
+type Harness struct {
+	Lexer     Tokenizer
+	Reference Tokenizer
+
+	// Types translates the type names of the lexer to those of the
+	// reference.  Names not in Types are compared unchanged.
+	Types map[string]string
+
+	// Ignore, if not nil, removes the tokens of either side for which it
+	// returns true before they are compared, such as comments which only
+	// one side reports.
+	Ignore func(Token) bool
+}
+
+// Diff tokenizes src with the lexer and the reference and compares their
+// tokens.  It returns an error if either fails to tokenize src.
+func (h *Harness) Diff(src string) ([]Divergence, error) {
+	got, err := h.Lexer(src)
+	if err != nil {
+		return nil, fmt.Errorf("lexer: %v", err)
+	}
+	want, err := h.Reference(src)
+	if err != nil {
+		return nil, fmt.Errorf("reference: %v", err)
+	}
+	return Compare(h.filter(got), h.filter(want), h.Types), nil
+}
+
+func (h *Harness) filter(toks []Token) []Token {
+	if h.Ignore == nil {
+		return toks
+	}
+	var kept []Token
+	for _, t := range toks {
+		if !h.Ignore(t) {
+			kept = append(kept, t)
+		}
+	}
+	return kept
+}
+
+// Check reports the divergences of each input of corpus as errors of t.
+func (h *Harness) Check(t testing.TB, corpus ...string) {
+	t.Helper()
+	for _, src := range corpus {
+		divs, err := h.Diff(src)
+		if err != nil {
+			t.Errorf("input %q: %v", src, err)
+			continue
+		}
+		for i := range divs {
+			t.Errorf("input %q: %v", src, &divs[i])
+		}
+	}
+}
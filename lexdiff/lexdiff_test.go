@@ -0,0 +1,113 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexdiff
+
+import (
+	"reflect"
+	"strconv"
+	"testing"
+	"text/scanner"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+const (
+	itemIdent lexer.ItemType = iota
+	itemInt
+	itemPunct
+)
+
+var typeNames = []string{"ident", "int"}
+
+func typeName(item *lexer.Item) string {
+	if item.Type == itemPunct {
+		return strconv.Quote(item.Value)
+	}
+	return typeNames[item.Type]
+}
+
+const letters = "abcdefghijklmnopqrstuvwxyz."
+
+// lexPorted lexes identifiers, integers and punctuation, incorrectly
+// including dots in identifiers.
+func lexPorted(l *lexer.Lexer) lexer.StateFn {
+	l.AcceptRun(" \n")
+	l.Ignore()
+	switch {
+	case l.AcceptRun(letters) > 0:
+		l.AcceptRun(letters + "0123456789")
+		l.Emit(itemIdent)
+	case l.AcceptRun("0123456789") > 0:
+		l.Emit(itemInt)
+	case l.Accept("+-*/=;()"):
+		l.Emit(itemPunct)
+	case l.Accept("#"):
+		return l.Errorf("unexpected %q", l.Current())
+	default:
+		return nil
+	}
+	return lexPorted
+}
+
+func TestHarness(t *testing.T) {
+	h := &Harness{
+		Lexer:     Lexer(lexPorted, typeName),
+		Reference: TextScanner(scanner.GoTokens),
+		Types:     map[string]string{"ident": "Ident", "int": "Int"},
+	}
+	h.Check(t, "x = 1 + y2", "f(a) * 3")
+
+	divs, err := h.Diff("a = b.c + 1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Divergence{
+		{Boundary, 4, 7,
+			[]Token{{4, 7, "ident", "b.c"}},
+			[]Token{{4, 5, "Ident", "b"}, {5, 6, `"."`, "."}, {6, 7, "Ident", "c"}}},
+	}
+	if !reflect.DeepEqual(divs, want) {
+		t.Errorf("got %v\nwant %v", divs, want)
+	}
+	if s := divs[0].String(); s != `4-7: boundary: got ident "b.c", want Ident "b", "." ".", Ident "c"` {
+		t.Errorf("String() = %q", s)
+	}
+
+	if _, err := h.Diff("a # b"); err == nil {
+		t.Errorf("no error for a lexer error")
+	}
+}
+
+func TestCompare(t *testing.T) {
+	got := []Token{{0, 2, "a", ""}, {3, 4, "b", ""}, {6, 8, "c", ""}, {9, 12, "d", ""}}
+	want := []Token{{0, 2, "a", ""}, {4, 5, "x", ""}, {6, 7, "c", ""}, {7, 9, "c", ""}, {9, 12, "D", ""}}
+	var kinds []Kind
+	for _, d := range Compare(got, want, map[string]string{"d": "D"}) {
+		kinds = append(kinds, d.Kind)
+	}
+	if !reflect.DeepEqual(kinds, []Kind{Extra, Missing, Boundary}) {
+		t.Errorf("kinds %v", kinds)
+	}
+}
+
+func TestGoScanner(t *testing.T) {
+	toks, err := GoScanner("x := 1 // one\r\nreturn\n")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []Token{
+		{0, 1, "IDENT", "x"},
+		{2, 4, ":=", ":="},
+		{5, 6, "INT", "1"},
+		{7, 13, "COMMENT", "// one"},
+		{15, 21, "return", "return"},
+	}
+	if !reflect.DeepEqual(toks, want) {
+		t.Errorf("got %v\nwant %v", toks, want)
+	}
+	if _, err := GoScanner(`"open`); err == nil {
+		t.Errorf("no error for an unterminated string")
+	}
+}
@@ -18,19 +18,19 @@ There are some key differences to Pike's presented code.  Next has been renamed
 Advance to be more idiomatic with Backup. Next is used by the parser to
 retrieve items from the lexer.
 
-Two APIs
+# Two APIs
 
 The Lexer type has two APIs, one is used byte StateFn types.  The other is
 called by the parser. These APIs are called the scanner and the parser APIs
 here.
 
-The parser API
+# The parser API
 
 The only function the parser calls on the lexer is Next to retreive the next
 token from the input stream.  Eventually an item with type ItemEOF is returned
 at which point there are no more tokens in the stream.
 
-The scanner API
+# The scanner API
 
 The lexer uses Emit to construct complete lexemes to return from
 future/concurrent calls to Next by the parser.  The scanner uses a combination
@@ -48,18 +48,45 @@ character classes.
 
 The remaining methods provide low level functionality that can be combined to
 address corner cases.
+
+# Concurrency
+
+A lexer definition is separate from the state of a run over an input.  The
+definition is the start state, the options and the tables states consult,
+and is immutable once built, so that one definition can serve any number of
+goroutines.  A Lexer holds the state of a single run and belongs to the
+goroutine calling its Next method; it is never safe for concurrent use
+(SyncLexer serializes access to one).  Program packages a definition for
+services which lex many inputs in parallel.
+
+	var prog = lexer.NewProgram(func() lexer.StateFn { return lexStart })
+
+	go func() { lex := prog.New(a); ... }()
+	go func() { lex := prog.New(b); ... }()
+
+A definition is safe to share as long as its states keep mutable state only
+in closures created by the start function, as Program and Language do, and
+not in package variables.  The values given to WithStateGraph, WithRecording,
+WithCoverage, WithTrace and WithDiagnostics are mutated by the lexers using
+them, so options holding them must not be shared by lexers running
+concurrently.
+The states built by package rules and the languages of package presets are
+safe to share.
 */
 package lexer
 
 import (
 	"container/list"
 	"fmt"
+	"io"
 	"math"
 	"strings"
 	"unicode"
 	"unicode/utf8"
 )
 
+// EOF is the rune returned by Advance when input has been consumed, unless
+// another sentinel is given to WithEOF.
 const EOF rune = 0x04
 
 // IsEOF returns true if n is zero.
@@ -79,24 +106,89 @@ type StateFn func(*Lexer) StateFn
 // Lexer contains an input string and state associate with the lexing the
 // input.
 type Lexer struct {
-	input string     // string being scanned
-	start int        // start position for the current lexeme
-	pos   int        // current position
-	width int        // length of the last rune read
-	last  rune       // the last rune read
-	state StateFn    // the current state
-	items *list.List // Buffer of lexed items
+	input     string     // string being scanned
+	start     int        // start position for the current lexeme
+	pos       int        // current position
+	width     int        // length of the last rune read
+	last      rune       // the last rune read
+	state     StateFn    // the current state
+	items     *list.List // Buffer of lexed items
+	begin     StateFn    // the start state given to New
+	name      string     // name of the input, see WithName
+	tabs      int        // tab width, see WithTabWidth
+	lines     []int      // offsets of known line starts
+	limits    Limits     // resource limits, see WithLimits
+	trace     io.Writer  // destination of trace output, see WithTrace
+	policy    ErrorPolicy
+	eof       rune // rune returned by Advance at the end of input
+	track     bool // stamp items with line and column, see WithLineTracking
+	graph     *StateGraph
+	rec       *Recording
+	sync      string // synchronization runes, see WithSyncRunes
+	sink      DiagnosticSink
+	quiet     bool // do not emit items reported to sink
+	opts      []Option
+	delim     string               // record delimiter, see WithRecordDelim
+	base      int                  // offset of input in the parent lexer's source
+	inRecord  bool                 // created by NextRecord
+	raw       bool                 // do not decompress input, see WithoutDecompression
+	strict    bool                 // check invariants, see WithStrict
+	end       int                  // end of the last emitted lexeme
+	unread    bool                 // Advance read an invalid rune and did not move
+	backed    bool                 // Backup was called since the last Advance
+	steps     int                  // number of state function calls
+	count     int                  // number of items emitted
+	errors    int                  // number of errors emitted
+	halted    bool                 // lexing was stopped by the lexer itself
+	jump      int                  // state requested by StateTable.Goto, plus one
+	ipool     *ItemPool            // see WithItemPool
+	format    *ItemFormat          // see WithItemFormat
+	cover     *Coverage            // see WithCoverage
+	eofs      EOFPolicy            // see WithEOFPolicy
+	eofSent   bool                 // Next returned an item of type ItemEOF
+	framed    bool                 // the input ends a record, see AppendRecord
+	frames    []string             // records waiting for the current one to end
+	stop      StopReason           // see StopReason
+	stopCheck bool                 // see WithStopCheck
+	probe     bool                 // the lexer asks a state for its name, see Named
+	onEnter   func(string, *Lexer) // see WithStateHooks
+	onExit    func(string, *Lexer)
+	sprof     *StateProfiler   // see WithStateProfiler
+	watch     *watchdog        // see WithWatchdog
+	rescan    *rescanCheck     // see WithRescanCheck
+	unit      ColumnUnit       // see WithColumnUnit
+	runes     bool             // see WithRuneOffsets
+	rcur      runeCursor       // rune offsets counted so far
+	rbase     runeCursor       // rune offsets of the first retained byte
+	pcache    positionCache    // the last position computed
+	scanned   int              // offset through which lines are indexed
+	retain    int              // bytes retained before the lexeme, see WithRetention
+	pins      []int            // offsets pinned by Pin
+	window    bool             // input is discarded, see WithRetention
+	dropped   int              // bytes of source discarded
+	dropLine  int              // line of the first retained byte, from zero
+	dropCol   int              // column of the first retained byte, from zero
+	lineBase  int              // lines of the source preceding the line index, see Load
+	modes     []string         // see PushMode
+	xforms    []InputTransform // see WithInputTransform
+	src       string           // the source of a transformed input
+	tmap      *TransformMap    // maps input offsets to the source
 }
 
-// Create a new lexer. Must be given a non-nil state.
-func New(start StateFn, input string) *Lexer {
-	if start == nil {
-		panic("nil start state")
-	}
-	return &Lexer{
-		state: start,
-		input: input,
-		items: list.New(),
+// Create a new lexer. Must be given a non-nil state.  The lexer's behavior
+// may be altered by opts.
+func New(start StateFn, input string, opts ...Option) *Lexer {
+	l := &Lexer{items: list.New(), lines: []int{0}}
+	l.reset(start, input, opts)
+	return l
+}
+
+// checkInput makes l halt if its input exceeds the MaxInput limit.
+func (l *Lexer) checkInput() {
+	if l.limits.MaxInput > 0 && len(l.input) > l.limits.MaxInput {
+		l.state = func(l *Lexer) StateFn {
+			return l.halt("input exceeds the limit of %d bytes", l.limits.MaxInput)
+		}
 	}
 }
 
@@ -131,16 +223,20 @@ func (l *Lexer) Last() (r rune, width int) {
 // position, and returns the input rune with its size in bytes (encoded as
 // UTF-8).  Invalid UTF-8 codepoints cause the current call and all subsequent
 // calls to return (utf8.RuneError, 1).  If there is no input the returned size
-// is zero.
+// is zero and the returned rune is EOF (or the sentinel given to WithEOF).
 func (l *Lexer) Advance() (rune, int) {
+	l.record(Call{Op: "advance"})
 	if l.pos >= len(l.input) {
 		l.width = 0
-		return EOF, l.width
+		return l.eof, l.width
 	}
 	l.last, l.width = utf8.DecodeRuneInString(l.input[l.pos:])
+	l.backed = false
 	if l.last == utf8.RuneError && l.width == 1 {
+		l.unread = true
 		return l.last, l.width
 	}
+	l.unread = false
 	l.pos += l.width
 	return l.last, l.width
 }
@@ -149,28 +245,127 @@ func (l *Lexer) Advance() (rune, int) {
 // back in the input string accordingly. Backup should only be called after a
 // call to Advance.
 func (l *Lexer) Backup() {
+	l.record(Call{Op: "backup"})
+	if l.strict && l.width > 0 {
+		switch {
+		case l.unread:
+			l.violation("Backup after Advance read an invalid rune")
+		case l.backed:
+			l.violation("Backup called twice after Advance")
+		case l.pos-l.width < l.start:
+			l.violation("Backup past the start of the lexeme")
+		}
+	}
+	l.backed = true
 	l.pos -= l.width
 }
 
 // Peek returns the next rune in the input stream without adding it to the
-// current lexeme.
+// current lexeme.  Its results are those Advance would return.  Peek does not
+// change the values reported by Last, so it may be called between Advance and
+// Backup.
 func (l *Lexer) Peek() (rune, int) {
-	c, n := l.Advance()
-	l.Backup()
-	return c, n
+	if l.pos >= len(l.input) {
+		return l.eof, 0
+	}
+	return utf8.DecodeRuneInString(l.input[l.pos:])
+}
+
+// Checkpoint is a saved lexer position returned by Lexer.Checkpoint.
+type Checkpoint struct {
+	start  int
+	pos    int
+	width  int
+	last   rune
+	items  int
+	end    int
+	errors int
+}
+
+// Checkpoint saves the position of l so that it may be later restored.
+func (l *Lexer) Checkpoint() Checkpoint {
+	return Checkpoint{l.start, l.pos, l.width, l.last, l.items.Len(), l.end, l.errors}
+}
+
+// Restore resets l to the position saved in c.  Any items emitted since c was
+// created are discarded, and errors among them no longer count toward the
+// error policy of l.  Restore has no effect after l halts, such as on
+// exceeding a limit, so that the error item stating why it halted is kept.
+// Restore must only be called from the StateFn that created c.
+func (l *Lexer) Restore(c Checkpoint) {
+	if l.halted {
+		return
+	}
+	l.record(Call{Op: "restore", Args: []int{c.start, c.pos, c.width, int(c.last), l.items.Len() - c.items, c.end, l.errors - c.errors}})
+	l.start, l.pos, l.width, l.last, l.end, l.errors = c.start, c.pos, c.width, c.last, c.end, c.errors
+	l.unread, l.backed = false, false
+	for l.items.Len() > c.items {
+		l.items.Remove(l.items.Back())
+		l.count--
+	}
+}
+
+// Try calls fn and returns its result.  If fn returns false l is restored to
+// its position before the call and items emitted by fn are discarded.
+//
+//	isFloat := l.Try(func(l *lexer.Lexer) bool {
+//		return l.AcceptRun(digits) > 0 && l.Accept(".") && l.AcceptRun(digits) > 0
+//	})
+func (l *Lexer) Try(fn func(*Lexer) bool) bool {
+	c := l.Checkpoint()
+	if fn(l) {
+		return true
+	}
+	l.Restore(c)
+	return false
 }
 
 // Ignore throws away the current lexeme.
 func (l *Lexer) Ignore() {
+	l.record(Call{Op: "ignore"})
+	if l.strict && l.unread {
+		l.violation("Ignore after Advance read an invalid rune")
+	}
 	l.start = l.pos
 }
 
+// SyncTo discards input up to the next rune in valid, which is left unread,
+// and returns true.  If no rune in valid remains SyncTo discards the rest of
+// the input and returns false.  SyncTo is used to recover from an error by
+// skipping to a point where lexing can resume, such as a statement
+// terminator.
+//
+//	l.Errorf("unexpected %q", r)
+//	l.SyncTo(";\n")
+//	return lexStatement
+func (l *Lexer) SyncTo(valid string) bool {
+	l.record(Call{Op: "syncto", Arg: valid})
+	return l.syncTo(valid)
+}
+
+func (l *Lexer) syncTo(valid string) bool {
+	i := strings.IndexAny(l.input[l.pos:], valid)
+	if i < 0 {
+		l.pos = len(l.input)
+	} else {
+		l.pos += i
+	}
+	l.start = l.pos
+	l.width, l.unread, l.backed = 0, false, false
+	return i >= 0
+}
+
 // Accept advances the lexer if the next rune is in valid.
 func (l *Lexer) Accept(valid string) (ok bool) {
-	r, _ := l.Advance()
-	ok = strings.IndexRune(valid, r) >= 0
-	if !ok {
-		l.Backup()
+	r, n := l.Advance()
+	if !IsEOF(r, n) && !IsInvalid(r, n) {
+		ok = strings.IndexRune(valid, r) >= 0
+		if !ok {
+			l.Backup()
+		}
+	}
+	if l.cover != nil {
+		l.cover.branch(l, "Accept", valid, ok)
 	}
 	return
 }
@@ -179,35 +374,91 @@ func (l *Lexer) Accept(valid string) (ok bool) {
 func (l *Lexer) AcceptFunc(fn func(rune) bool) (ok bool) {
 	switch r, n := l.Advance(); {
 	case IsEOF(r, n):
-		return false
 	case IsInvalid(r, n):
-		return false
 	case fn(r):
-		return true
+		ok = true
 	default:
 		l.Backup()
-		return false
 	}
+	if l.cover != nil {
+		l.cover.branch(l, "AcceptFunc", l.cover.names.name(fn), ok)
+	}
+	return
 }
 
 // AcceptRange advances l's position if the current rune is in tab.
 func (l *Lexer) AcceptRange(tab *unicode.RangeTable) (ok bool) {
-	r, _ := l.Advance()
-	ok = unicode.Is(tab, r)
-	if !ok {
-		l.Backup()
+	r, n := l.Advance()
+	if !IsEOF(r, n) && !IsInvalid(r, n) {
+		ok = unicode.Is(tab, r)
+		if !ok {
+			l.Backup()
+		}
+	}
+	if l.cover != nil {
+		l.cover.branch(l, "AcceptRange", "", ok)
+	}
+	return
+}
+
+// AcceptRanges advances l's position if the current rune is in any of tabs.
+// To match a union of many tables frequently, pass a single table built with
+// MergeRanges.
+func (l *Lexer) AcceptRanges(tabs ...*unicode.RangeTable) (ok bool) {
+	r, n := l.Advance()
+	if !IsEOF(r, n) && !IsInvalid(r, n) {
+		ok = unicode.In(r, tabs...)
+		if !ok {
+			l.Backup()
+		}
+	}
+	if l.cover != nil {
+		l.cover.branch(l, "AcceptRanges", "", ok)
 	}
 	return
 }
 
 // AcceptRun advances l's position as long as the current rune is in valid.
+// When valid contains only ASCII characters the run is scanned bytewise.
 func (l *Lexer) AcceptRun(valid string) (n int) {
+	n = l.skipASCII(valid)
 	for l.Accept(valid) {
 		n++
 	}
 	return
 }
 
+// AcceptN advances l's position over exactly n runes in valid.  If fewer than
+// n runes in valid are next in the input AcceptN returns false and l's
+// position is unchanged.
+func (l *Lexer) AcceptN(valid string, n int) bool {
+	c := l.Checkpoint()
+	for i := 0; i < n; i++ {
+		if !l.Accept(valid) {
+			l.Restore(c)
+			return false
+		}
+	}
+	return true
+}
+
+// AcceptBetween advances l's position over at least min and at most max runes
+// in valid and returns the number of runes accepted.  If fewer than min runes
+// in valid are next in the input AcceptBetween returns zero and l's position
+// is unchanged.
+func (l *Lexer) AcceptBetween(valid string, min, max int) int {
+	c := l.Checkpoint()
+	n := 0
+	for n < max && l.Accept(valid) {
+		n++
+	}
+	if n < min {
+		l.Restore(c)
+		return 0
+	}
+	return n
+}
+
 // AcceptRunFunc advances l's position as long as fn returns true for the next
 // input rune.
 func (l *Lexer) AcceptRunFunc(fn func(rune) bool) int {
@@ -218,6 +469,42 @@ func (l *Lexer) AcceptRunFunc(fn func(rune) bool) int {
 	return n
 }
 
+// AdvanceWhile advances l's position as long as fn returns true for the next
+// input rune and returns the input consumed.  It has the effect of
+// AcceptRunFunc, which it is much faster than for long runs as it decodes
+// the input in a single pass without calling Advance for each rune.  As with
+// Advance, the run ends at invalid UTF-8.  After AdvanceWhile Last returns
+// the last rune consumed, which Backup removes from the lexeme.
+func (l *Lexer) AdvanceWhile(fn func(rune) bool) (consumed string) {
+	start, pos := l.pos, l.pos
+	input := l.input
+	var last rune
+	width := 0
+	for pos < len(input) {
+		r, n := rune(input[pos]), 1
+		if r >= utf8.RuneSelf {
+			r, n = utf8.DecodeRuneInString(input[pos:])
+			if r == utf8.RuneError && n == 1 {
+				break
+			}
+		}
+		if !fn(r) {
+			break
+		}
+		last, width = r, n
+		pos += n
+	}
+	if pos == start {
+		return ""
+	}
+	consumed = input[start:pos]
+	l.record(Call{Op: "advancewhile", Arg: consumed, Args: []int{width, int(last)}})
+	l.pos = pos
+	l.last, l.width = last, width
+	l.unread, l.backed = false, false
+	return consumed
+}
+
 // AcceptRunRange advances l's possition as long as the current rune is in tab.
 func (l *Lexer) AcceptRunRange(tab *unicode.RangeTable) (n int) {
 	for l.AcceptRange(tab) {
@@ -226,11 +513,35 @@ func (l *Lexer) AcceptRunRange(tab *unicode.RangeTable) (n int) {
 	return
 }
 
+// AcceptRunRanges advances l's position as long as the current rune is in
+// any of tabs.
+func (l *Lexer) AcceptRunRanges(tabs ...*unicode.RangeTable) (n int) {
+	for l.AcceptRanges(tabs...) {
+		n++
+	}
+	return
+}
+
 // AcceptString advances the lexer len(s) bytes if the next len(s) bytes equal
 // s. AcceptString returns true if l advanced.
 func (l *Lexer) AcceptString(s string) (ok bool) {
+	if l.rescan != nil {
+		l.rescan.check(l, s)
+	}
+	ok = l.acceptString(s)
+	if l.cover != nil {
+		l.cover.branch(l, "AcceptString", s, ok)
+	}
+	return
+}
+
+// acceptString is AcceptString without coverage, for text taken from the
+// input itself.
+func (l *Lexer) acceptString(s string) bool {
 	if strings.HasPrefix(l.input[l.pos:], s) {
+		l.record(Call{Op: "acceptstring", Arg: s})
 		l.pos += len(s)
+		l.unread = false
 		return true
 	}
 	return false
@@ -238,51 +549,253 @@ func (l *Lexer) AcceptString(s string) (ok bool) {
 
 // Errorf causes an error item to be emitted from l.Next().  The item's value
 // (and its error message) are the result of evaluating format and vs with
-// fmt.Sprintf.
+// fmt.Sprintf.  The item's Payload is a *LexError holding the message and the
+// current lexeme, which is the text that failed to lex.
 func (l *Lexer) Errorf(format string, vs ...interface{}) StateFn {
-	l.enqueue(&Item{
-		ItemError,
-		l.start,
-		fmt.Sprintf(format, vs...),
-	})
+	msg := fmt.Sprintf(format, vs...)
+	l.errors++
+	l.enqueue(l.errorItem(msg))
+	l.record(Call{Op: "errorf", Arg: msg})
 	return nil
 }
 
+// ErrorAt causes an error item for err to be emitted from l.Next(), as by
+// Errorf but positioned at the offending text of err rather than at the
+// current lexeme.  The item's Payload is err, which should be an error
+// returned by a method of l, such as ScanCharLiteral.
+func (l *Lexer) ErrorAt(err *LexError) StateFn {
+	l.errors++
+	i := l.newItem()
+	i.Type, i.Pos, i.Value, i.Payload = ItemError, err.Pos, err.Msg, err
+	l.enqueue(i)
+	l.record(Call{Op: "errorat", Arg: err.Msg, Args: []int{err.Pos, err.End}})
+	return nil
+}
+
+// Warnf causes a warning item to be emitted from l.Next().  Warnings report
+// recoverable problems, such as deprecated syntax, and do not affect lexing.
+// The item's value is the result of evaluating format and vs with
+// fmt.Sprintf and its Payload is a *LexError for the current lexeme.
+func (l *Lexer) Warnf(format string, vs ...interface{}) {
+	msg := fmt.Sprintf(format, vs...)
+	item := l.errorItem(msg)
+	item.Type = ItemWarning
+	l.enqueue(item)
+	l.record(Call{Op: "warnf", Arg: msg})
+}
+
+// errorItem returns an error item for the current lexeme.
+func (l *Lexer) errorItem(msg string) *Item {
+	pos, end := l.offset(l.start), l.offset(l.pos)
+	if l.pos == l.start {
+		end = pos
+	}
+	i := l.newItem()
+	i.Type, i.Pos, i.Value = ItemError, pos, msg
+	i.Payload = l.lexError(msg, pos, end)
+	return i
+}
+
 // Emit the current value as an Item with the specified type.
 func (l *Lexer) Emit(t ItemType) {
-	l.enqueue(&Item{
-		t,
-		l.start,
-		l.input[l.start:l.pos],
-	})
+	l.emit(t, l.input[l.start:l.pos], Call{Op: "emit", Type: t})
+}
+
+// EmitValue emits the current lexeme as an Item with the specified type and
+// the value v in place of the text of the lexeme, such as a decoded string
+// literal.
+func (l *Lexer) EmitValue(t ItemType, v string) {
+	l.emit(t, v, Call{Op: "emitvalue", Type: t, Arg: v})
+}
+
+func (l *Lexer) emit(t ItemType, v string, c Call) {
+	if l.strict {
+		switch {
+		case l.start > l.pos:
+			l.violation("Emit with start after the current position")
+		case l.start < l.end:
+			l.violation("Emit overlaps the lexeme emitted ending at %d", l.end)
+		}
+	}
+	if max := l.limits.MaxTokenLen; max > 0 && l.pos-l.start > max {
+		l.halt("token exceeds the limit of %d bytes", max)
+		return
+	}
+	i := l.newItem()
+	i.Type, i.Pos, i.Value = t, l.offset(l.start), v
+	if end := l.offset(l.pos); end != i.Pos+len(v) {
+		i.end = end + 1
+	}
+	l.enqueue(i)
+	l.record(c)
 	l.start = l.pos
+	l.end = l.pos
 }
 
-// The method by which items are extracted from the input.
-// Returns nil if the lexer has entered a nil state.
+// The method by which items are extracted from the input.  After the lexer
+// stops Next returns an item of type ItemEOF, once or on every call as
+// determined by WithEOFPolicy, and nil after the first under EOFOnce.
 func (l *Lexer) Next() (i *Item) {
 	for {
 		if head := l.dequeue(); head != nil {
 			return head
 		}
 		if l.state == nil {
-			return &Item{ItemEOF, l.start, ""}
+			return l.stopped()
 		}
-		l.state = l.state(l)
+		l.step()
+	}
+}
+
+// Pending returns the number of items emitted by l which have not been
+// returned by Next.
+func (l *Lexer) Pending() int {
+	return l.items.Len()
+}
+
+// Full returns true if the item queue of l is at the MaxQueue limit given to
+// WithLimits.  A state which emits many items should return (to itself or
+// another state) when l is full, pausing lexing until the parser has
+// consumed the queued items.  Emitting an item into a full queue halts the
+// lexer with an error.
+//
+//	for !l.Full() {
+//		if !lexField(l) {
+//			return lexRecordEnd
+//		}
+//	}
+//	return lexFields
+func (l *Lexer) Full() bool {
+	return l.limits.MaxQueue > 0 && l.items.Len() >= l.limits.MaxQueue
+}
+
+// Continue restarts a lexer that has reached the nil state at state start.
+// Lexing resumes at the current position, so input that remains unconsumed or
+// was given to Append is scanned by start.  Continue allows a stream whose
+// framing alternates between token languages to be lexed by one Lexer.
+// Continue panics if l has not reached the nil state or if start is nil.
+func (l *Lexer) Continue(start StateFn) {
+	if start == nil {
+		panic("nil start state")
+	}
+	if l.state != nil {
+		panic("lexer is not stopped")
+	}
+	l.state = start
+	l.begin = start
+	l.halted = false
+	l.eofSent = false
+	l.record(Call{Op: "continue"})
+}
+
+// Append adds s to the end of l's input.  If the source would exceed
+// MaxOffset bytes Append halts l and s is not added.
+func (l *Lexer) Append(s string) {
+	if len(s) > maxOffset-l.dropped-len(l.Source()) {
+		l.halt("source exceeds the maximum offset of %d bytes", maxOffset)
+		return
+	}
+	if len(l.xforms) > 0 {
+		var input string
+		l.src += s
+		input, l.tmap = l.transform(l.src)
+		s = input[len(l.input):]
+	}
+	l.record(Call{Op: "append", Arg: s})
+	l.input += s
+	if max := l.limits.MaxInput; max > 0 && len(l.input) > max {
+		l.halt("input exceeds the limit of %d bytes", max)
+	}
+	l.discard()
+}
+
+// step calls the current state function and applies l's limits and error
+// policy to the result.
+func (l *Lexer) step() {
+	if max := l.limits.MaxSteps; max > 0 && l.steps >= max {
+		l.state = l.halt("lexer exceeded the limit of %d steps in state %s", max, graphName(l.state))
+		return
+	}
+	l.steps++
+	if l.rec != nil {
+		l.record(Call{Op: "state", Arg: graphName(l.state)})
+	}
+	if l.cover != nil {
+		l.cover.enter(l.state)
+	}
+	if l.trace != nil {
+		fmt.Fprintf(l.trace, "%sstate %s\n", l.tracePrefix(), stateName(l.state))
+	}
+	errors := l.errors
+	prev := l.state
+	var name string
+	if l.onEnter != nil || l.onExit != nil {
+		name = graphName(prev)
+	}
+	if l.onEnter != nil {
+		l.onEnter(name, l)
+	}
+	if l.sprof != nil {
+		mark := l.sprof.enter(l)
+		l.state = l.call()
+		l.sprof.exit(l, mark)
+	} else {
+		l.state = l.call()
+	}
+	if l.onExit != nil {
+		l.onExit(name, l)
+	}
+	if l.graph != nil {
+		l.graph.add(prev, l.state)
+	}
+	switch {
+	case l.halted:
+		l.state = nil
+	case l.errors == errors:
+	case l.policy == ErrorHalt:
+		l.state = nil
+	case l.policy == ErrorResume && l.state == nil:
+		l.resume()
+	}
+	if l.state == nil {
+		l.setStopReason(prev, errors)
 	}
-	panic("unreachable")
 }
 
 func (l *Lexer) enqueue(i *Item) {
+	if l.halted {
+		return
+	}
+	if max := l.limits.MaxItems; max > 0 && l.count >= max {
+		l.halt("lexer exceeded the limit of %d items", max)
+		return
+	}
+	if max := l.limits.MaxQueue; max > 0 && l.items.Len() >= max {
+		l.halt("item queue exceeded the limit of %d items", max)
+		return
+	}
+	l.count++
+	l.stamp(i)
+	if l.trace != nil {
+		fmt.Fprintf(l.trace, "%semit %d %d %q\n", l.tracePrefix(), i.Type, i.Pos, i.Value)
+	}
 	l.items.PushBack(i)
 }
 
+// dequeue removes the next item from the queue of l and returns it.  Errors
+// and warnings are reported to the sink of l as they are dequeued, rather
+// than when emitted, so that those discarded by Restore are never reported.
 func (l *Lexer) dequeue() *Item {
-	head := l.items.Front()
-	if head == nil {
-		return nil
+	for {
+		head := l.items.Front()
+		if head == nil {
+			return nil
+		}
+		i := l.items.Remove(head).(*Item)
+		if l.report(i) {
+			return i
+		}
 	}
-	return l.items.Remove(head).(*Item)
 }
 
 // A type for all the types of items in the language being lexed.
@@ -292,6 +805,10 @@ type ItemType uint16
 const (
 	ItemEOF ItemType = math.MaxUint16 - iota
 	ItemError
+	ItemWarning
+	ItemShebang   // a #! line; see Preamble
+	ItemDirective // an editor or encoding directive; see Preamble
+	ItemEOR       // the end of a record; see EndRecord and AppendRecord
 )
 
 // An individual scanned item (a lexeme).
@@ -299,28 +816,75 @@ type Item struct {
 	Type  ItemType
 	Pos   int
 	Value string
+
+	// Line and Column give the position of the item when the lexer was
+	// created with WithLineTracking.  Otherwise they are zero.
+	Line, Column int
+
+	// RuneOffset and UTF16Offset give the offset of the item in runes and
+	// in UTF-16 code units when the lexer was created with WithRuneOffsets.
+	// Otherwise they are zero.
+	RuneOffset, UTF16Offset int
+
+	// Payload holds a value decoded from the lexeme, if any.  See EmitInt,
+	// EmitFloat and EmitNumber.
+	Payload interface{}
+
+	// Lang names the embedded language whose lexer produced the item, see
+	// Delegate.  It is empty for the items of the lexer itself.
+	Lang string
+
+	end      int         // end of a lexeme unlike the value, plus one, or zero
+	pool     *ItemPool   // see WithItemPool
+	format   *ItemFormat // see WithItemFormat
+	strict   bool        // released items are marked rather than recycled
+	released bool
 }
 
-// Err returns the error corresponding to i, if one exists.
+// Err returns the error corresponding to i, if one exists.  The error is a
+// *LexError if the item was emitted by Errorf.
 func (i *Item) Err() error {
-	if i.Type == ItemError {
-		return (*Error)(i)
+	if i.Type != ItemError {
+		return nil
 	}
-	return nil
+	if err, ok := i.Payload.(*LexError); ok {
+		return err
+	}
+	return (*Error)(i)
 }
 
-// String returns the raw lexeme of i.
+// String returns the raw lexeme of i.  Values longer than the limit of the
+// item's ItemFormat are quoted and truncated, and values are redacted as it
+// describes.
 func (i *Item) String() string {
 	switch i.Type {
-	case ItemError:
-		return i.Value
+	case ItemError, ItemWarning:
+		return i.display()
 	case ItemEOF:
 		return "EOF"
 	}
-	if len(i.Value) > 10 {
-		return fmt.Sprintf("%.10q...", i.Value)
+	v := i.display()
+	if limit := i.itemFormat().Limit; limit > 0 && utf8.RuneCountInString(v) > limit {
+		return fmt.Sprintf("%.*q...", limit, v)
 	}
-	return i.Value
+	return v
+}
+
+// LexError describes an error encountered while lexing.  It separates the
+// message describing the error from the source text which caused it.
+type LexError struct {
+	Msg    string // description of the error
+	Pos    int    // byte offset of the start of the offending text
+	End    int    // byte offset of the end of the offending text
+	Lexeme string // the offending text, which may be empty
+
+	src       string // the input containing the error
+	base      int    // offset of src in the source, see WithRetention
+	line, col int    // line and column of src in the source, from zero
+}
+
+func (err *LexError) Error() string {
+	return err.Msg
 }
 
 // Error is an item of type ItemError
@@ -11,7 +11,9 @@ package lexer
  */
 
 import (
+    "strings"
     "testing"
+    "unicode"
 )
 
 
@@ -19,3 +21,266 @@ func TestLexer(t *testing.T) {
 
 }
 
+
+func TestAcceptN(t *testing.T) {
+	const hex = "0123456789abcdefABCDEF"
+	for _, test := range []struct {
+		input string
+		n     int
+		ok    bool
+		pos   int
+	}{
+		{"00e9", 4, true, 4},
+		{"00e9f", 4, true, 4},
+		{"00g9", 4, false, 0},
+		{"", 0, true, 0},
+	} {
+		lex := New(nilState, test.input)
+		if ok := lex.AcceptN(hex, test.n); ok != test.ok || lex.Pos() != test.pos {
+			t.Errorf("AcceptN(%q, %d) = %v at %d", test.input, test.n, ok, lex.Pos())
+		}
+	}
+}
+
+func TestAcceptEOF(t *testing.T) {
+	lex := New(nilState, "ab")
+	if n := lex.AcceptRun("ab" + string(EOF)); n != 2 || lex.Accept(string(EOF)) {
+		t.Errorf("accepted %d runes and EOF", n)
+	}
+	lex = New(nilState, "a", WithEOF('z'))
+	if n := lex.AcceptRun("az"); n != 1 || lex.Pos() != 1 {
+		t.Errorf("accepted %d runes at %d", n, lex.Pos())
+	}
+}
+
+func TestAcceptBetween(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		min, max int
+		n        int
+	}{
+		{"1x", 1, 2, 1},
+		{"12x", 1, 2, 2},
+		{"123", 1, 2, 2},
+		{"x", 1, 2, 0},
+		{"1x", 2, 2, 0},
+		{"x", 0, 2, 0},
+	} {
+		lex := New(nilState, test.input)
+		if n := lex.AcceptBetween(digits, test.min, test.max); n != test.n || lex.Pos() != test.n {
+			t.Errorf("AcceptBetween(%q, %d, %d) = %d at %d", test.input, test.min, test.max, n, lex.Pos())
+		}
+	}
+}
+
+func TestContinue(t *testing.T) {
+	lexHeader := func(l *Lexer) StateFn {
+		l.AcceptRun("abcdefghijklmnopqrstuvwxyz")
+		l.Emit(1)
+		l.AcceptString("\n")
+		l.Ignore()
+		return nil
+	}
+	lexDigits := func(l *Lexer) StateFn {
+		if l.AcceptRun("0123456789") > 0 {
+			l.Emit(2)
+		}
+		return nil
+	}
+	lex := New(lexHeader, "len\n")
+	if item := lex.Next(); item.Type != 1 || item.Value != "len" {
+		t.Fatalf("unexpected item %v", item)
+	}
+	if item := lex.Next(); item.Type != ItemEOF {
+		t.Fatalf("unexpected item %v", item)
+	}
+	lex.Append("123")
+	lex.Continue(lexDigits)
+	if item := lex.Next(); item.Type != 2 || item.Value != "123" || item.Pos != 4 {
+		t.Fatalf("unexpected item %v", item)
+	}
+	if item := lex.Next(); item.Type != ItemEOF {
+		t.Fatalf("unexpected item %v", item)
+	}
+}
+
+func TestAdvanceWhile(t *testing.T) {
+	for _, test := range []struct {
+		input    string
+		consumed string
+		last     rune
+	}{
+		{"abc def", "abc", 'c'},
+		{"héé1", "héé", 'é'},
+		{"ab\xffc", "ab", 'b'},
+		{"1ab", "", 0},
+		{"", "", 0},
+	} {
+		lex := New(nilState, test.input)
+		got := lex.AdvanceWhile(unicode.IsLetter)
+		if got != test.consumed || lex.Pos() != len(got) {
+			t.Errorf("AdvanceWhile(%q) = %q at %d", test.input, got, lex.Pos())
+		}
+		if r, _ := lex.Last(); got != "" && r != test.last {
+			t.Errorf("AdvanceWhile(%q): last %q", test.input, r)
+		}
+		if n := lex.AcceptRunFunc(unicode.IsLetter); n != 0 {
+			t.Errorf("AdvanceWhile(%q) left %d letters", test.input, n)
+		}
+	}
+	lex := New(nilState, "abé ")
+	lex.AdvanceWhile(unicode.IsLetter)
+	lex.Backup()
+	if lex.Current() != "ab" {
+		t.Errorf("lexeme %q after Backup", lex.Current())
+	}
+}
+
+func BenchmarkAdvanceWhile(b *testing.B) {
+	input := strings.Repeat("identifier", 100)
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		New(nilState, input).AdvanceWhile(unicode.IsLetter)
+	}
+}
+
+func BenchmarkAcceptRunFunc(b *testing.B) {
+	input := strings.Repeat("identifier", 100)
+	b.SetBytes(int64(len(input)))
+	for i := 0; i < b.N; i++ {
+		New(nilState, input).AcceptRunFunc(unicode.IsLetter)
+	}
+}
+
+func TestPeekPreservesLast(t *testing.T) {
+	lex := New(nilState, "aé\xff")
+	lex.Advance()
+	lex.Advance()
+	if r, n := lex.Peek(); !IsInvalid(r, n) {
+		t.Errorf("peek returned %q %d", r, n)
+	}
+	if r, n := lex.Last(); r != 'é' || n != 2 {
+		t.Errorf("last returned %q %d", r, n)
+	}
+	lex.Backup()
+	if lex.Pos() != 1 {
+		t.Errorf("position %d", lex.Pos())
+	}
+	if r, n := lex.Peek(); r != 'é' || n != 2 {
+		t.Errorf("peek returned %q %d", r, n)
+	}
+	lex = New(nilState, "")
+	if r, n := lex.Peek(); !IsEOF(r, n) || lex.Pos() != 0 {
+		t.Errorf("peek returned %q %d", r, n)
+	}
+}
+
+func TestTry(t *testing.T) {
+	lex := New(nilState, "12.x")
+	float := func(l *Lexer) bool {
+		l.AcceptRun("0123456789")
+		l.Emit(1)
+		return l.Accept(".") && l.AcceptRun("0123456789") > 0
+	}
+	if lex.Try(float) {
+		t.Errorf("matched %q", lex.Current())
+	}
+	if lex.Pos() != 0 || lex.Start() != 0 || lex.items.Len() != 0 {
+		t.Errorf("not restored: position %d start %d items %d", lex.Pos(), lex.Start(), lex.items.Len())
+	}
+	if !lex.Try(func(l *Lexer) bool { return l.AcceptString("12") }) || lex.Pos() != 2 {
+		t.Errorf("position %d", lex.Pos())
+	}
+}
+
+func TestTryErrorHalt(t *testing.T) {
+	var diags DiagnosticList
+	lexNumber := func(l *Lexer) StateFn {
+		hex := l.Try(func(l *Lexer) bool {
+			if !l.AcceptString("0x") {
+				return false
+			}
+			if l.AcceptRun("0123456789abcdef") == 0 {
+				l.Errorf("malformed hex number")
+				return false
+			}
+			return true
+		})
+		if !hex {
+			l.AcceptRun("0123456789x")
+		}
+		l.Emit(1)
+		return nil
+	}
+	lex := New(lexNumber, "0x", WithErrorPolicy(ErrorHalt), WithDiagnostics(&diags))
+	if item := lex.Next(); item.Type != 1 || item.Value != "0x" {
+		t.Errorf("item %v", item)
+	}
+	if item := lex.Next(); item.Type != ItemEOF {
+		t.Errorf("item %v", item)
+	}
+	if reason := lex.StopReason(); reason != StopCompleted || len(diags) != 0 {
+		t.Errorf("stopped %v with diagnostics %v", reason, diags)
+	}
+}
+
+func TestTryLimitHalt(t *testing.T) {
+	lex := New(func(l *Lexer) StateFn {
+		l.Try(func(l *Lexer) bool {
+			l.AcceptString("ab")
+			l.Emit(1)
+			l.AcceptString("cd")
+			l.Emit(1)
+			return false
+		})
+		return nil
+	}, "abcd", WithLimits(Limits{MaxItems: 1}))
+	if item := lex.Next(); item.Type != 1 || item.Value != "ab" {
+		t.Errorf("item %v", item)
+	}
+	if item := lex.Next(); item.Type != ItemError || item.Value != "lexer exceeded the limit of 1 items" {
+		t.Errorf("item %v", item)
+	}
+	if reason := lex.StopReason(); reason != StopHalted {
+		t.Errorf("stopped %v", reason)
+	}
+}
+
+func TestErrorLexeme(t *testing.T) {
+	lex := New(func(l *Lexer) StateFn {
+		l.AcceptString("ab ")
+		l.Ignore()
+		l.AcceptString("0x")
+		return l.Errorf("malformed number")
+	}, "ab 0xg")
+	item := lex.Next()
+	err, ok := item.Err().(*LexError)
+	if !ok {
+		t.Fatalf("error %#v", item.Err())
+	}
+	if item.Value != "malformed number" || err.Msg != item.Value {
+		t.Errorf("message %q %q", item.Value, err.Msg)
+	}
+	if err.Lexeme != "0x" || err.Pos != 3 || err.End != 5 {
+		t.Errorf("lexeme %q at %d-%d", err.Lexeme, err.Pos, err.End)
+	}
+}
+
+func TestWarnf(t *testing.T) {
+	lex := New(func(l *Lexer) StateFn {
+		l.AcceptString("ab")
+		l.Warnf("deprecated %q", l.Current())
+		l.Emit(1)
+		return nil
+	}, "ab", WithErrorPolicy(ErrorHalt))
+	item := lex.Next()
+	if item.Type != ItemWarning || item.Value != `deprecated "ab"` || item.Err() != nil {
+		t.Errorf("unexpected item %+v", item)
+	}
+	if w, ok := item.Payload.(*LexError); !ok || w.Lexeme != "ab" {
+		t.Errorf("payload %#v", item.Payload)
+	}
+	if item = lex.Next(); item.Type != 1 {
+		t.Errorf("unexpected item %+v", item)
+	}
+}
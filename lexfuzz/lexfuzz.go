@@ -0,0 +1,146 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package lexfuzz checks lexers built with package lexer against invariants
+that should hold for any input.  A Harness lexes an input to completion and
+reports the first violation of the following.
+
+	the lexer terminates within a bounded number of state calls
+	the lexer does not panic
+	item positions lie within the input and never decrease
+	the value of each non-error item is the input at its position
+	the values of non-error items do not overlap
+	if no error is emitted, lexing ends at the end of the input
+
+The Fuzz method makes a harness a fuzz target for go test -fuzz.
+
+	func FuzzLexer(f *testing.F) {
+		h := &lexfuzz.Harness{Start: lexStart}
+		h.Fuzz(f, "seed input", "another seed")
+	}
+*/
+package lexfuzz
+
+import (
+	"fmt"
+	"runtime/debug"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Harness describes the lexer under test.
+type Harness struct {
+	// Start is the lexer's start state.
+	Start lexer.StateFn
+
+	// Options are passed to lexer.New.
+	Options []lexer.Option
+
+	// MaxSteps bounds the number of state function calls allowed while
+	// lexing an input.  When MaxSteps is zero the bound is 16 calls per
+	// byte of input, plus 16.
+	MaxSteps int
+
+	// Contiguous requires non-error items to cover the input without gaps.
+	// Set it for lexers which emit all their input, including whitespace.
+	Contiguous bool
+}
+
+// Violation describes input for which a lexer violates an invariant.
+type Violation struct {
+	Input string
+	Msg   string
+}
+
+func (v *Violation) Error() string {
+	return fmt.Sprintf("lexfuzz: %s (input %q)", v.Msg, v.Input)
+}
+
+// Check lexes input to completion and returns a *Violation describing the
+// first invariant violated, if any.
+func (h *Harness) Check(input string) (err error) {
+	fail := func(format string, vs ...interface{}) error {
+		return &Violation{input, fmt.Sprintf(format, vs...)}
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			err = fail("panic: %v\n%s", e, debug.Stack())
+		}
+	}()
+	max := h.MaxSteps
+	if max <= 0 {
+		max = 16*len(input) + 16
+	}
+	steps := 0
+	var wrap func(lexer.StateFn) lexer.StateFn
+	wrap = func(fn lexer.StateFn) lexer.StateFn {
+		if fn == nil {
+			return nil
+		}
+		return func(l *lexer.Lexer) lexer.StateFn {
+			steps++
+			if steps > max {
+				return nil
+			}
+			return wrap(fn(l))
+		}
+	}
+	lex := lexer.New(wrap(h.Start), input, h.Options...)
+	prev, end, errs := 0, 0, 0
+	for {
+		item := lex.Next()
+		if item == nil {
+			return fail("Next returned nil before an item of type ItemEOF")
+		}
+		if steps > max {
+			return fail("lexer did not terminate within %d steps", max)
+		}
+		if item.Pos < 0 || item.Pos > len(input) {
+			return fail("item %v at position %d outside the input", item, item.Pos)
+		}
+		if item.Pos < prev {
+			return fail("item %v at position %d precedes position %d", item, item.Pos, prev)
+		}
+		prev = item.Pos
+		switch item.Type {
+		case lexer.ItemError:
+			errs++
+			continue
+		case lexer.ItemWarning:
+			continue
+		case lexer.ItemEOF:
+			if errs == 0 && item.Pos != len(input) {
+				return fail("lexing ended at position %d of %d without error", item.Pos, len(input))
+			}
+			if errs == 0 && h.Contiguous && end != len(input) {
+				return fail("input after position %d not emitted", end)
+			}
+			return nil
+		}
+		if item.Pos < end {
+			return fail("item %v at position %d overlaps the previous item ending at %d", item, item.Pos, end)
+		}
+		if h.Contiguous && item.Pos != end {
+			return fail("input from position %d to %d not emitted", end, item.Pos)
+		}
+		if item.Pos+len(item.Value) > len(input) || input[item.Pos:item.Pos+len(item.Value)] != item.Value {
+			return fail("item %q at position %d does not match the input", item.Value, item.Pos)
+		}
+		end = item.Pos + len(item.Value)
+	}
+}
+
+// Fuzz adds seeds to the corpus of f and fuzzes h.Check with it.
+func (h *Harness) Fuzz(f *testing.F, seeds ...string) {
+	for _, s := range seeds {
+		f.Add(s)
+	}
+	f.Fuzz(func(t *testing.T, input string) {
+		if err := h.Check(input); err != nil {
+			t.Fatal(err)
+		}
+	})
+}
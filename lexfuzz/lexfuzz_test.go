@@ -0,0 +1,83 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexfuzz
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+	"github.com/bmatsuo/go-lexer/presets/jsonlex"
+)
+
+func lexWords(l *lexer.Lexer) lexer.StateFn {
+	if l.AcceptRun(" ") > 0 {
+		l.Emit(2)
+	}
+	if l.AcceptRun("abcdefghijklmnopqrstuvwxyz") > 0 {
+		l.Emit(1)
+		return lexWords
+	}
+	if _, n := l.Advance(); n == 0 {
+		return nil
+	}
+	return l.Errorf("unexpected %q", l.Current())
+}
+
+func TestCheck(t *testing.T) {
+	for _, test := range []struct {
+		name  string
+		start lexer.StateFn
+		input string
+		err   string
+	}{
+		{"words", lexWords, "ab cd", ""},
+		{"error", lexWords, "ab 1", ""},
+		{"loop", func(l *lexer.Lexer) lexer.StateFn {
+			var loop lexer.StateFn
+			loop = func(*lexer.Lexer) lexer.StateFn { return loop }
+			return loop
+		}, "ab", "did not terminate"},
+		{"panic", func(l *lexer.Lexer) lexer.StateFn {
+			panic("boom")
+		}, "ab", "panic: boom"},
+		{"early", func(l *lexer.Lexer) lexer.StateFn {
+			l.Advance()
+			l.Emit(1)
+			return nil
+		}, "ab", "ended at position 1 of 2"},
+		{"overlap", func(l *lexer.Lexer) lexer.StateFn {
+			l.Advance()
+			l.Advance()
+			l.Emit(1)
+			l.Backup()
+			l.Ignore()
+			l.Advance()
+			l.Emit(1)
+			return nil
+		}, "ab", "overlaps the previous item ending at 2"},
+		{"gap", func(l *lexer.Lexer) lexer.StateFn {
+			l.Advance()
+			l.Ignore()
+			l.Advance()
+			l.Emit(1)
+			return nil
+		}, "ab", "input from position 0 to 1 not emitted"},
+	} {
+		h := &Harness{Start: test.start, Contiguous: true}
+		err := h.Check(test.input)
+		switch {
+		case test.err == "" && err != nil:
+			t.Errorf("%s: %v", test.name, err)
+		case test.err != "" && (err == nil || !strings.Contains(err.Error(), test.err)):
+			t.Errorf("%s: error %v (expected %q)", test.name, err, test.err)
+		}
+	}
+}
+
+func FuzzJSON(f *testing.F) {
+	h := &Harness{Start: jsonlex.Lex, Options: []lexer.Option{lexer.WithStrict()}}
+	h.Fuzz(f, `{"a": [1, 2.5e3, true, null]}`, `"é\n"`, `[`)
+}
@@ -0,0 +1,177 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package lexpb writes the items and diagnostics of a lexer as protocol
+buffers, so that programs in other languages, such as analysis scripts or
+web frontends, can consume the output of Go services with generated code
+instead of custom parsers.  The messages are described by the schema in
+lexer.proto, which is published with the package.
+
+A Writer is a lexer.Sink writing a stream of length-delimited Event
+messages.
+
+	w := lexpb.NewWriter(conn)
+	err := lex.Drain(w)
+
+The messages are encoded directly, so the package has no dependencies.
+*/
+package lexpb
+
+import (
+	"bufio"
+	"encoding/binary"
+	"io"
+	"strconv"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Field numbers of the messages in lexer.proto.
+const (
+	eventToken      = 1
+	eventDiagnostic = 2
+
+	tokenType     = 1
+	tokenTypeName = 2
+	tokenOffset   = 3
+	tokenValue    = 4
+	tokenLine     = 5
+	tokenColumn   = 6
+	tokenLang     = 7
+
+	diagSeverity = 1
+	diagMessage  = 2
+	diagName     = 3
+	diagOffset   = 4
+	diagEnd      = 5
+	diagLine     = 6
+	diagColumn   = 7
+	diagLexeme   = 8
+)
+
+// Wire types of protocol buffers.
+const (
+	wireVarint = 0
+	wireBytes  = 2
+)
+
+// MarshalToken returns the encoding of item as a Token message.
+func MarshalToken(item *lexer.Item) []byte {
+	var b []byte
+	b = appendVarint(b, tokenType, uint64(item.Type))
+	b = appendString(b, tokenTypeName, typeName(item.Type))
+	b = appendVarint(b, tokenOffset, uint64(item.Pos))
+	b = appendString(b, tokenValue, item.Value)
+	b = appendVarint(b, tokenLine, uint64(item.Line))
+	b = appendVarint(b, tokenColumn, uint64(item.Column))
+	b = appendString(b, tokenLang, item.Lang)
+	return b
+}
+
+// MarshalDiagnostic returns the encoding of d as a Diagnostic message.
+func MarshalDiagnostic(d *lexer.Diagnostic) []byte {
+	var b []byte
+	b = appendVarint(b, diagSeverity, uint64(d.Severity))
+	b = appendString(b, diagMessage, d.Err.Msg)
+	b = appendString(b, diagName, d.Name)
+	b = appendVarint(b, diagOffset, uint64(d.Err.Pos))
+	b = appendVarint(b, diagEnd, uint64(d.Err.End))
+	b = appendVarint(b, diagLine, uint64(d.Position.Line))
+	b = appendVarint(b, diagColumn, uint64(d.Position.Column))
+	b = appendString(b, diagLexeme, d.Err.Lexeme)
+	return b
+}
+
+// typeName returns the name registered for t, or "" if there is none.
+func typeName(t lexer.ItemType) string {
+	name := t.String()
+	if name == "ItemType("+strconv.Itoa(int(t))+")" {
+		return ""
+	}
+	return name
+}
+
+// Writer writes a stream of Event messages.  A Writer is a lexer.Sink and a
+// lexer.DiagnosticSink.  Items are written as tokens, except errors and
+// warnings, which are written as diagnostics.
+type Writer struct {
+	w    *bufio.Writer
+	name string
+	err  error
+}
+
+// NewWriter returns a writer to w.  The name of the input given in the
+// diagnostics of items is name.
+func NewWriter(w io.Writer, name string) *Writer {
+	return &Writer{w: bufio.NewWriter(w), name: name}
+}
+
+// Write writes item as an event.  An item of type ItemEOF is not written.
+func (w *Writer) Write(item lexer.Item) error {
+	switch item.Type {
+	case lexer.ItemEOF:
+		return w.err
+	case lexer.ItemError, lexer.ItemWarning:
+		if err, ok := item.Payload.(*lexer.LexError); ok {
+			d := lexer.Diagnostic{
+				Name:     w.name,
+				Position: lexer.Position{Offset: item.Pos, Line: item.Line, Column: item.Column},
+				Err:      err,
+			}
+			if item.Type == lexer.ItemWarning {
+				d.Severity = lexer.SeverityWarning
+			}
+			w.event(eventDiagnostic, MarshalDiagnostic(&d))
+			return w.err
+		}
+	}
+	w.event(eventToken, MarshalToken(&item))
+	return w.err
+}
+
+// Report writes d as an event.  Errors are returned by Write and Flush.
+func (w *Writer) Report(d lexer.Diagnostic) {
+	w.event(eventDiagnostic, MarshalDiagnostic(&d))
+}
+
+// Flush writes buffered events to the underlying writer.
+func (w *Writer) Flush() error {
+	if w.err == nil {
+		w.err = w.w.Flush()
+	}
+	return w.err
+}
+
+func (w *Writer) event(field int, msg []byte) {
+	if w.err != nil {
+		return
+	}
+	b := appendBytes(nil, field, msg)
+	b = append(binary.AppendUvarint(make([]byte, 0, len(b)+binary.MaxVarintLen32), uint64(len(b))), b...)
+	_, w.err = w.w.Write(b)
+}
+
+// appendVarint appends a varint field, omitting the default value zero.
+func appendVarint(b []byte, field int, v uint64) []byte {
+	if v == 0 {
+		return b
+	}
+	b = binary.AppendUvarint(b, uint64(field<<3|wireVarint))
+	return binary.AppendUvarint(b, v)
+}
+
+// appendString appends a string field, omitting the empty string.
+func appendString(b []byte, field int, s string) []byte {
+	if s == "" {
+		return b
+	}
+	return appendBytes(b, field, []byte(s))
+}
+
+func appendBytes(b []byte, field int, v []byte) []byte {
+	b = binary.AppendUvarint(b, uint64(field<<3|wireBytes))
+	b = binary.AppendUvarint(b, uint64(len(v)))
+	return append(b, v...)
+}
@@ -0,0 +1,86 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexpb
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"reflect"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// fields decodes a message into a map from field numbers to values, which
+// are uint64 for varints and string for bytes.
+func fields(t *testing.T, msg []byte) map[int]interface{} {
+	m := make(map[int]interface{})
+	for len(msg) > 0 {
+		key, n := binary.Uvarint(msg)
+		msg = msg[n:]
+		v, n := binary.Uvarint(msg)
+		if n <= 0 {
+			t.Fatalf("bad field %d", key>>3)
+		}
+		msg = msg[n:]
+		switch key & 7 {
+		case wireVarint:
+			m[int(key>>3)] = v
+		case wireBytes:
+			m[int(key>>3)] = string(msg[:v])
+			msg = msg[v:]
+		default:
+			t.Fatalf("wire type %d", key&7)
+		}
+	}
+	return m
+}
+
+func TestWriter(t *testing.T) {
+	lexer.RegisterItemType(41, "Word")
+	lex := lexer.New(func(l *lexer.Lexer) lexer.StateFn {
+		l.AcceptString("ab")
+		l.Emit(41)
+		l.AcceptString(" ")
+		l.Ignore()
+		l.AcceptString("!")
+		return l.Errorf("unexpected %q", l.Current())
+	}, "ab !", lexer.WithLineTracking())
+	var buf bytes.Buffer
+	if err := lex.Drain(NewWriter(&buf, "in.txt")); err != nil {
+		t.Fatal(err)
+	}
+	r := bufio.NewReader(&buf)
+	var events []map[int]interface{}
+	for {
+		n, err := binary.ReadUvarint(r)
+		if err != nil {
+			break
+		}
+		msg := make([]byte, n)
+		r.Read(msg)
+		events = append(events, fields(t, msg))
+	}
+	if len(events) != 2 {
+		t.Fatalf("events %v", events)
+	}
+	token := fields(t, []byte(events[0][eventToken].(string)))
+	want := map[int]interface{}{
+		tokenType: uint64(41), tokenTypeName: "Word", tokenValue: "ab",
+		tokenLine: uint64(1), tokenColumn: uint64(1),
+	}
+	if !reflect.DeepEqual(token, want) {
+		t.Errorf("token %v", token)
+	}
+	diag := fields(t, []byte(events[1][eventDiagnostic].(string)))
+	want = map[int]interface{}{
+		diagMessage: `unexpected "!"`, diagName: "in.txt", diagOffset: uint64(3), diagEnd: uint64(4),
+		diagLine: uint64(1), diagColumn: uint64(4), diagLexeme: "!",
+	}
+	if !reflect.DeepEqual(diag, want) {
+		t.Errorf("diagnostic %v", diag)
+	}
+}
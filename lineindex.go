@@ -0,0 +1,131 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"sort"
+	"unicode/utf8"
+)
+
+// LineIndex converts between the byte offsets of items and the lines and
+// columns of editor protocols, such as the UTF-16 positions of the Language
+// Server Protocol.  The lines of the text are indexed once, with the lines
+// made only of ASCII characters, whose columns are byte offsets in every
+// unit, marked so that converting a position on them takes constant time.
+// A language server keeps one index for each open document and converts
+// the positions of all its diagnostics and semantic tokens with it.
+//
+//	ix := lexer.NewLineIndex(text, lexer.ColumnUTF16)
+//	p := ix.Position(item.Pos) // p.Line-1 and p.Column-1 are LSP positions
+//
+// Tabs count as one column in every unit, as they do in editor protocols.
+type LineIndex struct {
+	src   string
+	unit  ColumnUnit
+	lines []int  // offsets of line starts
+	ascii []bool // the line contains only ASCII characters
+}
+
+// NewLineIndex indexes the lines of src, whose columns are counted in unit.
+func NewLineIndex(src string, unit ColumnUnit) *LineIndex {
+	ix := &LineIndex{src: src, unit: unit, lines: []int{0}}
+	ascii := true
+	for i := 0; i < len(src); i++ {
+		switch c := src[i]; {
+		case c == '\n':
+			ix.lines = append(ix.lines, i+1)
+			ix.ascii = append(ix.ascii, ascii)
+			ascii = true
+		case c >= utf8.RuneSelf:
+			ascii = false
+		}
+	}
+	ix.ascii = append(ix.ascii, ascii)
+	return ix
+}
+
+// Lines returns the number of lines of the text, counting the empty line
+// following a final newline.
+func (ix *LineIndex) Lines() int {
+	return len(ix.lines)
+}
+
+// Position returns the line and column of the byte offset, which is clamped
+// to the text.  An offset inside a multi-byte character has the column of
+// the character.
+func (ix *LineIndex) Position(offset int) Position {
+	if offset < 0 {
+		offset = 0
+	}
+	if offset > len(ix.src) {
+		offset = len(ix.src)
+	}
+	line := sort.Search(len(ix.lines), func(i int) bool { return ix.lines[i] > offset }) - 1
+	start := ix.lines[line]
+	if ix.ascii[line] {
+		return Position{Offset: offset, Line: line + 1, Column: offset - start + 1}
+	}
+	col := 0
+	for i := start; i < offset; {
+		r, n := utf8.DecodeRuneInString(ix.src[i:])
+		if i+n > offset {
+			break
+		}
+		col += ix.columns(r, n)
+		i += n
+	}
+	return Position{Offset: offset, Line: line + 1, Column: col + 1}
+}
+
+// Offset returns the byte offset of the position at line and column, both
+// counted from 1.  Lines beyond the text are clamped to it, and columns
+// beyond the end of a line to the end of the line, excluding its
+// terminating "\n" or "\r\n".  A column inside a character, such as the
+// second UTF-16 code unit of a character outside the Basic Multilingual
+// Plane, is moved to the following character.
+func (ix *LineIndex) Offset(line, col int) int {
+	if line < 1 {
+		return 0
+	}
+	if line > len(ix.lines) {
+		return len(ix.src)
+	}
+	start := ix.lines[line-1]
+	end := len(ix.src)
+	if line < len(ix.lines) {
+		end = ix.lines[line] - 1
+		if end > start && ix.src[end-1] == '\r' {
+			end--
+		}
+	}
+	if ix.ascii[line-1] {
+		if col < 1 {
+			return start
+		}
+		if start+col-1 > end {
+			return end
+		}
+		return start + col - 1
+	}
+	i := start
+	for c := 1; c < col && i < end; {
+		r, n := utf8.DecodeRuneInString(ix.src[i:end])
+		c += ix.columns(r, n)
+		i += n
+	}
+	return i
+}
+
+func (ix *LineIndex) columns(r rune, n int) int {
+	switch ix.unit {
+	case ColumnBytes:
+		return n
+	case ColumnUTF16:
+		if r >= 0x10000 && n == 4 {
+			return 2
+		}
+	}
+	return 1
+}
@@ -0,0 +1,85 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLineIndex(t *testing.T) {
+	src := "ab\r\ncé😀d\n\n\tx"
+	for _, test := range []struct {
+		unit   ColumnUnit
+		offset int
+		line   int
+		col    int
+	}{
+		{ColumnUTF16, 0, 1, 1},
+		{ColumnUTF16, 2, 1, 3},
+		{ColumnUTF16, 4, 2, 1},
+		{ColumnUTF16, 7, 2, 3},
+		{ColumnUTF16, 11, 2, 5},
+		{ColumnUTF16, 9, 2, 3}, // inside the emoji
+		{ColumnUTF16, 13, 3, 1},
+		{ColumnUTF16, 15, 4, 2},
+		{ColumnUTF16, 99, 4, 3},
+		{ColumnRunes, 11, 2, 4},
+		{ColumnBytes, 11, 2, 8},
+	} {
+		ix := NewLineIndex(src, test.unit)
+		p := ix.Position(test.offset)
+		if p.Line != test.line || p.Column != test.col {
+			t.Errorf("unit %d offset %d: position %v (expected %d:%d)", test.unit, test.offset, p, test.line, test.col)
+		}
+		want := test.offset
+		switch {
+		case want > len(src):
+			want = len(src)
+		case want == 9:
+			want = 7
+		}
+		if off := ix.Offset(p.Line, p.Column); off != want {
+			t.Errorf("unit %d %v: offset %d (expected %d)", test.unit, p, off, want)
+		}
+	}
+	ix := NewLineIndex(src, ColumnUTF16)
+	if n := ix.Lines(); n != 4 {
+		t.Errorf("%d lines", n)
+	}
+	for _, test := range []struct{ line, col, offset int }{
+		{1, 99, 2}, // before "\r\n"
+		{2, 4, 11}, // second unit of the emoji
+		{2, 99, 12},
+		{9, 1, len(src)},
+		{0, 5, 0},
+	} {
+		if off := ix.Offset(test.line, test.col); off != test.offset {
+			t.Errorf("offset of %d:%d %d (expected %d)", test.line, test.col, off, test.offset)
+		}
+	}
+}
+
+// TestLineIndexLexer checks that the index agrees with the columns stamped
+// by a lexer counting in the same unit.
+func TestLineIndexLexer(t *testing.T) {
+	src := strings.Repeat("fé 😀x ab\n", 50)
+	ix := NewLineIndex(src, ColumnUTF16)
+	lex := New(lexFields, src, WithLineTracking(), WithColumnUnit(ColumnUTF16))
+	for item := lex.Next(); item.Type != ItemEOF; item = lex.Next() {
+		if p := ix.Position(item.Pos); p.Line != item.Line || p.Column != item.Column {
+			t.Fatalf("%q at %d: index %v lexer %d:%d", item.Value, item.Pos, p, item.Line, item.Column)
+		}
+	}
+}
+
+func BenchmarkPositionLongLine(b *testing.B) {
+	src := strings.Repeat("é ", 5000)
+	for i := 0; i < b.N; i++ {
+		lex := New(lexFields, src, WithLineTracking())
+		for lex.Next().Type != ItemEOF {
+		}
+	}
+}
@@ -0,0 +1,82 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+)
+
+// AcceptLine advances l's position to the end of the current line and returns
+// the number of bytes accepted.  The line terminator, "\n" or "\r\n", is not
+// accepted.
+func (l *Lexer) AcceptLine() int {
+	end := l.lineEnd()
+	n := end - l.pos
+	l.pos = end
+	l.width, l.unread, l.backed = 0, false, false
+	return n
+}
+
+// EmitLine emits the rest of the current line, which may be empty, as an item
+// of type t and then discards the line terminator.
+func (l *Lexer) EmitLine(t ItemType) {
+	l.AcceptLine()
+	l.Emit(t)
+	l.acceptNewline()
+	l.Ignore()
+}
+
+// lineEnd returns the offset of the terminator of the current line.
+func (l *Lexer) lineEnd() int {
+	i := strings.IndexByte(l.input[l.pos:], '\n')
+	if i < 0 {
+		return len(l.input)
+	}
+	end := l.pos + i
+	if end > l.pos && l.input[end-1] == '\r' {
+		end--
+	}
+	return end
+}
+
+func (l *Lexer) acceptNewline() bool {
+	return l.AcceptString("\n") || l.AcceptString("\r\n")
+}
+
+// ByLine returns a state which lexes input one line at a time.  For each line
+// the lexer starts in state line and runs with its input limited to the line,
+// excluding the line terminator, until a state returns nil.  Any remainder of
+// the line is then discarded and the next line begins.  Lexing stops at the
+// end of the input or after a line in which an error was emitted.
+//
+// Item positions are offsets in the entire input.
+func ByLine(line StateFn) StateFn {
+	var run func(StateFn) StateFn
+	run = func(state StateFn) StateFn {
+		return func(l *Lexer) StateFn {
+			full := l.input
+			errors := l.errors
+			l.input = full[:l.lineEnd()]
+			next := state(l)
+			end := len(l.input)
+			l.input = full
+			if next != nil {
+				return run(next)
+			}
+			if l.errors > errors {
+				return nil
+			}
+			l.pos = end
+			l.width = 0
+			if !l.acceptNewline() {
+				l.Ignore()
+				return nil
+			}
+			l.Ignore()
+			return run(line)
+		}
+	}
+	return run(line)
+}
@@ -0,0 +1,65 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestEmitLine(t *testing.T) {
+	lex := New(func(l *Lexer) StateFn {
+		if l.Pos() == len(l.Input()) {
+			return nil
+		}
+		l.AcceptString("#")
+		l.EmitLine(1)
+		return l.state
+	}, "#ab\r\n\ncd")
+	var items []string
+	for _, v := range collect(lex) {
+		items = append(items, fmt.Sprintf("%q", v))
+	}
+	if s := strings.Join(items, "|"); s != `"#ab"|""|"cd"` {
+		t.Errorf("items %s", s)
+	}
+}
+
+func TestByLine(t *testing.T) {
+	lexKey := func(l *Lexer) StateFn {
+		if l.AcceptRun("abcdefghijklmnopqrstuvwxyz") == 0 {
+			return nil
+		}
+		l.Emit(1)
+		if l.Accept("=") {
+			l.Ignore()
+			if l.AcceptLine() == 0 {
+				return l.Errorf("missing value")
+			}
+			l.Emit(2)
+		}
+		if strings.ContainsAny(l.Input()[l.Pos():], "\r\n") {
+			t.Errorf("input beyond the line: %q", l.Input()[l.Pos():])
+		}
+		return nil
+	}
+	for _, test := range []struct {
+		input string
+		items string
+	}{
+		{"a=1\nb=two\r\nc #ignored\n\nd=4", "1:0:a|2:2:1|1:4:b|2:6:two|1:11:c|1:23:d|2:25:4"},
+		{"a=1\nb=\nc=3", "1:0:a|2:2:1|1:4:b|65534:6:missing value"},
+	} {
+		lex := New(ByLine(lexKey), test.input)
+		var items []string
+		for item := lex.Next(); item.Type != ItemEOF; item = lex.Next() {
+			items = append(items, fmt.Sprintf("%d:%d:%s", item.Type, item.Pos, item.Value))
+		}
+		if s := strings.Join(items, "|"); s != test.items {
+			t.Errorf("%q: items %s", test.input, s)
+		}
+	}
+}
@@ -0,0 +1,72 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+// Memo caches the results of speculative scans made with Try, so that a
+// lexer which backtracks heavily, such as one trying several literals
+// sharing long prefixes, does not scan the same input repeatedly.  A scan
+// is identified by a key naming the scanner and by the position at which it
+// is tried.  The results are those of packrat parsing: a scan tried again
+// at the same position returns the same result and leaves the lexer at the
+// same position without being called.
+//
+// Scans must depend only on the input, and not on state outside the lexer.
+// Scans which emit items are not cached.  The cache is cleared when the
+// input of the lexer changes, as it does after Append.  A Memo holds the
+// results of one lexer and should be kept by its state functions.
+type Memo struct {
+	Hits, Misses int // counts of the scans served from and added to the cache
+
+	input   string
+	results map[memoKey]memoResult
+}
+
+type memoKey struct {
+	key        string
+	start, pos int
+}
+
+type memoResult struct {
+	ok  bool
+	end Checkpoint // position of the lexer after a successful scan
+}
+
+// NewMemo returns an empty Memo.
+func NewMemo() *Memo {
+	return &Memo{results: make(map[memoKey]memoResult)}
+}
+
+// Try calls l.Try(fn), or returns the result cached for key at the position
+// of l.
+//
+//	memo := lexer.NewMemo()
+//	...
+//	if memo.Try(l, "float", acceptFloat) {
+//		l.Emit(itemFloat)
+//	}
+func (m *Memo) Try(l *Lexer, key string, fn func(*Lexer) bool) bool {
+	if m.input != l.input {
+		m.input = l.input
+		m.results = make(map[memoKey]memoResult)
+	}
+	k := memoKey{key, l.start, l.pos}
+	if r, ok := m.results[k]; ok {
+		m.Hits++
+		if r.ok {
+			end := r.end
+			end.items, end.errors = l.items.Len(), l.errors
+			l.Restore(end)
+		}
+		return r.ok
+	}
+	items := l.items.Len()
+	ok := l.Try(fn)
+	if l.items.Len() != items {
+		return ok
+	}
+	m.Misses++
+	m.results[k] = memoResult{ok, l.Checkpoint()}
+	return ok
+}
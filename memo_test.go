@@ -0,0 +1,57 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func TestMemo(t *testing.T) {
+	calls := 0
+	keyword := func(kw string) func(*Lexer) bool {
+		return func(l *Lexer) bool {
+			calls++
+			return l.AcceptRun("abcdefghijklmnopqrstuvwxyz") > 0 && l.Current() == kw
+		}
+	}
+	memo := NewMemo()
+	lex := New(nilState, "forward")
+	if memo.Try(lex, "for", keyword("for")) || lex.Pos() != 0 {
+		t.Errorf("matched for at %d", lex.Pos())
+	}
+	if !memo.Try(lex, "forward", keyword("forward")) || lex.Pos() != 7 {
+		t.Errorf("did not match forward at %d", lex.Pos())
+	}
+	lex.Restore(Checkpoint{})
+	if memo.Try(lex, "for", keyword("for")) || !memo.Try(lex, "forward", keyword("forward")) || lex.Pos() != 7 {
+		t.Errorf("cached results differ at %d", lex.Pos())
+	}
+	if calls != 2 || memo.Hits != 2 || memo.Misses != 2 {
+		t.Errorf("%d calls %d hits %d misses", calls, memo.Hits, memo.Misses)
+	}
+
+	lex.Ignore()
+	if memo.Try(lex, "for", keyword("for")) || calls != 3 {
+		t.Errorf("result cached at another position")
+	}
+}
+
+func TestMemoEmit(t *testing.T) {
+	calls := 0
+	emit := func(l *Lexer) bool {
+		calls++
+		l.AcceptRun("ab")
+		l.Emit(1)
+		return true
+	}
+	memo := NewMemo()
+	lex := New(nilState, "ab")
+	memo.Try(lex, "emit", emit)
+	lex.Restore(Checkpoint{})
+	memo.Try(lex, "emit", emit)
+	if calls != 2 || memo.Hits != 0 || lex.items.Len() != 1 {
+		t.Errorf("%d calls %d hits %d items", calls, memo.Hits, lex.items.Len())
+	}
+}
@@ -0,0 +1,50 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"reflect"
+)
+
+// Named returns a state which calls fn and is called name wherever the
+// lexer names its states: in traces, state graphs, recordings, coverage
+// reports, snapshots, stop diagnostics, the error of the MaxSteps limit and
+// the panics of strict mode.  Without a name a state is known by the name
+// the runtime gives its function, which is unreadable for closures.
+//
+//	func lexString(quote rune) lexer.StateFn {
+//		return lexer.Named("string "+string(quote), func(l *lexer.Lexer) lexer.StateFn {
+//			...
+//		})
+//	}
+//
+// Named panics if fn is nil.
+func Named(name string, fn StateFn) StateFn {
+	if fn == nil {
+		panic("lexer: Named with a nil state")
+	}
+	return func(l *Lexer) StateFn {
+		if l.probe {
+			l.name = name
+			return nil
+		}
+		return fn(l)
+	}
+}
+
+// namedCode is the code pointer shared by the states returned by Named.
+var namedCode = reflect.ValueOf(Named("", func(*Lexer) StateFn { return nil })).Pointer()
+
+// namedState returns the name given to fn by Named, if any.  The name is
+// obtained by calling fn with a probe lexer, which only the states returned
+// by Named are given.
+func namedState(fn StateFn) (string, bool) {
+	if fn == nil || reflect.ValueOf(fn).Pointer() != namedCode {
+		return "", false
+	}
+	probe := &Lexer{probe: true}
+	fn(probe)
+	return probe.name, true
+}
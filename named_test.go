@@ -0,0 +1,64 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestNamed(t *testing.T) {
+	var words StateFn
+	words = Named("words", func(l *Lexer) StateFn {
+		if lexWords(l) == nil {
+			return nil
+		}
+		return words
+	})
+	var buf bytes.Buffer
+	g := new(StateGraph)
+	c := new(Coverage)
+	items := collect(New(words, "ab cd", WithTrace(&buf), WithStateGraph(g), WithCoverage(c)))
+	if !reflect.DeepEqual(items, []string{"ab", "cd"}) {
+		t.Errorf("items %q", items)
+	}
+	if !strings.HasPrefix(buf.String(), "state words\n") {
+		t.Errorf("trace %q", buf.String())
+	}
+	if n := g.Transitions("words", "words"); n != 2 {
+		t.Errorf("%d transitions words -> words", n)
+	}
+	if rep := c.Report(); len(rep.States) != 1 || rep.States[0].Name != "words" {
+		t.Errorf("coverage %v", rep.States)
+	}
+}
+
+func TestNamedDiagnostics(t *testing.T) {
+	early := Named("early", nilState)
+	item := New(early, "x", WithStopCheck()).Next()
+	if !strings.HasPrefix(item.Value, "state early stopped") {
+		t.Errorf("stop check %q", item.Value)
+	}
+	var loop StateFn
+	loop = Named("loop", func(*Lexer) StateFn { return loop })
+	item = New(loop, "x", WithLimits(Limits{MaxSteps: 10})).Next()
+	if !strings.HasSuffix(item.Value, "in state loop") {
+		t.Errorf("limit error %q", item.Value)
+	}
+	backup := Named("backup", func(l *Lexer) StateFn {
+		l.Advance()
+		l.Backup()
+		l.Backup()
+		return nil
+	})
+	defer func() {
+		if e, _ := recover().(string); !strings.Contains(e, "(state backup,") {
+			t.Errorf("panic %q", e)
+		}
+	}()
+	New(backup, "x", WithStrict()).Next()
+}
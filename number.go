@@ -0,0 +1,79 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strconv"
+)
+
+// EmitInt emits the current lexeme as an item of type t with its value, an
+// int64, stored in the item's Payload.  The lexeme is interpreted as a Go
+// integer literal, optionally signed, so base prefixes (0x, 0o, 0b, and 0
+// for octal) and underscores between digits are permitted.
+//
+// If the lexeme is not a valid integer or overflows an int64, EmitInt emits
+// an error positioned at the lexeme, discards the lexeme, and returns false.
+func (l *Lexer) EmitInt(t ItemType) bool {
+	s := l.Current()
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err != nil {
+		return l.numberError("integer", s, err)
+	}
+	l.emitPayload(t, v)
+	return true
+}
+
+// EmitFloat emits the current lexeme as an item of type t with its value, a
+// float64, stored in the item's Payload.  The lexeme is interpreted as a Go
+// floating-point literal, optionally signed, so hexadecimal mantissas and
+// underscores between digits are permitted.
+//
+// If the lexeme is not a valid number or is out of range for a float64,
+// EmitFloat emits an error positioned at the lexeme, discards the lexeme,
+// and returns false.
+func (l *Lexer) EmitFloat(t ItemType) bool {
+	s := l.Current()
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return l.numberError("floating-point", s, err)
+	}
+	l.emitPayload(t, v)
+	return true
+}
+
+// EmitNumber emits the current lexeme as an item of type t.  The item's
+// Payload is an int64 if the lexeme is an integer literal (as with EmitInt)
+// and a float64 otherwise (as with EmitFloat).  An integer literal which
+// overflows an int64 is an error, it is not converted to a float64.
+func (l *Lexer) EmitNumber(t ItemType) bool {
+	s := l.Current()
+	v, err := strconv.ParseInt(s, 0, 64)
+	if err == nil {
+		l.emitPayload(t, v)
+		return true
+	}
+	if err.(*strconv.NumError).Err == strconv.ErrRange {
+		return l.numberError("integer", s, err)
+	}
+	return l.EmitFloat(t)
+}
+
+func (l *Lexer) emitPayload(t ItemType, v interface{}) {
+	n := l.count
+	l.Emit(t)
+	if l.count > n {
+		l.items.Back().Value.(*Item).Payload = v
+	}
+}
+
+func (l *Lexer) numberError(kind, s string, err error) bool {
+	if err.(*strconv.NumError).Err == strconv.ErrRange {
+		l.Errorf("%s literal %s out of range", kind, s)
+	} else {
+		l.Errorf("invalid %s literal %q", kind, s)
+	}
+	l.Ignore()
+	return false
+}
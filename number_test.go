@@ -0,0 +1,58 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func TestEmitNumber(t *testing.T) {
+	const itemNum ItemType = 1
+	for _, test := range []struct {
+		emit    func(*Lexer, ItemType) bool
+		input   string
+		payload interface{}
+		err     string
+	}{
+		{(*Lexer).EmitInt, "42", int64(42), ""},
+		{(*Lexer).EmitInt, "-0x_1F", int64(-31), ""},
+		{(*Lexer).EmitInt, "1_000_000", int64(1000000), ""},
+		{(*Lexer).EmitInt, "0b101", int64(5), ""},
+		{(*Lexer).EmitInt, "9223372036854775808", nil, "integer literal 9223372036854775808 out of range"},
+		{(*Lexer).EmitInt, "1.5", nil, `invalid integer literal "1.5"`},
+		{(*Lexer).EmitFloat, "1_000.5", 1000.5, ""},
+		{(*Lexer).EmitFloat, "0x1p-2", 0.25, ""},
+		{(*Lexer).EmitFloat, "1e400", nil, "floating-point literal 1e400 out of range"},
+		{(*Lexer).EmitNumber, "0o17", int64(15), ""},
+		{(*Lexer).EmitNumber, ".5e1", 5.0, ""},
+		{(*Lexer).EmitNumber, "99999999999999999999", nil, "integer literal 99999999999999999999 out of range"},
+		{(*Lexer).EmitNumber, "1__0", nil, `invalid floating-point literal "1__0"`},
+	} {
+		var ok bool
+		lex := New(func(l *Lexer) StateFn {
+			l.AcceptString(l.Input()[l.Pos():])
+			ok = test.emit(l, itemNum)
+			return nil
+		}, "  "+test.input)
+		lex.AcceptString("  ")
+		lex.Ignore()
+		item := lex.Next()
+		if ok != (test.err == "") {
+			t.Errorf("%q: emit returned %v", test.input, ok)
+		}
+		if item.Pos != 2 {
+			t.Errorf("%q: position %d", test.input, item.Pos)
+		}
+		if test.err != "" {
+			if item.Type != ItemError || item.Value != test.err {
+				t.Errorf("%q: unexpected item %v (expected error %q)", test.input, item, test.err)
+			}
+			continue
+		}
+		if item.Type != itemNum || item.Value != test.input || item.Payload != test.payload {
+			t.Errorf("%q: unexpected item %v %#v (expected %#v)", test.input, item, item.Payload, test.payload)
+		}
+	}
+}
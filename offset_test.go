@@ -0,0 +1,63 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"math"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMaxOffset(t *testing.T) {
+	if MaxOffset != math.MaxInt {
+		t.Errorf("MaxOffset %d is not the greatest int", MaxOffset)
+	}
+	defer func(max int) { maxOffset = max }(maxOffset)
+	maxOffset = 100
+
+	lex := New(lexLines, "", WithRetention(10))
+	lex.Next()
+	var items []string
+	for i := 0; i < 30 && lex.StopReason() != StopHalted; i++ {
+		lex.Append("abcd\n")
+		if lex.StopReason() != StopHalted {
+			lex.Continue(lexLines)
+		}
+		items = append(items, collect(lex)...)
+	}
+	if lex.Discarded() == 0 {
+		t.Errorf("nothing discarded")
+	}
+	last := items[len(items)-1]
+	if len(items) != 21 || last != "source exceeds the maximum offset of 100 bytes" {
+		t.Errorf("%d items, last %q", len(items), last)
+	}
+	if why := lex.StopReason(); why != StopHalted {
+		t.Errorf("reason %v", why)
+	}
+
+	var buf strings.Builder
+	snap := New(lexWords, "ab cd")
+	snap.Next()
+	if err := snap.Save(&buf); err != nil {
+		t.Fatal(err)
+	}
+	big := strings.Replace(buf.String(), `"Start":2`, `"Start":98`, 1)
+	big = strings.Replace(big, `"Pos":2`, `"Pos":98`, 1)
+	if _, err := Load(strings.NewReader(big), " cd", []StateFn{lexWords}); err == nil ||
+		!strings.Contains(err.Error(), "exceeds the maximum offset") {
+		t.Errorf("Load error %v", err)
+	}
+	ok := strings.Replace(big, `"Start":98`, `"Start":97`, 1)
+	ok = strings.Replace(ok, `"Pos":98`, `"Pos":97`, 1)
+	l, err := Load(strings.NewReader(ok), " cd", []StateFn{lexWords})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if items := collect(l); !reflect.DeepEqual(items, []string{"cd"}) {
+		t.Errorf("items %q", items)
+	}
+}
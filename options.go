@@ -0,0 +1,214 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"unicode/utf8"
+)
+
+// An Option configures a Lexer created by New.
+type Option func(*Lexer)
+
+// WithName sets the name of the input (e.g. a file name) to n.  The name is
+// used in trace output.
+func WithName(n string) Option {
+	return func(l *Lexer) { l.name = n }
+}
+
+// Name returns the name of the input given to WithName.
+func (l *Lexer) Name() string {
+	return l.name
+}
+
+// WithTabWidth sets the number of columns between tab stops used when
+// computing a Position.  The default width is 1, counting a tab as a single
+// column.
+func WithTabWidth(n int) Option {
+	return func(l *Lexer) {
+		if n < 1 {
+			n = 1
+		}
+		l.tabs = n
+	}
+}
+
+// WithEOF sets the rune returned by Advance at the end of input to r.  The
+// default, EOF, may collide with data in binary formats.  Regardless of the
+// sentinel, the end of input is identified by IsEOF.
+func WithEOF(r rune) Option {
+	return func(l *Lexer) { l.eof = r }
+}
+
+// WithLineTracking causes the lexer to set the Line and Column of each item,
+// including the final ItemEOF.  Columns are computed as by Lexer.Position.
+func WithLineTracking() Option {
+	return func(l *Lexer) { l.track = true }
+}
+
+// stamp sets the format of i, and its line and column if l tracks lines.
+func (l *Lexer) stamp(i *Item) *Item {
+	i.format = l.format
+	if l.track {
+		p := l.Position(i.Pos)
+		i.Line, i.Column = p.Line, p.Column
+	}
+	if l.runes {
+		i.RuneOffset, i.UTF16Offset = l.RuneOffset(i.Pos)
+	}
+	return i
+}
+
+// WithStrict causes the lexer to check the invariants of the scanner API at
+// run time.  A lexer in strict mode panics when
+//
+//	Emit produces a lexeme which overlaps or precedes a previous lexeme
+//	Backup moves the position before the start of the lexeme
+//	Backup is called twice without an intervening Advance
+//	Backup follows an Advance which read an invalid rune
+//	Ignore follows an Advance which read an invalid rune
+//
+// Strict mode turns silent corruption of the lexer's position into failures
+// during development.
+func WithStrict() Option {
+	return func(l *Lexer) { l.strict = true }
+}
+
+// violation panics with a message describing a misuse of the scanner API.
+func (l *Lexer) violation(format string, vs ...interface{}) {
+	lo, hi := l.pos-10, l.pos+10
+	if lo < 0 {
+		lo = 0
+	}
+	if hi > len(l.input) {
+		hi = len(l.input)
+	}
+	state := "?"
+	if l.state != nil {
+		state = stateName(l.state)
+	}
+	panic(fmt.Sprintf("lexer: %s (state %s, start %d, position %d, near %q)",
+		fmt.Sprintf(format, vs...), state, l.start, l.pos, l.input[lo:hi]))
+}
+
+// Limits bounds the resources a Lexer may use.  A zero field imposes no
+// limit.  When a limit is exceeded the lexer emits an error and halts.
+type Limits struct {
+	MaxInput    int // maximum length of the input in bytes
+	MaxItems    int // maximum number of items emitted
+	MaxTokenLen int // maximum length of an emitted item in bytes
+	MaxSteps    int // maximum number of state function calls
+	MaxQueue    int // maximum number of items awaiting Next, see Full
+}
+
+// WithLimits bounds the resources used by the lexer.
+func WithLimits(lim Limits) Option {
+	return func(l *Lexer) { l.limits = lim }
+}
+
+// WithTrace writes a line to w for each state function called and each item
+// emitted by the lexer.  Tracing is meant for debugging lexers.
+func WithTrace(w io.Writer) Option {
+	return func(l *Lexer) { l.trace = w }
+}
+
+// WithStateHooks calls onEnter before each call of a state function and
+// onExit after it returns, with the name of the state, as given by Named or
+// else the name of its function without its package path, and the lexer.
+// Hooks implement custom tracing, the timing of states or checks of the
+// invariants of a lexer at state boundaries without changes to the states.
+// Either hook may be nil.
+//
+//	start := make(map[string]time.Time)
+//	lexer.WithStateHooks(
+//		func(name string, l *lexer.Lexer) { start[name] = time.Now() },
+//		func(name string, l *lexer.Lexer) { spent[name] += time.Since(start[name]) },
+//	)
+func WithStateHooks(onEnter, onExit func(stateName string, l *Lexer)) Option {
+	return func(l *Lexer) { l.onEnter, l.onExit = onEnter, onExit }
+}
+
+// ErrorPolicy determines how a Lexer continues after a state emits an error.
+type ErrorPolicy int
+
+const (
+	// ErrorDefault leaves the decision to the state function.  Lexing
+	// continues with the state it returns.
+	ErrorDefault ErrorPolicy = iota
+	// ErrorHalt stops lexing after the first error.
+	ErrorHalt
+	// ErrorResume restarts lexing at the start state when a state returns
+	// nil after emitting an error.  The rune at the beginning of the failed
+	// lexeme is skipped, as is input preceding the next synchronization point
+	// if any were given to WithSyncRunes.
+	ErrorResume
+)
+
+// WithErrorPolicy sets the lexer's error policy.
+func WithErrorPolicy(p ErrorPolicy) Option {
+	return func(l *Lexer) { l.policy = p }
+}
+
+// halt emits an error and stops the lexer.  It returns nil so it can be
+// returned as a state.
+func (l *Lexer) halt(format string, vs ...interface{}) StateFn {
+	if l.halted {
+		return nil
+	}
+	msg := fmt.Sprintf(format, vs...)
+	l.record(Call{Op: "halt", Arg: msg})
+	l.errors++
+	l.items.PushBack(l.stamp(l.errorItem(msg)))
+	l.halted = true
+	l.state = nil
+	return nil
+}
+
+// resume skips the first rune of the current lexeme and restarts lexing at
+// the start state.
+func (l *Lexer) resume() {
+	if l.start >= len(l.input) {
+		return
+	}
+	l.record(Call{Op: "resume", Arg: l.sync})
+	_, n := utf8.DecodeRuneInString(l.input[l.start:])
+	l.pos = l.start + n
+	l.start = l.pos
+	if l.sync != "" {
+		l.syncTo(l.sync)
+	}
+	l.state = l.begin
+}
+
+// WithSyncRunes sets the synchronization points used by the ErrorResume
+// policy.  After an error the lexer skips the first rune of the failed lexeme
+// and then any runes not in valid, resuming before the next rune in valid.
+// Statement terminators, newlines and braces make good synchronization
+// points, allowing several errors to be reported for one input.
+func WithSyncRunes(valid string) Option {
+	return func(l *Lexer) { l.sync = valid }
+}
+
+func (l *Lexer) tracePrefix() string {
+	if l.name == "" {
+		return ""
+	}
+	return l.name + ": "
+}
+
+// stateName returns the name given to fn by Named or the name of the
+// function fn.
+func stateName(fn StateFn) string {
+	if name, ok := namedState(fn); ok {
+		return name
+	}
+	if f := runtime.FuncForPC(reflect.ValueOf(fn).Pointer()); f != nil {
+		return f.Name()
+	}
+	return "?"
+}
@@ -0,0 +1,211 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+)
+
+// lexWords emits each run of letters with type 1 and fails on anything else.
+func lexWords(l *Lexer) StateFn {
+	l.AcceptRun(" ")
+	l.Ignore()
+	if l.AcceptRun("abcdefghijklmnopqrstuvwxyz") > 0 {
+		l.Emit(1)
+		return lexWords
+	}
+	if _, n := l.Advance(); n == 0 {
+		return nil
+	}
+	return l.Errorf("unexpected %q", l.Current())
+}
+
+func collect(lex Stream) (items []string) {
+	for {
+		item := lex.Next()
+		if item.Type == ItemEOF {
+			return items
+		}
+		items = append(items, item.Value)
+	}
+}
+
+func TestErrorPolicy(t *testing.T) {
+	for _, test := range []struct {
+		policy ErrorPolicy
+		items  string
+	}{
+		{ErrorDefault, "ab|unexpected \"1\""},
+		{ErrorHalt, "ab|unexpected \"1\""},
+		{ErrorResume, "ab|unexpected \"1\"|cd|unexpected \"2\"|e"},
+	} {
+		items := collect(New(lexWords, "ab 1cd 2e", WithErrorPolicy(test.policy)))
+		if s := strings.Join(items, "|"); s != test.items {
+			t.Errorf("policy %d: %s", test.policy, s)
+		}
+	}
+}
+
+func TestLimits(t *testing.T) {
+	for _, test := range []struct {
+		limits Limits
+		items  string
+	}{
+		{Limits{}, "ab|cd|ef"},
+		{Limits{MaxInput: 4}, "input exceeds the limit of 4 bytes"},
+		{Limits{MaxItems: 2}, "ab|cd|lexer exceeded the limit of 2 items"},
+		{Limits{MaxTokenLen: 1}, "token exceeds the limit of 1 bytes"},
+		{Limits{MaxSteps: 1}, "ab|lexer exceeded the limit of 1 steps in state go-lexer.lexWords"},
+	} {
+		items := collect(New(lexWords, "ab cd ef", WithLimits(test.limits)))
+		if s := strings.Join(items, "|"); s != test.items {
+			t.Errorf("%+v: %s", test.limits, s)
+		}
+	}
+}
+
+func TestTrace(t *testing.T) {
+	var buf bytes.Buffer
+	lex := New(lexWords, "ab", WithName("test"), WithTrace(&buf))
+	if lex.Name() != "test" {
+		t.Errorf("name %q", lex.Name())
+	}
+	collect(lex)
+	lines := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	if len(lines) != 3 {
+		t.Fatalf("trace %q", buf.String())
+	}
+	if !strings.HasPrefix(lines[0], "test: state ") || !strings.HasSuffix(lines[0], ".lexWords") {
+		t.Errorf("trace line %q", lines[0])
+	}
+	if lines[1] != `test: emit 1 0 "ab"` {
+		t.Errorf("trace line %q", lines[1])
+	}
+}
+
+func TestWithEOF(t *testing.T) {
+	lex := New(nilState, "", WithEOF(-1))
+	if r, n := lex.Advance(); r != -1 || !IsEOF(r, n) {
+		t.Errorf("advance returned %q %d", r, n)
+	}
+	lex = New(nilState, "\x04")
+	if r, n := lex.Advance(); r != EOF || IsEOF(r, n) {
+		t.Errorf("advance returned %q %d", r, n)
+	}
+}
+
+func TestLineTracking(t *testing.T) {
+	lex := New(lexWords, "ab\n", WithLineTracking())
+	item := lex.Next()
+	if item.Line != 1 || item.Column != 1 {
+		t.Errorf("item %v at %d:%d", item, item.Line, item.Column)
+	}
+	item = lex.Next()
+	if item.Type != ItemError || item.Line != 1 || item.Column != 3 {
+		t.Errorf("item %v at %d:%d", item, item.Line, item.Column)
+	}
+	lex = New(lexWords, "ab\ncd", WithLineTracking(), WithErrorPolicy(ErrorResume))
+	collect(lex)
+	item = lex.Next()
+	if item.Type != ItemEOF || item.Pos != 5 || item.Line != 2 || item.Column != 3 {
+		t.Errorf("item %v at %d %d:%d", item, item.Pos, item.Line, item.Column)
+	}
+}
+
+func TestStrict(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		fn    func(l *Lexer)
+		msg   string
+	}{
+		{"ab", func(l *Lexer) { l.Advance(); l.Ignore(); l.Backup() }, "Backup past the start"},
+		{"ab", func(l *Lexer) { l.Advance(); l.Backup(); l.Backup() }, "Backup called twice"},
+		{"\xff", func(l *Lexer) { l.Advance(); l.Backup() }, "Backup after Advance read an invalid rune"},
+		{"\xff", func(l *Lexer) { l.AcceptRun("a"); l.Ignore() }, "Ignore after Advance read an invalid rune"},
+		{"ab", func(l *Lexer) {
+			l.Advance()
+			l.Emit(1)
+			l.Try(func(l *Lexer) bool { l.Advance(); l.Emit(1); return false })
+			l.Advance()
+			l.Emit(1)
+		}, ""},
+		{"a", func(l *Lexer) { l.Accept("a"); l.Accept("a"); l.Emit(1) }, ""},
+	} {
+		func() {
+			defer func() {
+				e := recover()
+				msg, _ := e.(string)
+				switch {
+				case test.msg == "" && e != nil:
+					t.Errorf("unexpected panic: %v", e)
+				case test.msg != "" && !strings.Contains(msg, test.msg):
+					t.Errorf("panic %v (expected %q)", e, test.msg)
+				}
+			}()
+			lex := New(func(l *Lexer) StateFn { test.fn(l); return nil }, test.input, WithStrict())
+			lex.Next()
+		}()
+	}
+}
+
+func TestSyncRunes(t *testing.T) {
+	var lexStatement StateFn
+	lexStatement = func(l *Lexer) StateFn {
+		if l.Accept(";") {
+			l.Emit(2)
+			return lexStatement
+		}
+		return lexWords(l)
+	}
+	const input = "ab 1cd; ef 2 gh;ij"
+	items := collect(New(lexStatement, input, WithErrorPolicy(ErrorResume), WithSyncRunes(";")))
+	if s := strings.Join(items, "|"); s != `ab|unexpected "1"|;|ef|unexpected "2"|;|ij` {
+		t.Errorf("items %s", s)
+	}
+	lex := New(nilState, "a\xffb;c")
+	if !lex.SyncTo(";") || lex.Pos() != 3 || lex.Start() != 3 {
+		t.Errorf("position %d start %d", lex.Pos(), lex.Start())
+	}
+	if !lex.AcceptString(";c") || lex.SyncTo(";") || lex.Pos() != 5 {
+		t.Errorf("position %d", lex.Pos())
+	}
+}
+
+func TestMaxQueue(t *testing.T) {
+	var lexAll StateFn
+	lexAll = func(l *Lexer) StateFn {
+		for !l.Full() {
+			if l.AcceptRun(" ") > 0 {
+				l.Ignore()
+			}
+			if l.AcceptRun("abcdefghijklmnopqrstuvwxyz") == 0 {
+				return nil
+			}
+			l.Emit(1)
+		}
+		return lexAll
+	}
+	lex := New(lexAll, "ab cd ef gh ij", WithLimits(Limits{MaxQueue: 2}))
+	for i := 0; i < 5; i++ {
+		lex.Next()
+		if n := lex.Pending(); n > 1 {
+			t.Fatalf("%d items pending", n)
+		}
+	}
+	lexGreedy := func(l *Lexer) StateFn {
+		for l.AcceptRun("abcdefghijklmnopqrstuvwxyz") > 0 {
+			l.Emit(1)
+			l.AcceptRun(" ")
+			l.Ignore()
+		}
+		return nil
+	}
+	items := collect(New(lexGreedy, "ab cd ef", WithLimits(Limits{MaxQueue: 2})))
+	if s := strings.Join(items, "|"); s != "ab|cd|item queue exceeded the limit of 2 items" {
+		t.Errorf("items %s", s)
+	}
+}
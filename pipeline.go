@@ -0,0 +1,175 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"time"
+)
+
+// Stream is a source of items ending with an item of type ItemEOF, such as
+// a *Lexer, a *SyncLexer or a *Pipeline.
+type Stream interface {
+	Next() *Item
+}
+
+// StageFunc processes an item of a pipeline.  It passes the items which
+// replace item, if any, to emit: none to filter item out, item itself or a
+// modified item to map it, or several items to inject items into the
+// stream.  The item of type ItemEOF ending the stream is processed last, so
+// that stages may emit items they hold back, and is never passed to emit.
+// A non-nil error stops the pipeline.
+type StageFunc func(item *Item, emit func(*Item)) error
+
+// StageMetrics describes the work done by a stage of a pipeline.
+type StageMetrics struct {
+	Name string
+	In   int           // items processed
+	Out  int           // items emitted
+	Time time.Duration // time spent in the stage, excluding later stages
+}
+
+// StageError is an error returned by a stage of a pipeline.
+type StageError struct {
+	Stage string
+	Err   error
+}
+
+func (err *StageError) Error() string {
+	return err.Stage + ": " + err.Err.Error()
+}
+
+type stage struct {
+	StageMetrics
+	fn StageFunc
+}
+
+// Pipeline passes the items of a stream through a sequence of stages.
+//
+//	p := lexer.NewPipeline(lex).
+//		Filter("comments", func(item *lexer.Item) bool { return item.Type != itemComment }).
+//		Stage("semicolons", insertSemicolons)
+//	err := p.Run(func(item *lexer.Item) error { ... })
+//
+// A Pipeline is itself a Stream.
+type Pipeline struct {
+	src    Stream
+	stages []*stage
+	queue  []*Item
+	eof    *Item
+	err    error
+}
+
+// NewPipeline returns a pipeline without stages reading items from src.
+func NewPipeline(src Stream) *Pipeline {
+	return &Pipeline{src: src}
+}
+
+// Stage appends a stage named name which processes items with fn and
+// returns p.
+func (p *Pipeline) Stage(name string, fn StageFunc) *Pipeline {
+	p.stages = append(p.stages, &stage{StageMetrics{Name: name}, fn})
+	return p
+}
+
+// Filter appends a stage named name which removes the items for which keep
+// returns false and returns p.
+func (p *Pipeline) Filter(name string, keep func(*Item) bool) *Pipeline {
+	return p.Stage(name, func(item *Item, emit func(*Item)) error {
+		if item.Type != ItemEOF && keep(item) {
+			emit(item)
+		}
+		return nil
+	})
+}
+
+// Map appends a stage named name which replaces each item by the result of
+// fn and returns p.
+func (p *Pipeline) Map(name string, fn func(*Item) *Item) *Pipeline {
+	return p.Stage(name, func(item *Item, emit func(*Item)) error {
+		if item.Type != ItemEOF {
+			emit(fn(item))
+		}
+		return nil
+	})
+}
+
+// Next returns the next item leaving the last stage of the pipeline.  After
+// the stream ends, or a stage returns an error, Next returns an item of type
+// ItemEOF.
+func (p *Pipeline) Next() *Item {
+	for len(p.queue) == 0 {
+		if p.eof != nil {
+			return p.eof
+		}
+		item := p.src.Next()
+		if item.Type == ItemEOF {
+			p.eof = item
+		}
+		p.push(0, item)
+		if p.err != nil {
+			p.queue = nil
+			if p.eof == nil {
+				p.eof = &Item{Type: ItemEOF, Pos: item.Pos}
+			}
+		}
+	}
+	item := p.queue[0]
+	p.queue = p.queue[1:]
+	return item
+}
+
+// push passes item to stage i, or queues it if i follows the last stage.
+// It returns the time spent in later stages.
+func (p *Pipeline) push(i int, item *Item) time.Duration {
+	if p.err != nil {
+		return 0
+	}
+	if i == len(p.stages) {
+		if item.Type != ItemEOF {
+			p.queue = append(p.queue, item)
+		}
+		return 0
+	}
+	s := p.stages[i]
+	s.In++
+	var later time.Duration
+	start := time.Now()
+	err := s.fn(item, func(out *Item) {
+		if out.Type != ItemEOF {
+			s.Out++
+			later += p.push(i+1, out)
+		}
+	})
+	if i+1 < len(p.stages) && item.Type == ItemEOF {
+		later += p.push(i+1, item)
+	}
+	total := time.Since(start)
+	s.Time += total - later
+	if err != nil && p.err == nil {
+		p.err = &StageError{s.Name, err}
+	}
+	return total
+}
+
+// Err returns the error which stopped the pipeline, if any.  Errors of
+// stages are of type *StageError.
+func (p *Pipeline) Err() error {
+	return p.err
+}
+
+// Metrics returns the metrics of the stages of p, in order.
+func (p *Pipeline) Metrics() []StageMetrics {
+	m := make([]StageMetrics, len(p.stages))
+	for i, s := range p.stages {
+		m[i] = s.StageMetrics
+	}
+	return m
+}
+
+// Run passes each item leaving the pipeline to sink until the stream ends.
+// It returns the first error of a stage or of sink.
+func (p *Pipeline) Run(sink func(*Item) error) error {
+	return p.Drain(SinkFunc(func(item Item) error { return sink(&item) }))
+}
@@ -0,0 +1,80 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestPipeline(t *testing.T) {
+	var held *Item
+	p := NewPipeline(New(lexWords, "ab skip cd ef")).
+		Filter("skip", func(item *Item) bool { return item.Value != "skip" }).
+		Map("upper", func(item *Item) *Item {
+			up := *item
+			up.Value = strings.ToUpper(item.Value)
+			return &up
+		}).
+		Stage("pairs", func(item *Item, emit func(*Item)) error {
+			if held == nil && item.Type != ItemEOF {
+				held = item
+				return nil
+			}
+			if held != nil {
+				emit(held)
+				emit(&Item{Type: 2, Pos: held.Pos, Value: ","})
+				held = nil
+			}
+			if item.Type != ItemEOF {
+				emit(item)
+			}
+			return nil
+		})
+	var got []string
+	err := p.Run(func(item *Item) error {
+		got = append(got, item.Value)
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{"AB", ",", "CD", "EF", ","}; !reflect.DeepEqual(got, expect) {
+		t.Errorf("items %q (expected %q)", got, expect)
+	}
+	if item := p.Next(); item.Type != ItemEOF || item.Pos != 13 {
+		t.Errorf("unexpected item %v", item)
+	}
+	var counts [][2]int
+	for _, m := range p.Metrics() {
+		counts = append(counts, [2]int{m.In, m.Out})
+	}
+	if expect := [][2]int{{5, 3}, {4, 3}, {4, 5}}; !reflect.DeepEqual(counts, expect) {
+		t.Errorf("metrics %v (expected %v)", counts, expect)
+	}
+}
+
+func TestPipelineErrors(t *testing.T) {
+	bad := errors.New("bad")
+	p := NewPipeline(New(lexWords, "ab cd ef")).
+		Stage("fail", func(item *Item, emit func(*Item)) error {
+			if item.Value == "cd" {
+				return bad
+			}
+			emit(item)
+			return nil
+		})
+	if got := collect(p); !reflect.DeepEqual(got, []string{"ab"}) {
+		t.Errorf("items %q", got)
+	}
+	if err, ok := p.Err().(*StageError); !ok || err.Err != bad || err.Error() != "fail: bad" {
+		t.Errorf("error %v", p.Err())
+	}
+	if err := NewPipeline(New(lexWords, "ab cd")).Run(func(*Item) error { return bad }); err != bad {
+		t.Errorf("error %v", err)
+	}
+}
@@ -0,0 +1,66 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"sync"
+)
+
+// ItemPool recycles the items of lexers, for consumers which are done with
+// each item soon after receiving it.  Items returned by lexers using a pool
+// are given back to it by Item.Release.  An ItemPool may be shared by many
+// lexers, including lexers running concurrently.  The zero value is an
+// empty pool ready to use.
+type ItemPool struct {
+	pool sync.Pool
+}
+
+// WithItemPool makes the lexer take its items from p.  Releasing items is
+// optional; items never released are collected as garbage as usual.
+//
+// In strict mode (see WithStrict) released items are not recycled.  They are
+// instead marked, so that releasing an item twice, or formatting an item
+// after releasing it, panics.
+func WithItemPool(p *ItemPool) Option {
+	return func(l *Lexer) { l.ipool = p }
+}
+
+// newItem returns an empty item, from the pool of l if it has one.
+func (l *Lexer) newItem() *Item {
+	if l.ipool == nil {
+		return new(Item)
+	}
+	i, _ := l.ipool.pool.Get().(*Item)
+	if i == nil {
+		i = new(Item)
+	}
+	i.pool, i.strict = l.ipool, l.strict
+	return i
+}
+
+// Release gives i back to the pool of the lexer which returned it, if the
+// lexer has one (see WithItemPool).  Neither i nor the string values of its
+// fields may be used after Release.  Release has no effect on items which do
+// not come from a pool.
+func (i *Item) Release() {
+	if i.pool == nil {
+		return
+	}
+	if i.strict {
+		i.checkLive()
+		*i = Item{Type: ItemError, Value: "released item", pool: i.pool, strict: true, released: true}
+		return
+	}
+	p := i.pool
+	*i = Item{}
+	p.pool.Put(i)
+}
+
+// checkLive panics if i has been released by a lexer in strict mode.
+func (i *Item) checkLive() {
+	if i.released {
+		panic("lexer: use of released item")
+	}
+}
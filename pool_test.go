@@ -0,0 +1,77 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestItemPool(t *testing.T) {
+	var pool ItemPool
+	for i := 0; i < 3; i++ {
+		lex := New(lexWords, "ab cd 9", WithItemPool(&pool))
+		var got []string
+		for {
+			item := lex.Next()
+			got = append(got, item.String())
+			if item.Type == ItemEOF {
+				item.Release()
+				break
+			}
+			item.Release()
+		}
+		if want := []string{"ab", "cd", `unexpected "9"`, "EOF"}; !reflect.DeepEqual(got, want) {
+			t.Errorf("items %q, want %q", got, want)
+		}
+	}
+
+	item := New(lexWords, "ab").Next()
+	item.Release()
+	if item.Value != "ab" {
+		t.Errorf("item without a pool changed by Release")
+	}
+}
+
+func TestItemPoolAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items under the race detector")
+	}
+	var pool ItemPool
+	lex := func(opts ...Option) func() {
+		return func() {
+			lex := New(lexWords, "ab cd ef gh ij kl", opts...)
+			for item := lex.Next(); item.Type != ItemEOF; item = lex.Next() {
+				item.Release()
+			}
+		}
+	}
+	lex(WithItemPool(&pool))()
+	pooled := testing.AllocsPerRun(100, lex(WithItemPool(&pool)))
+	unpooled := testing.AllocsPerRun(100, lex())
+	if pooled+6 > unpooled {
+		t.Errorf("%v allocations with a pool, %v without", pooled, unpooled)
+	}
+}
+
+func TestItemPoolStrict(t *testing.T) {
+	var pool ItemPool
+	lex := New(lexWords, "ab", WithItemPool(&pool), WithStrict())
+	item := lex.Next()
+	item.Release()
+	for name, use := range map[string]func(){
+		"Release": item.Release,
+		"String":  func() { _ = item.String() },
+	} {
+		func() {
+			defer func() {
+				if e := recover(); e != "lexer: use of released item" {
+					t.Errorf("%s after Release: panic %v", name, e)
+				}
+			}()
+			use()
+		}()
+	}
+}
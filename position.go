@@ -0,0 +1,98 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"sort"
+	"unicode/utf8"
+)
+
+// Position describes a location in the input.
+type Position struct {
+	Offset int // byte offset, starting at 0
+	Line   int // line number, starting at 1
+	Column int // column number, starting at 1 (character count)
+}
+
+// MaxOffset is the greatest byte offset in the source of a lexer.  Offsets
+// are ints, so that where ints have 32 bits, as on 386 and arm, the source
+// of a lexer may not exceed 2GB.  A string cannot be longer, but a source
+// streamed through Append with WithRetention, or resumed by Load at a large
+// offset, can.  A lexer whose source would exceed MaxOffset halts with an
+// error instead of reporting positions which have wrapped around.
+const MaxOffset = int(^uint(0) >> 1)
+
+// maxOffset is MaxOffset, which tests lower.
+var maxOffset = MaxOffset
+
+func (p Position) String() string {
+	return fmt.Sprintf("%d:%d", p.Line, p.Column)
+}
+
+// Position returns the line and column of the byte offset in l's source.
+// Columns count runes, with tabs advancing to the next tab stop set by
+// WithTabWidth, or the units set by WithColumnUnit.  Offsets outside the
+// input, or in input discarded by the lexer (see WithRetention), are
+// clamped.
+func (l *Lexer) Position(offset int) Position {
+	src := l.Source()
+	if offset < l.dropped {
+		offset = l.dropped
+	}
+	if offset > l.dropped+len(src) {
+		offset = l.dropped + len(src)
+	}
+	l.indexLines(offset)
+	line := sort.Search(len(l.lines), func(i int) bool { return l.lines[i] > offset }) - 1
+	col, start := 0, l.lines[line]
+	if start < l.dropped {
+		col, start = l.dropCol, l.dropped
+	}
+	// Continue from the last position on the line, so that stamping the
+	// items of a long line does not walk it from its start each time.
+	from := start
+	if c := l.pcache; c.start == start && c.offset >= start && c.offset <= offset {
+		from, col = c.offset, c.col
+	}
+	text := src[from-l.dropped : offset-l.dropped]
+	for i := 0; i < len(text); {
+		r, n := utf8.DecodeRuneInString(text[i:])
+		col += l.columns(r, n, col)
+		i += n
+	}
+	l.pcache = positionCache{start, offset, col}
+	return Position{Offset: offset, Line: l.lineBase + line + 1, Column: col + 1}
+}
+
+// positionCache holds the last column computed by Position.
+type positionCache struct {
+	start  int // offset of the start of the line, or of the retained input
+	offset int
+	col    int // column of offset, from zero
+}
+
+// indexLines extends the index of line starts through offset.  Lines are
+// indexed through the retained input before input is discarded.
+func (l *Lexer) indexLines(offset int) {
+	src := l.Source()
+	i := l.lines[len(l.lines)-1]
+	if i < l.scanned {
+		i = l.scanned
+	}
+	if i < l.dropped {
+		i = l.dropped
+	}
+	for i < offset {
+		c := src[i-l.dropped]
+		i++
+		if c == '\n' {
+			l.lines = append(l.lines, i)
+		}
+	}
+	if i > l.scanned {
+		l.scanned = i
+	}
+}
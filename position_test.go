@@ -0,0 +1,40 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func TestPosition(t *testing.T) {
+	const input = "ab\n\té\tx\n\nz"
+	for _, test := range []struct {
+		tabs   int
+		offset int
+		pos    string
+	}{
+		{0, 0, "1:1"},
+		{0, 2, "1:3"},
+		{0, 3, "2:1"},
+		{0, 7, "2:4"},
+		{4, 7, "2:9"},
+		{8, 4, "2:9"},
+		{0, 9, "3:1"},
+		{0, 10, "4:1"},
+		{0, 99, "4:2"},
+		{0, -1, "1:1"},
+	} {
+		lex := New(nilState, input, WithTabWidth(test.tabs))
+		if p := lex.Position(test.offset); p.String() != test.pos {
+			t.Errorf("tabs %d offset %d: %v (expected %s)", test.tabs, test.offset, p, test.pos)
+		}
+	}
+	lex := New(nilState, input)
+	for _, offset := range []int{11, 0, 3} {
+		if p := lex.Position(offset); p.Offset != offset {
+			t.Errorf("offset %d: %+v", offset, p)
+		}
+	}
+}
@@ -0,0 +1,135 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+)
+
+// Directive is the Payload of an item of type ItemDirective.
+type Directive struct {
+	Kind string            // "coding", "emacs" or "vim"
+	Vars map[string]string // variables set by the directive
+}
+
+// Preamble returns a start state which scans the special lines at the
+// beginning of a script before continuing with next.  A #! line on the
+// first line is emitted as an ItemShebang whose Payload is the base name of
+// the interpreter, skipping env.  A line among the first two which is a
+// comment beginning with the string comment and holds an editor or encoding
+// directive is emitted as an ItemDirective with a *Directive Payload.  The
+// directives recognized are
+//
+//	# -*- coding: utf-8 -*-      Emacs file variables, Kind "emacs"
+//	# vim: set ts=4 sw=4:        Vim modelines, Kind "vim"
+//	# coding=latin-1             encoding declarations (PEP 263), Kind "coding"
+//
+// The newlines ending these lines are ignored.
+//
+//	lex := lexer.New(lexer.Preamble("#", lexScript), input)
+func Preamble(comment string, next StateFn) StateFn {
+	return func(l *Lexer) StateFn {
+		if l.pos == 0 && strings.HasPrefix(l.input, "#!") {
+			line := l.line()
+			l.acceptString(line)
+			l.emitPayload(ItemShebang, interpreter(line))
+			l.AcceptString("\n")
+			l.Ignore()
+		}
+		for lines := 2 - strings.Count(l.input[:l.pos], "\n"); lines > 0; lines-- {
+			line := l.line()
+			text := strings.TrimLeft(line, " \t")
+			if comment == "" || !strings.HasPrefix(text, comment) {
+				break
+			}
+			d := parseDirective(text[len(comment):])
+			if d == nil {
+				break
+			}
+			l.acceptString(line)
+			l.emitPayload(ItemDirective, d)
+			l.AcceptString("\n")
+			l.Ignore()
+		}
+		return next
+	}
+}
+
+// line returns the rest of the current line, without its newline.
+func (l *Lexer) line() string {
+	rest := l.input[l.pos:]
+	if i := strings.IndexByte(rest, '\n'); i >= 0 {
+		rest = rest[:i]
+	}
+	return rest
+}
+
+// parseDirective returns the directive in the text of a comment, or nil.
+func parseDirective(text string) *Directive {
+	if i := strings.Index(text, "-*-"); i >= 0 {
+		if j := strings.Index(text[i+3:], "-*-"); j >= 0 {
+			return &Directive{"emacs", emacsVars(text[i+3 : i+3+j])}
+		}
+	}
+	for _, prefix := range []string{"vim:", "vi:", "ex:"} {
+		i := strings.Index(text, prefix)
+		if i < 0 || i > 0 && text[i-1] != ' ' && text[i-1] != '\t' {
+			continue
+		}
+		return &Directive{"vim", vimVars(text[i+len(prefix):])}
+	}
+	if i := strings.Index(text, "coding"); i >= 0 && i+6 < len(text) && (text[i+6] == ':' || text[i+6] == '=') {
+		enc := strings.TrimLeft(text[i+7:], " \t")
+		n := strings.IndexFunc(enc, func(r rune) bool {
+			return !(r == '-' || r == '_' || r == '.' || r >= '0' && r <= '9' || r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z')
+		})
+		if n < 0 {
+			n = len(enc)
+		}
+		if n > 0 {
+			return &Directive{"coding", map[string]string{"coding": enc[:n]}}
+		}
+	}
+	return nil
+}
+
+// emacsVars parses the file variables "mode: python; coding: utf-8", or a
+// lone mode "python".
+func emacsVars(s string) map[string]string {
+	vars := make(map[string]string)
+	if !strings.Contains(s, ":") {
+		if mode := strings.TrimSpace(s); mode != "" {
+			vars["mode"] = mode
+		}
+		return vars
+	}
+	for _, f := range strings.Split(s, ";") {
+		if i := strings.IndexByte(f, ':'); i >= 0 {
+			vars[strings.TrimSpace(f[:i])] = strings.TrimSpace(f[i+1:])
+		}
+	}
+	return vars
+}
+
+// vimVars parses the options of the modelines "set ts=4 sw=4:" and
+// "ts=4 sw=4".  Options without values are mapped to "".
+func vimVars(s string) map[string]string {
+	s = strings.TrimSpace(s)
+	if strings.HasPrefix(s, "set ") || strings.HasPrefix(s, "se ") {
+		s = s[strings.IndexByte(s, ' ')+1:]
+		if i := strings.IndexByte(s, ':'); i >= 0 {
+			s = s[:i]
+		}
+	}
+	vars := make(map[string]string)
+	for _, f := range strings.FieldsFunc(s, func(r rune) bool { return r == ' ' || r == '\t' || r == ':' }) {
+		if i := strings.IndexByte(f, '='); i >= 0 {
+			vars[f[:i]] = f[i+1:]
+		} else {
+			vars[f] = ""
+		}
+	}
+	return vars
+}
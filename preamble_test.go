@@ -0,0 +1,48 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPreamble(t *testing.T) {
+	input := "#!/usr/bin/env python3\n# -*- coding: utf-8 -*-\n# vim: ts=4\nab"
+	its := items(New(Preamble("#", lexWords), input, WithErrorPolicy(ErrorResume)))
+	if len(its) < 3 {
+		t.Fatalf("items %v", its)
+	}
+	if its[0].Type != ItemShebang || its[0].Value != "#!/usr/bin/env python3" || its[0].Payload != "python3" {
+		t.Errorf("shebang %+v", its[0])
+	}
+	want := &Directive{"emacs", map[string]string{"coding": "utf-8"}}
+	if its[1].Type != ItemDirective || its[1].Pos != 23 || !reflect.DeepEqual(its[1].Payload, want) {
+		t.Errorf("directive %+v %v", &its[1], its[1].Payload)
+	}
+	if its[2].Type != ItemError {
+		t.Errorf("third line scanned as a directive: %+v", &its[2])
+	}
+}
+
+func TestParseDirective(t *testing.T) {
+	for _, test := range []struct {
+		text string
+		d    *Directive
+	}{
+		{" -*- python -*-", &Directive{"emacs", map[string]string{"mode": "python"}}},
+		{" -*- mode: ruby; coding: latin-1 -*-", &Directive{"emacs", map[string]string{"mode": "ruby", "coding": "latin-1"}}},
+		{" vim: set ts=4 sw=4 et: trailing", &Directive{"vim", map[string]string{"ts": "4", "sw": "4", "et": ""}}},
+		{" vi:noai:sw=3", &Directive{"vim", map[string]string{"noai": "", "sw": "3"}}},
+		{" This Python file uses the following encoding: utf-8", &Directive{"coding", map[string]string{"coding": "utf-8"}}},
+		{" coding=iso-8859-15 ", &Directive{"coding", map[string]string{"coding": "iso-8859-15"}}},
+		{" coding", nil},
+		{" a comment about evim:", nil},
+	} {
+		if d := parseDirective(test.text); !reflect.DeepEqual(d, test.d) {
+			t.Errorf("%q: %v", test.text, d)
+		}
+	}
+}
@@ -0,0 +1,182 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package csvlex lexes comma-separated (and similarly delimited) values.
+
+The input format is the one described by RFC 4180 and accepted by
+encoding/csv.  Each field is emitted as an ItemField whose value is the raw
+field text, including quotes when the field is quoted; Unquote recovers the
+field's contents.  Every record is terminated by an ItemRecordEnd whose value
+is the line terminator ("\n", "\r\n" or "" at the end of input).
+
+Unlike encoding/csv the lexer streams items with their byte offsets, so
+callers can report positions for individual fields.  A quoting error emits an
+error item and lexing resumes at the next record.
+*/
+package csvlex
+
+import (
+	"strings"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// CSV item types.
+const (
+	ItemField lexer.ItemType = iota
+	ItemRecordEnd
+)
+
+// Config controls the dialect lexed.
+type Config struct {
+	// Comma is the field separator.  The zero value is treated as ','.
+	Comma rune
+
+	// LazyQuotes allows a quote to appear in an unquoted field and a
+	// non-doubled quote to appear in a quoted field, as with the field of the
+	// same name in encoding/csv.
+	LazyQuotes bool
+}
+
+// TSV is the configuration for tab-separated values.
+var TSV = &Config{Comma: '\t'}
+
+func init() {
+	lexer.RegisterLanguage(&lexer.Language{Name: "csv", Start: new(Config).Start, Filenames: []string{"*.csv"}})
+	lexer.RegisterLanguage(&lexer.Language{Name: "tsv", Start: TSV.Start, Filenames: []string{"*.tsv", "*.tab"}})
+}
+
+// New returns a lexer for input.  If c is nil fields are separated by commas.
+func New(input string, c *Config) *lexer.Lexer {
+	if c == nil {
+		c = new(Config)
+	}
+	return lexer.New(c.Start(), input)
+}
+
+// Start returns the start state of a lexer for the dialect described by c.
+func (c *Config) Start() lexer.StateFn {
+	s := &scanner{comma: c.Comma, lazy: c.LazyQuotes}
+	if s.comma == 0 {
+		s.comma = ','
+	}
+	return s.lexRecord
+}
+
+type scanner struct {
+	comma rune
+	lazy  bool
+}
+
+// lexRecord is the state at the beginning of a record.
+func (s *scanner) lexRecord(l *lexer.Lexer) lexer.StateFn {
+	if l.Pos() >= len(l.Input()) {
+		return nil
+	}
+	return s.lexField
+}
+
+// lexField is the state at the beginning of a field.
+func (s *scanner) lexField(l *lexer.Lexer) lexer.StateFn {
+	if l.Accept(`"`) {
+		return s.lexQuoted
+	}
+	for {
+		r, n := l.Advance()
+		switch {
+		case lexer.IsEOF(r, n) || r == s.comma || r == '\n' || s.atCRLF(l, r):
+			l.Backup()
+			l.Emit(ItemField)
+			return s.lexSeparator
+		case lexer.IsInvalid(r, n):
+			return l.Errorf("invalid utf-8 rune")
+		case r == '"' && !s.lazy:
+			return s.fail(l, `bare " in non-quoted field`)
+		}
+	}
+}
+
+// lexQuoted scans a quoted field after its opening quote.
+func (s *scanner) lexQuoted(l *lexer.Lexer) lexer.StateFn {
+	for {
+		r, n := l.Advance()
+		switch {
+		case lexer.IsEOF(r, n):
+			if s.lazy {
+				l.Emit(ItemField)
+				return s.lexSeparator
+			}
+			return l.Errorf("extraneous or missing \" in quoted field")
+		case lexer.IsInvalid(r, n):
+			return l.Errorf("invalid utf-8 rune")
+		case r != '"':
+		case l.Accept(`"`):
+		default:
+			if s.atSeparator(l) {
+				l.Emit(ItemField)
+				return s.lexSeparator
+			}
+			if !s.lazy {
+				return s.fail(l, `extraneous or missing " in quoted field`)
+			}
+		}
+	}
+}
+
+// lexSeparator scans the separator or line terminator following a field.
+func (s *scanner) lexSeparator(l *lexer.Lexer) lexer.StateFn {
+	switch {
+	case l.AcceptString(string(s.comma)):
+		l.Ignore()
+		return s.lexField
+	case l.AcceptString("\n") || l.AcceptString("\r\n"):
+		l.Emit(ItemRecordEnd)
+		return s.lexRecord
+	default:
+		l.Emit(ItemRecordEnd)
+		return nil
+	}
+}
+
+// atCRLF returns true if r is a carriage return immediately followed by a
+// newline.
+func (s *scanner) atCRLF(l *lexer.Lexer, r rune) bool {
+	return r == '\r' && strings.HasPrefix(l.Input()[l.Pos():], "\n")
+}
+
+// atSeparator returns true if the next input terminates a field.
+func (s *scanner) atSeparator(l *lexer.Lexer) bool {
+	rest := l.Input()[l.Pos():]
+	return rest == "" ||
+		strings.HasPrefix(rest, string(s.comma)) ||
+		strings.HasPrefix(rest, "\n") ||
+		strings.HasPrefix(rest, "\r\n")
+}
+
+// fail emits an error and skips to the start of the next record.
+func (s *scanner) fail(l *lexer.Lexer, format string, vs ...interface{}) lexer.StateFn {
+	l.Errorf(format, vs...)
+	rest := l.Input()[l.Pos():]
+	if i := strings.IndexByte(rest, '\n'); i >= 0 {
+		l.AcceptString(rest[:i+1])
+		l.Ignore()
+		return s.lexRecord
+	}
+	return nil
+}
+
+// Unquote returns the contents of a field value.  Quoted fields have their
+// enclosing quotes removed and doubled quotes replaced with a single quote.
+// Other fields are returned unchanged.
+func Unquote(field string) string {
+	if len(field) == 0 || field[0] != '"' {
+		return field
+	}
+	field = field[1:]
+	if strings.HasSuffix(field, `"`) {
+		field = field[:len(field)-1]
+	}
+	return strings.Replace(field, `""`, `"`, -1)
+}
@@ -0,0 +1,69 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package csvlex
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// records lexes input and returns the unquoted fields of each record.  Errors
+// are returned as a record containing the single string "!pos".
+func records(input string, c *Config) [][]string {
+	lex := New(input, c)
+	var recs [][]string
+	var rec []string
+	for {
+		item := lex.Next()
+		switch item.Type {
+		case lexer.ItemEOF:
+			return recs
+		case lexer.ItemError:
+			recs = append(recs, []string{"!" + string(rune('0'+item.Pos))})
+		case ItemField:
+			rec = append(rec, Unquote(item.Value))
+		case ItemRecordEnd:
+			recs = append(recs, rec)
+			rec = nil
+		}
+	}
+}
+
+func TestLex(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		config *Config
+		expect [][]string
+	}{
+		{"", nil, nil},
+		{"a,b,c\n", nil, [][]string{{"a", "b", "c"}}},
+		{"a,,c\r\nd,e,", nil, [][]string{{"a", "", "c"}, {"d", "e", ""}}},
+		{`"a,b","c""d","e` + "\n" + `f"` + "\n", nil, [][]string{{"a,b", `c"d`, "e\nf"}}},
+		{"a\tb,c\n", TSV, [][]string{{"a", "b,c"}}},
+		{"a\"b\nc\n", nil, [][]string{{"!0"}, {"c"}}},
+		{"\"a\"b,c\nd\n", nil, [][]string{{"!0"}, {"d"}}},
+		{"a\"b,\"c\"d\"\n", &Config{LazyQuotes: true}, [][]string{{`a"b`, `c"d`}}},
+		{"\"abc", nil, [][]string{{"!0"}}},
+	} {
+		recs := records(test.input, test.config)
+		if !reflect.DeepEqual(recs, test.expect) {
+			t.Errorf("%q: %q (expected %q)", test.input, recs, test.expect)
+		}
+	}
+}
+
+func TestPositions(t *testing.T) {
+	lex := New("ab,\"c\"\nd", nil)
+	var pos []int
+	for item := lex.Next(); item.Type != lexer.ItemEOF; item = lex.Next() {
+		pos = append(pos, item.Pos)
+	}
+	expect := []int{0, 3, 6, 7, 8}
+	if !reflect.DeepEqual(pos, expect) {
+		t.Errorf("positions %v (expected %v)", pos, expect)
+	}
+}
@@ -0,0 +1,163 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package exprlex lexes and parses a small expression language.
+
+	# the area of a circle
+	pi * r^2 + max(a, -b) / 2.5e-1 >= 10 && name != "n/a"
+
+The language has identifiers, decimal numbers, double quoted strings with Go
+escape sequences, function calls, comments from # to the end of a line, and
+the operators below, listed in order of increasing precedence.
+
+	||
+	&&
+	==  !=
+	<  <=  >  >=
+	+  -
+	*  /  %
+	-  !            (unary)
+	^               (right associative)
+
+Lex is the start state of the lexer.  Parse lexes an expression and parses it
+with a Pratt (top down operator precedence) parser into a tree of Nodes.  The
+package serves as a complete example of a lexer and parser built with package
+lexer.
+*/
+package exprlex
+
+import (
+	"strings"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Expression item types.
+const (
+	ItemIdent      lexer.ItemType = iota // name
+	ItemNumber                           // 1.5e3, Payload is a float64
+	ItemString                           // "quoted"
+	ItemOperator                         // + - * / % ^ ! == != < <= > >= && ||
+	ItemLeftParen                        // (
+	ItemRightParen                       // )
+	ItemComma                            // ,
+	ItemComment                          // # comment
+)
+
+const (
+	whitespace = " \t\r\n"
+	digits     = "0123456789"
+)
+
+func init() {
+	lexer.RegisterLanguage(&lexer.Language{
+		Name:  "expr",
+		Start: func() lexer.StateFn { return Lex },
+	})
+}
+
+// New returns a lexer for the expression in input.
+func New(input string) *lexer.Lexer {
+	return lexer.New(Lex, input)
+}
+
+// Lex is the start state of the expression lexer.
+func Lex(l *lexer.Lexer) lexer.StateFn {
+	l.AcceptRun(whitespace)
+	l.Ignore()
+	r, n := l.Peek()
+	switch {
+	case lexer.IsEOF(r, n):
+		return nil
+	case lexer.IsInvalid(r, n):
+		l.AcceptBytes(1)
+		return fail(l, "invalid utf-8 rune")
+	case r == '#':
+		l.AcceptLine()
+		l.Emit(ItemComment)
+	case r == '"':
+		if err := l.AcceptQuoted('"', lexer.GoEscapes); err != nil {
+			l.AcceptLine()
+			return fail(l, "%v", err)
+		}
+		l.Emit(ItemString)
+	case r == '.' || strings.ContainsRune(digits, r):
+		return lexNumber
+	case isIdentStart(r):
+		l.AcceptRunFunc(isIdent)
+		l.Emit(ItemIdent)
+	case l.Accept("("):
+		l.Emit(ItemLeftParen)
+	case l.Accept(")"):
+		l.Emit(ItemRightParen)
+	case l.Accept(","):
+		l.Emit(ItemComma)
+	default:
+		return lexOperator
+	}
+	return Lex
+}
+
+// lexNumber scans a decimal number with an optional fraction and exponent.
+func lexNumber(l *lexer.Lexer) lexer.StateFn {
+	whole := l.AcceptRun(digits)
+	frac := l.Try(func(l *lexer.Lexer) bool {
+		return l.Accept(".") && (l.AcceptRun(digits) > 0 || whole > 0)
+	})
+	if whole == 0 && !frac {
+		l.Advance()
+		return fail(l, "unexpected '.'")
+	}
+	l.Try(func(l *lexer.Lexer) bool {
+		if !l.Accept("eE") {
+			return false
+		}
+		l.Accept("+-")
+		return l.AcceptRun(digits) > 0
+	})
+	if r, _ := l.Peek(); isIdent(r) {
+		l.AcceptRunFunc(isIdent)
+		return fail(l, "invalid number %q", l.Current())
+	}
+	l.EmitFloat(ItemNumber)
+	return Lex
+}
+
+// lexOperator scans an operator.  Two character operators take precedence.
+func lexOperator(l *lexer.Lexer) lexer.StateFn {
+	for _, op := range []string{"==", "!=", "<=", ">=", "&&", "||"} {
+		if l.AcceptString(op) {
+			l.Emit(ItemOperator)
+			return Lex
+		}
+	}
+	if l.Accept("+-*/%^!<>") {
+		l.Emit(ItemOperator)
+		return Lex
+	}
+	r, _ := l.Advance()
+	return fail(l, "unexpected %q", r)
+}
+
+// fail emits an error for the current lexeme, discards it, and continues
+// lexing.
+func fail(l *lexer.Lexer, format string, vs ...interface{}) lexer.StateFn {
+	l.Errorf(format, vs...)
+	l.Ignore()
+	return Lex
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+}
+
+func isIdent(r rune) bool {
+	return isIdentStart(r) || '0' <= r && r <= '9'
+}
+
+// Unquote returns the value of the string literal s.
+func Unquote(s string) (string, error) {
+	return lexer.DecodeEscapes(s[1:len(s)-1], lexer.GoEscapes)
+}
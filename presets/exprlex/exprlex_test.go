@@ -0,0 +1,97 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exprlex
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+var typeNames = map[lexer.ItemType]string{
+	ItemIdent:       "ident",
+	ItemNumber:      "number",
+	ItemString:      "string",
+	ItemOperator:    "op",
+	ItemLeftParen:   "lparen",
+	ItemRightParen:  "rparen",
+	ItemComma:       "comma",
+	ItemComment:     "comment",
+	lexer.ItemError: "error",
+}
+
+func lexAll(input string) []string {
+	lex := New(input)
+	var items []string
+	for {
+		item := lex.Next()
+		if item.Type == lexer.ItemEOF {
+			return items
+		}
+		items = append(items, fmt.Sprintf("%s:%d:%q", typeNames[item.Type], item.Pos, item.Value))
+	}
+}
+
+func TestLex(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		expect []string
+	}{
+		{"", nil},
+		{"f(x_1, .5)>=2e3 # c", []string{
+			`ident:0:"f"`, `lparen:1:"("`, `ident:2:"x_1"`, `comma:5:","`, `number:7:".5"`,
+			`rparen:9:")"`, `op:10:">="`, `number:12:"2e3"`, `comment:16:"# c"`,
+		}},
+		{`a&&!"x\ty"`, []string{`ident:0:"a"`, `op:1:"&&"`, `op:3:"!"`, `string:4:"\"x\\ty\""`}},
+		{"1. 2e 3x", []string{`number:0:"1."`, `error:3:"invalid number \"2e\""`, `error:6:"invalid number \"3x\""`}},
+		{"a = . @ \"b\n1", []string{
+			`ident:0:"a"`, `error:2:"unexpected '='"`, `error:4:"unexpected '.'"`,
+			`error:6:"unexpected '@'"`, `error:8:"unterminated literal"`, `number:11:"1"`,
+		}},
+	} {
+		items := lexAll(test.input)
+		if !reflect.DeepEqual(items, test.expect) {
+			t.Errorf("%q:\n\t%q\n(expected)\n\t%q", test.input, items, test.expect)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		tree  string
+		err   string
+	}{
+		{"1 + 2 * 3", "(1 + (2 * 3))", ""},
+		{"(1 + 2) * 3", "((1 + 2) * 3)", ""},
+		{"2 ^ 3 ^ 2", "(2 ^ (3 ^ 2))", ""},
+		{"-a ^ 2 - -b", "((-(a ^ 2)) - (-b))", ""},
+		{"a < b == !c || d && e", "(((a < b) == (!c)) || (d && e))", ""},
+		{`max(x, f(), "s\n") # note`, `max(x, f(), "s\n")`, ""},
+		{"1 +\n  * 2", "", "2:3: unexpected *"},
+		{"f(1, 2", "", "1:7: expected ',' or ')' in call opened at 1:2"},
+		{"(1", "", "1:3: expected ')' to close '(' at 1:1"},
+		{"1 2", "", "1:3: unexpected 2"},
+		{"a $ b", "", "1:3: unexpected '$'"},
+		{"", "", "1:1: unexpected end of expression"},
+	} {
+		n, err := Parse(test.input)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%q: error %v (expected %q)", test.input, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+			continue
+		}
+		if n.String() != test.tree {
+			t.Errorf("%q: parsed %s (expected %s)", test.input, n, test.tree)
+		}
+	}
+}
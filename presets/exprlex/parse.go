@@ -0,0 +1,247 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package exprlex
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Node is a node of an expression tree.
+type Node interface {
+	Pos() int // byte offset of the node in the input
+	String() string
+}
+
+type (
+	// Ident is an identifier.
+	Ident struct {
+		Offset int
+		Name   string
+	}
+
+	// Number is a numeric literal.
+	Number struct {
+		Offset int
+		Value  float64
+	}
+
+	// String is a string literal.
+	String struct {
+		Offset int
+		Value  string
+	}
+
+	// Unary is an operator applied to one operand.
+	Unary struct {
+		Offset int
+		Op     string
+		X      Node
+	}
+
+	// Binary is an operator applied to two operands.
+	Binary struct {
+		Op   string
+		X, Y Node
+	}
+
+	// Call is a function call.
+	Call struct {
+		Func *Ident
+		Args []Node
+	}
+)
+
+func (n *Ident) Pos() int  { return n.Offset }
+func (n *Number) Pos() int { return n.Offset }
+func (n *String) Pos() int { return n.Offset }
+func (n *Unary) Pos() int  { return n.Offset }
+func (n *Binary) Pos() int { return n.X.Pos() }
+func (n *Call) Pos() int   { return n.Func.Pos() }
+
+func (n *Ident) String() string  { return n.Name }
+func (n *Number) String() string { return strconv.FormatFloat(n.Value, 'g', -1, 64) }
+func (n *String) String() string { return strconv.Quote(n.Value) }
+func (n *Unary) String() string  { return "(" + n.Op + n.X.String() + ")" }
+func (n *Binary) String() string { return "(" + n.X.String() + " " + n.Op + " " + n.Y.String() + ")" }
+
+func (n *Call) String() string {
+	args := make([]string, len(n.Args))
+	for i, a := range n.Args {
+		args[i] = a.String()
+	}
+	return n.Func.String() + "(" + strings.Join(args, ", ") + ")"
+}
+
+// SyntaxError describes a lexical or grammatical error in an expression.
+type SyntaxError struct {
+	Position lexer.Position
+	Msg      string
+}
+
+func (err *SyntaxError) Error() string {
+	return fmt.Sprintf("%v: %s", err.Position, err.Msg)
+}
+
+// binding powers of binary operators
+var infix = map[string]int{
+	"||": 1,
+	"&&": 2,
+	"==": 3, "!=": 3,
+	"<": 4, "<=": 4, ">": 4, ">=": 4,
+	"+": 5, "-": 5,
+	"*": 6, "/": 6, "%": 6,
+	"^": 7,
+}
+
+// unaryPower is the binding power of the operand of a unary operator.  Only
+// ^ binds more tightly, so -a^2 is -(a^2).
+const unaryPower = 6
+
+// Parse parses the expression in input.  The error, if any, is a
+// *SyntaxError.
+func Parse(input string) (Node, error) {
+	p := &parser{lex: New(input)}
+	p.next()
+	n, err := p.expr(0)
+	if err != nil {
+		return nil, err
+	}
+	if p.tok.Type != lexer.ItemEOF {
+		return nil, p.unexpected()
+	}
+	return n, nil
+}
+
+type parser struct {
+	lex *lexer.Lexer
+	tok *lexer.Item // lookahead
+}
+
+// next advances the lookahead to the next item which is not a comment.
+func (p *parser) next() {
+	p.tok = p.lex.Next()
+	for p.tok.Type == ItemComment {
+		p.tok = p.lex.Next()
+	}
+}
+
+func (p *parser) errorf(item *lexer.Item, format string, vs ...interface{}) error {
+	return &SyntaxError{p.lex.Position(item.Pos), fmt.Sprintf(format, vs...)}
+}
+
+// expr parses an expression containing binary operators which bind more
+// tightly than power.
+func (p *parser) expr(power int) (Node, error) {
+	left, err := p.prefix()
+	if err != nil {
+		return nil, err
+	}
+	for p.tok.Type == ItemOperator {
+		op := p.tok.Value
+		bp, ok := infix[op]
+		if !ok || bp <= power {
+			break
+		}
+		p.next()
+		rbp := bp
+		if op == "^" {
+			rbp-- // right associative
+		}
+		right, err := p.expr(rbp)
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: op, X: left, Y: right}
+	}
+	return left, nil
+}
+
+// prefix parses an operand with its prefix operators.
+func (p *parser) prefix() (Node, error) {
+	tok := p.tok
+	switch tok.Type {
+	case ItemNumber:
+		p.next()
+		return &Number{tok.Pos, tok.Payload.(float64)}, nil
+	case ItemString:
+		p.next()
+		s, err := Unquote(tok.Value)
+		if err != nil {
+			return nil, p.errorf(tok, "%v", err)
+		}
+		return &String{tok.Pos, s}, nil
+	case ItemIdent:
+		p.next()
+		id := &Ident{tok.Pos, tok.Value}
+		if p.tok.Type == ItemLeftParen {
+			return p.call(id)
+		}
+		return id, nil
+	case ItemLeftParen:
+		p.next()
+		n, err := p.expr(0)
+		if err != nil {
+			return nil, err
+		}
+		if p.tok.Type != ItemRightParen {
+			return nil, p.errorf(p.tok, "expected ')' to close '(' at %v", p.lex.Position(tok.Pos))
+		}
+		p.next()
+		return n, nil
+	case ItemOperator:
+		if tok.Value == "-" || tok.Value == "!" {
+			p.next()
+			x, err := p.expr(unaryPower)
+			if err != nil {
+				return nil, err
+			}
+			return &Unary{tok.Pos, tok.Value, x}, nil
+		}
+	}
+	return nil, p.unexpected()
+}
+
+// unexpected returns an error for the lookahead item.  Lexical errors are
+// reported with their own message.
+func (p *parser) unexpected() error {
+	switch p.tok.Type {
+	case lexer.ItemError:
+		return p.errorf(p.tok, "%s", p.tok.Value)
+	case lexer.ItemEOF:
+		return p.errorf(p.tok, "unexpected end of expression")
+	}
+	return p.errorf(p.tok, "unexpected %s", p.tok)
+}
+
+// call parses the arguments of a function call.
+func (p *parser) call(fn *Ident) (Node, error) {
+	lparen := p.tok
+	p.next()
+	c := &Call{Func: fn}
+	if p.tok.Type == ItemRightParen {
+		p.next()
+		return c, nil
+	}
+	for {
+		arg, err := p.expr(0)
+		if err != nil {
+			return nil, err
+		}
+		c.Args = append(c.Args, arg)
+		switch p.tok.Type {
+		case ItemComma:
+			p.next()
+		case ItemRightParen:
+			p.next()
+			return c, nil
+		default:
+			return nil, p.errorf(p.tok, "expected ',' or ')' in call opened at %v", p.lex.Position(lparen.Pos))
+		}
+	}
+}
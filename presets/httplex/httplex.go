@@ -0,0 +1,173 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package httplex lexes HTTP header fields as specified by RFC 7230.
+
+	Content-Type: text/html; charset="utf-8"
+	X-Folded: first line
+	 continued
+
+Each field is emitted as an ItemFieldName and an ItemColon followed by the
+items of its value and an ItemCRLF.  Values are split into tokens,
+separators, quoted strings and runs of other text (obs-text), with optional
+whitespace discarded.  A line folded with obs-fold produces an ItemFold.  The
+empty line ending the header produces an ItemEndHeaders, after which the
+lexer stops; the message body begins at the lexer's position.
+
+The lexer is byte-oriented and does not require the input to be UTF-8.  Bare
+LF line terminators are accepted.  A malformed field produces an error item
+and lexing continues with the next line.
+*/
+package httplex
+
+import (
+	"io"
+	"strings"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Header item types.
+const (
+	ItemFieldName    lexer.ItemType = iota // field-name
+	ItemColon                              // :
+	ItemToken                              // token
+	ItemSeparator                          // one of ()<>@,;\/[]?={}
+	ItemQuotedString                       // "quoted string"
+	ItemText                               // other field content
+	ItemFold                               // CRLF followed by whitespace
+	ItemCRLF                               // end of a field
+	ItemEndHeaders                         // empty line ending the header
+)
+
+const (
+	tchar      = "!#$%&'*+-.^_`|~0123456789abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"
+	separators = `()<>@,;\/[]?={}`
+	ows        = " \t"
+)
+
+func init() {
+	lexer.RegisterLanguage(&lexer.Language{
+		Name:  "http",
+		Start: func() lexer.StateFn { return Lex },
+	})
+}
+
+// New returns a lexer for the header in input.
+func New(input string) *lexer.Lexer {
+	return lexer.New(Lex, input)
+}
+
+// NewReader returns a lexer for the header read from r.  The header is not
+// lexed as it arrives: NewReader reads all of r into memory before it
+// returns, including any message body following the header, which begins at
+// the lexer's position once it stops.  To lex a header from a connection
+// which stays open, read it up to the empty line and give it to New.
+func NewReader(r io.Reader) (*lexer.Lexer, error) {
+	return lexer.NewReader(Lex, r, lexer.WithoutDecompression())
+}
+
+// Lex is the start state of the header lexer.  It begins each field line.
+func Lex(l *lexer.Lexer) lexer.StateFn {
+	switch {
+	case l.Pos() >= len(l.Input()):
+		return nil
+	case acceptNewline(l):
+		l.Emit(ItemEndHeaders)
+		return nil
+	case l.AcceptByteRun(ows) > 0:
+		return fail(l, "whitespace before field name")
+	}
+	if l.AcceptByteRun(tchar) == 0 {
+		return fail(l, "invalid field name")
+	}
+	l.Emit(ItemFieldName)
+	if l.AcceptByteRun(ows) > 0 {
+		return fail(l, "whitespace between field name and colon")
+	}
+	if !l.AcceptByte(":") {
+		return fail(l, "missing colon after field name")
+	}
+	l.Emit(ItemColon)
+	return lexValue
+}
+
+// lexValue scans the items of a field value.
+func lexValue(l *lexer.Lexer) lexer.StateFn {
+	l.AcceptByteRun(ows)
+	l.Ignore()
+	if acceptNewline(l) {
+		if l.AcceptByteRun(ows) > 0 {
+			l.Emit(ItemFold)
+			return lexValue
+		}
+		l.Emit(ItemCRLF)
+		return Lex
+	}
+	c, ok := l.AdvanceByte()
+	switch {
+	case !ok:
+		return l.Errorf("unterminated header field")
+	case c == '"':
+		return lexQuoted
+	case strings.IndexByte(tchar, c) >= 0:
+		l.AcceptByteRun(tchar)
+		l.Emit(ItemToken)
+	case strings.IndexByte(separators, c) >= 0:
+		l.Emit(ItemSeparator)
+	case c >= 0x80:
+		for acceptText(l) {
+		}
+		l.Emit(ItemText)
+	default:
+		return fail(l, "invalid byte %#x in field value", c)
+	}
+	return lexValue
+}
+
+// lexQuoted scans a quoted string following its opening quote.
+func lexQuoted(l *lexer.Lexer) lexer.StateFn {
+	for {
+		c, ok := l.AdvanceByte()
+		switch {
+		case !ok || c == '\r' || c == '\n':
+			l.Backup()
+			return fail(l, "unterminated quoted string")
+		case c == '"':
+			l.Emit(ItemQuotedString)
+			return lexValue
+		case c == '\\':
+			if c, ok := l.AdvanceByte(); !ok || c == '\r' || c == '\n' {
+				l.Backup()
+				return fail(l, "unterminated quoted string")
+			}
+		case c < 0x20 && c != '\t' || c == 0x7f:
+			return fail(l, "invalid byte %#x in quoted string", c)
+		}
+	}
+}
+
+// acceptText advances over a byte of obs-text.
+func acceptText(l *lexer.Lexer) bool {
+	rest := l.Input()[l.Pos():]
+	return rest != "" && rest[0] >= 0x80 && l.AcceptBytes(1)
+}
+
+func acceptNewline(l *lexer.Lexer) bool {
+	return l.AcceptString("\r\n") || l.AcceptString("\n")
+}
+
+// fail emits an error and skips to the beginning of the next line.
+func fail(l *lexer.Lexer, format string, vs ...interface{}) lexer.StateFn {
+	l.Errorf(format, vs...)
+	rest := l.Input()[l.Pos():]
+	if i := strings.IndexByte(rest, '\n'); i >= 0 {
+		l.AcceptBytes(i + 1)
+	} else {
+		l.AcceptBytes(len(rest))
+	}
+	l.Ignore()
+	return Lex
+}
@@ -0,0 +1,83 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package httplex
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+var typeNames = map[lexer.ItemType]string{
+	ItemFieldName:    "name",
+	ItemColon:        "colon",
+	ItemToken:        "token",
+	ItemSeparator:    "sep",
+	ItemQuotedString: "quoted",
+	ItemText:         "text",
+	ItemFold:         "fold",
+	ItemCRLF:         "crlf",
+	ItemEndHeaders:   "end",
+	lexer.ItemError:  "error",
+}
+
+func lexAll(lex *lexer.Lexer) []string {
+	var items []string
+	for {
+		item := lex.Next()
+		if item.Type == lexer.ItemEOF {
+			return items
+		}
+		items = append(items, fmt.Sprintf("%s:%d:%q", typeNames[item.Type], item.Pos, item.Value))
+	}
+}
+
+func TestLex(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		expect []string
+	}{
+		{"", nil},
+		{"Host: example.com\r\n\r\nbody", []string{
+			`name:0:"Host"`, `colon:4:":"`, `token:6:"example.com"`, `crlf:17:"\r\n"`, `end:19:"\r\n"`,
+		}},
+		{"Content-Type: text/html; charset=\"a\\\"b\"\n", []string{
+			`name:0:"Content-Type"`, `colon:12:":"`, `token:14:"text"`, `sep:18:"/"`, `token:19:"html"`,
+			`sep:23:";"`, `token:25:"charset"`, `sep:32:"="`, `quoted:33:"\"a\\\"b\""`, `crlf:39:"\n"`,
+		}},
+		{"X: a\r\n \tb \xff\xfe\r\n", []string{
+			`name:0:"X"`, `colon:1:":"`, `token:3:"a"`, `fold:4:"\r\n \t"`, `token:8:"b"`,
+			`text:10:"\xff\xfe"`, `crlf:12:"\r\n"`,
+		}},
+		{"Bad : x\r\nY:\r\n", []string{
+			`name:0:"Bad"`, `error:3:"whitespace between field name and colon"`,
+			`name:9:"Y"`, `colon:10:":"`, `crlf:11:"\r\n"`,
+		}},
+		{"X: \"open\r\nY: \x01\r\n", []string{
+			`name:0:"X"`, `colon:1:":"`, `error:3:"unterminated quoted string"`,
+			`name:10:"Y"`, `colon:11:":"`, `error:13:"invalid byte 0x1 in field value"`,
+		}},
+		{"X: y", []string{`name:0:"X"`, `colon:1:":"`, `token:3:"y"`, `error:4:"unterminated header field"`}},
+	} {
+		items := lexAll(New(test.input))
+		if !reflect.DeepEqual(items, test.expect) {
+			t.Errorf("%q:\n\t%q\n(expected)\n\t%q", test.input, items, test.expect)
+		}
+	}
+}
+
+func TestNewReader(t *testing.T) {
+	lex, err := NewReader(strings.NewReader("\x1f\x8b: x\n\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := lexAll(lex)
+	if !reflect.DeepEqual(items, []string{`error:0:"invalid field name"`, `end:6:"\n"`}) {
+		t.Errorf("items %q", items)
+	}
+}
@@ -0,0 +1,255 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package inilex lexes INI files and similar line-oriented configuration formats.
+
+The lexer accepts the common subset of INI and TOML syntax.
+
+	; comment
+	# comment
+	[section]
+	[[array.of.tables]]
+	key = bare value ; trailing comment
+	"quoted key": "quoted\tvalue"
+	other = 'literal value'
+
+Section headers are emitted as bracket items surrounding an ItemSection.  Each
+key/value pair is emitted as an ItemKey, an ItemSeparator, and either an
+ItemString (quoted) or ItemValue (bare, with surrounding whitespace removed).
+Bare values extend to the end of the line or to a comment character preceded
+by whitespace.  Every line ends with an ItemNewline, except possibly the last.
+
+A malformed line produces an error item and lexing continues with the next
+line.
+*/
+package inilex
+
+import (
+	"strings"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Configuration item types.
+const (
+	ItemComment      lexer.ItemType = iota // ; comment or # comment
+	ItemLeftBracket                        // [ or [[
+	ItemSection                            // section name
+	ItemRightBracket                       // ] or ]]
+	ItemKey                                // key or "key"
+	ItemSeparator                          // = or :
+	ItemValue                              // bare value
+	ItemString                             // "value" or 'value'
+	ItemNewline                            // \n or \r\n
+)
+
+const (
+	space    = " \t"
+	comments = ";#"
+)
+
+func init() {
+	lexer.RegisterLanguage(&lexer.Language{
+		Name:      "ini",
+		Start:     func() lexer.StateFn { return Lex },
+		Filenames: []string{"*.ini", "*.cfg", ".editorconfig", ".gitconfig"},
+		Analyze:   analyze,
+	})
+}
+
+// analyze returns the confidence that text is an INI file, judged by its
+// first line which is not blank or a comment.
+func analyze(text string) float64 {
+	for _, line := range strings.Split(text, "\n") {
+		line = strings.TrimSpace(line)
+		switch {
+		case line == "" || line[0] == ';' || line[0] == '#':
+			continue
+		case line[0] == '[' && line[len(line)-1] == ']':
+			return 0.4
+		}
+		return 0
+	}
+	return 0
+}
+
+// New returns a lexer for the configuration in input.
+func New(input string) *lexer.Lexer {
+	return lexer.New(Lex, input)
+}
+
+// Lex is the start state of the configuration lexer.  It begins each line.
+func Lex(l *lexer.Lexer) lexer.StateFn {
+	skipSpace(l)
+	switch {
+	case l.Pos() >= len(l.Input()):
+		return nil
+	case atNewline(l):
+		return lexNewline
+	case l.Accept(comments):
+		return lexComment
+	case l.AcceptString("[[") || l.Accept("["):
+		l.Emit(ItemLeftBracket)
+		return lexSection
+	default:
+		return lexKey
+	}
+}
+
+// lexNewline emits a line terminator.  At the end of input it does nothing.
+func lexNewline(l *lexer.Lexer) lexer.StateFn {
+	if l.AcceptString("\n") || l.AcceptString("\r\n") {
+		l.Emit(ItemNewline)
+		return Lex
+	}
+	if l.Pos() >= len(l.Input()) {
+		return nil
+	}
+	return fail(l, "unexpected text at end of line")
+}
+
+// lexComment scans the remainder of a line following a comment character.
+func lexComment(l *lexer.Lexer) lexer.StateFn {
+	acceptToEOL(l)
+	l.Emit(ItemComment)
+	return lexNewline
+}
+
+// lexSection scans a section name following its opening bracket.
+func lexSection(l *lexer.Lexer) lexer.StateFn {
+	skipSpace(l)
+	if l.Accept(`"'`) {
+		if fn := acceptQuoted(l); fn != nil {
+			return fn
+		}
+	} else if !acceptBare(l, "]") {
+		return fail(l, "missing section name")
+	}
+	l.Emit(ItemSection)
+	skipSpace(l)
+	if !l.AcceptString("]]") && !l.Accept("]") {
+		return fail(l, "missing ] in section header")
+	}
+	l.Emit(ItemRightBracket)
+	return lexTrailer
+}
+
+// lexKey scans a key and its separator.
+func lexKey(l *lexer.Lexer) lexer.StateFn {
+	if l.Accept(`"'`) {
+		if fn := acceptQuoted(l); fn != nil {
+			return fn
+		}
+	} else if !acceptBare(l, "=:") {
+		return fail(l, "missing key")
+	}
+	l.Emit(ItemKey)
+	skipSpace(l)
+	if !l.Accept("=:") {
+		return fail(l, "missing separator after key")
+	}
+	l.Emit(ItemSeparator)
+	return lexValue
+}
+
+// lexValue scans the value following a separator.
+func lexValue(l *lexer.Lexer) lexer.StateFn {
+	skipSpace(l)
+	if l.Accept(`"'`) {
+		if fn := acceptQuoted(l); fn != nil {
+			return fn
+		}
+		l.Emit(ItemString)
+		return lexTrailer
+	}
+	acceptBare(l, "")
+	l.Emit(ItemValue)
+	return lexTrailer
+}
+
+// lexTrailer scans optional whitespace and a comment before the end of a
+// line.
+func lexTrailer(l *lexer.Lexer) lexer.StateFn {
+	skipSpace(l)
+	if l.Accept(comments) {
+		return lexComment
+	}
+	return lexNewline
+}
+
+// acceptBare advances over a bare word terminated by a rune in stop, a
+// comment, or the end of the line.  Internal whitespace is included in the
+// word, trailing whitespace is not.  Comment characters only begin a comment
+// at the start of the word or following whitespace.
+func acceptBare(l *lexer.Lexer, stop string) bool {
+	terminal := func(r rune) bool {
+		return r == '\n' || r == '\r' || strings.ContainsRune(stop, r)
+	}
+	start := l.Pos()
+	if rest := l.Input()[start:]; rest != "" && strings.IndexByte(comments, rest[0]) >= 0 {
+		return false
+	}
+	for {
+		l.AcceptRunFunc(func(r rune) bool {
+			return !terminal(r) && !strings.ContainsRune(space, r)
+		})
+		c := l.Checkpoint()
+		if skipSpace(l) == 0 || l.Accept(comments) || !l.AcceptFunc(func(r rune) bool { return !terminal(r) }) {
+			l.Restore(c)
+			break
+		}
+	}
+	return l.Pos() > start
+}
+
+// acceptQuoted advances over a quoted string following its opening quote.
+// Double quoted strings may contain backslash escapes.  A non-nil StateFn is
+// returned if the string is malformed.
+func acceptQuoted(l *lexer.Lexer) lexer.StateFn {
+	quote, _ := l.Last()
+	for {
+		r, n := l.Advance()
+		switch {
+		case lexer.IsEOF(r, n) || r == '\n':
+			l.Backup()
+			return fail(l, "unterminated string")
+		case lexer.IsInvalid(r, n):
+			return l.Errorf("invalid utf-8 rune")
+		case r == quote:
+			return nil
+		case r == '\\' && quote == '"':
+			l.AcceptFunc(func(r rune) bool { return r != '\n' })
+		}
+	}
+}
+
+// fail emits an error and skips to the end of the current line.
+func fail(l *lexer.Lexer, format string, vs ...interface{}) lexer.StateFn {
+	l.Errorf(format, vs...)
+	acceptToEOL(l)
+	l.Ignore()
+	return lexNewline
+}
+
+func skipSpace(l *lexer.Lexer) int {
+	n := l.AcceptRun(space)
+	if l.Pos() == l.Start()+n {
+		l.Ignore()
+	}
+	return n
+}
+
+func atNewline(l *lexer.Lexer) bool {
+	rest := l.Input()[l.Pos():]
+	return strings.HasPrefix(rest, "\n") || strings.HasPrefix(rest, "\r\n")
+}
+
+func acceptToEOL(l *lexer.Lexer) {
+	rest := l.Input()[l.Pos():]
+	if i := strings.IndexByte(rest, '\n'); i >= 0 {
+		rest = strings.TrimSuffix(rest[:i], "\r")
+	}
+	l.AcceptString(rest)
+}
@@ -0,0 +1,84 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package inilex
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+var typeNames = map[lexer.ItemType]string{
+	ItemComment:      "comment",
+	ItemLeftBracket:  "lbrack",
+	ItemSection:      "section",
+	ItemRightBracket: "rbrack",
+	ItemKey:          "key",
+	ItemSeparator:    "sep",
+	ItemValue:        "value",
+	ItemString:       "string",
+	ItemNewline:      "nl",
+	lexer.ItemError:  "error",
+}
+
+func lexAll(input string) []string {
+	lex := New(input)
+	var items []string
+	for {
+		item := lex.Next()
+		if item.Type == lexer.ItemEOF {
+			return items
+		}
+		items = append(items, fmt.Sprintf("%s:%d:%q", typeNames[item.Type], item.Pos, item.Value))
+	}
+}
+
+func TestLex(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		expect []string
+	}{
+		{"", nil},
+		{"[main]\n", []string{`lbrack:0:"["`, `section:1:"main"`, `rbrack:5:"]"`, `nl:6:"\n"`}},
+		{"[[ a b ]] # c", []string{`lbrack:0:"[["`, `section:3:"a b"`, `rbrack:7:"]]"`, `comment:10:"# c"`}},
+		{"key = some value ; c\r\n", []string{
+			`key:0:"key"`, `sep:4:"="`, `value:6:"some value"`, `comment:17:"; c"`, `nl:20:"\r\n"`,
+		}},
+		{`"k x": "a\"b"`, []string{`key:0:"\"k x\""`, `sep:5:":"`, `string:7:"\"a\\\"b\""`}},
+		{"url=http://x#y\nempty=", []string{
+			`key:0:"url"`, `sep:3:"="`, `value:4:"http://x#y"`, `nl:14:"\n"`,
+			`key:15:"empty"`, `sep:20:"="`, `value:21:""`,
+		}},
+		{"  ; indented\n\n", []string{`comment:2:"; indented"`, `nl:12:"\n"`, `nl:13:"\n"`}},
+	} {
+		items := lexAll(test.input)
+		if !reflect.DeepEqual(items, test.expect) {
+			t.Errorf("%q:\n\t%q\n(expected)\n\t%q", test.input, items, test.expect)
+		}
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		expect []string
+	}{
+		{"[a\nk=v", []string{
+			`lbrack:0:"["`, `section:1:"a"`, `error:2:"missing ] in section header"`, `nl:2:"\n"`,
+			`key:3:"k"`, `sep:4:"="`, `value:5:"v"`,
+		}},
+		{"novalue\nk='x\n", []string{
+			`key:0:"novalue"`, `error:7:"missing separator after key"`, `nl:7:"\n"`,
+			`key:8:"k"`, `sep:9:"="`, `error:10:"unterminated string"`, `nl:12:"\n"`,
+		}},
+	} {
+		items := lexAll(test.input)
+		if !reflect.DeepEqual(items, test.expect) {
+			t.Errorf("%q:\n\t%q\n(expected)\n\t%q", test.input, items, test.expect)
+		}
+	}
+}
@@ -0,0 +1,211 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package jsonlex is a JSON lexer conforming to RFC 8259.
+
+	http://tools.ietf.org/html/rfc8259
+
+Whitespace is discarded and every other token of the JSON grammar is emitted
+as an item.  String items contain the quoted source text, Unquote decodes
+them.  Malformed tokens, including those containing invalid UTF-8, produce an
+error item after which lexing resumes at the next token, so a single pass
+reports every lexical error in the input.
+*/
+package jsonlex
+
+import (
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// JSON item types.
+const (
+	ItemBeginObject lexer.ItemType = iota // {
+	ItemEndObject                         // }
+	ItemBeginArray                        // [
+	ItemEndArray                          // ]
+	ItemColon                             // :
+	ItemComma                             // ,
+	ItemString                            // "..."
+	ItemNumber                            // -1.5e3
+	ItemTrue                              // true
+	ItemFalse                             // false
+	ItemNull                              // null
+)
+
+const (
+	whitespace = " \t\n\r"
+	digits     = "0123456789"
+)
+
+func init() {
+	lexer.RegisterLanguage(&lexer.Language{
+		Name:      "json",
+		Start:     func() lexer.StateFn { return Lex },
+		Filenames: []string{"*.json"},
+		Analyze:   analyze,
+	})
+}
+
+// analyze returns the confidence that text is a JSON document.
+func analyze(text string) float64 {
+	text = strings.TrimLeft(text, whitespace)
+	if !strings.HasPrefix(text, "{") && !strings.HasPrefix(text, "[") {
+		return 0
+	}
+	if json.Valid([]byte(text)) {
+		return 1
+	}
+	return 0.3
+}
+
+// New returns a lexer for the JSON document input.
+func New(input string) *lexer.Lexer {
+	return lexer.New(Lex, input)
+}
+
+// Lex is the start state of the JSON lexer.
+func Lex(l *lexer.Lexer) lexer.StateFn {
+	l.AcceptRun(whitespace)
+	l.Ignore()
+	switch r, n := l.Advance(); {
+	case lexer.IsEOF(r, n):
+		return nil
+	case lexer.IsInvalid(r, n):
+		l.AdvanceByte()
+		return fail(l, "invalid utf-8 rune")
+	case r == '{':
+		l.Emit(ItemBeginObject)
+	case r == '}':
+		l.Emit(ItemEndObject)
+	case r == '[':
+		l.Emit(ItemBeginArray)
+	case r == ']':
+		l.Emit(ItemEndArray)
+	case r == ':':
+		l.Emit(ItemColon)
+	case r == ',':
+		l.Emit(ItemComma)
+	case r == '"':
+		return lexString
+	case r == '-' || '0' <= r && r <= '9':
+		l.Backup()
+		return lexNumber
+	case 'a' <= r && r <= 'z':
+		l.Backup()
+		return lexLiteral
+	default:
+		return fail(l, "unexpected rune %q", r)
+	}
+	return Lex
+}
+
+// lexString scans a string after its opening quote.
+func lexString(l *lexer.Lexer) lexer.StateFn {
+	var msg string
+	for {
+		switch r, n := l.Advance(); {
+		case lexer.IsEOF(r, n):
+			return l.Errorf("unterminated string")
+		case lexer.IsInvalid(r, n):
+			if msg == "" {
+				msg = "invalid utf-8 rune in string"
+			}
+			l.AdvanceByte()
+		case r == '"':
+			if msg != "" {
+				l.Errorf("%s", msg)
+				l.Ignore()
+				return Lex
+			}
+			l.Emit(ItemString)
+			return Lex
+		case r < 0x20:
+			if msg == "" {
+				msg = "control character in string"
+			}
+		case r == '\\':
+			l.Backup()
+			if err := l.AcceptEscape(lexer.JSONEscapes); err != nil {
+				if msg == "" {
+					msg = "invalid escape sequence in string"
+				}
+				l.Advance()
+			}
+		}
+	}
+}
+
+// lexNumber scans a number.  The lexer is positioned at a '-' or a digit.
+func lexNumber(l *lexer.Lexer) lexer.StateFn {
+	l.Accept("-")
+	switch {
+	case l.Accept("0"):
+		if l.Accept(digits) {
+			return fail(l, "leading zero in number")
+		}
+	case l.AcceptRun(digits) == 0:
+		return fail(l, "missing digits in number")
+	}
+	if l.Accept(".") && l.AcceptRun(digits) == 0 {
+		return fail(l, "missing digits after decimal point")
+	}
+	if l.Accept("eE") {
+		l.Accept("+-")
+		if l.AcceptRun(digits) == 0 {
+			return fail(l, "missing digits in exponent")
+		}
+	}
+	l.Emit(ItemNumber)
+	return Lex
+}
+
+// lexLiteral scans one of the literal names true, false and null.
+func lexLiteral(l *lexer.Lexer) lexer.StateFn {
+	l.AcceptRunFunc(func(r rune) bool { return 'a' <= r && r <= 'z' })
+	switch l.Current() {
+	case "true":
+		l.Emit(ItemTrue)
+	case "false":
+		l.Emit(ItemFalse)
+	case "null":
+		l.Emit(ItemNull)
+	default:
+		return fail(l, "invalid literal %q", l.Current())
+	}
+	return Lex
+}
+
+// fail emits an error and skips the remainder of the malformed token so that
+// lexing can resume.
+func fail(l *lexer.Lexer, format string, vs ...interface{}) lexer.StateFn {
+	l.Errorf(format, vs...)
+	for {
+		l.AcceptRunFunc(func(r rune) bool {
+			return !strings.ContainsRune(whitespace+`{}[]:,"`, r)
+		})
+		if r, n := l.Peek(); !lexer.IsInvalid(r, n) {
+			break
+		}
+		l.AdvanceByte()
+	}
+	l.Ignore()
+	return Lex
+}
+
+// ErrSyntax is returned by Unquote when its argument is not a quoted string.
+var ErrSyntax = errors.New("invalid JSON string")
+
+// Unquote decodes the value of a JSON string item.  An invalid escape
+// sequence results in a *lexer.EscapeError.
+func Unquote(s string) (string, error) {
+	if len(s) < 2 || s[0] != '"' || s[len(s)-1] != '"' {
+		return "", ErrSyntax
+	}
+	return lexer.DecodeEscapes(s[1:len(s)-1], lexer.JSONEscapes)
+}
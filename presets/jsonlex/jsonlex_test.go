@@ -0,0 +1,131 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package jsonlex
+
+import (
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+func lexAll(input string) []*lexer.Item {
+	lex := New(input)
+	var items []*lexer.Item
+	for {
+		item := lex.Next()
+		items = append(items, item)
+		if item.Type == lexer.ItemEOF {
+			return items
+		}
+	}
+}
+
+func TestLex(t *testing.T) {
+	items := lexAll(`{"a": [1, -0.5e+3, true, false, null], "b\"": "é"}`)
+	expect := []struct {
+		typ lexer.ItemType
+		val string
+	}{
+		{ItemBeginObject, "{"},
+		{ItemString, `"a"`},
+		{ItemColon, ":"},
+		{ItemBeginArray, "["},
+		{ItemNumber, "1"},
+		{ItemComma, ","},
+		{ItemNumber, "-0.5e+3"},
+		{ItemComma, ","},
+		{ItemTrue, "true"},
+		{ItemComma, ","},
+		{ItemFalse, "false"},
+		{ItemComma, ","},
+		{ItemNull, "null"},
+		{ItemEndArray, "]"},
+		{ItemComma, ","},
+		{ItemString, `"b\""`},
+		{ItemColon, ":"},
+		{ItemString, `"é"`},
+		{ItemEndObject, "}"},
+		{lexer.ItemEOF, ""},
+	}
+	if len(items) != len(expect) {
+		t.Fatalf("%d items (expected %d): %v", len(items), len(expect), items)
+	}
+	for i, item := range items {
+		if item.Type != expect[i].typ || item.Value != expect[i].val {
+			t.Errorf("item %d: %d %q (expected %d %q)", i, item.Type, item.Value, expect[i].typ, expect[i].val)
+		}
+	}
+}
+
+func TestRecovery(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		pos   []int
+	}{
+		{`[01, 2]`, []int{1}},
+		{`[-, 1.]`, []int{1, 4}},
+		{`["\q", nul, "ok"]`, []int{1, 7}},
+		{"[\"a\tb\", 1e]", []int{1, 8}},
+		{`[1] @ [2]`, []int{4}},
+		{`"abc`, []int{0}},
+		{"[\xff, 1]", []int{1}},
+		{"[1\xffx\xfe, \"a\xffb\", 2]", []int{2, 7}},
+	} {
+		var pos []int
+		for _, item := range lexAll(test.input) {
+			if item.Type == lexer.ItemError {
+				pos = append(pos, item.Pos)
+			}
+		}
+		if len(pos) != len(test.pos) {
+			t.Errorf("%q: errors at %v (expected %v)", test.input, pos, test.pos)
+			continue
+		}
+		for i := range pos {
+			if pos[i] != test.pos[i] {
+				t.Errorf("%q: errors at %v (expected %v)", test.input, pos, test.pos)
+				break
+			}
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	for _, test := range []struct {
+		in, out string
+		ok      bool
+	}{
+		{`"abc"`, "abc", true},
+		{`"a\"\\\/\b\f\n\r\t"`, "a\"\\/\b\f\n\r\t", true},
+		{`"é"`, "é", true},
+		{`"😀!"`, "\U0001f600!", true},
+		{`"\ud83d"`, "�", true},
+		{`"\x"`, "", false},
+		{`"\u12"`, "", false},
+		{`abc`, "", false},
+	} {
+		out, err := Unquote(test.in)
+		if (err == nil) != test.ok || out != test.out {
+			t.Errorf("Unquote(%s) = %q, %v", test.in, out, err)
+		}
+	}
+}
+
+func TestSniff(t *testing.T) {
+	for _, test := range []struct {
+		filename, content string
+		ok                bool
+	}{
+		{"data.JSON", "", true},
+		{"", ` {"a": [1]}`, true},
+		{"", `[1, 2`, true},
+		{"", `a = 1`, false},
+	} {
+		lang := lexer.Sniff(test.filename, test.content)
+		if (lang != nil && lang.Name == "json") != test.ok {
+			t.Errorf("%q %q: language %v", test.filename, test.content, lang)
+		}
+	}
+}
@@ -0,0 +1,275 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package mdlex lexes the inline structure of Markdown text.
+
+The lexer follows the inline rules of CommonMark.
+
+	http://spec.commonmark.org/
+
+Input is a block of inline content, such as the text of a paragraph.  The
+lexer emits code spans, backslash escapes, autolinks, line breaks, and the
+brackets and destinations of links and images.  Runs of '*' and '_' are
+emitted as delimiter items classified by whether they can open emphasis,
+close it, or both.  Matching delimiters into emphasis is left to the
+consumer, which can do so with the positions of the delimiter items rather
+than re-scanning the text.
+
+Classifying delimiter runs is context-sensitive.  It depends on the character
+preceding and the character following the run.  The preceding character is
+the base of the preceding grapheme, so a combining mark following a letter is
+treated as part of the letter.
+*/
+package mdlex
+
+import (
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Markdown inline item types.
+const (
+	ItemText       lexer.ItemType = iota // plain text
+	ItemEscape                           // \*
+	ItemCodeSpan                         // `code`
+	ItemDelimOpen                        // * or _ run that can open emphasis
+	ItemDelimClose                       // * or _ run that can close emphasis
+	ItemDelimBoth                        // * or _ run that can open or close emphasis
+	ItemLinkOpen                         // [
+	ItemImageOpen                        // ![
+	ItemLinkClose                        // ]
+	ItemLinkDest                         // (destination "title")
+	ItemAutolink                         // <http://example.com>
+	ItemHardBreak                        // two spaces or a backslash before a newline
+	ItemNewline                          // soft line break
+)
+
+// special runes begin something other than text.
+const special = "\\`*_[]!<\n "
+
+func init() {
+	lexer.RegisterLanguage(&lexer.Language{
+		Name:      "markdown",
+		Aliases:   []string{"md"},
+		Start:     func() lexer.StateFn { return Lex },
+		Filenames: []string{"*.md", "*.markdown"},
+	})
+}
+
+// New returns a lexer for the inline Markdown in input.
+func New(input string) *lexer.Lexer {
+	return lexer.New(Lex, input)
+}
+
+// Lex is the start state of the Markdown lexer.
+func Lex(l *lexer.Lexer) lexer.StateFn {
+	rest := l.Input()[l.Pos():]
+	switch {
+	case rest == "":
+		emitText(l)
+		return nil
+	case strings.HasPrefix(rest, "\\\n"):
+		emitText(l)
+		l.AcceptString("\\\n")
+		l.Emit(ItemHardBreak)
+	case rest[0] == '\\' && len(rest) > 1 && isASCIIPunct(rest[1]):
+		emitText(l)
+		l.AcceptString(rest[:2])
+		l.Emit(ItemEscape)
+	case rest[0] == '`':
+		return lexCodeSpan
+	case rest[0] == '*' || rest[0] == '_':
+		emitText(l)
+		return lexDelim
+	case strings.HasPrefix(rest, "!["):
+		emitText(l)
+		l.AcceptString("![")
+		l.Emit(ItemImageOpen)
+	case rest[0] == '[':
+		emitText(l)
+		l.AcceptString("[")
+		l.Emit(ItemLinkOpen)
+	case rest[0] == ']':
+		emitText(l)
+		l.AcceptString("]")
+		l.Emit(ItemLinkClose)
+		acceptLinkDest(l)
+	case rest[0] == '<' && autolinkLen(rest) > 0:
+		emitText(l)
+		l.AcceptString(rest[:autolinkLen(rest)])
+		l.Emit(ItemAutolink)
+	case rest[0] == '\n':
+		emitText(l)
+		l.AcceptString("\n")
+		l.Emit(ItemNewline)
+	case strings.HasPrefix(strings.TrimLeft(rest, " "), "\n") && len(rest)-len(strings.TrimLeft(rest, " ")) >= 2:
+		emitText(l)
+		l.AcceptRun(" ")
+		l.AcceptString("\n")
+		l.Emit(ItemHardBreak)
+	default:
+		// accumulate text, including special runes that begin nothing.
+		if r, n := l.Advance(); lexer.IsInvalid(r, n) {
+			return l.Errorf("invalid utf-8 rune")
+		}
+		l.AcceptRunFunc(func(r rune) bool { return !strings.ContainsRune(special, r) })
+	}
+	return Lex
+}
+
+// emitText emits any pending text.
+func emitText(l *lexer.Lexer) {
+	if l.Pos() > l.Start() {
+		l.Emit(ItemText)
+	}
+}
+
+// lexCodeSpan scans a backtick string and, if a closing backtick string of
+// the same length follows, the code span it opens.  An unmatched backtick
+// string is text.
+func lexCodeSpan(l *lexer.Lexer) lexer.StateFn {
+	rest := l.Input()[l.Pos():]
+	n := len(rest) - len(strings.TrimLeft(rest, "`"))
+	fence := rest[:n]
+	for i := n; i < len(rest); {
+		j := strings.Index(rest[i:], fence)
+		if j < 0 {
+			break
+		}
+		i += j
+		m := len(rest[i:]) - len(strings.TrimLeft(rest[i:], "`"))
+		if m == n {
+			emitText(l)
+			l.AcceptString(rest[:i+n])
+			l.Emit(ItemCodeSpan)
+			return Lex
+		}
+		i += m
+	}
+	l.AcceptString(fence)
+	return Lex
+}
+
+// lexDelim scans a run of '*' or '_' and classifies it using the runes on
+// either side of the run.
+func lexDelim(l *lexer.Lexer) lexer.StateFn {
+	c := l.Input()[l.Pos()]
+	l.AcceptRun(string(c))
+	before := prevChar(l.Input()[:l.Start()])
+	after, _ := utf8.DecodeRuneInString(l.Input()[l.Pos():])
+	if l.Pos() == len(l.Input()) {
+		after = '\n'
+	}
+	left := !isSpace(after) && (!isPunct(after) || isSpace(before) || isPunct(before))
+	right := !isSpace(before) && (!isPunct(before) || isSpace(after) || isPunct(after))
+	canOpen, canClose := left, right
+	if c == '_' {
+		canOpen = left && (!right || isPunct(before))
+		canClose = right && (!left || isPunct(after))
+	}
+	switch {
+	case canOpen && canClose:
+		l.Emit(ItemDelimBoth)
+	case canOpen:
+		l.Emit(ItemDelimOpen)
+	case canClose:
+		l.Emit(ItemDelimClose)
+	default:
+		l.Emit(ItemText)
+	}
+	return Lex
+}
+
+// prevChar returns the base rune of the last grapheme in s, or '\n' if s is
+// empty.
+func prevChar(s string) rune {
+	for s != "" {
+		r, n := utf8.DecodeLastRuneInString(s)
+		if !unicode.Is(unicode.M, r) {
+			return r
+		}
+		s = s[:len(s)-n]
+	}
+	return '\n'
+}
+
+// acceptLinkDest emits the destination and title following a link's closing
+// bracket, if there is one.
+func acceptLinkDest(l *lexer.Lexer) {
+	rest := l.Input()[l.Pos():]
+	if !strings.HasPrefix(rest, "(") {
+		return
+	}
+	depth := 0
+	quote := byte(0)
+	for i := 0; i < len(rest); i++ {
+		switch c := rest[i]; {
+		case c == '\\':
+			i++
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+			if depth == 0 {
+				l.AcceptString(rest[:i+1])
+				l.Emit(ItemLinkDest)
+				return
+			}
+		case c == '\n' && strings.HasPrefix(rest[i+1:], "\n"):
+			return
+		}
+	}
+}
+
+// autolinkLen returns the length of the autolink at the beginning of s or
+// zero if s does not begin with an autolink.
+func autolinkLen(s string) int {
+	end := strings.IndexAny(s, "> \t\n")
+	if end < 0 || s[end] != '>' {
+		return 0
+	}
+	body := s[1:end]
+	if i := strings.IndexByte(body, ':'); i >= 2 && i <= 32 && isScheme(body[:i]) {
+		return end + 1
+	}
+	if at := strings.IndexByte(body, '@'); at > 0 && at < len(body)-1 && !strings.ContainsAny(body, "<\\") {
+		return end + 1
+	}
+	return 0
+}
+
+func isScheme(s string) bool {
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		case i > 0 && ('0' <= c && c <= '9' || c == '+' || c == '.' || c == '-'):
+		default:
+			return false
+		}
+	}
+	return true
+}
+
+func isASCIIPunct(c byte) bool {
+	return c < utf8.RuneSelf && strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isSpace(r rune) bool {
+	return unicode.IsSpace(r)
+}
+
+func isPunct(r rune) bool {
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -0,0 +1,75 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package mdlex
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+var typeNames = map[lexer.ItemType]string{
+	ItemText:       "text",
+	ItemEscape:     "esc",
+	ItemCodeSpan:   "code",
+	ItemDelimOpen:  "open",
+	ItemDelimClose: "close",
+	ItemDelimBoth:  "both",
+	ItemLinkOpen:   "[",
+	ItemImageOpen:  "![",
+	ItemLinkClose:  "]",
+	ItemLinkDest:   "dest",
+	ItemAutolink:   "auto",
+	ItemHardBreak:  "br",
+	ItemNewline:    "nl",
+}
+
+func lexAll(input string) []string {
+	lex := New(input)
+	var items []string
+	for {
+		item := lex.Next()
+		if item.Type == lexer.ItemEOF {
+			return items
+		}
+		items = append(items, fmt.Sprintf("%s:%s", typeNames[item.Type], item.Value))
+	}
+}
+
+func TestLex(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		expect []string
+	}{
+		{"", nil},
+		{"plain text", []string{"text:plain text"}},
+		{"*em* and __strong__", []string{
+			"open:*", "text:em", "close:*", "text: and ", "open:__", "text:strong", "close:__",
+		}},
+		{"a*b*c snake_case_name", []string{
+			"text:a", "both:*", "text:b", "both:*", "text:c snake", "text:_", "text:case", "text:_", "text:name",
+		}},
+		{"* not em", []string{"text:*", "text: not em"}},
+		{"é\u0301*x*", []string{"text:é\u0301", "both:*", "text:x", "close:*"}},
+		{"use `a *b*` or ``x`y``", []string{"text:use ", "code:`a *b*`", "text: or ", "code:``x`y``"}},
+		{"`unmatched", []string{"text:`unmatched"}},
+		{`\*not\* em`, []string{`esc:\*`, "text:not", `esc:\*`, "text: em"}},
+		{"[a](http://x.io/(y) \"t\") ![i][r]", []string{
+			"[:[", "text:a", "]:]", `dest:(http://x.io/(y) "t")`, "text: ",
+			"![:![", "text:i", "]:]", "[:[", "text:r", "]:]",
+		}},
+		{"see <http://go.dev> or <a@b.c> not <x>", []string{
+			"text:see ", "auto:<http://go.dev>", "text: or ", "auto:<a@b.c>", "text: not <x>",
+		}},
+		{"a  \nb\\\nc\nd", []string{"text:a", "br:  \n", "text:b", "br:\\\n", "text:c", "nl:\n", "text:d"}},
+	} {
+		items := lexAll(test.input)
+		if !reflect.DeepEqual(items, test.expect) {
+			t.Errorf("%q:\n\t%q\n(expected)\n\t%q", test.input, items, test.expect)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package shlex splits command lines into words following POSIX shell rules.
+
+	http://pubs.opengroup.org/onlinepubs/9699919799/utilities/V3_chap02.html
+
+Words are emitted as ItemWord with their raw text, quotes and all, so item
+positions map exactly onto the input.  Control and redirection operators are
+emitted as ItemOperator, unescaped newlines as ItemNewline and comments as
+ItemComment.  Blanks and backslash-newline line continuations between words
+are discarded.
+
+Quote removal and parameter expansion are applied to a word's value with
+Unquote and Expand.  Command substitutions, $(...) and `...`, are recognized
+so that words containing them are split correctly, but they are never
+executed.
+*/
+package shlex
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Shell item types.
+const (
+	ItemWord     lexer.ItemType = iota // echo, "a b", $HOME/x
+	ItemOperator                       // | || & && ; ;; < > >> << <& >& <> >| ( )
+	ItemNewline                        // \n
+	ItemComment                        // # text
+)
+
+const blanks = " \t"
+
+// operators lists operators before their prefixes so that the longest
+// operator is matched.
+var operators = []string{
+	"&&", "||", ";;", "<<", ">>", "<&", ">&", "<>", ">|",
+	"&", "|", ";", "<", ">", "(", ")",
+}
+
+// metachars terminate unquoted words.
+const metachars = blanks + "\n|&;<>()"
+
+func init() {
+	lexer.RegisterLanguage(&lexer.Language{
+		Name:         "sh",
+		Aliases:      []string{"shell"},
+		Start:        func() lexer.StateFn { return Lex },
+		Filenames:    []string{"*.sh"},
+		Interpreters: []string{"sh", "bash", "dash", "ksh"},
+	})
+}
+
+// New returns a lexer for the command line input.
+func New(input string) *lexer.Lexer {
+	return lexer.New(Lex, input)
+}
+
+// Lex is the start state of the shell lexer.
+func Lex(l *lexer.Lexer) lexer.StateFn {
+	for l.AcceptRun(blanks) > 0 || l.AcceptString("\\\n") {
+	}
+	l.Ignore()
+	switch {
+	case l.Pos() >= len(l.Input()):
+		return nil
+	case l.Accept("\n"):
+		l.Emit(ItemNewline)
+		return Lex
+	case l.Accept("#"):
+		l.AcceptRunFunc(func(r rune) bool { return r != '\n' })
+		l.Emit(ItemComment)
+		return Lex
+	}
+	for _, op := range operators {
+		if l.AcceptString(op) {
+			l.Emit(ItemOperator)
+			return Lex
+		}
+	}
+	return lexWord
+}
+
+// lexWord scans a word.
+func lexWord(l *lexer.Lexer) lexer.StateFn {
+	for {
+		r, n := l.Advance()
+		switch {
+		case lexer.IsEOF(r, n):
+			l.Emit(ItemWord)
+			return nil
+		case lexer.IsInvalid(r, n):
+			return l.Errorf("invalid utf-8 rune")
+		case strings.ContainsRune(metachars, r):
+			l.Backup()
+			l.Emit(ItemWord)
+			return Lex
+		case r == '\\':
+			if r, n := l.Advance(); lexer.IsEOF(r, n) {
+				return l.Errorf("trailing backslash")
+			}
+		case r == '\'':
+			if !acceptPast(l, "'") {
+				return l.Errorf("unterminated single quote")
+			}
+		case r == '"':
+			if err := acceptDoubleQuoted(l); err != "" {
+				return l.Errorf("%s", err)
+			}
+		case r == '`':
+			if err := acceptBackquoted(l); err != "" {
+				return l.Errorf("%s", err)
+			}
+		case r == '$':
+			if err := acceptDollar(l); err != "" {
+				return l.Errorf("%s", err)
+			}
+		}
+	}
+}
+
+// acceptPast advances past the next occurrence of s.
+func acceptPast(l *lexer.Lexer, s string) bool {
+	rest := l.Input()[l.Pos():]
+	i := strings.Index(rest, s)
+	if i < 0 {
+		return false
+	}
+	return l.AcceptString(rest[:i+len(s)])
+}
+
+// acceptDoubleQuoted advances past the end of a double quoted string.  It
+// returns a non-empty error message if the string is malformed.
+func acceptDoubleQuoted(l *lexer.Lexer) string {
+	for {
+		r, n := l.Advance()
+		switch {
+		case lexer.IsEOF(r, n):
+			return "unterminated double quote"
+		case lexer.IsInvalid(r, n):
+			return "invalid utf-8 rune"
+		case r == '"':
+			return ""
+		case r == '\\':
+			l.Accept("$`\"\\\n")
+		case r == '`':
+			if err := acceptBackquoted(l); err != "" {
+				return err
+			}
+		case r == '$':
+			if err := acceptDollar(l); err != "" {
+				return err
+			}
+		}
+	}
+}
+
+// acceptBackquoted advances past the end of a `...` command substitution.
+func acceptBackquoted(l *lexer.Lexer) string {
+	for {
+		r, n := l.Advance()
+		switch {
+		case lexer.IsEOF(r, n):
+			return "unterminated command substitution"
+		case lexer.IsInvalid(r, n):
+			return "invalid utf-8 rune"
+		case r == '`':
+			return ""
+		case r == '\\':
+			l.Accept("$`\\")
+		}
+	}
+}
+
+// acceptDollar advances over the remainder of a parameter expansion or
+// command substitution following a '$'.
+func acceptDollar(l *lexer.Lexer) string {
+	switch {
+	case l.AcceptString("(("):
+		return acceptNested(l, '(', ')', 2, "unterminated arithmetic expansion")
+	case l.Accept("("):
+		return acceptNested(l, '(', ')', 1, "unterminated command substitution")
+	case l.Accept("{"):
+		return acceptNested(l, '{', '}', 1, "unterminated parameter expansion")
+	}
+	if l.AcceptRunFunc(isNameStart) > 0 {
+		l.AcceptRunFunc(isNameRune)
+		return ""
+	}
+	l.Accept("0123456789@*#?$!-")
+	return ""
+}
+
+// acceptNested advances until depth unquoted close runes have been read
+// (excluding those balanced by open runes).
+func acceptNested(l *lexer.Lexer, open, close rune, depth int, unterminated string) string {
+	for depth > 0 {
+		r, n := l.Advance()
+		switch {
+		case lexer.IsEOF(r, n):
+			return unterminated
+		case lexer.IsInvalid(r, n):
+			return "invalid utf-8 rune"
+		case r == open:
+			depth++
+		case r == close:
+			depth--
+		case r == '\\':
+			l.Advance()
+		case r == '\'':
+			if !acceptPast(l, "'") {
+				return "unterminated single quote"
+			}
+		case r == '"':
+			if err := acceptDoubleQuoted(l); err != "" {
+				return err
+			}
+		case r == '`':
+			if err := acceptBackquoted(l); err != "" {
+				return err
+			}
+		}
+	}
+	return ""
+}
+
+func isNameStart(r rune) bool {
+	return r == '_' || 'a' <= r && r <= 'z' || 'A' <= r && r <= 'Z'
+}
+
+func isNameRune(r rune) bool {
+	return isNameStart(r) || '0' <= r && r <= '9'
+}
+
+// ErrSyntax is returned when a word passed to Unquote or Expand is malformed.
+var ErrSyntax = errors.New("invalid shell word")
+
+// Unquote performs quote removal on a word.  Parameter expansions are left
+// in place.
+func Unquote(word string) (string, error) {
+	return expand(word, nil)
+}
+
+// Expand performs parameter expansion and quote removal on a word.  The
+// value of each $NAME, ${NAME} and special parameter ($1, $?, ...) outside
+// single quotes is given by lookup.  Parameter expansions with modifiers,
+// such as ${NAME:-default}, and command substitutions are left unexpanded.
+func Expand(word string, lookup func(name string) string) (string, error) {
+	if lookup == nil {
+		lookup = func(string) string { return "" }
+	}
+	return expand(word, lookup)
+}
+
+func expand(word string, lookup func(string) string) (string, error) {
+	var buf []byte
+	quoted := false
+	for i := 0; i < len(word); i++ {
+		c := word[i]
+		switch {
+		case c == '\\' && !quoted:
+			if i+1 == len(word) {
+				return "", ErrSyntax
+			}
+			i++
+			if word[i] != '\n' {
+				buf = append(buf, word[i])
+			}
+		case c == '\\' && quoted:
+			if i+1 < len(word) && strings.IndexByte("$`\"\\\n", word[i+1]) >= 0 {
+				i++
+				if word[i] != '\n' {
+					buf = append(buf, word[i])
+				}
+			} else {
+				buf = append(buf, c)
+			}
+		case c == '\'' && !quoted:
+			j := strings.IndexByte(word[i+1:], '\'')
+			if j < 0 {
+				return "", ErrSyntax
+			}
+			buf = append(buf, word[i+1:i+1+j]...)
+			i += j + 1
+		case c == '"':
+			quoted = !quoted
+		case c == '$' && lookup != nil:
+			name, n := paramName(word[i+1:])
+			if n == 0 {
+				buf = append(buf, c)
+				continue
+			}
+			buf = append(buf, lookup(name)...)
+			i += n
+		default:
+			buf = append(buf, c)
+		}
+	}
+	if quoted {
+		return "", ErrSyntax
+	}
+	return string(buf), nil
+}
+
+// paramName returns the name of the parameter expanded at the beginning of s
+// and the number of bytes in its expansion.  If s does not begin with a
+// simple parameter expansion paramName returns n == 0.
+func paramName(s string) (name string, n int) {
+	if s == "" {
+		return "", 0
+	}
+	if s[0] == '{' {
+		end := strings.IndexByte(s, '}')
+		if end < 0 {
+			return "", 0
+		}
+		name = s[1:end]
+		if name == "" || !validName(name) {
+			return "", 0
+		}
+		return name, end + 1
+	}
+	if strings.IndexByte("0123456789@*#?$!-", s[0]) >= 0 {
+		return s[:1], 1
+	}
+	for n < len(s) && isNameRune(rune(s[n])) && (n > 0 || isNameStart(rune(s[0]))) {
+		n++
+	}
+	return s[:n], n
+}
+
+func validName(name string) bool {
+	if len(name) == 1 && strings.IndexByte("0123456789@*#?$!-", name[0]) >= 0 {
+		return true
+	}
+	for i := 0; i < len(name); i++ {
+		if !isNameRune(rune(name[i])) || i == 0 && !isNameStart(rune(name[i])) {
+			return strings.Trim(name, "0123456789") == ""
+		}
+	}
+	return true
+}
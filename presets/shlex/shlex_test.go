@@ -0,0 +1,88 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package shlex
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+var typeNames = map[lexer.ItemType]string{
+	ItemWord:        "w",
+	ItemOperator:    "op",
+	ItemNewline:     "nl",
+	ItemComment:     "c",
+	lexer.ItemError: "error",
+}
+
+func lexAll(input string) []string {
+	lex := New(input)
+	var items []string
+	for {
+		item := lex.Next()
+		if item.Type == lexer.ItemEOF {
+			return items
+		}
+		items = append(items, fmt.Sprintf("%s:%d:%s", typeNames[item.Type], item.Pos, item.Value))
+	}
+}
+
+func TestLex(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		expect []string
+	}{
+		{"", nil},
+		{"echo hello   world", []string{"w:0:echo", "w:5:hello", "w:13:world"}},
+		{`a "b c" 'd e'f g\ h`, []string{`w:0:a`, `w:2:"b c"`, `w:8:'d e'f`, `w:15:g\ h`}},
+		{"ls -l|wc>>out 2>&1 &&x", []string{
+			"w:0:ls", "w:3:-l", "op:5:|", "w:6:wc", "op:8:>>", "w:10:out",
+			"w:14:2", "op:15:>&", "w:17:1", "op:19:&&", "w:21:x",
+		}},
+		{"a # c\nb\\\n c", []string{"w:0:a", "c:2:# c", "nl:5:\n", "w:6:b\\\n", "w:10:c"}},
+		{`x=$(echo ")"; y) "$(a)"`, []string{`w:0:x=$(echo ")"; y)`, `w:17:"$(a)"`}},
+		{"echo ${A:-b c} `d e`", []string{"w:0:echo", "w:5:${A:-b c}", "w:15:`d e`"}},
+		{`a 'b`, []string{"w:0:a", "error:2:unterminated single quote"}},
+		{`"a`, []string{"error:0:unterminated double quote"}},
+	} {
+		items := lexAll(test.input)
+		if !reflect.DeepEqual(items, test.expect) {
+			t.Errorf("%q:\n\t%q\n(expected)\n\t%q", test.input, items, test.expect)
+		}
+	}
+}
+
+func TestExpand(t *testing.T) {
+	env := map[string]string{"HOME": "/home/x", "1": "one", "?": "0"}
+	lookup := func(name string) string { return env[name] }
+	for _, test := range []struct {
+		word, unquoted, expanded string
+	}{
+		{`abc`, `abc`, `abc`},
+		{`'a b'c\ d`, `a bc d`, `a bc d`},
+		{`"a \"b\" \x"`, `a "b" \x`, `a "b" \x`},
+		{`$HOME/bin`, `$HOME/bin`, `/home/x/bin`},
+		{`"${HOME}x" '$HOME'`, `${HOME}x $HOME`, `/home/xx $HOME`},
+		{`$1$?$UNSET.`, `$1$?$UNSET.`, `one0.`},
+		{`${A:-b}`, `${A:-b}`, `${A:-b}`},
+	} {
+		s, err := Unquote(test.word)
+		if err != nil || s != test.unquoted {
+			t.Errorf("Unquote(%s) = %q, %v (expected %q)", test.word, s, err, test.unquoted)
+		}
+		s, err = Expand(test.word, lookup)
+		if err != nil || s != test.expanded {
+			t.Errorf("Expand(%s) = %q, %v (expected %q)", test.word, s, err, test.expanded)
+		}
+	}
+	for _, word := range []string{`'a`, `"a`, `a\`} {
+		if _, err := Unquote(word); err != ErrSyntax {
+			t.Errorf("Unquote(%s) error %v", word, err)
+		}
+	}
+}
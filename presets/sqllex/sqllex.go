@@ -0,0 +1,328 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package sqllex lexes SQL statements.
+
+The lexer recognizes keywords, identifiers (bare and quoted), string literals
+with doubled-quote escapes, numeric literals, operators, punctuation, bind
+parameters and comments.  Whitespace is discarded.  Differences between SQL
+dialects are described by a Dialect; ANSI, MySQL, PostgreSQL, SQLite and
+SQLServer are provided.
+
+	lex := sqllex.New("SELECT `id` FROM t -- all rows", sqllex.MySQL)
+
+Keywords are matched case-insensitively against a list of common reserved
+words.  Whether a particular word is reserved in a particular dialect is left
+to the parser, which may treat an ItemKeyword as an identifier.
+*/
+package sqllex
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// SQL item types.
+const (
+	ItemKeyword     lexer.ItemType = iota // SELECT
+	ItemIdent                             // name
+	ItemQuotedIdent                       // "name", `name` or [name]
+	ItemString                            // 'text'
+	ItemNumber                            // 1, 1.5, .5e-3, 0x1F
+	ItemOperator                          // =, <>, ||, ::
+	ItemPunct                             // ( ) , ; .
+	ItemParam                             // ?, $1, :name, @name
+	ItemComment                           // -- text, /* text */
+)
+
+// Dialect describes the lexical syntax of an SQL implementation.
+type Dialect struct {
+	// LineComments lists the prefixes beginning comments that extend to the
+	// end of the line.
+	LineComments []string
+
+	// NestedComments allows /* */ comments to nest.
+	NestedComments bool
+
+	// IdentQuotes lists the runes opening quoted identifiers.  The rune '['
+	// is closed by ']', any other rune closes itself.
+	IdentQuotes string
+
+	// BackslashEscapes allows backslash escapes in string literals.
+	BackslashEscapes bool
+
+	// Params lists the runes introducing bind parameters.  '?' is a complete
+	// parameter, others must be followed by a name or number.
+	Params string
+}
+
+// Common SQL dialects.
+var (
+	ANSI       = &Dialect{LineComments: []string{"--"}, IdentQuotes: `"`, Params: "?"}
+	MySQL      = &Dialect{LineComments: []string{"--", "#"}, IdentQuotes: "`", BackslashEscapes: true, Params: "?"}
+	PostgreSQL = &Dialect{LineComments: []string{"--"}, NestedComments: true, IdentQuotes: `"`, Params: "$"}
+	SQLite     = &Dialect{LineComments: []string{"--"}, IdentQuotes: "\"`[", Params: "?:@$"}
+	SQLServer  = &Dialect{LineComments: []string{"--"}, NestedComments: true, IdentQuotes: `"[`, Params: "@"}
+)
+
+// operators lists multi-rune operators before their prefixes so that the
+// longest operator is matched.
+var operators = []string{
+	"->>", "<=>", "<<", ">>", "<>", "!=", "<=", ">=", "||", "::", "->", "=>",
+	"=", "<", ">", "+", "-", "*", "/", "%", "&", "|", "^", "~", "!",
+}
+
+var keywords = make(map[string]bool)
+
+func init() {
+	for _, kw := range strings.Fields(`
+		ALL ALTER AND ANY AS ASC BETWEEN BY CASE CAST CHECK COLUMN CONSTRAINT
+		CREATE CROSS DEFAULT DELETE DESC DISTINCT DROP ELSE END EXCEPT EXISTS
+		FALSE FOREIGN FROM FULL GROUP HAVING IN INDEX INNER INSERT INTERSECT
+		INTO IS JOIN KEY LEFT LIKE LIMIT NOT NULL OFFSET ON OR ORDER OUTER
+		PRIMARY REFERENCES RIGHT SELECT SET TABLE THEN TRUE UNION UNIQUE UPDATE
+		USING VALUES VIEW WHEN WHERE WITH`) {
+		keywords[kw] = true
+	}
+	for _, lang := range []*lexer.Language{
+		{Name: "sql", Start: ANSI.Start, Filenames: []string{"*.sql"}, Analyze: analyze},
+		{Name: "mysql", Start: MySQL.Start},
+		{Name: "postgresql", Aliases: []string{"postgres"}, Start: PostgreSQL.Start},
+		{Name: "sqlite", Start: SQLite.Start},
+		{Name: "sqlserver", Aliases: []string{"tsql"}, Start: SQLServer.Start},
+	} {
+		lexer.RegisterLanguage(lang)
+	}
+}
+
+// analyze returns the confidence that text is SQL, judged by its first
+// word.
+func analyze(text string) float64 {
+	fields := strings.Fields(text)
+	if len(fields) == 0 {
+		return 0
+	}
+	switch strings.ToUpper(fields[0]) {
+	case "SELECT", "INSERT", "UPDATE", "DELETE", "CREATE", "ALTER", "DROP":
+		return 0.5
+	}
+	return 0
+}
+
+// IsKeyword returns true if word is a keyword recognized by the lexer.
+func IsKeyword(word string) bool {
+	return keywords[strings.ToUpper(word)]
+}
+
+// New returns a lexer for the SQL in input.  If d is nil ANSI is used.
+func New(input string, d *Dialect) *lexer.Lexer {
+	if d == nil {
+		d = ANSI
+	}
+	return lexer.New(d.Start(), input)
+}
+
+// Start returns the start state of a lexer for d.
+func (d *Dialect) Start() lexer.StateFn {
+	s := &scanner{d}
+	return s.lex
+}
+
+type scanner struct {
+	*Dialect
+}
+
+// lex is the state between tokens.
+func (s *scanner) lex(l *lexer.Lexer) lexer.StateFn {
+	l.AcceptRunFunc(unicode.IsSpace)
+	l.Ignore()
+	for _, prefix := range s.LineComments {
+		if l.AcceptString(prefix) {
+			return s.lexLineComment
+		}
+	}
+	if l.AcceptString("/*") {
+		return s.lexBlockComment
+	}
+	r, n := l.Advance()
+	switch {
+	case lexer.IsEOF(r, n):
+		return nil
+	case lexer.IsInvalid(r, n):
+		return l.Errorf("invalid utf-8 rune")
+	case r == '\'':
+		return s.lexString
+	case strings.ContainsRune(s.IdentQuotes, r):
+		return s.lexQuotedIdent
+	case strings.ContainsRune(s.Params, r):
+		return s.lexParam
+	case isDigit(r) || r == '.' && l.AcceptFunc(isDigit):
+		l.Backup()
+		return s.lexNumber
+	case strings.ContainsRune("(),;.", r):
+		l.Emit(ItemPunct)
+		return s.lex
+	case isIdentStart(r):
+		return s.lexIdent
+	}
+	l.Backup()
+	for _, op := range operators {
+		if l.AcceptString(op) {
+			l.Emit(ItemOperator)
+			return s.lex
+		}
+	}
+	return l.Errorf("unexpected rune %q", r)
+}
+
+// lexLineComment scans a comment after its prefix.
+func (s *scanner) lexLineComment(l *lexer.Lexer) lexer.StateFn {
+	l.AcceptRunFunc(func(r rune) bool { return r != '\n' })
+	l.Emit(ItemComment)
+	return s.lex
+}
+
+// lexBlockComment scans a comment after its opening /*.
+func (s *scanner) lexBlockComment(l *lexer.Lexer) lexer.StateFn {
+	depth := 1
+	for depth > 0 {
+		switch {
+		case l.AcceptString("*/"):
+			depth--
+		case s.NestedComments && l.AcceptString("/*"):
+			depth++
+		default:
+			if r, n := l.Advance(); lexer.IsEOF(r, n) {
+				return l.Errorf("unterminated comment")
+			} else if lexer.IsInvalid(r, n) {
+				return l.Errorf("invalid utf-8 rune")
+			}
+		}
+	}
+	l.Emit(ItemComment)
+	return s.lex
+}
+
+// lexString scans a string literal after its opening quote.
+func (s *scanner) lexString(l *lexer.Lexer) lexer.StateFn {
+	if !s.acceptQuoted(l, '\'', s.BackslashEscapes) {
+		return l.Errorf("unterminated string")
+	}
+	l.Emit(ItemString)
+	return s.lex
+}
+
+// lexQuotedIdent scans a quoted identifier after its opening quote.
+func (s *scanner) lexQuotedIdent(l *lexer.Lexer) lexer.StateFn {
+	quote, _ := l.Last()
+	if quote == '[' {
+		quote = ']'
+	}
+	if !s.acceptQuoted(l, quote, false) {
+		return l.Errorf("unterminated quoted identifier")
+	}
+	l.Emit(ItemQuotedIdent)
+	return s.lex
+}
+
+// acceptQuoted advances to the closing quote.  A doubled quote does not
+// close the literal.  acceptQuoted returns false if the input ends first.
+func (s *scanner) acceptQuoted(l *lexer.Lexer, quote rune, backslash bool) bool {
+	for {
+		r, n := l.Advance()
+		switch {
+		case lexer.IsEOF(r, n) || lexer.IsInvalid(r, n):
+			return false
+		case r == quote:
+			if !l.AcceptString(string(quote)) {
+				return true
+			}
+		case r == '\\' && backslash:
+			l.AcceptFunc(func(rune) bool { return true })
+		}
+	}
+}
+
+// lexParam scans a bind parameter after its first rune.
+func (s *scanner) lexParam(l *lexer.Lexer) lexer.StateFn {
+	if r, _ := l.Last(); r != '?' && l.AcceptRunFunc(isIdentRune) == 0 {
+		if r == ':' && l.Accept(":") {
+			// a postgres cast operator in a dialect allowing :name
+			l.Emit(ItemOperator)
+			return s.lex
+		}
+		return l.Errorf("missing parameter name")
+	}
+	l.Emit(ItemParam)
+	return s.lex
+}
+
+// lexNumber scans a numeric literal.
+func (s *scanner) lexNumber(l *lexer.Lexer) lexer.StateFn {
+	if l.AcceptString("0x") || l.AcceptString("0X") {
+		if l.AcceptRun("0123456789abcdefABCDEF") == 0 {
+			return l.Errorf("missing digits in hexadecimal literal")
+		}
+	} else {
+		l.AcceptRunFunc(isDigit)
+		if l.Accept(".") {
+			l.AcceptRunFunc(isDigit)
+		}
+		if l.Accept("eE") {
+			l.Accept("+-")
+			if l.AcceptRunFunc(isDigit) == 0 {
+				return l.Errorf("missing digits in exponent")
+			}
+		}
+	}
+	if l.AcceptFunc(isIdentRune) {
+		return l.Errorf("invalid numeric literal")
+	}
+	l.Emit(ItemNumber)
+	return s.lex
+}
+
+// lexIdent scans an identifier or keyword after its first rune.
+func (s *scanner) lexIdent(l *lexer.Lexer) lexer.StateFn {
+	l.AcceptRunFunc(isIdentRune)
+	if IsKeyword(l.Current()) {
+		l.Emit(ItemKeyword)
+	} else {
+		l.Emit(ItemIdent)
+	}
+	return s.lex
+}
+
+// Unquote returns the contents of a string literal or quoted identifier,
+// replacing doubled quotes with a single quote.  Backslash escapes are not
+// decoded.  Unquote returns s unchanged if it is not quoted.
+func Unquote(s string) string {
+	if len(s) < 2 {
+		return s
+	}
+	first, last := s[0], s[len(s)-1]
+	switch {
+	case first == '[' && last == ']':
+		return strings.Replace(s[1:len(s)-1], "]]", "]", -1)
+	case first == last && strings.IndexByte("'\"`", first) >= 0:
+		q := string(first)
+		return strings.Replace(s[1:len(s)-1], q+q, q, -1)
+	}
+	return s
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+func isIdentRune(r rune) bool {
+	return r == '_' || r == '$' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
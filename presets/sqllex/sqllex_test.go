@@ -0,0 +1,105 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sqllex
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+var typeNames = map[lexer.ItemType]string{
+	ItemKeyword:     "kw",
+	ItemIdent:       "id",
+	ItemQuotedIdent: "qid",
+	ItemString:      "str",
+	ItemNumber:      "num",
+	ItemOperator:    "op",
+	ItemPunct:       "p",
+	ItemParam:       "param",
+	ItemComment:     "comment",
+	lexer.ItemError: "error",
+}
+
+func lexAll(input string, d *Dialect) []string {
+	lex := New(input, d)
+	var items []string
+	for {
+		item := lex.Next()
+		if item.Type == lexer.ItemEOF {
+			return items
+		}
+		items = append(items, fmt.Sprintf("%s:%s", typeNames[item.Type], item.Value))
+	}
+}
+
+func TestLex(t *testing.T) {
+	for _, test := range []struct {
+		input   string
+		dialect *Dialect
+		expect  []string
+	}{
+		{
+			`select "a b", x.y from t where s = 'it''s' and n >= .5e1 -- done`, nil,
+			[]string{
+				`kw:select`, `qid:"a b"`, `p:,`, `id:x`, `p:.`, `id:y`, `kw:from`, `id:t`,
+				`kw:where`, `id:s`, `op:=`, `str:'it''s'`, `kw:and`, `id:n`, `op:>=`,
+				`num:.5e1`, `comment:-- done`,
+			},
+		},
+		{
+			"SELECT `id` # note\nFROM t WHERE a <> 'x\\'y' LIMIT ?", MySQL,
+			[]string{
+				"kw:SELECT", "qid:`id`", "comment:# note", "kw:FROM", "id:t", "kw:WHERE",
+				"id:a", "op:<>", `str:'x\'y'`, "kw:LIMIT", "param:?",
+			},
+		},
+		{
+			"/* a /* b */ c */ x::int || $1->>'k'", PostgreSQL,
+			[]string{
+				"comment:/* a /* b */ c */", "id:x", "op:::", "id:int", "op:||",
+				"param:$1", "op:->>", "str:'k'",
+			},
+		},
+		{
+			"[my col] = @p and 0x1F", SQLServer,
+			[]string{"qid:[my col]", "op:=", "param:@p", "kw:and", "num:0x1F"},
+		},
+		{"'open", nil, []string{"error:unterminated string"}},
+		{"12abc", nil, []string{"error:invalid numeric literal"}},
+		{"/* a /* b */", PostgreSQL, []string{"error:unterminated comment"}},
+	} {
+		items := lexAll(test.input, test.dialect)
+		if !reflect.DeepEqual(items, test.expect) {
+			t.Errorf("%q:\n\t%q\n(expected)\n\t%q", test.input, items, test.expect)
+		}
+	}
+}
+
+func TestUnquote(t *testing.T) {
+	for _, test := range [][2]string{
+		{`'it''s'`, `it's`},
+		{`"a""b"`, `a"b`},
+		{"`a`", "a"},
+		{"[a]]b]", "a]b"},
+		{"abc", "abc"},
+	} {
+		if s := Unquote(test[0]); s != test[1] {
+			t.Errorf("Unquote(%s) = %q (expected %q)", test[0], s, test[1])
+		}
+	}
+}
+
+func TestRegistered(t *testing.T) {
+	lex := lexer.Lookup("postgres").New("$1 /* a /* b */ */")
+	if item := lex.Next(); item.Type != ItemParam || item.Value != "$1" {
+		t.Errorf("unexpected item %v", item)
+	}
+	if item := lex.Next(); item.Type != ItemComment || item.Value != "/* a /* b */ */" {
+		t.Errorf("unexpected item %v", item)
+	}
+}
@@ -0,0 +1,354 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package tmpllex lexes text containing delimited actions in the style of
+text/template.
+
+	Hello, {{.Name}}!{{if .Admin -}} (admin) {{- end}}
+
+The lexer alternates between two modes.  Outside actions all input is emitted
+as ItemText.  An action begins with an ItemActionStart and ends with an
+ItemActionEnd, and in between the action is split into identifiers, fields,
+variables, literals and punctuation.  The delimiters are configurable, so the
+package also serves as a reference for lexing any language embedded in plain
+text.
+
+As with text/template, a left delimiter followed by "- " trims the white
+space preceding it and a right delimiter preceded by " -" trims the white
+space following it.  Trimmed white space is not emitted.  Comments, enclosed
+in C-style block comment markers immediately inside the delimiters, are
+emitted as ItemComment.
+*/
+package tmpllex
+
+import (
+	"strings"
+	"unicode"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Template item types.
+const (
+	ItemText        lexer.ItemType = iota // plain text outside actions
+	ItemActionStart                       // {{ or {{-
+	ItemActionEnd                         // }} or -}}
+	ItemComment                           // /* comment */
+	ItemKeyword                           // if, range, end, ...
+	ItemIdent                             // function name
+	ItemField                             // .Field
+	ItemVariable                          // $x
+	ItemDot                               // .
+	ItemString                            // "quoted"
+	ItemRawString                         // `raw`
+	ItemChar                              // 'c'
+	ItemNumber                            // 12, 0x1f, 1.5e3
+	ItemBool                              // true, false
+	ItemNil                               // nil
+	ItemPipe                              // |
+	ItemAssign                            // =
+	ItemDeclare                           // :=
+	ItemComma                             // ,
+	ItemLeftParen                         // (
+	ItemRightParen                        // )
+)
+
+var keywords = map[string]bool{
+	"block": true, "break": true, "continue": true, "define": true,
+	"else": true, "end": true, "if": true, "range": true, "template": true,
+	"with": true,
+}
+
+const (
+	trimMarker = "-"
+	spaceChars = " \t\r\n"
+)
+
+// Delims holds the action delimiters.  Empty delimiters default to "{{" and
+// "}}".
+type Delims struct {
+	Left  string
+	Right string
+}
+
+func init() {
+	lexer.RegisterLanguage(&lexer.Language{
+		Name:      "gotemplate",
+		Aliases:   []string{"tmpl"},
+		Start:     Delims{}.Start,
+		Filenames: []string{"*.tmpl", "*.gotmpl"},
+	})
+}
+
+// New returns a lexer for input with the default delimiters.
+func New(input string) *lexer.Lexer {
+	return lexer.New(Delims{}.Start(), input)
+}
+
+// Start returns the start state of a lexer using the delimiters d.
+func (d Delims) Start() lexer.StateFn {
+	s := &scanner{left: d.Left, right: d.Right}
+	if s.left == "" {
+		s.left = "{{"
+	}
+	if s.right == "" {
+		s.right = "}}"
+	}
+	return s.lexText
+}
+
+type scanner struct {
+	left   string
+	right  string
+	parens int
+}
+
+// lexText scans text up to the next left delimiter.
+func (s *scanner) lexText(l *lexer.Lexer) lexer.StateFn {
+	rest := l.Input()[l.Pos():]
+	i := strings.Index(rest, s.left)
+	if i < 0 {
+		l.AcceptString(rest)
+		if l.Pos() > l.Start() {
+			l.Emit(ItemText)
+		}
+		return nil
+	}
+	text := rest[:i]
+	trim := strings.HasPrefix(rest[i+len(s.left):], trimMarker+" ")
+	if trim {
+		text = strings.TrimRight(text, spaceChars)
+	}
+	l.AcceptString(text)
+	if l.Pos() > l.Start() {
+		l.Emit(ItemText)
+	}
+	l.AcceptString(rest[len(text):i])
+	l.Ignore()
+	return s.lexLeftDelim
+}
+
+// lexLeftDelim scans the left delimiter, which is known to be present.
+func (s *scanner) lexLeftDelim(l *lexer.Lexer) lexer.StateFn {
+	l.AcceptString(s.left)
+	if strings.HasPrefix(l.Input()[l.Pos():], trimMarker+" ") {
+		l.AcceptString(trimMarker)
+	}
+	l.Emit(ItemActionStart)
+	s.parens = 0
+	c := l.Checkpoint()
+	l.AcceptRun(spaceChars)
+	l.Ignore()
+	if l.AcceptString("/*") {
+		return s.lexComment
+	}
+	l.Restore(c)
+	return s.lexInsideAction
+}
+
+// lexComment scans a comment.  The comment must be immediately followed by
+// the right delimiter.
+func (s *scanner) lexComment(l *lexer.Lexer) lexer.StateFn {
+	rest := l.Input()[l.Pos():]
+	i := strings.Index(rest, "*/")
+	if i < 0 {
+		return l.Errorf("unclosed comment")
+	}
+	l.AcceptString(rest[:i+2])
+	l.Emit(ItemComment)
+	c := l.Checkpoint()
+	l.AcceptRun(spaceChars)
+	if !s.atRightDelim(l) {
+		l.Restore(c)
+		return l.Errorf("comment ends before closing delimiter")
+	}
+	l.Ignore()
+	return s.lexRightDelim
+}
+
+// atRightDelim returns true if the lexer is positioned at a right delimiter,
+// possibly preceded by a trim marker.
+func (s *scanner) atRightDelim(l *lexer.Lexer) bool {
+	rest := l.Input()[l.Pos():]
+	return strings.HasPrefix(rest, s.right) || strings.HasPrefix(rest, trimMarker+s.right)
+}
+
+// lexRightDelim scans the right delimiter and any white space it trims.
+func (s *scanner) lexRightDelim(l *lexer.Lexer) lexer.StateFn {
+	trim := l.AcceptString(trimMarker)
+	l.AcceptString(s.right)
+	l.Emit(ItemActionEnd)
+	if trim {
+		l.AcceptRun(spaceChars)
+		l.Ignore()
+	}
+	return s.lexText
+}
+
+// lexInsideAction scans the elements inside action delimiters.
+func (s *scanner) lexInsideAction(l *lexer.Lexer) lexer.StateFn {
+	c := l.Checkpoint()
+	if l.AcceptRun(spaceChars) > 0 {
+		if l.AcceptString(trimMarker + s.right) {
+			l.Restore(c)
+			l.AcceptRun(spaceChars)
+			l.Ignore()
+			return s.lexRightDelim
+		}
+		l.Restore(c)
+		l.AcceptRun(spaceChars)
+		l.Ignore()
+	}
+	if strings.HasPrefix(l.Input()[l.Pos():], s.right) {
+		if s.parens > 0 {
+			return l.Errorf("unclosed left paren")
+		}
+		return s.lexRightDelim
+	}
+	r, n := l.Advance()
+	switch {
+	case lexer.IsEOF(r, n):
+		return l.Errorf("unclosed action")
+	case lexer.IsInvalid(r, n):
+		return l.Errorf("invalid utf-8 rune")
+	case r == '|':
+		l.Emit(ItemPipe)
+	case r == '=':
+		l.Emit(ItemAssign)
+	case r == ':':
+		if !l.Accept("=") {
+			return l.Errorf("expected :=")
+		}
+		l.Emit(ItemDeclare)
+	case r == ',':
+		l.Emit(ItemComma)
+	case r == '(':
+		s.parens++
+		l.Emit(ItemLeftParen)
+	case r == ')':
+		s.parens--
+		if s.parens < 0 {
+			return l.Errorf("unexpected right paren")
+		}
+		l.Emit(ItemRightParen)
+	case r == '"':
+		return s.lexQuote
+	case r == '`':
+		return s.lexRawQuote
+	case r == '\'':
+		return s.lexChar
+	case r == '$':
+		l.AcceptRunFunc(isAlphaNumeric)
+		l.Emit(ItemVariable)
+	case r == '.':
+		if l.AcceptFunc(isDigit) {
+			return s.lexNumber
+		}
+		if l.AcceptRunFunc(isAlphaNumeric) > 0 {
+			l.Emit(ItemField)
+		} else {
+			l.Emit(ItemDot)
+		}
+	case r == '+' || r == '-' || isDigit(r):
+		return s.lexNumber
+	case isAlphaNumeric(r):
+		return s.lexIdent
+	default:
+		return l.Errorf("unrecognized character in action: %q", r)
+	}
+	return s.lexInsideAction
+}
+
+// lexIdent scans an identifier or keyword.
+func (s *scanner) lexIdent(l *lexer.Lexer) lexer.StateFn {
+	l.AcceptRunFunc(isAlphaNumeric)
+	switch word := l.Current(); {
+	case keywords[word]:
+		l.Emit(ItemKeyword)
+	case word == "true" || word == "false":
+		l.Emit(ItemBool)
+	case word == "nil":
+		l.Emit(ItemNil)
+	default:
+		l.Emit(ItemIdent)
+	}
+	return s.lexInsideAction
+}
+
+// lexNumber scans a number.  Its first rune has been read.
+func (s *scanner) lexNumber(l *lexer.Lexer) lexer.StateFn {
+	digits := "0123456789_"
+	if l.AcceptString("x") || l.AcceptString("X") {
+		digits = "0123456789abcdefABCDEF_"
+	}
+	l.AcceptRun(digits)
+	if l.Accept(".") {
+		l.AcceptRun(digits)
+	}
+	if l.Accept("eEpP") {
+		l.Accept("+-")
+		l.AcceptRun("0123456789_")
+	}
+	l.Accept("i")
+	if l.AcceptFunc(isAlphaNumeric) {
+		return l.Errorf("bad number syntax: %q", l.Current())
+	}
+	l.Emit(ItemNumber)
+	return s.lexInsideAction
+}
+
+// lexQuote scans a double quoted string after its opening quote.
+func (s *scanner) lexQuote(l *lexer.Lexer) lexer.StateFn {
+	if !acceptQuoted(l, '"') {
+		return l.Errorf("unterminated quoted string")
+	}
+	l.Emit(ItemString)
+	return s.lexInsideAction
+}
+
+// lexChar scans a character constant after its opening quote.
+func (s *scanner) lexChar(l *lexer.Lexer) lexer.StateFn {
+	if !acceptQuoted(l, '\'') {
+		return l.Errorf("unterminated character constant")
+	}
+	l.Emit(ItemChar)
+	return s.lexInsideAction
+}
+
+// lexRawQuote scans a raw string after its opening quote.
+func (s *scanner) lexRawQuote(l *lexer.Lexer) lexer.StateFn {
+	rest := l.Input()[l.Pos():]
+	i := strings.IndexByte(rest, '`')
+	if i < 0 {
+		return l.Errorf("unterminated raw quoted string")
+	}
+	l.AcceptString(rest[:i+1])
+	l.Emit(ItemRawString)
+	return s.lexInsideAction
+}
+
+// acceptQuoted advances past the closing quote of a single line quoted
+// literal allowing backslash escapes.
+func acceptQuoted(l *lexer.Lexer, quote rune) bool {
+	for {
+		r, n := l.Advance()
+		switch {
+		case lexer.IsEOF(r, n) || lexer.IsInvalid(r, n) || r == '\n':
+			return false
+		case r == quote:
+			return true
+		case r == '\\':
+			l.AcceptFunc(func(r rune) bool { return r != '\n' })
+		}
+	}
+}
+
+func isDigit(r rune) bool {
+	return '0' <= r && r <= '9'
+}
+
+func isAlphaNumeric(r rune) bool {
+	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
+}
@@ -0,0 +1,86 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package tmpllex
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+var typeNames = map[lexer.ItemType]string{
+	ItemText:        "text",
+	ItemActionStart: "start",
+	ItemActionEnd:   "end",
+	ItemComment:     "comment",
+	ItemKeyword:     "kw",
+	ItemIdent:       "id",
+	ItemField:       "field",
+	ItemVariable:    "var",
+	ItemDot:         "dot",
+	ItemString:      "str",
+	ItemRawString:   "raw",
+	ItemChar:        "char",
+	ItemNumber:      "num",
+	ItemBool:        "bool",
+	ItemNil:         "nil",
+	ItemPipe:        "pipe",
+	ItemAssign:      "assign",
+	ItemDeclare:     "declare",
+	ItemComma:       "comma",
+	ItemLeftParen:   "lparen",
+	ItemRightParen:  "rparen",
+	lexer.ItemError: "error",
+}
+
+func lexAll(lex *lexer.Lexer) []string {
+	var items []string
+	for {
+		item := lex.Next()
+		if item.Type == lexer.ItemEOF {
+			return items
+		}
+		items = append(items, fmt.Sprintf("%s:%s", typeNames[item.Type], item.Value))
+	}
+}
+
+func TestLex(t *testing.T) {
+	for _, test := range []struct {
+		input  string
+		expect []string
+	}{
+		{"", nil},
+		{"plain", []string{"text:plain"}},
+		{"Hi {{.Name}}!", []string{"text:Hi ", "start:{{", "field:.Name", "end:}}", "text:!"}},
+		{"a  {{- if $x := .A | len}} b {{- end -}}  c", []string{
+			"text:a", "start:{{-", "kw:if", "var:$x", "declare::=", "field:.A",
+			"pipe:|", "id:len", "end:}}", "text: b", "start:{{-", "kw:end", "end:-}}", "text:c",
+		}},
+		{"{{printf \"%d\" (add 1 -2.5) 'x' `r` true nil .}}", []string{
+			"start:{{", "id:printf", `str:"%d"`, "lparen:(", "id:add", "num:1",
+			"num:-2.5", "rparen:)", "char:'x'", "raw:`r`", "bool:true", "nil:nil",
+			"dot:.", "end:}}",
+		}},
+		{"{{/* c */}}x", []string{"start:{{", "comment:/* c */", "end:}}", "text:x"}},
+		{"{{.A", []string{"start:{{", "field:.A", "error:unclosed action"}},
+		{"{{(x}}", []string{"start:{{", "lparen:(", "id:x", "error:unclosed left paren"}},
+	} {
+		items := lexAll(New(test.input))
+		if !reflect.DeepEqual(items, test.expect) {
+			t.Errorf("%q:\n\t%q\n(expected)\n\t%q", test.input, items, test.expect)
+		}
+	}
+}
+
+func TestDelims(t *testing.T) {
+	lex := lexer.New(Delims{"<%", "%>"}.Start(), "a {{b}} <% c %>")
+	items := lexAll(lex)
+	expect := []string{"text:a {{b}} ", "start:<%", "id:c", "end:%>"}
+	if !reflect.DeepEqual(items, expect) {
+		t.Errorf("%q (expected %q)", items, expect)
+	}
+}
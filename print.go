@@ -0,0 +1,114 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bufio"
+	"io"
+	"strings"
+)
+
+// Reconstruct returns the source text of items, which must be in the order
+// they were emitted.  The text between items, such as the space a lexer
+// ignores, is copied from original, so the items of a lexer reproduce its
+// input exactly when original is the input, or when original is empty and
+// the lexer emits every byte.  The values of errors and warnings are
+// messages and are not written.
+//
+// An item is taken to span the length of its value from its position, so
+// items whose values differ from their lexemes, such as items emitted by
+// EmitValue, are written with their values but may displace the text
+// following them.
+func Reconstruct(items []Item, original string) string {
+	var b strings.Builder
+	last := 0
+	for i := range items {
+		item := &items[i]
+		if item.Type == ItemError || item.Type == ItemWarning || item.Type == ItemEOF {
+			continue
+		}
+		if last < item.Pos && item.Pos <= len(original) {
+			b.WriteString(original[last:item.Pos])
+		}
+		b.WriteString(item.Value)
+		if end := item.Pos + len(item.Value); end > last {
+			last = end
+		}
+	}
+	if last < len(original) {
+		b.WriteString(original[last:])
+	}
+	return b.String()
+}
+
+// Printer writes items with normalized spacing, as a basis for formatters.
+// By default a single space separates items.
+//
+//	p := &lexer.Printer{
+//		NoSpaceBefore: map[lexer.ItemType]bool{itemComma: true, itemRParen: true},
+//		NoSpaceAfter:  map[lexer.ItemType]bool{itemLParen: true},
+//		NewlineAfter:  map[lexer.ItemType]bool{itemSemicolon: true},
+//	}
+type Printer struct {
+	// Trivia reports whether item is space or other text whose original
+	// form is not written.  Trivia may be nil.
+	Trivia func(item *Item) bool
+
+	// NoSpaceBefore and NoSpaceAfter hold the types of items not separated
+	// by space from the item preceding or following them.
+	NoSpaceBefore map[ItemType]bool
+	NoSpaceAfter  map[ItemType]bool
+
+	// NewlineAfter holds the types of items followed by a newline.
+	NewlineAfter map[ItemType]bool
+
+	// Space, if not nil, returns the text written between prev and next in
+	// place of the separators given by the fields above.
+	Space func(prev, next *Item) string
+}
+
+// Print writes the values of items to w separated as configured.  Errors,
+// warnings and trivia are not written.
+func (p *Printer) Print(w io.Writer, items []Item) error {
+	bw := bufio.NewWriter(w)
+	var prev *Item
+	for i := range items {
+		item := &items[i]
+		switch {
+		case item.Type == ItemError || item.Type == ItemWarning || item.Type == ItemEOF:
+			continue
+		case p.Trivia != nil && p.Trivia(item):
+			continue
+		}
+		if prev != nil {
+			bw.WriteString(p.space(prev, item))
+		}
+		bw.WriteString(item.Value)
+		prev = item
+	}
+	if prev != nil && p.Space == nil && p.NewlineAfter[prev.Type] {
+		bw.WriteByte('\n')
+	}
+	return bw.Flush()
+}
+
+// Sprint returns the values of items separated as Print separates them.
+func (p *Printer) Sprint(items []Item) string {
+	var b strings.Builder
+	p.Print(&b, items)
+	return b.String()
+}
+
+func (p *Printer) space(prev, next *Item) string {
+	switch {
+	case p.Space != nil:
+		return p.Space(prev, next)
+	case p.NewlineAfter[prev.Type]:
+		return "\n"
+	case p.NoSpaceAfter[prev.Type] || p.NoSpaceBefore[next.Type]:
+		return ""
+	}
+	return " "
+}
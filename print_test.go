@@ -0,0 +1,67 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func items(lex Stream) (items []Item) {
+	for {
+		item := lex.Next()
+		if item.Type == ItemEOF {
+			return items
+		}
+		items = append(items, *item)
+	}
+}
+
+func TestReconstruct(t *testing.T) {
+	input := "  ab cd   ef  "
+	its := items(New(lexWords, input))
+	if s := Reconstruct(its, input); s != input {
+		t.Errorf("round trip %q", s)
+	}
+	if s := Reconstruct(its, ""); s != "abcdef" {
+		t.Errorf("without original %q", s)
+	}
+	its = items(New(lexWords, "ab 1 cd", WithErrorPolicy(ErrorResume)))
+	if s := Reconstruct(its, "ab 1 cd"); s != "ab 1 cd" {
+		t.Errorf("with error %q", s)
+	}
+}
+
+func TestPrinter(t *testing.T) {
+	const (
+		word ItemType = iota
+		comma
+		lparen
+		rparen
+		semi
+		space
+	)
+	its := []Item{
+		{Type: word, Value: "f"}, {Type: lparen, Value: "("}, {Type: word, Value: "a"},
+		{Type: comma, Value: ","}, {Type: space, Value: "  "}, {Type: word, Value: "b"},
+		{Type: rparen, Value: ")"}, {Type: semi, Value: ";"}, {Type: word, Value: "g"},
+		{Type: semi, Value: ";"},
+	}
+	p := &Printer{
+		Trivia:        func(item *Item) bool { return item.Type == space },
+		NoSpaceBefore: map[ItemType]bool{comma: true, lparen: true, rparen: true, semi: true},
+		NoSpaceAfter:  map[ItemType]bool{lparen: true},
+		NewlineAfter:  map[ItemType]bool{semi: true},
+	}
+	if s := p.Sprint(its); s != "f(a, b);\ng;\n" {
+		t.Errorf("%q", s)
+	}
+	if s := (&Printer{}).Sprint(its[:3]); s != "f ( a" {
+		t.Errorf("default %q", s)
+	}
+	p = &Printer{Space: func(prev, next *Item) string { return "|" }}
+	if s := p.Sprint(its[:3]); s != "f|(|a" {
+		t.Errorf("space func %q", s)
+	}
+}
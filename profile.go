@@ -0,0 +1,215 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"fmt"
+	"sort"
+	"text/tabwriter"
+)
+
+// TypeProfile describes the items of one type in a Profile.
+type TypeProfile struct {
+	Type  ItemType
+	Count int // number of items
+	Bytes int // total length of their lexemes
+}
+
+// LineProfile counts the errors and warnings emitted on a line.
+type LineProfile struct {
+	Line     int
+	Errors   int
+	Warnings int
+}
+
+// Profile is a report on the items of a stream, for the analysis of a corpus
+// or the tuning of a lexer on real inputs.
+type Profile struct {
+	Items int // number of items, excluding the item of type ItemEOF
+	Bytes int // total length of the lexemes of the items
+	Lines int // greatest line of an item, or zero without line tracking
+
+	// Types describes the items of each type, by decreasing count.
+	Types []TypeProfile
+
+	// Longest holds the longest items, longest first.
+	Longest []Item
+
+	// ErrorLines describes the lines with errors or warnings, by decreasing
+	// number of errors.  Items without line numbers are counted on line 0.
+	ErrorLines []LineProfile
+}
+
+// Profiler is a Sink gathering a Profile of the items written to it.
+//
+//	p := lexer.NewProfiler(10)
+//	err := lex.Drain(p)
+//	fmt.Print(p.Profile())
+//
+// Error lines are only known for lexers created with WithLineTracking.
+type Profiler struct {
+	n       int
+	items   int
+	bytes   int
+	lines   int
+	types   map[ItemType]*TypeProfile
+	errs    map[int]*LineProfile
+	longest []Item
+}
+
+// NewProfiler returns a profiler keeping the n longest items.
+func NewProfiler(n int) *Profiler {
+	return &Profiler{
+		n:     n,
+		types: make(map[ItemType]*TypeProfile),
+		errs:  make(map[int]*LineProfile),
+	}
+}
+
+// Write adds item to the profile.  It never returns an error.
+func (p *Profiler) Write(item Item) error {
+	if item.Type == ItemEOF {
+		return nil
+	}
+	n := lexemeLen(&item)
+	p.items++
+	p.bytes += n
+	if item.Line > p.lines {
+		p.lines = item.Line
+	}
+	t := p.types[item.Type]
+	if t == nil {
+		t = &TypeProfile{Type: item.Type}
+		p.types[item.Type] = t
+	}
+	t.Count++
+	t.Bytes += n
+	if item.Type == ItemError || item.Type == ItemWarning {
+		lp := p.errs[item.Line]
+		if lp == nil {
+			lp = &LineProfile{Line: item.Line}
+			p.errs[item.Line] = lp
+		}
+		if item.Type == ItemError {
+			lp.Errors++
+		} else {
+			lp.Warnings++
+		}
+		return nil
+	}
+	p.keep(item)
+	return nil
+}
+
+// lexemeLen returns the length of the value of item or, for errors and
+// warnings, of the offending text.
+func lexemeLen(item *Item) int {
+	if item.Type == ItemError || item.Type == ItemWarning {
+		if err, ok := item.Payload.(*LexError); ok {
+			return len(err.Lexeme)
+		}
+	}
+	return len(item.Value)
+}
+
+// keep inserts item among the longest items if it is long enough.  Earlier
+// items are kept on ties.
+func (p *Profiler) keep(item Item) {
+	i := sort.Search(len(p.longest), func(i int) bool {
+		return len(p.longest[i].Value) < len(item.Value)
+	})
+	if i >= p.n {
+		return
+	}
+	if len(p.longest) < p.n {
+		p.longest = append(p.longest, Item{})
+	}
+	copy(p.longest[i+1:], p.longest[i:])
+	p.longest[i] = item
+}
+
+// Profile returns the profile of the items written to p so far.
+func (p *Profiler) Profile() *Profile {
+	prof := &Profile{
+		Items:   p.items,
+		Bytes:   p.bytes,
+		Lines:   p.lines,
+		Longest: append([]Item(nil), p.longest...),
+	}
+	for _, t := range p.types {
+		prof.Types = append(prof.Types, *t)
+	}
+	sort.Slice(prof.Types, func(i, j int) bool {
+		a, b := prof.Types[i], prof.Types[j]
+		if a.Count != b.Count {
+			return a.Count > b.Count
+		}
+		return a.Type < b.Type
+	})
+	for _, lp := range p.errs {
+		prof.ErrorLines = append(prof.ErrorLines, *lp)
+	}
+	sort.Slice(prof.ErrorLines, func(i, j int) bool {
+		a, b := prof.ErrorLines[i], prof.ErrorLines[j]
+		if a.Errors != b.Errors {
+			return a.Errors > b.Errors
+		}
+		return a.Line < b.Line
+	})
+	return prof
+}
+
+// ErrorDensity returns the number of errors per line, or zero if the lines
+// are unknown.
+func (prof *Profile) ErrorDensity() float64 {
+	if prof.Lines == 0 {
+		return 0
+	}
+	n := 0
+	for _, lp := range prof.ErrorLines {
+		n += lp.Errors
+	}
+	return float64(n) / float64(prof.Lines)
+}
+
+// String returns the profile as a table for display.
+//
+//	12 items, 80 bytes, 3 lines, 0.33 errors per line
+//
+//	TYPE   COUNT  BYTES  AVG
+//	Ident  7      42     6.0
+//	...
+func (prof *Profile) String() string {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "%d items, %d bytes", prof.Items, prof.Bytes)
+	if prof.Lines > 0 {
+		fmt.Fprintf(&buf, ", %d lines, %.2f errors per line", prof.Lines, prof.ErrorDensity())
+	}
+	fmt.Fprintf(&buf, "\n\n")
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "TYPE\tCOUNT\tBYTES\tAVG\n")
+	for _, t := range prof.Types {
+		fmt.Fprintf(w, "%v\t%d\t%d\t%.1f\n", t.Type, t.Count, t.Bytes, float64(t.Bytes)/float64(t.Count))
+	}
+	w.Flush()
+	if len(prof.Longest) > 0 {
+		fmt.Fprintf(&buf, "\nLONGEST\n")
+		for i := range prof.Longest {
+			item := &prof.Longest[i]
+			fmt.Fprintf(w, "%+v\t%d bytes\n", item, len(item.Value))
+		}
+		w.Flush()
+	}
+	if len(prof.ErrorLines) > 0 {
+		fmt.Fprintf(&buf, "\n")
+		fmt.Fprintf(w, "LINE\tERRORS\tWARNINGS\n")
+		for _, lp := range prof.ErrorLines {
+			fmt.Fprintf(w, "%d\t%d\t%d\n", lp.Line, lp.Errors, lp.Warnings)
+		}
+		w.Flush()
+	}
+	return buf.String()
+}
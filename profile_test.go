@@ -0,0 +1,43 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestProfiler(t *testing.T) {
+	p := NewProfiler(2)
+	lex := New(lexWords, "ab cdef xyz\nx1!", WithLineTracking(), WithErrorPolicy(ErrorResume))
+	if err := lex.Drain(p); err != nil {
+		t.Fatal(err)
+	}
+	prof := p.Profile()
+	if prof.Items != 7 || prof.Bytes != 13 || prof.Lines != 2 {
+		t.Errorf("items %d bytes %d lines %d", prof.Items, prof.Bytes, prof.Lines)
+	}
+	types := []TypeProfile{{1, 4, 10}, {ItemError, 3, 3}}
+	if !reflect.DeepEqual(prof.Types, types) {
+		t.Errorf("types %v", prof.Types)
+	}
+	if len(prof.Longest) != 2 || prof.Longest[0].Value != "cdef" || prof.Longest[1].Value != "xyz" {
+		t.Errorf("longest %v", prof.Longest)
+	}
+	lines := []LineProfile{{2, 2, 0}, {1, 1, 0}}
+	if !reflect.DeepEqual(prof.ErrorLines, lines) {
+		t.Errorf("error lines %v", prof.ErrorLines)
+	}
+	if d := prof.ErrorDensity(); d != 1.5 {
+		t.Errorf("density %v", d)
+	}
+	s := prof.String()
+	for _, want := range []string{"7 items, 13 bytes, 2 lines, 1.50 errors per line", "Error", `"cdef"`, "LINE"} {
+		if !strings.Contains(s, want) {
+			t.Errorf("report missing %q:\n%s", want, s)
+		}
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"sync"
+)
+
+// Program is an immutable lexer definition from which many lexers are
+// created, such as one lexer for each request handled by a server.  The
+// expensive parts of a definition, such as the automata compiled by package
+// rules, keyword tables or range tables built with MergeRanges, are built
+// once before the program is created and shared by its lexers.
+//
+//	var prog = lexer.NewProgram(func() lexer.StateFn { return start }, lexer.WithLineTracking())
+//
+//	func handle(input string) {
+//		lex := prog.New(input)
+//		defer prog.Release(lex)
+//		...
+//	}
+//
+// Lexers returned by New are recycled once given to Release, so that
+// creating a lexer does not allocate after a program has warmed up.
+type Program struct {
+	start func() StateFn
+	opts  []Option
+	pool  sync.Pool
+}
+
+// NewProgram returns a program whose lexers begin in the state returned by
+// start and are configured by opts.  Start is called once for each lexer,
+// as Language.Start is, so that lexers may keep their state in the closure
+// it returns.
+func NewProgram(start func() StateFn, opts ...Option) *Program {
+	return &Program{start: start, opts: opts}
+}
+
+// New returns a lexer over input configured by the options of p followed by
+// opts.
+func (p *Program) New(input string, opts ...Option) *Lexer {
+	if len(opts) > 0 {
+		opts = append(append([]Option(nil), p.opts...), opts...)
+	} else {
+		opts = p.opts
+	}
+	l, _ := p.pool.Get().(*Lexer)
+	if l == nil {
+		return New(p.start(), input, opts...)
+	}
+	l.reset(p.start(), input, opts)
+	return l
+}
+
+// Release returns l, which must have been returned by p.New, to p for reuse.
+// Neither l nor the items it returned may be used after Release.
+func (p *Program) Release(l *Lexer) {
+	p.pool.Put(l)
+}
+
+// reset prepares l to lex input from start as New does, reusing the memory
+// of l.
+func (l *Lexer) reset(start StateFn, input string, opts []Option) {
+	if start == nil {
+		panic("nil start state")
+	}
+	items, lines := l.items, l.lines[:1]
+	items.Init()
+	lines[0] = 0
+	*l = Lexer{
+		state: start,
+		begin: start,
+		input: input,
+		items: items,
+		tabs:  1,
+		lines: lines,
+		eof:   EOF,
+		opts:  opts,
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+	l.transformInput()
+	l.checkInput()
+}
@@ -0,0 +1,76 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestProgram(t *testing.T) {
+	prog := NewProgram(func() StateFn { return lexWords }, WithLineTracking())
+	lex := prog.New("ab cd 1")
+	for lex.Next().Type != ItemEOF {
+	}
+	prog.Release(lex)
+	for i := 0; i < 3; i++ {
+		lex := prog.New("gh  ij", WithName("input"))
+		var got []string
+		for item := lex.Next(); item.Type != ItemEOF; item = lex.Next() {
+			got = append(got, fmt.Sprintf("%s@%d:%d", item.Value, item.Line, item.Column))
+		}
+		want := []string{"gh@1:1", "ij@1:5"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("items %q, want %q", got, want)
+		}
+		prog.Release(lex)
+	}
+}
+
+func TestProgramAllocs(t *testing.T) {
+	if raceEnabled {
+		t.Skip("sync.Pool drops items under the race detector")
+	}
+	prog := NewProgram(func() StateFn { return lexWords })
+	prog.Release(prog.New(""))
+	allocs := testing.AllocsPerRun(100, func() {
+		prog.Release(prog.New("ab cd"))
+	})
+	if allocs > 0 {
+		t.Errorf("New allocated %v times", allocs)
+	}
+}
+
+// TestProgramConcurrent lexes with one program from many goroutines.  Run it
+// with the race detector to check that lexers share no mutable state.
+func TestProgramConcurrent(t *testing.T) {
+	prog := NewProgram(func() StateFn { return lexWords }, WithLineTracking())
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				word := strings.Repeat("abcdefgh"[g:g+1], i%5+1)
+				lex := prog.New(word + " " + word)
+				got := collect(lex)
+				prog.Release(lex)
+				if !reflect.DeepEqual(got, []string{word, word}) {
+					errs <- fmt.Errorf("goroutine %d: items %q", g, got)
+					return
+				}
+			}
+		}(g)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+}
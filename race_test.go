@@ -0,0 +1,11 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+//go:build race
+
+package lexer
+
+// raceEnabled is true when tests run with the race detector, under which
+// sync.Pool drops items at random.
+const raceEnabled = true
@@ -0,0 +1,83 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"sort"
+	"unicode"
+)
+
+// MergeRanges returns a table containing the union of the runes in tabs.  The
+// ranges of the result are sorted and coalesced so that testing membership
+// in the union costs a single binary search instead of one per table.
+//
+//	identRune := lexer.MergeRanges(unicode.Letter, unicode.Digit, lexer.RunesTable("_"))
+func MergeRanges(tabs ...*unicode.RangeTable) *unicode.RangeTable {
+	var spans []span
+	for _, tab := range tabs {
+		for _, r := range tab.R16 {
+			spans = appendRange(spans, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+		}
+		for _, r := range tab.R32 {
+			spans = appendRange(spans, rune(r.Lo), rune(r.Hi), rune(r.Stride))
+		}
+	}
+	return newRangeTable(spans)
+}
+
+// RunesTable returns a table containing the runes in s.
+func RunesTable(s string) *unicode.RangeTable {
+	var spans []span
+	for _, r := range s {
+		spans = append(spans, span{r, r})
+	}
+	return newRangeTable(spans)
+}
+
+// span is an inclusive range of runes.
+type span struct {
+	lo, hi rune
+}
+
+func appendRange(spans []span, lo, hi, stride rune) []span {
+	if stride <= 1 {
+		return append(spans, span{lo, hi})
+	}
+	for r := lo; r <= hi; r += stride {
+		spans = append(spans, span{r, r})
+	}
+	return spans
+}
+
+// newRangeTable sorts and merges spans into a table.
+func newRangeTable(spans []span) *unicode.RangeTable {
+	sort.Slice(spans, func(i, j int) bool { return spans[i].lo < spans[j].lo })
+	var merged []span
+	for _, s := range spans {
+		if n := len(merged); n > 0 && s.lo <= merged[n-1].hi+1 {
+			if s.hi > merged[n-1].hi {
+				merged[n-1].hi = s.hi
+			}
+			continue
+		}
+		merged = append(merged, s)
+	}
+	tab := new(unicode.RangeTable)
+	for _, s := range merged {
+		if s.lo <= 0xFFFF && s.hi > 0xFFFF {
+			tab.R16 = append(tab.R16, unicode.Range16{Lo: uint16(s.lo), Hi: 0xFFFF, Stride: 1})
+			s.lo = 0x10000
+		}
+		if s.hi <= 0xFFFF {
+			tab.R16 = append(tab.R16, unicode.Range16{Lo: uint16(s.lo), Hi: uint16(s.hi), Stride: 1})
+			if s.hi <= unicode.MaxLatin1 {
+				tab.LatinOffset++
+			}
+		} else {
+			tab.R32 = append(tab.R32, unicode.Range32{Lo: uint32(s.lo), Hi: uint32(s.hi), Stride: 1})
+		}
+	}
+	return tab
+}
@@ -0,0 +1,42 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+	"unicode"
+)
+
+func TestMergeRanges(t *testing.T) {
+	tabs := []*unicode.RangeTable{unicode.Letter, unicode.Digit, unicode.Lu, RunesTable("_$")}
+	merged := MergeRanges(tabs...)
+	for r := rune(0); r <= unicode.MaxRune; r++ {
+		if unicode.Is(merged, r) != unicode.In(r, tabs...) {
+			t.Fatalf("membership of %U differs", r)
+		}
+	}
+	for i := 1; i < len(merged.R16); i++ {
+		if merged.R16[i].Lo <= merged.R16[i-1].Hi+1 {
+			t.Errorf("ranges %v and %v not merged", merged.R16[i-1], merged.R16[i])
+		}
+	}
+}
+
+func TestAcceptRanges(t *testing.T) {
+	lex := New(nilState, "ab_12-")
+	if n := lex.AcceptRunRanges(unicode.Letter, unicode.Digit, RunesTable("_")); n != 5 {
+		t.Errorf("accepted %d runes", n)
+	}
+	if lex.AcceptRanges(unicode.Letter, unicode.Digit) {
+		t.Errorf("accepted %q", lex.Current())
+	}
+	if lex.Pos() != 5 {
+		t.Errorf("position %d", lex.Pos())
+	}
+	lex = New(nilState, "")
+	if lex.AcceptRanges(unicode.Cc) {
+		t.Errorf("accepted EOF")
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"strings"
+)
+
+// RawFence describes the delimiters of a raw string literal, whose contents
+// are not decoded.
+type RawFence struct {
+	// Open returns the length of the opening fence at the beginning of s and
+	// the closing fence it requires, or zero if s does not begin with an
+	// opening fence.  Fences, such as the delimiters of C++ raw strings, may
+	// choose their closing fence.
+	Open func(s string) (n int, close string)
+
+	// Multiline allows newlines in the literal.
+	Multiline bool
+
+	// Escaped makes a backslash keep the rune following it from ending the
+	// literal, as in the raw strings of Python.  The backslash remains in
+	// the contents.
+	Escaped bool
+
+	// Doubled makes the closing fence written twice stand for itself
+	// rather than end the literal, as in the verbatim strings of C#.
+	Doubled bool
+}
+
+// Raw string fences for common languages.  Prefixes of the fences, such as
+// the u8 of u8R"(...)" in C++ or the b of br"..." in Rust, must be accepted
+// before calling AcceptRaw.
+var (
+	GoRaw     = RawFence{Open: FixedFence("`", "`"), Multiline: true}
+	PythonRaw = RawFence{Open: pythonRawOpen, Escaped: true}
+	CppRaw    = RawFence{Open: cppRawOpen, Multiline: true}
+	RustRaw   = RawFence{Open: rustRawOpen, Multiline: true}
+	CSharpRaw = RawFence{Open: FixedFence(`@"`, `"`), Multiline: true, Doubled: true}
+)
+
+// FixedFence returns the Open function of a RawFence with the given opening
+// and closing fences.
+//
+//	markdown := lexer.RawFence{Open: lexer.FixedFence("```", "```"), Multiline: true}
+func FixedFence(open, close string) func(s string) (int, string) {
+	return func(s string) (int, string) {
+		if !strings.HasPrefix(s, open) {
+			return 0, ""
+		}
+		return len(open), close
+	}
+}
+
+// pythonRawOpen opens r"...", r'...', R"..." and R'...'.
+func pythonRawOpen(s string) (int, string) {
+	if len(s) < 2 || (s[0] != 'r' && s[0] != 'R') || (s[1] != '"' && s[1] != '\'') {
+		return 0, ""
+	}
+	return 2, s[1:2]
+}
+
+// cppRawOpen opens R"delim(...)delim", where delim is at most 16 characters
+// other than spaces, parentheses, backslashes and control characters.
+func cppRawOpen(s string) (int, string) {
+	if !strings.HasPrefix(s, `R"`) {
+		return 0, ""
+	}
+	for i := 2; i < len(s) && i <= 2+16; i++ {
+		switch c := s[i]; {
+		case c == '(':
+			return i + 1, ")" + s[2:i] + `"`
+		case c == ')' || c == '\\' || c <= ' ' || c >= 0x7f:
+			return 0, ""
+		}
+	}
+	return 0, ""
+}
+
+// rustRawOpen opens r"...", r#"..."#, r##"..."## and so on.
+func rustRawOpen(s string) (int, string) {
+	if !strings.HasPrefix(s, "r") {
+		return 0, ""
+	}
+	i := 1
+	for i < len(s) && s[i] == '#' {
+		i++
+	}
+	if i == len(s) || s[i] != '"' {
+		return 0, ""
+	}
+	return i + 1, `"` + s[1:i]
+}
+
+// AcceptRaw advances the lexer over a raw string literal delimited as
+// described by fence and returns its contents, the text between the fences.
+// If the input does not begin with a valid literal the lexer does not
+// advance and an error is returned.
+//
+//	if body, err := l.AcceptRaw(lexer.CppRaw); err == nil {
+//		l.EmitValue(itemString, body)
+//	}
+func (l *Lexer) AcceptRaw(fence RawFence) (string, error) {
+	c := l.Checkpoint()
+	n, end := fence.Open(l.input[l.pos:])
+	if n == 0 {
+		return "", fmt.Errorf("expected raw string")
+	}
+	l.acceptString(l.input[l.pos : l.pos+n])
+	start := l.pos
+	for {
+		s := l.input[l.pos:]
+		if strings.HasPrefix(s, end) {
+			if fence.Doubled && strings.HasPrefix(s[len(end):], end) {
+				l.acceptString(s[:2*len(end)])
+				continue
+			}
+			body := l.input[start:l.pos]
+			l.acceptString(end)
+			if fence.Doubled {
+				body = strings.Replace(body, end+end, end, -1)
+			}
+			return body, nil
+		}
+		if fence.Escaped && strings.HasPrefix(s, `\`) {
+			l.Advance()
+		}
+		r, n := l.Advance()
+		switch {
+		case IsEOF(r, n):
+			l.Restore(c)
+			return "", fmt.Errorf("unterminated raw string")
+		case r == '\n' && !fence.Multiline:
+			l.Restore(c)
+			return "", fmt.Errorf("newline in raw string")
+		case IsInvalid(r, n):
+			l.Restore(c)
+			return "", fmt.Errorf("invalid utf-8 rune in raw string")
+		}
+	}
+}
@@ -0,0 +1,56 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func TestAcceptRaw(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		fence RawFence
+		body  string
+		pos   int
+		err   string
+	}{
+		{"`a\\n\nb` x", GoRaw, "a\\n\nb", 7, ""},
+		{"`ab", GoRaw, "", 0, "unterminated raw string"},
+		{`"ab"`, GoRaw, "", 0, "expected raw string"},
+		{`r"a\"b" x`, PythonRaw, `a\"b`, 7, ""},
+		{`R'a\\'`, PythonRaw, `a\\`, 6, ""},
+		{"r'a\nb'", PythonRaw, "", 0, "newline in raw string"},
+		{`R"x(a)"b)x" y`, CppRaw, `a)"b`, 11, ""},
+		{`R"(a)"`, CppRaw, "a", 6, ""},
+		{`R"x y(a)x y"`, CppRaw, "", 0, "expected raw string"},
+		{`R"0123456789abcdefg(a)0123456789abcdefg"`, CppRaw, "", 0, "expected raw string"},
+		{`R"x(a)"`, CppRaw, "", 0, "unterminated raw string"},
+		{`r##"a"#b"##`, RustRaw, `a"#b`, 11, ""},
+		{`r"a"`, RustRaw, "a", 4, ""},
+		{`r#a"#`, RustRaw, "", 0, "expected raw string"},
+		{`@"a""b"""`, CSharpRaw, `a"b"`, 9, ""},
+		{"`a\xffb`", GoRaw, "", 0, "invalid utf-8 rune in raw string"},
+	} {
+		lex := New(nilState, test.input)
+		body, err := lex.AcceptRaw(test.fence)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err || lex.Pos() != 0 {
+				t.Errorf("%s: error %v at %d (expected %q)", test.input, err, lex.Pos(), test.err)
+			}
+			continue
+		}
+		if err != nil || body != test.body || lex.Pos() != test.pos {
+			t.Errorf("%s: %q %v at %d (expected %q at %d)", test.input, body, err, lex.Pos(), test.body, test.pos)
+		}
+	}
+}
+
+func TestFixedFence(t *testing.T) {
+	fence := RawFence{Open: FixedFence("```", "```"), Multiline: true}
+	lex := New(nilState, "```\na`b\n```")
+	if body, err := lex.AcceptRaw(fence); err != nil || body != "\na`b\n" || lex.Pos() != 11 {
+		t.Errorf("%q %v at %d", body, err, lex.Pos())
+	}
+}
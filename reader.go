@@ -0,0 +1,130 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bufio"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"sync"
+)
+
+// A Decompressor returns a reader of the data decompressed from r.
+type Decompressor func(r io.Reader) (io.Reader, error)
+
+type decompressor struct {
+	name  string
+	magic string
+	fn    Decompressor
+}
+
+var decompressors struct {
+	sync.RWMutex
+	list []decompressor
+}
+
+// Magic numbers of compression formats without a decompressor in the
+// standard library.  Input beginning with one of these cannot be read unless
+// a decompressor is registered for it.
+var knownFormats = []decompressor{
+	{name: "zstd", magic: "\x28\xb5\x2f\xfd"},
+	{name: "xz", magic: "\xfd7zXZ\x00"},
+}
+
+func init() {
+	RegisterDecompressor("gzip", "\x1f\x8b", func(r io.Reader) (io.Reader, error) {
+		return gzip.NewReader(r)
+	})
+}
+
+// RegisterDecompressor makes NewReader decompress input beginning with the
+// bytes magic using fn.  Only the gzip format is registered by default, as
+// the standard library has no decompressor for other formats.  Formats such
+// as zstd and xz are supported by registering a decompressor from a third
+// party package.
+//
+//	lexer.RegisterDecompressor("zstd", "\x28\xb5\x2f\xfd", func(r io.Reader) (io.Reader, error) {
+//		return zstd.NewReader(r)
+//	})
+func RegisterDecompressor(name, magic string, fn Decompressor) {
+	decompressors.Lock()
+	defer decompressors.Unlock()
+	for i, d := range decompressors.list {
+		if d.name == name {
+			decompressors.list[i] = decompressor{name, magic, fn}
+			return
+		}
+	}
+	decompressors.list = append(decompressors.list, decompressor{name, magic, fn})
+}
+
+// WithoutDecompression makes NewReader lex its input as is, even if it begins
+// with the magic number of a compression format.
+func WithoutDecompression() Option {
+	return func(l *Lexer) { l.raw = true }
+}
+
+// NewReader creates a lexer over the contents of r, as New does for a string.
+// Input compressed in a format registered with RegisterDecompressor is
+// detected by its magic number and decompressed transparently, unless the
+// WithoutDecompression option is given.  Only gzip is registered by default:
+// input beginning with the magic number of zstd or xz makes NewReader return
+// an error unless a decompressor is registered for the format.
+//
+// The input is buffered, not streamed.  NewReader reads all of r, and
+// decompresses it, into memory before it returns, and the lexer operates on
+// the entire input.  Input arriving over time can be lexed as it is read by
+// giving it to Append instead.  If a MaxInput limit is given to WithLimits,
+// reading stops after the limit is exceeded and the lexer emits the
+// corresponding error.
+func NewReader(start StateFn, r io.Reader, opts ...Option) (*Lexer, error) {
+	l := New(start, "", opts...)
+	br := bufio.NewReader(r)
+	if !l.raw {
+		dr, err := decompress(br)
+		if err != nil {
+			return nil, err
+		}
+		r = dr
+	} else {
+		r = br
+	}
+	if max := l.limits.MaxInput; max > 0 {
+		r = io.LimitReader(r, int64(max)+1)
+	}
+	var buf bytes.Buffer
+	if _, err := buf.ReadFrom(r); err != nil {
+		return nil, err
+	}
+	l.input = buf.String()
+	if l.rec != nil {
+		l.rec.Input = l.input
+	}
+	l.transformInput()
+	l.checkInput()
+	return l, nil
+}
+
+// decompress returns a reader of the decompressed contents of br.
+func decompress(br *bufio.Reader) (io.Reader, error) {
+	decompressors.RLock()
+	list := make([]decompressor, 0, len(decompressors.list)+len(knownFormats))
+	list = append(list, decompressors.list...)
+	list = append(list, knownFormats...)
+	decompressors.RUnlock()
+	for _, d := range list {
+		magic, _ := br.Peek(len(d.magic))
+		if string(magic) != d.magic {
+			continue
+		}
+		if d.fn == nil {
+			return nil, fmt.Errorf("lexer: no decompressor registered for %s input", d.name)
+		}
+		return d.fn(br)
+	}
+	return br, nil
+}
@@ -0,0 +1,48 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"compress/gzip"
+	"strings"
+	"testing"
+)
+
+func TestNewReader(t *testing.T) {
+	var gz bytes.Buffer
+	w := gzip.NewWriter(&gz)
+	w.Write([]byte("ab cd"))
+	w.Close()
+	for _, test := range []struct {
+		input string
+		opts  []Option
+		items string
+		err   string
+	}{
+		{"ab cd", nil, "ab|cd", ""},
+		{gz.String(), nil, "ab|cd", ""},
+		{gz.String()[:2] + "ab", []Option{WithoutDecompression()}, `unexpected "\x1f"`, ""},
+		{"ab cd ef", []Option{WithLimits(Limits{MaxInput: 4})}, "input exceeds the limit of 4 bytes", ""},
+		{"\x28\xb5\x2f\xfd....", nil, "", "lexer: no decompressor registered for zstd input"},
+		{"\xfd7zXZ\x00....", nil, "", "lexer: no decompressor registered for xz input"},
+		{gz.String()[:12], nil, "", "unexpected EOF"},
+	} {
+		lex, err := NewReader(lexWords, strings.NewReader(test.input), test.opts...)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%q: error %v (expected %q)", test.input, err, test.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+			continue
+		}
+		if s := strings.Join(collect(lex), "|"); s != test.items {
+			t.Errorf("%q: items %s", test.input, s)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// A Recording is the sequence of scanner API calls made on a lexer along with
+// its input.  A recording can be saved to a file and replayed without the
+// state functions that made it, producing the same items.  Recordings make
+// it possible to attach a reproducible trace to a bug report.
+//
+// Item payloads (see EmitInt) are not recorded.
+type Recording struct {
+	Input string
+	Calls []Call
+}
+
+// Call is a single recorded call.  Op names the method called.  The meaning
+// of the remaining fields depends on Op.
+type Call struct {
+	Op   string
+	Type ItemType `json:",omitempty"`
+	Arg  string   `json:",omitempty"`
+	Args []int    `json:",omitempty"`
+}
+
+// WithRecording records the input of the lexer and the calls made on it in r.
+func WithRecording(r *Recording) Option {
+	return func(l *Lexer) {
+		if l.inRecord {
+			return
+		}
+		r.Input = l.input
+		r.Calls = nil
+		l.rec = r
+	}
+}
+
+// record appends c to l's recording.  Calls made after the lexer halts have
+// no effect and are not recorded.
+func (l *Lexer) record(c Call) {
+	if l.rec != nil && !l.halted {
+		l.rec.Calls = append(l.rec.Calls, c)
+	}
+}
+
+// Save writes r to w as JSON.
+func (r *Recording) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// LoadRecording reads a recording written by Save.
+func LoadRecording(rd io.Reader) (*Recording, error) {
+	r := new(Recording)
+	if err := json.NewDecoder(rd).Decode(r); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Replay executes the calls in r on a new lexer over r's input.  The
+// returned lexer's Next method returns the items produced by the recorded
+// calls.
+func (r *Recording) Replay() (*Lexer, error) {
+	l := New(func(*Lexer) StateFn { return nil }, r.Input)
+	for i, c := range r.Calls {
+		switch c.Op {
+		case "state":
+		case "advance":
+			l.Advance()
+		case "advancebyte":
+			l.AdvanceByte()
+		case "backup":
+			l.Backup()
+		case "ignore":
+			l.Ignore()
+		case "acceptstring":
+			if !l.acceptString(c.Arg) {
+				return nil, fmt.Errorf("call %d: %s %q does not match the input", i, c.Op, c.Arg)
+			}
+		case "advancewhile":
+			if len(c.Args) != 2 || c.Args[0] < 1 || c.Args[0] > len(c.Arg) || !l.acceptString(c.Arg) {
+				return nil, fmt.Errorf("call %d: invalid %s arguments %q %v", i, c.Op, c.Arg, c.Args)
+			}
+			l.width, l.last, l.backed = c.Args[0], rune(c.Args[1]), false
+		case "emit":
+			l.Emit(c.Type)
+		case "emitvalue":
+			l.EmitValue(c.Type, c.Arg)
+		case "errorf":
+			l.Errorf("%s", c.Arg)
+		case "errorat":
+			if len(c.Args) != 2 || c.Args[0] < 0 || c.Args[0] > c.Args[1] || c.Args[1] > len(l.input) {
+				return nil, fmt.Errorf("call %d: invalid %s arguments %v", i, c.Op, c.Args)
+			}
+			l.ErrorAt(l.lexError(c.Arg, c.Args[0], c.Args[1]))
+		case "warnf":
+			l.Warnf("%s", c.Arg)
+		case "restore":
+			if len(c.Args) != 7 || c.Args[4] > l.items.Len() || c.Args[6] > l.errors {
+				return nil, fmt.Errorf("call %d: invalid %s arguments %v", i, c.Op, c.Args)
+			}
+			l.Restore(Checkpoint{c.Args[0], c.Args[1], c.Args[2], rune(c.Args[3]), l.items.Len() - c.Args[4], c.Args[5], l.errors - c.Args[6]})
+		case "append":
+			l.Append(c.Arg)
+		case "continue":
+			l.halted = false
+		case "syncto":
+			l.SyncTo(c.Arg)
+		case "resume":
+			l.sync = c.Arg
+			l.resume()
+		case "halt":
+			l.halt("%s", c.Arg)
+		default:
+			return nil, fmt.Errorf("call %d: unknown operation %q", i, c.Op)
+		}
+		if l.start < 0 || l.start > l.pos || l.pos > len(l.input) {
+			return nil, fmt.Errorf("call %d: %s moved the lexer outside its input", i, c.Op)
+		}
+	}
+	l.state = nil
+	return l, nil
+}
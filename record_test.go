@@ -0,0 +1,110 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"unicode"
+)
+
+func allItems(lex *Lexer) (items []Item) {
+	for {
+		item := lex.Next()
+		items = append(items, *item)
+		if item.Type == ItemEOF {
+			return items
+		}
+	}
+}
+
+func TestRecordingReplay(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		opts  []Option
+	}{
+		{"ab cd", nil},
+		{"ab 1cd é x", []Option{WithErrorPolicy(ErrorResume)}},
+		{"ab cd ef", []Option{WithLimits(Limits{MaxItems: 2})}},
+		{"ab cd", []Option{WithLimits(Limits{MaxInput: 2})}},
+	} {
+		rec := new(Recording)
+		want := allItems(New(lexWords, test.input, append(test.opts, WithRecording(rec))...))
+		var buf bytes.Buffer
+		if err := rec.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		loaded, err := LoadRecording(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		lex, err := loaded.Replay()
+		if err != nil {
+			t.Errorf("%q: %v", test.input, err)
+			continue
+		}
+		if got := allItems(lex); !reflect.DeepEqual(got, want) {
+			t.Errorf("%q: replay %v (expected %v)", test.input, got, want)
+		}
+	}
+}
+
+func TestReplayInvalid(t *testing.T) {
+	for _, rec := range []Recording{
+		{"ab", []Call{{Op: "jump"}}},
+		{"ab", []Call{{Op: "acceptstring", Arg: "x"}}},
+		{"ab", []Call{{Op: "restore", Args: []int{0, 9, 0, 0, 0}}}},
+		{"ab", []Call{{Op: "advancewhile", Arg: "ab"}}},
+		{"ab", []Call{{Op: "advancewhile", Arg: "ab", Args: []int{3, 'b'}}}},
+	} {
+		if _, err := rec.Replay(); err == nil {
+			t.Errorf("%v: no error", rec.Calls)
+		}
+	}
+}
+
+func TestReplayAdvanceWhile(t *testing.T) {
+	rec := new(Recording)
+	lex := New(func(l *Lexer) StateFn {
+		l.AdvanceWhile(unicode.IsLetter)
+		l.Backup()
+		l.Emit(1)
+		l.Advance()
+		l.Emit(2)
+		return nil
+	}, "abé1", WithRecording(rec))
+	want := allItems(lex)
+	if want[0].Value != "ab" || want[1].Value != "é" {
+		t.Errorf("unexpected items %v", want)
+	}
+	lex, err := rec.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := allItems(lex); !reflect.DeepEqual(got, want) {
+		t.Errorf("replay %v (expected %v)", got, want)
+	}
+}
+
+func TestEmitValue(t *testing.T) {
+	rec := new(Recording)
+	lex := New(func(l *Lexer) StateFn {
+		l.AcceptString("0x1f")
+		l.EmitValue(1, "31")
+		return nil
+	}, "0x1f", WithRecording(rec))
+	want := allItems(lex)
+	if want[0].Value != "31" || want[0].Pos != 0 {
+		t.Errorf("unexpected item %+v", want[0])
+	}
+	lex, err := rec.Replay()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := allItems(lex); !reflect.DeepEqual(got, want) {
+		t.Errorf("replay %v (expected %v)", got, want)
+	}
+}
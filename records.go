@@ -0,0 +1,63 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+)
+
+// WithRecordDelim sets the delimiter separating the records returned by
+// NextRecord.  The default delimiter is "\n".
+func WithRecordDelim(delim string) Option {
+	return func(l *Lexer) { l.delim = delim }
+}
+
+// NextRecord returns a new lexer over the next record of l's remaining input
+// and advances l past the record and its delimiter.  Records are separated by
+// "\n" or the delimiter given to WithRecordDelim, and a delimiter ending the
+// input does not begin an empty record.  NextRecord returns nil when no
+// input remains.
+//
+// A record lexer begins in the start state of l and is created with the
+// options given to l, except for those which apply to l's input as a whole:
+// the record is not transformed again by WithInputTransform, as it is
+// taken from l's transformed input, and it is not recorded by
+// WithRecording.  Item positions are relative to the record; the offset of
+// the record in l's source is given by the record lexer's Base method.
+// Record lexers are independent of l and of each other, so records can be
+// lexed in parallel.  Options which share state between lexers, such as
+// WithDiagnostics, must then be safe for concurrent use.
+func (l *Lexer) NextRecord() *Lexer {
+	if l.pos >= len(l.input) {
+		return nil
+	}
+	delim := l.delim
+	if delim == "" {
+		delim = "\n"
+	}
+	start := l.pos
+	rest := l.input[start:]
+	n, skip := len(rest), 0
+	if i := strings.Index(rest, delim); i >= 0 {
+		n, skip = i, len(delim)
+	}
+	l.pos = start + n + skip
+	l.start = l.pos
+	l.width = 0
+	opts := append([]Option{asRecord}, l.opts...)
+	rec := New(l.begin, rest[:n], opts...)
+	rec.base = l.base + l.offset(start)
+	return rec
+}
+
+// asRecord marks a lexer created by NextRecord, so that options which must
+// not be applied twice to the same input skip it.
+func asRecord(l *Lexer) { l.inRecord = true }
+
+// Base returns the offset of l's input within the source of the lexer whose
+// NextRecord method created l.  Base returns zero for other lexers.
+func (l *Lexer) Base() int {
+	return l.base
+}
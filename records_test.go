@@ -0,0 +1,49 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestNextRecord(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		opts  []Option
+		out   string
+	}{
+		{"ab cd\n\nef\n", nil, "0[ab cd]|6[]|7[ef]"},
+		{"ab cd\nef", nil, "0[ab cd]|6[ef]"},
+		{"ab;;cd;;", []Option{WithRecordDelim(";;")}, "0[ab]|4[cd]"},
+		{"", nil, ""},
+	} {
+		lex := New(lexWords, test.input, test.opts...)
+		var records []string
+		for rec := lex.NextRecord(); rec != nil; rec = lex.NextRecord() {
+			records = append(records, fmt.Sprintf("%d[%s]", rec.Base(), strings.Join(collect(rec), " ")))
+		}
+		if s := strings.Join(records, "|"); s != test.out {
+			t.Errorf("%q: records %s", test.input, s)
+		}
+	}
+}
+
+func TestNextRecordTransform(t *testing.T) {
+	var r Recording
+	lex := New(lexWords, "&amp;amp;\nab cd",
+		WithInputTransform(ReplaceInput("&amp;", "&")), WithRecording(&r))
+	var records []string
+	for rec := lex.NextRecord(); rec != nil; rec = lex.NextRecord() {
+		records = append(records, fmt.Sprintf("%d[%s]", rec.Base(), rec.Input()))
+	}
+	if s := strings.Join(records, "|"); s != "0[&amp;]|10[ab cd]" {
+		t.Errorf("records %s", s)
+	}
+	if r.Input != "&amp;\nab cd" {
+		t.Errorf("recorded input %q", r.Input)
+	}
+}
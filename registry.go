@@ -0,0 +1,121 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// Language is a lexer definition registered under a name, so that programs
+// handling many languages can choose a lexer at run time.
+type Language struct {
+	Name    string   // canonical name, such as "json"
+	Aliases []string // other names, such as "sh" for "bash"
+
+	// Start returns the start state of a new lexer for the language.  Start
+	// is called once for each lexer, so lexers may keep their state in the
+	// closure it returns.
+	Start func() StateFn
+
+	// Options are given to each lexer before those passed to New.
+	Options []Option
+
+	// Filenames lists patterns, in the syntax of path.Match, matching the
+	// base names of files in the language, such as "*.json".
+	Filenames []string
+
+	// Interpreters lists the programs named by the #! line of scripts in
+	// the language, such as "bash".
+	Interpreters []string
+
+	// Analyze returns the confidence, between 0 and 1, that text is in the
+	// language.  Analyze may be nil.
+	Analyze func(text string) float64
+}
+
+// New returns a lexer for the language over input.
+func (lang *Language) New(input string, opts ...Option) *Lexer {
+	return New(lang.Start(), input, lang.options(opts)...)
+}
+
+// NewReader returns a lexer for the language over the contents of r, as
+// the function NewReader does.
+func (lang *Language) NewReader(r io.Reader, opts ...Option) (*Lexer, error) {
+	return NewReader(lang.Start(), r, lang.options(opts)...)
+}
+
+// Program returns a program creating lexers for the language configured by
+// the options of lang followed by opts.
+func (lang *Language) Program(opts ...Option) *Program {
+	return NewProgram(lang.Start, lang.options(opts)...)
+}
+
+func (lang *Language) options(opts []Option) []Option {
+	return append(append([]Option(nil), lang.Options...), opts...)
+}
+
+// names returns the name and aliases of lang.
+func (lang *Language) names() []string {
+	return append([]string{lang.Name}, lang.Aliases...)
+}
+
+var languages struct {
+	sync.RWMutex
+	list  []*Language
+	names map[string]*Language
+}
+
+// RegisterLanguage makes lang available to Lookup under its name and
+// aliases, which are not case sensitive.  The lexers of package presets
+// register themselves when imported.
+//
+//	import _ "github.com/bmatsuo/go-lexer/presets/jsonlex"
+//
+//	lex := lexer.Lookup("json").New(input)
+//
+// A language registered with the name of an earlier language replaces it.
+func RegisterLanguage(lang *Language) {
+	languages.Lock()
+	defer languages.Unlock()
+	if languages.names == nil {
+		languages.names = make(map[string]*Language)
+	}
+	list := make([]*Language, 0, len(languages.list)+1)
+	for _, l := range languages.list {
+		if strings.EqualFold(l.Name, lang.Name) {
+			for _, name := range l.names() {
+				if languages.names[strings.ToLower(name)] == l {
+					delete(languages.names, strings.ToLower(name))
+				}
+			}
+			continue
+		}
+		list = append(list, l)
+	}
+	languages.list = append(list, lang)
+	for _, name := range lang.names() {
+		languages.names[strings.ToLower(name)] = lang
+	}
+}
+
+// Lookup returns the language registered with the given name or alias, or
+// nil if there is none.
+func Lookup(name string) *Language {
+	languages.RLock()
+	defer languages.RUnlock()
+	return languages.names[strings.ToLower(name)]
+}
+
+// Languages returns the registered languages sorted by name.
+func Languages() []*Language {
+	languages.RLock()
+	list := append([]*Language(nil), languages.list...)
+	languages.RUnlock()
+	sort.Slice(list, func(i, j int) bool { return list[i].Name < list[j].Name })
+	return list
+}
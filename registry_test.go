@@ -0,0 +1,50 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestRegisterLanguage(t *testing.T) {
+	words := &Language{
+		Name:    "test-words",
+		Aliases: []string{"TW"},
+		Start:   func() StateFn { return lexWords },
+		Options: []Option{WithName("words")},
+	}
+	RegisterLanguage(words)
+	if Lookup("tw") != words || Lookup("Test-Words") != words || Lookup("test") != nil {
+		t.Fatalf("lookup failed")
+	}
+	lex := Lookup("tw").New("ab cd")
+	if got := collect(lex); !reflect.DeepEqual(got, []string{"ab", "cd"}) || lex.Name() != "words" {
+		t.Errorf("items %q name %q", got, lex.Name())
+	}
+	lex, err := words.NewReader(strings.NewReader("ef"), WithName("override"))
+	if err != nil || lex.Name() != "override" {
+		t.Errorf("name %q error %v", lex.Name(), err)
+	}
+	lex = words.Program().New("gh")
+	if got := collect(lex); !reflect.DeepEqual(got, []string{"gh"}) || lex.Name() != "words" {
+		t.Errorf("program items %q name %q", got, lex.Name())
+	}
+
+	RegisterLanguage(&Language{Name: "test-words", Start: words.Start})
+	if Lookup("tw") != nil || Lookup("test-words") == words {
+		t.Errorf("language not replaced")
+	}
+	n := 0
+	for _, lang := range Languages() {
+		if lang.Name == "test-words" {
+			n++
+		}
+	}
+	if n != 1 {
+		t.Errorf("%d languages named test-words", n)
+	}
+}
@@ -0,0 +1,136 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// RenderOptions controls the output of RenderError.
+type RenderOptions struct {
+	// Name identifies the input (e.g. a file name) in the output.
+	Name string
+
+	// Source is the input containing the error.  It is only needed for
+	// errors not emitted by a Lexer, which remember their input.
+	Source string
+
+	// Severity labels the message.  The default is "error".
+	Severity string
+
+	// TabWidth is the number of columns between tab stops.  Tabs in the
+	// rendered line are expanded to spaces.  The default width is 4.
+	TabWidth int
+
+	// Color enables ANSI color escapes.
+	Color bool
+}
+
+const (
+	ansiReset  = "\x1b[0m"
+	ansiBold   = "\x1b[1m"
+	ansiRed    = "\x1b[1;31m"
+	ansiYellow = "\x1b[1;33m"
+	ansiBlue   = "\x1b[1;34m"
+)
+
+// RenderError formats err for display to a user, in the style of the rustc
+// compiler.  The output names the location of the error and shows the line
+// containing it, with the offending text underlined.
+//
+//	error: unexpected "1"
+//	 --> input.txt:2:4
+//	  |
+//	2 | ab 1cd
+//	  |    ^
+func RenderError(err *LexError, opts RenderOptions) string {
+	src, pos, end := opts.Source, err.Pos, err.End
+	line, col := 1, 0
+	if src == "" {
+		// The lexer's source may have been discarded before err.base.
+		src, pos, end = err.src, pos-err.base, end-err.base
+		line += err.line
+		col += err.col
+	}
+	severity := opts.Severity
+	if severity == "" {
+		severity = "error"
+	}
+	tabs := opts.TabWidth
+	if tabs < 1 {
+		tabs = 4
+	}
+	color := func(code, s string) string {
+		if !opts.Color {
+			return s
+		}
+		return code + s + ansiReset
+	}
+	mark := ansiRed
+	if severity == "warning" {
+		mark = ansiYellow
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "%s%s\n", color(mark, severity), color(ansiBold, ": "+err.Msg))
+	if pos < 0 || pos > len(src) {
+		return b.String()
+	}
+	lo := strings.LastIndexByte(src[:pos], '\n') + 1
+	hi := len(src)
+	if i := strings.IndexByte(src[pos:], '\n'); i >= 0 {
+		hi = pos + i
+	}
+	if end > hi {
+		end = hi
+	}
+	if end < pos {
+		end = pos
+	}
+	line += strings.Count(src[:lo], "\n")
+	if lo > 0 {
+		col = 0
+	}
+	text, tcol := expandTabs(src[lo:pos], 0, tabs)
+	span, width := expandTabs(src[pos:end], tcol, tabs)
+	rest, _ := expandTabs(src[end:hi], tcol+width, tabs)
+	if width == 0 {
+		width = 1
+	}
+
+	num := strconv.Itoa(line)
+	pad := strings.Repeat(" ", len(num))
+	loc := strconv.Itoa(line) + ":" + strconv.Itoa(col+tcol+1)
+	if opts.Name != "" {
+		loc = opts.Name + ":" + loc
+	}
+	fmt.Fprintf(&b, "%s%s %s\n", pad, color(ansiBlue, "-->"), loc)
+	fmt.Fprintf(&b, "%s %s\n", pad, color(ansiBlue, "|"))
+	fmt.Fprintf(&b, "%s %s%s\n", color(ansiBlue, num+" |"), text, span+rest)
+	fmt.Fprintf(&b, "%s %s%s\n", pad, color(ansiBlue, "|"),
+		" "+strings.Repeat(" ", tcol)+color(mark, strings.Repeat("^", width)))
+	return b.String()
+}
+
+// expandTabs replaces the tabs in s with spaces, assuming s begins at column
+// col, and returns the result with its width in columns.
+func expandTabs(s string, col, tabs int) (string, int) {
+	var b bytes.Buffer
+	start := col
+	for _, r := range s {
+		if r == '\t' {
+			n := tabs - col%tabs
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(r)
+		col++
+	}
+	return b.String(), col - start
+}
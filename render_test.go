@@ -0,0 +1,53 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func TestRenderError(t *testing.T) {
+	lex := New(func(l *Lexer) StateFn {
+		l.AcceptString("x = 1\n\tab ")
+		l.Ignore()
+		l.AcceptString("12")
+		return l.Errorf("unexpected number")
+	}, "x = 1\n\tab 12cd\n")
+	err := lex.Next().Err().(*LexError)
+	for _, test := range []struct {
+		err  *LexError
+		opts RenderOptions
+		out  string
+	}{
+		{err, RenderOptions{Name: "in.txt"}, "" +
+			"error: unexpected number\n" +
+			" --> in.txt:2:8\n" +
+			"  |\n" +
+			"2 |     ab 12cd\n" +
+			"  |        ^^\n"},
+		{err, RenderOptions{TabWidth: 2, Severity: "warning"}, "" +
+			"warning: unexpected number\n" +
+			" --> 2:6\n" +
+			"  |\n" +
+			"2 |   ab 12cd\n" +
+			"  |      ^^\n"},
+		{&LexError{Msg: "unterminated", Pos: 4, End: 9}, RenderOptions{Source: "ab\n\"cd\nef"}, "" +
+			"error: unterminated\n" +
+			" --> 2:2\n" +
+			"  |\n" +
+			"2 | \"cd\n" +
+			"  |  ^^\n"},
+		{&LexError{Msg: "bad", Pos: 0}, RenderOptions{Source: "", Color: true}, "" +
+			"\x1b[1;31merror\x1b[0m\x1b[1m: bad\x1b[0m\n" +
+			" \x1b[1;34m-->\x1b[0m 1:1\n" +
+			"  \x1b[1;34m|\x1b[0m\n" +
+			"\x1b[1;34m1 |\x1b[0m \n" +
+			"  \x1b[1;34m|\x1b[0m \x1b[1;31m^\x1b[0m\n"},
+	} {
+		if out := RenderError(test.err, test.opts); out != test.out {
+			t.Errorf("%s: rendered\n%s(expected)\n%s", test.err, out, test.out)
+		}
+	}
+}
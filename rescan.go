@@ -0,0 +1,59 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+// rescanCheck measures the input AcceptString examines more than once while
+// lexing a lexeme, see WithRescanCheck.
+type rescanCheck struct {
+	limit  int
+	start  int  // offset of the lexeme measured
+	high   int  // end of the input examined so far in the lexeme
+	bytes  int  // bytes examined again
+	calls  int  // calls of AcceptString
+	warned bool // the lexeme was reported
+}
+
+// WithRescanCheck makes the lexer emit a warning when calls of AcceptString
+// examine more than limit bytes of input which earlier calls made while
+// lexing the same lexeme already examined.  Re-examining input is harmless
+// in moderation, as when several keywords are tried at one position, but
+// grows quadratically in states which try long strings at each position of
+// a long lexeme or return to its start repeatedly.  The warning gives the
+// position of the lexeme and the counts measured, and is emitted at most
+// once for each lexeme.  A limit of 64K bytes finds quadratic states on
+// inputs of moderate size without reporting keyword matching.
+func WithRescanCheck(limit int) Option {
+	return func(l *Lexer) { l.rescan = &rescanCheck{limit: limit, start: -1} }
+}
+
+// check measures the input examined by a call of AcceptString(s) at l's
+// current position.
+func (c *rescanCheck) check(l *Lexer, s string) {
+	if l.start != c.start {
+		*c = rescanCheck{limit: c.limit, start: l.start, high: l.pos}
+	}
+	rest := l.input[l.pos:]
+	n := 0
+	for n < len(s) && n < len(rest) && s[n] == rest[n] {
+		n++
+	}
+	if n < len(s) && n < len(rest) {
+		n++ // the mismatched byte
+	}
+	c.calls++
+	if end := l.pos + n; end > c.high {
+		if l.pos < c.high {
+			c.bytes += c.high - l.pos
+		}
+		c.high = end
+	} else {
+		c.bytes += n
+	}
+	if c.bytes > c.limit && !c.warned {
+		c.warned = true
+		l.Warnf("AcceptString examined %d bytes again in %d calls while lexing %d bytes at offset %d; the state may be quadratic",
+			c.bytes, c.calls, l.pos-l.start, l.offset(l.start))
+	}
+}
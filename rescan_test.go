@@ -0,0 +1,59 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRescanCheck(t *testing.T) {
+	needle := strings.Repeat("a", 100) + "b"
+	search := func(l *Lexer) StateFn {
+		for !l.AcceptString(needle) {
+			if _, n := l.Advance(); n == 0 {
+				break
+			}
+		}
+		l.Emit(1)
+		return nil
+	}
+	input := strings.Repeat("a", 2000) + "b"
+	lex := New(search, input, WithRescanCheck(64<<10))
+	item := lex.Next()
+	if item.Type != ItemWarning || !strings.Contains(item.Value, "at offset 0; the state may be quadratic") {
+		t.Fatalf("item %v", item)
+	}
+	if item := lex.Next(); item.Type != 1 || len(item.Value) != len(input) {
+		t.Errorf("item %v", item)
+	}
+	if item := lex.Next(); item.Type != ItemEOF {
+		t.Errorf("second warning %v", item)
+	}
+
+	keywords := []string{"break", "case", "chan", "const", "continue", "default", "defer", "else"}
+	lexKeywords := func(l *Lexer) StateFn {
+		for {
+			l.AcceptRun(" ")
+			l.Ignore()
+			found := false
+			for _, kw := range keywords {
+				if l.AcceptString(kw) {
+					l.Emit(1)
+					found = true
+					break
+				}
+			}
+			if !found {
+				return nil
+			}
+		}
+	}
+	input = strings.Repeat("const continue default else ", 1000)
+	items := collect(New(lexKeywords, input, WithRescanCheck(64<<10)))
+	if len(items) != 4000 {
+		t.Errorf("%d items, first %q", len(items), items[0])
+	}
+}
@@ -0,0 +1,99 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+// WithRetention bounds the input kept by a lexer whose input arrives in
+// pieces given to Append, as when a long stream is read incrementally
+// rather than by NewReader, which reads its whole input at once.  After each
+// Append the lexer discards input ending more than n bytes before the start
+// of the current lexeme, except input from a position pinned with Pin.  The
+// retained input serves Backup, the excerpts of errors and spans recovered
+// with Text, such as trivia between items.  Input is discarded once the
+// discardable part is at least half the retained input, so that the cost of
+// copying the input remains proportional to its length.
+//
+// Item positions remain offsets in the whole stream, and Position continues
+// to count lines from its beginning.  The Input of the lexer and the offsets
+// given to and returned by the scanner API, such as Pos and Checkpoint, are
+// relative to the retained input, which begins at the offset returned by
+// Discarded.  Retention does not apply to lexers with input transforms.
+func WithRetention(n int) Option {
+	return func(l *Lexer) {
+		if n < 0 {
+			n = 0
+		}
+		l.retain, l.window = n, true
+	}
+}
+
+// Discarded returns the number of bytes of the source discarded by l, which
+// is the offset of the retained input in the source (see WithRetention).
+func (l *Lexer) Discarded() int {
+	return l.dropped
+}
+
+// Pin prevents l from discarding the source from offset pos until unpin is
+// called.  Input already discarded is not recovered.
+func (l *Lexer) Pin(pos int) (unpin func()) {
+	l.pins = append(l.pins, pos)
+	return func() {
+		for i, p := range l.pins {
+			if p == pos {
+				l.pins = append(l.pins[:i], l.pins[i+1:]...)
+				return
+			}
+		}
+	}
+}
+
+// Text returns the source between the offsets pos and end, and false if
+// part of it has been discarded or is beyond the input.
+func (l *Lexer) Text(pos, end int) (string, bool) {
+	src := l.Source()
+	if pos < l.dropped || end < pos || end-l.dropped > len(src) {
+		return "", false
+	}
+	return src[pos-l.dropped : end-l.dropped], true
+}
+
+// discard drops the input l no longer retains.
+func (l *Lexer) discard() {
+	if !l.window || len(l.xforms) > 0 {
+		return
+	}
+	keep := l.start - l.retain
+	for _, p := range l.pins {
+		if p-l.dropped < keep {
+			keep = p - l.dropped
+		}
+	}
+	if keep <= 0 || keep < len(l.input)/2 {
+		return
+	}
+	pos := l.Position(l.dropped + keep)
+	l.dropLine, l.dropCol = pos.Line-1, pos.Column-1
+	l.dropRunes(l.dropped + keep)
+	l.input = string([]byte(l.input[keep:]))
+	l.start -= keep
+	l.pos -= keep
+	l.end -= keep
+	l.dropped += keep
+}
+
+// lexError returns the error with message msg about the source between the
+// offsets pos and end.
+func (l *Lexer) lexError(msg string, pos, end int) *LexError {
+	src := l.Source()
+	return &LexError{
+		Msg:    msg,
+		Pos:    pos,
+		End:    end,
+		Lexeme: src[pos-l.dropped : end-l.dropped],
+		src:    src,
+		base:   l.dropped,
+		line:   l.dropLine,
+		col:    l.dropCol,
+	}
+}
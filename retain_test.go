@@ -0,0 +1,93 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func lexLines(l *Lexer) StateFn {
+	l.AcceptRun(" \n")
+	l.Ignore()
+	if l.AcceptRun("abcdefghijklmnopqrstuvwxyz") > 0 {
+		l.Emit(1)
+		return lexLines
+	}
+	if l.Accept(digits) {
+		return l.Errorf("unexpected %q", l.Current())
+	}
+	return nil
+}
+
+func TestRetention(t *testing.T) {
+	lex := New(lexLines, "", WithRetention(8), WithLineTracking())
+	lex.Next()
+	var items []*Item
+	for i := 0; i < 100; i++ {
+		line := fmt.Sprintf("line%c  w\n", 'a'+i%26)
+		if i == 50 {
+			line = "bad 7\n"
+		}
+		lex.Append(line)
+		lex.Continue(lexLines)
+		for item := lex.Next(); item.Type != ItemEOF; item = lex.Next() {
+			items = append(items, item)
+		}
+		if n := len(lex.Input()); n > 64 {
+			t.Fatalf("line %d: %d bytes retained", i+1, n)
+		}
+	}
+	if lex.Discarded() == 0 {
+		t.Fatalf("no input discarded")
+	}
+	last := items[len(items)-1]
+	if last.Value != "w" || last.Pos != 99*9-3+7 || last.Line != 100 || last.Column != 8 {
+		t.Errorf("last item %+v", last)
+	}
+	var bad *Item
+	for _, item := range items {
+		if item.Type == ItemError {
+			bad = item
+		}
+	}
+	if bad == nil || bad.Line != 51 {
+		t.Fatalf("error item %+v", bad)
+	}
+	want := "error: unexpected \"7\"\n  --> 51:5\n"
+	if got := RenderError(bad.Payload.(*LexError), RenderOptions{}); !strings.HasPrefix(got, want) || !strings.Contains(got, "| bad 7\n") {
+		t.Errorf("rendered error\n%s", got)
+	}
+	if _, ok := lex.Text(0, 4); ok {
+		t.Errorf("discarded text returned")
+	}
+}
+
+func TestRetentionPin(t *testing.T) {
+	lex := New(lexLines, "", WithRetention(0))
+	lex.Append("first ")
+	for lex.Next().Type != ItemEOF {
+	}
+	unpin := lex.Pin(0)
+	for i := 0; i < 20; i++ {
+		lex.Append("word ")
+		lex.Continue(lexLines)
+		for lex.Next().Type != ItemEOF {
+		}
+	}
+	if text, ok := lex.Text(0, 5); !ok || text != "first" {
+		t.Errorf("pinned text %q %v", text, ok)
+	}
+	unpin()
+	lex.Append("last")
+	lex.Continue(lexLines)
+	if item := lex.Next(); item.Value != "last" || item.Pos != 6+20*5 {
+		t.Errorf("item %+v", item)
+	}
+	if _, ok := lex.Text(0, 5); ok || lex.Discarded() == 0 {
+		t.Errorf("unpinned text retained")
+	}
+}
@@ -0,0 +1,124 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Rewriter edits source text at the spans of items, for refactoring tools.
+// The text of the source outside the edited spans is preserved byte for
+// byte.
+//
+//	rw := lexer.NewRewriter(lex.Input())
+//	for _, item := range items {
+//		if item.Type == itemIdent && item.Value == "old" {
+//			rw.Replace(&item, "new")
+//		}
+//	}
+//	out := rw.String()
+//
+// Positions always refer to the original source, so edits may be made in
+// any order.
+type Rewriter struct {
+	src   string
+	edits []edit
+}
+
+type edit struct {
+	pos, end int
+	text     string
+}
+
+// NewRewriter returns a rewriter of src without edits.
+func NewRewriter(src string) *Rewriter {
+	return &Rewriter{src: src}
+}
+
+// Span returns the byte offsets of the text of item in the source.  The
+// span of an error or a warning is that of the offending text.  Span
+// returns an error if the value of any other item is not the text at its
+// position, as for items emitted by EmitValue.
+func (rw *Rewriter) Span(item *Item) (pos, end int, err error) {
+	if lerr, ok := item.Payload.(*LexError); ok && (item.Type == ItemError || item.Type == ItemWarning) {
+		return lerr.Pos, lerr.End, nil
+	}
+	end = item.Pos + len(item.Value)
+	if item.Pos < 0 || end > len(rw.src) || rw.src[item.Pos:end] != item.Value {
+		return 0, 0, fmt.Errorf("%+v is not in the source", item)
+	}
+	return item.Pos, end, nil
+}
+
+// Replace replaces the text of item with text.
+func (rw *Rewriter) Replace(item *Item, text string) error {
+	pos, end, err := rw.Span(item)
+	if err != nil {
+		return err
+	}
+	return rw.ReplaceSpan(pos, end, text)
+}
+
+// Delete removes the text of item.
+func (rw *Rewriter) Delete(item *Item) error {
+	return rw.Replace(item, "")
+}
+
+// InsertBefore inserts text before the text of item.
+func (rw *Rewriter) InsertBefore(item *Item, text string) error {
+	pos, _, err := rw.Span(item)
+	if err != nil {
+		return err
+	}
+	return rw.ReplaceSpan(pos, pos, text)
+}
+
+// InsertAfter inserts text after the text of item.
+func (rw *Rewriter) InsertAfter(item *Item, text string) error {
+	_, end, err := rw.Span(item)
+	if err != nil {
+		return err
+	}
+	return rw.ReplaceSpan(end, end, text)
+}
+
+// ReplaceSpan replaces the source between the byte offsets pos and end with
+// text.  It returns an error if the span is not in the source or overlaps
+// a span already replaced.  Insertions at the same offset are written in
+// the order they were made.
+func (rw *Rewriter) ReplaceSpan(pos, end int, text string) error {
+	if pos < 0 || pos > end || end > len(rw.src) {
+		return fmt.Errorf("span [%d:%d] is not in the source", pos, end)
+	}
+	for _, e := range rw.edits {
+		if pos < e.end && e.pos < end {
+			return fmt.Errorf("span [%d:%d] overlaps edited span [%d:%d]", pos, end, e.pos, e.end)
+		}
+	}
+	rw.edits = append(rw.edits, edit{pos, end, text})
+	return nil
+}
+
+// String returns the edited source.
+func (rw *Rewriter) String() string {
+	edits := append([]edit(nil), rw.edits...)
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].pos != edits[j].pos {
+			return edits[i].pos < edits[j].pos
+		}
+		return edits[i].pos == edits[i].end && edits[j].pos != edits[j].end
+	})
+	var b strings.Builder
+	last := 0
+	for _, e := range edits {
+		b.WriteString(rw.src[last:e.pos])
+		b.WriteString(e.text)
+		last = e.end
+	}
+	b.WriteString(rw.src[last:])
+	return b.String()
+}
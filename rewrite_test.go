@@ -0,0 +1,50 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func TestRewriter(t *testing.T) {
+	input := "ab  cd\tab 1"
+	its := items(New(lexWords, input, WithErrorPolicy(ErrorResume)))
+	rw := NewRewriter(input)
+	for i := range its {
+		var err error
+		switch {
+		case its[i].Value == "ab":
+			err = rw.Replace(&its[i], "xyz")
+		case its[i].Value == "cd":
+			err = rw.InsertAfter(&its[i], "!")
+			if err == nil {
+				err = rw.InsertBefore(&its[i], "<")
+			}
+			if err == nil {
+				err = rw.InsertBefore(&its[i], "<")
+			}
+		case its[i].Type == ItemError:
+			err = rw.Delete(&its[i])
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	if s := rw.String(); s != "xyz  <<cd!xyz " {
+		t.Errorf("%q", s)
+	}
+	if err := rw.ReplaceSpan(3, 5, "x"); err == nil {
+		t.Errorf("overlapping span replaced")
+	}
+	if err := rw.ReplaceSpan(1, 1, "x"); err == nil {
+		t.Errorf("insertion into replaced span")
+	}
+	if err := rw.ReplaceSpan(4, 4, "x"); err != nil {
+		t.Errorf("insertion after replaced span: %v", err)
+	}
+	if err := rw.Replace(&Item{Type: 1, Pos: 4, Value: "xx"}, "y"); err == nil {
+		t.Errorf("replaced item not in source")
+	}
+}
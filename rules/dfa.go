@@ -0,0 +1,306 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"fmt"
+	"regexp/syntax"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// maxStates bounds the size of the automaton built from a rule set.
+const maxStates = 10000
+
+// The rules are analyzed with a deterministic automaton recognizing all of
+// their patterns at once.  Each state of the automaton is the set of
+// positions reachable in the compiled programs of the rules after reading
+// some text.  The rules accepting in a state are exactly the rules matching
+// that text.
+
+// nfaState is a position in the program of a rule.
+type nfaState struct {
+	rule int
+	pc   uint32
+}
+
+type dfaState struct {
+	nfa    []nfaState // consuming instructions reachable, sorted
+	accept []int      // rules matching the text leading to this state
+	next   []int      // next state for each rune class, or -1
+	win    int        // rule winning a match ending in this state, or -1
+	parent int        // state from which this state was first reached
+	via    rune       // rune leading from parent to this state
+}
+
+type dfa struct {
+	progs   []*syntax.Prog
+	classes []rune // lower bounds of the rune classes, ascending
+	ascii   [utf8.RuneSelf]int
+	states  []*dfaState
+}
+
+// compile builds the automaton for rules.
+func compile(rules []*Rule) (*dfa, []string) {
+	d := new(dfa)
+	var problems []string
+	for _, r := range rules {
+		re, err := syntax.Parse(r.source(), syntax.Perl)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", r, err))
+			continue
+		}
+		prog, err := syntax.Compile(re.Simplify())
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%v: %v", r, err))
+			continue
+		}
+		for _, inst := range prog.Inst {
+			if inst.Op == syntax.InstEmptyWidth {
+				problems = append(problems, fmt.Sprintf("%v: empty-width assertions are not supported", r))
+				break
+			}
+		}
+		d.progs = append(d.progs, prog)
+	}
+	if len(problems) > 0 {
+		return nil, problems
+	}
+	d.classes = runeClasses(d.progs)
+	for r := range d.ascii {
+		d.ascii[r] = d.search(rune(r))
+	}
+
+	index := make(map[string]int)
+	add := func(nfa []nfaState, accept []int, parent int, via rune) (int, bool) {
+		key := stateKey(nfa, accept)
+		if i, ok := index[key]; ok {
+			return i, true
+		}
+		if len(d.states) >= maxStates {
+			return -1, false
+		}
+		index[key] = len(d.states)
+		d.states = append(d.states, &dfaState{nfa: nfa, accept: accept, win: -1, parent: parent, via: via})
+		return len(d.states) - 1, true
+	}
+	var start []nfaState
+	var accept []int
+	for i, prog := range d.progs {
+		start, accept = d.closure(start, accept, i, uint32(prog.Start))
+	}
+	add(normalize(start), uniq(accept), -1, 0)
+	for i := 0; i < len(d.states); i++ {
+		s := d.states[i]
+		s.next = make([]int, len(d.classes))
+		for c, rep := range d.classes {
+			var nfa []nfaState
+			var accept []int
+			for _, ns := range s.nfa {
+				inst := &d.progs[ns.rule].Inst[ns.pc]
+				if inst.MatchRune(rep) {
+					nfa, accept = d.closure(nfa, accept, ns.rule, inst.Out)
+				}
+			}
+			if len(nfa) == 0 && len(accept) == 0 {
+				s.next[c] = -1
+				continue
+			}
+			j, ok := add(normalize(nfa), uniq(accept), i, rep)
+			if !ok {
+				return nil, []string{fmt.Sprintf("rule set requires more than %d automaton states", maxStates)}
+			}
+			s.next[c] = j
+		}
+	}
+	return d, nil
+}
+
+// closure adds the consuming instructions and matches reachable from pc
+// without consuming input.
+func (d *dfa) closure(nfa []nfaState, accept []int, rule int, pc uint32) ([]nfaState, []int) {
+	prog := d.progs[rule]
+	seen := make(map[uint32]bool)
+	stack := []uint32{pc}
+	for len(stack) > 0 {
+		pc := stack[len(stack)-1]
+		stack = stack[:len(stack)-1]
+		if seen[pc] {
+			continue
+		}
+		seen[pc] = true
+		inst := &prog.Inst[pc]
+		switch inst.Op {
+		case syntax.InstAlt, syntax.InstAltMatch:
+			stack = append(stack, inst.Out, inst.Arg)
+		case syntax.InstCapture, syntax.InstNop, syntax.InstEmptyWidth:
+			stack = append(stack, inst.Out)
+		case syntax.InstMatch:
+			accept = append(accept, rule)
+		case syntax.InstRune, syntax.InstRune1, syntax.InstRuneAny, syntax.InstRuneAnyNotNL:
+			nfa = append(nfa, nfaState{rule, pc})
+		}
+	}
+	return nfa, accept
+}
+
+// class returns the index of the class containing r.
+func (d *dfa) class(r rune) int {
+	if 0 <= r && r < utf8.RuneSelf {
+		return d.ascii[r]
+	}
+	return d.search(r)
+}
+
+func (d *dfa) search(r rune) int {
+	return sort.Search(len(d.classes), func(i int) bool { return d.classes[i] > r }) - 1
+}
+
+// witness returns text leading from the start state to state i.
+func (d *dfa) witness(i int) string {
+	var rs []rune
+	for ; d.states[i].parent >= 0; i = d.states[i].parent {
+		rs = append(rs, d.states[i].via)
+	}
+	for a, b := 0, len(rs)-1; a < b; a, b = a+1, b-1 {
+		rs[a], rs[b] = rs[b], rs[a]
+	}
+	return string(rs)
+}
+
+// runeClasses partitions the runes into intervals which every instruction
+// of progs either matches entirely or not at all.  It returns the lower
+// bound of each interval.
+func runeClasses(progs []*syntax.Prog) []rune {
+	bounds := map[rune]bool{0: true}
+	cut := func(lo, hi rune) {
+		bounds[lo] = true
+		if hi < unicode.MaxRune {
+			bounds[hi+1] = true
+		}
+	}
+	for _, prog := range progs {
+		for _, inst := range prog.Inst {
+			switch inst.Op {
+			case syntax.InstRune1:
+				cut(inst.Rune[0], inst.Rune[0])
+			case syntax.InstRuneAnyNotNL:
+				cut('\n', '\n')
+			case syntax.InstRune:
+				fold := syntax.Flags(inst.Arg)&syntax.FoldCase != 0
+				for i := 0; i+1 < len(inst.Rune); i += 2 {
+					lo, hi := inst.Rune[i], inst.Rune[i+1]
+					cut(lo, hi)
+					if fold && hi-lo < 1024 {
+						for r := lo; r <= hi; r++ {
+							for f := unicode.SimpleFold(r); f != r; f = unicode.SimpleFold(f) {
+								cut(f, f)
+							}
+						}
+					}
+				}
+				if len(inst.Rune) == 1 {
+					cut(inst.Rune[0], inst.Rune[0])
+					if syntax.Flags(inst.Arg)&syntax.FoldCase != 0 {
+						for f := unicode.SimpleFold(inst.Rune[0]); f != inst.Rune[0]; f = unicode.SimpleFold(f) {
+							cut(f, f)
+						}
+					}
+				}
+			}
+		}
+	}
+	classes := make([]rune, 0, len(bounds))
+	for r := range bounds {
+		if r <= unicode.MaxRune {
+			classes = append(classes, r)
+		}
+	}
+	sort.Slice(classes, func(i, j int) bool { return classes[i] < classes[j] })
+	return classes
+}
+
+func normalize(nfa []nfaState) []nfaState {
+	sort.Slice(nfa, func(i, j int) bool {
+		if nfa[i].rule != nfa[j].rule {
+			return nfa[i].rule < nfa[j].rule
+		}
+		return nfa[i].pc < nfa[j].pc
+	})
+	out := nfa[:0]
+	for i, s := range nfa {
+		if i == 0 || s != nfa[i-1] {
+			out = append(out, s)
+		}
+	}
+	return out
+}
+
+func uniq(xs []int) []int {
+	sort.Ints(xs)
+	out := xs[:0]
+	for i, x := range xs {
+		if i == 0 || x != xs[i-1] {
+			out = append(out, x)
+		}
+	}
+	return out
+}
+
+func stateKey(nfa []nfaState, accept []int) string {
+	var b strings.Builder
+	for _, s := range nfa {
+		fmt.Fprintf(&b, "%d.%d,", s.rule, s.pc)
+	}
+	b.WriteByte('|')
+	for _, r := range accept {
+		fmt.Fprintf(&b, "%d,", r)
+	}
+	return b.String()
+}
+
+// validate determines the winning rule of each state of d and returns the
+// problems found in rules: rules matching the empty string and rules of
+// equal priority matching the same text.  The rules winning some match are
+// marked in wins and an example of the text matched by each rule is recorded
+// in examples, from which the caller finds the rules that never win.
+func validate(d *dfa, rules []*Rule, wins map[*Rule]bool, examples map[*Rule]string) (problems []string) {
+	for _, r := range d.states[0].accept {
+		problems = append(problems, fmt.Sprintf("%v matches the empty string", rules[r]))
+	}
+	if problems != nil {
+		return problems
+	}
+	reported := make(map[[2]int]bool)
+	for i, s := range d.states {
+		if len(s.accept) == 0 {
+			continue
+		}
+		best := s.accept[0]
+		for _, r := range s.accept {
+			if _, ok := examples[rules[r]]; !ok {
+				examples[rules[r]] = d.witness(i)
+			}
+			if rules[r].beats(rules[best]) {
+				best = r
+			}
+		}
+		s.win = best
+		for _, r := range s.accept {
+			if rules[r].priority == rules[best].priority {
+				wins[rules[r]] = true
+			}
+			pair := [2]int{best, r}
+			if r != best && !rules[best].beats(rules[r]) && !reported[pair] {
+				reported[pair] = true
+				problems = append(problems, fmt.Sprintf("%v and %v are ambiguous: both match %q with priority %d",
+					rules[best], rules[r], d.witness(i), rules[r].priority))
+			}
+		}
+	}
+	return problems
+}
@@ -0,0 +1,458 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package ebnf extracts the terminals of a grammar written in the EBNF
+notation of the Go language specification and converts them into rules for
+package rules, so that a single grammar describes both the lexer and the
+parser of a language.
+
+	Expression = Term { ( "+" | "-" ) Term } .
+	Term       = ident | number | "(" Expression ")" .
+	ident      = letter { letter | digit } .
+	number     = digit { digit } .
+	letter     = "a" … "z" | "_" .
+	digit      = "0" … "9" .
+
+As in the Go specification, productions with lower-case names are lexical.
+The terminals of a grammar are the lexical productions referenced by the
+syntactic productions reachable from a start production, along with the
+literal tokens appearing in them.  Above, the terminals of Expression are
+ident, number, "+", "-", "(" and ")".
+
+Lexical productions are converted into regular expressions.  Those defined
+informally in a comment, such as unicode_letter in the Go specification,
+must be given a regular expression with Define.
+*/
+package ebnf
+
+import (
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/scanner"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bmatsuo/go-lexer"
+	"github.com/bmatsuo/go-lexer/rules"
+)
+
+// Error is a problem in a grammar.
+type Error struct {
+	Pos scanner.Position
+	Msg string
+}
+
+func (err *Error) Error() string {
+	if err.Pos.IsValid() {
+		return err.Pos.String() + ": " + err.Msg
+	}
+	return err.Msg
+}
+
+// Grammar is a set of productions.
+type Grammar struct {
+	prods   map[string]*production
+	defined map[string]string // regular expressions given by Define
+}
+
+type production struct {
+	pos  scanner.Position
+	name string
+	expr expr // nil if the production has no expression
+}
+
+// The expressions of productions.
+type (
+	expr        interface{}
+	alternative []expr
+	sequence    []expr
+	name        struct {
+		pos  scanner.Position
+		name string
+	}
+	token  string
+	span   struct{ lo, hi rune }
+	group  struct{ x expr }
+	option struct{ x expr }
+	repeat struct{ x expr }
+)
+
+// Parse reads a grammar from r.  The filename is used in the positions of
+// errors.  The error, if any, is an *Error.
+func Parse(filename string, r io.Reader) (*Grammar, error) {
+	p := &parser{g: &Grammar{prods: make(map[string]*production), defined: make(map[string]string)}}
+	p.s.Init(r)
+	p.s.Filename = filename
+	p.s.Error = func(s *scanner.Scanner, msg string) {
+		if p.err == nil {
+			p.err = &Error{s.Position, msg}
+		}
+	}
+	p.next()
+	for p.tok != scanner.EOF && p.err == nil {
+		p.production()
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	return p.g, nil
+}
+
+type parser struct {
+	s   scanner.Scanner
+	g   *Grammar
+	tok rune
+	lit string
+	pos scanner.Position
+	err error
+}
+
+func (p *parser) next() {
+	p.tok = p.s.Scan()
+	p.pos = p.s.Position
+	p.lit = p.s.TokenText()
+}
+
+func (p *parser) errorf(format string, v ...interface{}) {
+	if p.err == nil {
+		p.err = &Error{p.pos, fmt.Sprintf(format, v...)}
+	}
+	p.tok = scanner.EOF
+}
+
+func (p *parser) expect(tok rune) {
+	if p.tok != tok {
+		p.errorf("expected %s, found %q", scanner.TokenString(tok), p.lit)
+		return
+	}
+	p.next()
+}
+
+func (p *parser) production() {
+	prod := &production{pos: p.pos, name: p.lit}
+	p.expect(scanner.Ident)
+	p.expect('=')
+	if p.tok != '.' {
+		prod.expr = p.expression()
+	}
+	p.expect('.')
+	if p.err != nil {
+		return
+	}
+	if prev, ok := p.g.prods[prod.name]; ok {
+		p.pos = prod.pos
+		p.errorf("%s redeclared (previously declared at %v)", prod.name, prev.pos)
+		return
+	}
+	p.g.prods[prod.name] = prod
+}
+
+func (p *parser) expression() expr {
+	var alt alternative
+	for {
+		alt = append(alt, p.sequence())
+		if p.tok != '|' {
+			break
+		}
+		p.next()
+	}
+	if len(alt) == 1 {
+		return alt[0]
+	}
+	return alt
+}
+
+func (p *parser) sequence() expr {
+	var seq sequence
+	for {
+		x := p.term()
+		if x == nil {
+			break
+		}
+		seq = append(seq, x)
+	}
+	switch len(seq) {
+	case 0:
+		p.errorf("expected expression, found %q", p.lit)
+		return nil
+	case 1:
+		return seq[0]
+	}
+	return seq
+}
+
+func (p *parser) term() expr {
+	switch p.tok {
+	case scanner.Ident:
+		x := name{p.pos, p.lit}
+		p.next()
+		return x
+	case scanner.String, scanner.RawString:
+		lo := p.token()
+		if p.tok != '…' && !(p.tok == '.' && p.ellipsis()) {
+			return lo
+		}
+		pos := p.pos
+		p.next()
+		hi := p.token()
+		a, na := utf8.DecodeRuneInString(string(lo))
+		b, nb := utf8.DecodeRuneInString(string(hi))
+		if na != len(lo) || nb != len(hi) || a > b {
+			p.pos = pos
+			p.errorf("invalid range %q … %q", lo, hi)
+			return nil
+		}
+		return span{a, b}
+	case '(':
+		p.next()
+		x := group{p.expression()}
+		p.expect(')')
+		return x
+	case '[':
+		p.next()
+		x := option{p.expression()}
+		p.expect(']')
+		return x
+	case '{':
+		p.next()
+		x := repeat{p.expression()}
+		p.expect('}')
+		return x
+	}
+	return nil
+}
+
+// ellipsis consumes the rest of "..." when the current token is its first
+// period.
+func (p *parser) ellipsis() bool {
+	if p.s.Peek() != '.' {
+		return false
+	}
+	p.s.Next()
+	if p.s.Next() != '.' {
+		p.errorf("expected ...")
+		return false
+	}
+	return true
+}
+
+func (p *parser) token() token {
+	s, err := strconv.Unquote(p.lit)
+	if err != nil || p.tok != scanner.String && p.tok != scanner.RawString {
+		p.errorf("expected token, found %q", p.lit)
+		return ""
+	}
+	p.next()
+	return token(s)
+}
+
+// Define gives the lexical production name the regular expression expr,
+// in the syntax of package regexp, in place of its definition in g.
+func (g *Grammar) Define(name, expr string) {
+	g.defined[name] = expr
+}
+
+// lexical returns true if the production name is lexical.
+func lexical(name string) bool {
+	r, _ := utf8.DecodeRuneInString(name)
+	return !unicode.IsUpper(r)
+}
+
+// Terminal is a terminal of a grammar.
+type Terminal struct {
+	Name    string // production name, or literal token quoted as in Go
+	Literal bool   // the terminal is a literal token
+	Pattern string // regular expression matching the terminal
+}
+
+// Terminals returns the terminals of the syntactic productions reachable
+// from the production start, in the order they are first referenced.
+func (g *Grammar) Terminals(start string) ([]Terminal, error) {
+	prod, ok := g.prods[start]
+	if !ok {
+		return nil, &Error{Msg: fmt.Sprintf("missing production %s", start)}
+	}
+	if lexical(start) {
+		return nil, &Error{prod.pos, fmt.Sprintf("start production %s is lexical", start)}
+	}
+	w := &walker{g: g, visited: make(map[string]bool), seen: make(map[string]bool)}
+	w.visited[start] = true
+	w.walk(prod.expr)
+	return w.terms, w.err
+}
+
+// walker collects the terminals of syntactic productions.
+type walker struct {
+	g       *Grammar
+	visited map[string]bool // syntactic productions
+	seen    map[string]bool // terminals
+	terms   []Terminal
+	err     error
+}
+
+func (w *walker) add(t Terminal) {
+	if !w.seen[t.Name] {
+		w.seen[t.Name] = true
+		w.terms = append(w.terms, t)
+	}
+}
+
+func (w *walker) walk(x expr) {
+	if w.err != nil {
+		return
+	}
+	switch x := x.(type) {
+	case alternative:
+		for _, y := range x {
+			w.walk(y)
+		}
+	case sequence:
+		for _, y := range x {
+			w.walk(y)
+		}
+	case group:
+		w.walk(x.x)
+	case option:
+		w.walk(x.x)
+	case repeat:
+		w.walk(x.x)
+	case token:
+		w.add(Terminal{Name: strconv.Quote(string(x)), Literal: true, Pattern: regexp.QuoteMeta(string(x))})
+	case span:
+		w.add(Terminal{Name: fmt.Sprintf("%q … %q", x.lo, x.hi), Literal: true, Pattern: x.pattern()})
+	case name:
+		prod, ok := w.g.prods[x.name]
+		if !ok {
+			w.err = &Error{x.pos, fmt.Sprintf("missing production %s", x.name)}
+			return
+		}
+		if lexical(x.name) {
+			if !w.seen[x.name] {
+				var pattern string
+				pattern, w.err = w.g.pattern(prod, nil)
+				w.add(Terminal{Name: x.name, Pattern: pattern})
+			}
+			return
+		}
+		if !w.visited[x.name] {
+			w.visited[x.name] = true
+			w.walk(prod.expr)
+		}
+	}
+}
+
+func (s span) pattern() string {
+	return fmt.Sprintf(`[\x{%x}-\x{%x}]`, s.lo, s.hi)
+}
+
+// pattern returns the regular expression of the lexical production prod.
+// expanding lists the productions being expanded, to detect recursion.
+func (g *Grammar) pattern(prod *production, expanding []string) (string, error) {
+	if expr, ok := g.defined[prod.name]; ok {
+		return expr, nil
+	}
+	for _, name := range expanding {
+		if name == prod.name {
+			return "", &Error{prod.pos, fmt.Sprintf("lexical production %s is recursive", prod.name)}
+		}
+	}
+	if prod.expr == nil {
+		return "", &Error{prod.pos, fmt.Sprintf("lexical production %s has no expression; give it one with Define", prod.name)}
+	}
+	var buf strings.Builder
+	err := g.expand(&buf, prod.expr, append(expanding, prod.name))
+	return buf.String(), err
+}
+
+// expand writes the regular expression of x, an expression of a lexical
+// production, to buf.
+func (g *Grammar) expand(buf *strings.Builder, x expr, expanding []string) error {
+	switch x := x.(type) {
+	case alternative:
+		buf.WriteString("(?:")
+		for i, y := range x {
+			if i > 0 {
+				buf.WriteString("|")
+			}
+			if err := g.expand(buf, y, expanding); err != nil {
+				return err
+			}
+		}
+		buf.WriteString(")")
+	case sequence:
+		for _, y := range x {
+			if err := g.expand(buf, y, expanding); err != nil {
+				return err
+			}
+		}
+	case group:
+		buf.WriteString("(?:")
+		if err := g.expand(buf, x.x, expanding); err != nil {
+			return err
+		}
+		buf.WriteString(")")
+	case option, repeat:
+		buf.WriteString("(?:")
+		var err error
+		if o, ok := x.(option); ok {
+			err = g.expand(buf, o.x, expanding)
+			buf.WriteString(")?")
+		} else {
+			err = g.expand(buf, x.(repeat).x, expanding)
+			buf.WriteString(")*")
+		}
+		return err
+	case token:
+		buf.WriteString("(?:" + regexp.QuoteMeta(string(x)) + ")")
+	case span:
+		buf.WriteString(x.pattern())
+	case name:
+		prod, ok := g.prods[x.name]
+		if !ok {
+			return &Error{x.pos, fmt.Sprintf("missing production %s", x.name)}
+		}
+		if !lexical(x.name) {
+			return &Error{x.pos, fmt.Sprintf("lexical production %s refers to syntactic production %s", expanding[len(expanding)-1], x.name)}
+		}
+		expr, err := g.pattern(prod, expanding)
+		if err != nil {
+			return err
+		}
+		buf.WriteString("(?:" + expr + ")")
+	}
+	return nil
+}
+
+// Builder returns a Builder with a rule for each terminal of the syntactic
+// productions reachable from start.  The item type of each terminal is
+// looked up in types by its Name.  When types is nil terminals are numbered
+// from one in the order returned by Terminals.  Literal tokens are given a
+// priority above lexical productions, so that keywords win over
+// identifiers.  Builder adds no rules for white space or comments, which
+// grammars in this notation leave implicit.
+func (g *Grammar) Builder(start string, types map[string]lexer.ItemType) (*rules.Builder, error) {
+	terms, err := g.Terminals(start)
+	if err != nil {
+		return nil, err
+	}
+	b := rules.NewBuilder()
+	for i, term := range terms {
+		t, ok := lexer.ItemType(i+1), true
+		if types != nil {
+			t, ok = types[term.Name]
+		}
+		if !ok {
+			return nil, &Error{Msg: fmt.Sprintf("no item type for terminal %s", term.Name)}
+		}
+		r := b.Pattern(t, term.Pattern)
+		if term.Literal {
+			r.Priority(1)
+		}
+	}
+	return b, nil
+}
@@ -0,0 +1,110 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package ebnf
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+const calc = `
+Program    = { Statement } .
+Statement  = "let" ident "=" Expression ";" | Expression ";" .
+Expression = Term { ( "+" | "-" ) Term } .
+Term       = ident | number | string | "(" Expression ")" .
+ident      = letter { letter | digit } .
+number     = digit { digit } [ "." digit { digit } ] .
+string     = quote { char } quote .
+letter     = "a" … "z" | "A" ... "Z" | "_" .
+digit      = "0" … "9" .
+quote      = ` + "`\"`" + ` .
+char       = /* any character except a quote */ .
+`
+
+func TestTerminals(t *testing.T) {
+	g, err := Parse("calc.ebnf", strings.NewReader(calc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Terminals("Program"); err == nil || err.Error() != "calc.ebnf:12:1: lexical production char has no expression; give it one with Define" {
+		t.Errorf("error %v", err)
+	}
+	g.Define("char", `[^"]`)
+	terms, err := g.Terminals("Program")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, term := range terms {
+		names = append(names, term.Name)
+	}
+	expect := []string{`"let"`, "ident", `"="`, "number", "string", `"("`, `")"`, `"+"`, `"-"`, `";"`}
+	if !reflect.DeepEqual(names, expect) {
+		t.Errorf("terminals %q (expected %q)", names, expect)
+	}
+	if p := terms[1].Pattern; p != `(?:(?:[\x{61}-\x{7a}]|[\x{41}-\x{5a}]|(?:_)))(?:(?:(?:(?:[\x{61}-\x{7a}]|[\x{41}-\x{5a}]|(?:_)))|(?:[\x{30}-\x{39}])))*` {
+		t.Errorf("pattern %s", p)
+	}
+
+	b, err := g.Builder("Program", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b.Skip(`[ \t\n]+`)
+	start, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lex := lexer.New(start, `let x1 = (y + 2.5) - "s";`)
+	var items []string
+	for {
+		item := lex.Next()
+		if item.Type == lexer.ItemEOF {
+			break
+		}
+		items = append(items, fmt.Sprintf("%s:%q", terms[item.Type-1].Name, item.Value))
+	}
+	expect = []string{
+		`"let":"let"`, `ident:"x1"`, `"=":"="`, `"(":"("`, `ident:"y"`, `"+":"+"`,
+		`number:"2.5"`, `")":")"`, `"-":"-"`, `string:"\"s\""`, `";":";"`,
+	}
+	if !reflect.DeepEqual(items, expect) {
+		t.Errorf("items\n\t%q\n(expected)\n\t%q", items, expect)
+	}
+}
+
+func TestErrors(t *testing.T) {
+	for _, test := range []struct {
+		grammar, start, err string
+	}{
+		{`A = "a" `, "A", `g:1:9: expected ".", found ""`},
+		{`A = "b" … "a" .`, "A", `g:1:9: invalid range "b" … "a"`},
+		{`A = "a" . A = "b" .`, "A", `g:1:11: A redeclared (previously declared at g:1:1)`},
+		{`A = b .`, "A", `g:1:5: missing production b`},
+		{`A = b . b = "x" b .`, "A", `g:1:9: lexical production b is recursive`},
+		{`A = b . b = C . C = "c" .`, "A", `g:1:13: lexical production b refers to syntactic production C`},
+		{`a = "a" .`, "a", `g:1:1: start production a is lexical`},
+		{`A = "a" .`, "B", `missing production B`},
+	} {
+		g, err := Parse("g", strings.NewReader(test.grammar))
+		if err == nil {
+			_, err = g.Terminals(test.start)
+		}
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%s: error %v (expected %q)", test.grammar, err, test.err)
+		}
+	}
+	g, err := Parse("g", strings.NewReader(`A = "a" b . b = "b" .`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := g.Builder("A", map[string]lexer.ItemType{"b": 1}); err == nil || err.Error() != `no item type for terminal "a"` {
+		t.Errorf("error %v", err)
+	}
+}
@@ -0,0 +1,492 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package flex imports the token definitions of flex (and lex) scanners into
+the rules engine of package rules.
+
+	spec, err := flex.Parse(file)
+	b, err := spec.Builder(nil)
+	start, err := b.Build()
+
+Parse understands a subset of flex input: name definitions and start
+condition declarations (%s and %x) in the definitions section, and rules
+whose actions return a token, switch start conditions or do nothing.
+
+	DIGIT   [0-9]
+	%x STR
+	%%
+	{DIGIT}+        { return NUMBER; }
+	\"              { BEGIN(STR); return QUOTE; }
+	<STR>\"         { BEGIN(INITIAL); return QUOTE; }
+	<STR>[^"]+      return TEXT;
+	[ \t\n]+        ;
+
+Actions may also call yy_push_state and yy_pop_state, and the action "|"
+shares the action of the next rule.  Code blocks, %option lines and the
+user code section are ignored.  Trailing context, <<EOF>> rules and actions
+containing other statements are rejected.
+
+Flex prefers the first listed of the rules matching the longest text, so
+each rule is given a priority above the rules following it.
+*/
+package flex
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"regexp"
+	"strings"
+	"unicode"
+
+	"github.com/bmatsuo/go-lexer"
+	"github.com/bmatsuo/go-lexer/rules"
+)
+
+// Spec is the token definitions of a flex scanner.
+type Spec struct {
+	Inclusive []string // start conditions declared with %s
+	Exclusive []string // start conditions declared with %x
+	Rules     []Rule
+}
+
+// Rule is a rule of a flex scanner.
+type Rule struct {
+	Line    int      // line of the rule in the input
+	Conds   []string // start conditions of the rule, if any
+	Pattern string   // pattern translated to the syntax of package regexp
+	Token   string   // token returned by the action, or "" to skip the text
+	Begin   string   // condition entered by BEGIN, if any
+	Push    string   // condition entered by yy_push_state, if any
+	Pop     bool     // the action calls yy_pop_state
+}
+
+// Error is a problem in flex input.
+type Error struct {
+	Line int
+	Msg  string
+}
+
+func (err *Error) Error() string {
+	return fmt.Sprintf("line %d: %s", err.Line, err.Msg)
+}
+
+// Parse reads flex input from r.  The error, if any, is an *Error or an
+// error reading r.
+func Parse(r io.Reader) (*Spec, error) {
+	p := &parser{
+		scan: bufio.NewScanner(r),
+		defs: make(map[string]string),
+		spec: new(Spec),
+	}
+	if err := p.definitions(); err != nil {
+		return nil, err
+	}
+	if err := p.rules(); err != nil {
+		return nil, err
+	}
+	return p.spec, nil
+}
+
+type parser struct {
+	scan *bufio.Scanner
+	line int
+	defs map[string]string
+	spec *Spec
+}
+
+func (p *parser) next() (string, bool) {
+	if !p.scan.Scan() {
+		return "", false
+	}
+	p.line++
+	return p.scan.Text(), true
+}
+
+func (p *parser) errorf(format string, v ...interface{}) error {
+	return &Error{p.line, fmt.Sprintf(format, v...)}
+}
+
+// skipBlock skips the lines of a %{ ... %} code block.
+func (p *parser) skipBlock() error {
+	for {
+		line, ok := p.next()
+		if !ok {
+			return p.errorf("unterminated %%{ block")
+		}
+		if strings.HasPrefix(line, "%}") {
+			return nil
+		}
+	}
+}
+
+// definitions reads the definitions section, through the first "%%".
+func (p *parser) definitions() error {
+	for {
+		line, ok := p.next()
+		if !ok {
+			if err := p.scan.Err(); err != nil {
+				return err
+			}
+			return p.errorf("missing %%%% separating the rules")
+		}
+		fields := strings.Fields(line)
+		switch {
+		case strings.HasPrefix(line, "%%"):
+			return nil
+		case strings.HasPrefix(line, "%{"):
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+		case len(fields) == 0, line[0] == ' ', line[0] == '\t', strings.HasPrefix(line, "/*"):
+			// code and comments
+		case fields[0] == "%s", fields[0] == "%x":
+			if fields[0] == "%s" {
+				p.spec.Inclusive = append(p.spec.Inclusive, fields[1:]...)
+			} else {
+				p.spec.Exclusive = append(p.spec.Exclusive, fields[1:]...)
+			}
+		case line[0] == '%':
+			// %option, %pointer and the like
+		default:
+			name := fields[0]
+			expr, rest, err := p.pattern(strings.TrimSpace(line[len(name):]))
+			if err != nil {
+				return err
+			}
+			if rest != "" {
+				return p.errorf("unexpected %q after the definition of %s", rest, name)
+			}
+			p.defs[name] = expr
+		}
+	}
+}
+
+// rules reads the rules section, through the second "%%" if any.
+func (p *parser) rules() error {
+	var pending []Rule // rules with the action "|"
+	for {
+		line, ok := p.next()
+		if !ok || strings.HasPrefix(line, "%%") {
+			if len(pending) > 0 {
+				return &Error{pending[0].Line, `the last rule has the action "|"`}
+			}
+			return p.scan.Err()
+		}
+		switch {
+		case strings.HasPrefix(line, "%{"):
+			if err := p.skipBlock(); err != nil {
+				return err
+			}
+			continue
+		case strings.TrimSpace(line) == "", line[0] == ' ', line[0] == '\t':
+			continue
+		}
+		rule := Rule{Line: p.line}
+		if strings.Contains(line, "<<EOF>>") {
+			return p.errorf("<<EOF>> rules are not supported")
+		}
+		if line[0] == '<' {
+			end := strings.IndexByte(line, '>')
+			if end < 0 {
+				return p.errorf("unterminated start condition list")
+			}
+			rule.Conds = strings.Split(line[1:end], ",")
+			line = line[end+1:]
+		}
+		expr, action, err := p.pattern(line)
+		if err != nil {
+			return err
+		}
+		rule.Pattern = expr
+		for strings.Count(action, "{") > strings.Count(action, "}") {
+			more, ok := p.next()
+			if !ok {
+				return &Error{rule.Line, "unterminated action"}
+			}
+			action += "\n" + more
+		}
+		if action == "|" {
+			pending = append(pending, rule)
+			continue
+		}
+		if err := p.action(&rule, action); err != nil {
+			return err
+		}
+		for _, r := range pending {
+			r.Token, r.Begin, r.Push, r.Pop = rule.Token, rule.Begin, rule.Push, rule.Pop
+			p.spec.Rules = append(p.spec.Rules, r)
+		}
+		pending = pending[:0]
+		p.spec.Rules = append(p.spec.Rules, rule)
+	}
+}
+
+var (
+	returnStmt = regexp.MustCompile(`^return\s+([A-Za-z_][A-Za-z0-9_]*)$`)
+	beginStmt  = regexp.MustCompile(`^BEGIN\s*\(?\s*([A-Za-z_][A-Za-z0-9_]*)\s*\)?$`)
+	pushStmt   = regexp.MustCompile(`^yy_push_state\s*\(\s*([A-Za-z_][A-Za-z0-9_]*)\s*\)$`)
+	popStmt    = regexp.MustCompile(`^yy_pop_state\s*\(\s*\)$`)
+	comment    = regexp.MustCompile(`(?s)/\*.*?\*/`)
+)
+
+// action sets the effects of the action of r.
+func (p *parser) action(r *Rule, action string) error {
+	action = strings.TrimSpace(comment.ReplaceAllString(action, ""))
+	if strings.HasPrefix(action, "{") && strings.HasSuffix(action, "}") {
+		action = action[1 : len(action)-1]
+	}
+	for _, stmt := range strings.Split(action, ";") {
+		stmt = strings.TrimSpace(stmt)
+		if m := returnStmt.FindStringSubmatch(stmt); m != nil {
+			r.Token = m[1]
+		} else if m := beginStmt.FindStringSubmatch(stmt); m != nil {
+			r.Begin = m[1]
+		} else if m := pushStmt.FindStringSubmatch(stmt); m != nil {
+			r.Push = m[1]
+		} else if popStmt.MatchString(stmt) {
+			r.Pop = true
+		} else if stmt != "" {
+			return &Error{r.Line, fmt.Sprintf("unsupported action %q", stmt)}
+		}
+	}
+	return nil
+}
+
+// pattern translates the flex pattern at the beginning of s, which ends at
+// the first unquoted white space, and returns the rest of s without leading
+// white space.
+func (p *parser) pattern(s string) (expr, rest string, err error) {
+	var buf strings.Builder
+	i := 0
+	for i < len(s) && s[i] != ' ' && s[i] != '\t' {
+		switch c := s[i]; c {
+		case '\\':
+			if i+1 == len(s) {
+				return "", "", p.errorf("pattern ends with a backslash")
+			}
+			buf.WriteString(s[i : i+2])
+			i += 2
+		case '"':
+			buf.WriteString("(?:")
+			j := i + 1
+			for ; j < len(s) && s[j] != '"'; j++ {
+				if s[j] == '\\' && j+1 < len(s) {
+					j++
+					buf.WriteString(unescape(s[j]))
+					continue
+				}
+				buf.WriteString(regexp.QuoteMeta(s[j : j+1]))
+			}
+			if j == len(s) {
+				return "", "", p.errorf("unterminated string in pattern")
+			}
+			buf.WriteString(")")
+			i = j + 1
+		case '[':
+			j := classEnd(s, i)
+			if j < 0 {
+				return "", "", p.errorf("unterminated character class in pattern")
+			}
+			buf.WriteString(s[i:j])
+			i = j
+		case '{':
+			j := strings.IndexByte(s[i:], '}')
+			if j < 0 {
+				return "", "", p.errorf("unterminated { in pattern")
+			}
+			name := s[i+1 : i+j]
+			if strings.IndexFunc(name, func(r rune) bool { return r != ',' && !unicode.IsDigit(r) }) < 0 {
+				buf.WriteString(s[i : i+j+1])
+			} else if def, ok := p.defs[name]; ok {
+				buf.WriteString("(?:" + def + ")")
+			} else {
+				return "", "", p.errorf("undefined name %q", name)
+			}
+			i += j + 1
+		case '/':
+			return "", "", p.errorf("trailing context is not supported")
+		default:
+			buf.WriteByte(c)
+			i++
+		}
+	}
+	if buf.Len() == 0 {
+		return "", "", p.errorf("missing pattern")
+	}
+	return buf.String(), strings.TrimSpace(s[i:]), nil
+}
+
+// unescape returns a regular expression matching the character denoted by
+// the escape sequence \c in a quoted string.
+func unescape(c byte) string {
+	if strings.IndexByte("ntrfv", c) >= 0 {
+		return `\` + string(c)
+	}
+	return regexp.QuoteMeta(string(c))
+}
+
+// classEnd returns the index following the character class beginning at
+// s[i], or -1 if the class is unterminated.
+func classEnd(s string, i int) int {
+	j := i + 1
+	if j < len(s) && s[j] == '^' {
+		j++
+	}
+	if j < len(s) && s[j] == ']' {
+		j++
+	}
+	for ; j < len(s); j++ {
+		switch {
+		case s[j] == '\\':
+			j++
+		case strings.HasPrefix(s[j:], "[:"):
+			if k := strings.Index(s[j:], ":]"); k >= 0 {
+				j += k + 1
+			}
+		case s[j] == ']':
+			return j + 1
+		}
+	}
+	return -1
+}
+
+// Tokens returns the names of the tokens returned by the rules of s in the
+// order of their first use.
+func (s *Spec) Tokens() []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, r := range s.Rules {
+		if r.Token != "" && !seen[r.Token] {
+			seen[r.Token] = true
+			names = append(names, r.Token)
+		}
+	}
+	return names
+}
+
+// types returns item types for the tokens of s numbered from one in the
+// order of Tokens.
+func (s *Spec) types() map[string]lexer.ItemType {
+	types := make(map[string]lexer.ItemType)
+	for i, name := range s.Tokens() {
+		types[name] = lexer.ItemType(i + 1)
+	}
+	return types
+}
+
+// Builder returns a Builder holding the rules of s.  The item type of each
+// token is looked up in types.  When types is nil tokens are numbered from
+// one in the order of Tokens.
+func (s *Spec) Builder(types map[string]lexer.ItemType) (*rules.Builder, error) {
+	if types == nil {
+		types = s.types()
+	}
+	b := rules.NewBuilder()
+	b.Inclusive(s.Inclusive...)
+	b.Exclusive(s.Exclusive...)
+	for i, r := range s.Rules {
+		var rule *rules.Rule
+		if r.Token == "" {
+			rule = b.Skip(r.Pattern)
+		} else if t, ok := types[r.Token]; ok {
+			rule = b.Pattern(t, r.Pattern)
+		} else {
+			return nil, &Error{r.Line, fmt.Sprintf("no item type for token %s", r.Token)}
+		}
+		r.apply(rule, len(s.Rules)-i)
+	}
+	return b, nil
+}
+
+func (r *Rule) apply(rule *rules.Rule, priority int) {
+	rule.Priority(priority)
+	if len(r.Conds) > 0 {
+		rule.In(r.Conds...)
+	}
+	switch {
+	case r.Begin != "":
+		rule.Begin(cond(r.Begin))
+	case r.Push != "":
+		rule.Push(cond(r.Push))
+	case r.Pop:
+		rule.Pop()
+	}
+}
+
+// cond returns the name used by package rules for the flex condition name.
+func cond(name string) string {
+	if name == "0" {
+		return rules.Initial
+	}
+	return name
+}
+
+// Generate returns the source of a Go file in package pkg declaring a
+// constant of type lexer.ItemType for each token of s, numbered as by
+// Builder, and a function Rules returning a Builder holding the rules of s.
+func (s *Spec) Generate(pkg string) ([]byte, error) {
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated from flex definitions. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintf(&buf, "import (\n\t\"github.com/bmatsuo/go-lexer\"\n\t\"github.com/bmatsuo/go-lexer/rules\"\n)\n\n")
+	if tokens := s.Tokens(); len(tokens) > 0 {
+		fmt.Fprintf(&buf, "const (\n\t%s lexer.ItemType = iota + 1\n", tokens[0])
+		for _, name := range tokens[1:] {
+			fmt.Fprintf(&buf, "\t%s\n", name)
+		}
+		fmt.Fprintf(&buf, ")\n\n")
+	} else {
+		fmt.Fprintf(&buf, "var _ lexer.ItemType\n\n")
+	}
+	fmt.Fprintf(&buf, "// Rules returns a Builder holding the rules of the lexer.\n")
+	fmt.Fprintf(&buf, "func Rules() *rules.Builder {\n\tb := rules.NewBuilder()\n")
+	if len(s.Inclusive) > 0 {
+		fmt.Fprintf(&buf, "\tb.Inclusive(%s)\n", quoteList(s.Inclusive))
+	}
+	if len(s.Exclusive) > 0 {
+		fmt.Fprintf(&buf, "\tb.Exclusive(%s)\n", quoteList(s.Exclusive))
+	}
+	for i, r := range s.Rules {
+		if r.Token == "" {
+			fmt.Fprintf(&buf, "\tb.Skip(%s)", quote(r.Pattern))
+		} else {
+			fmt.Fprintf(&buf, "\tb.Pattern(%s, %s)", r.Token, quote(r.Pattern))
+		}
+		fmt.Fprintf(&buf, ".Priority(%d)", len(s.Rules)-i)
+		if len(r.Conds) > 0 {
+			fmt.Fprintf(&buf, ".In(%s)", quoteList(r.Conds))
+		}
+		switch {
+		case r.Begin != "":
+			fmt.Fprintf(&buf, ".Begin(%q)", cond(r.Begin))
+		case r.Push != "":
+			fmt.Fprintf(&buf, ".Push(%q)", cond(r.Push))
+		case r.Pop:
+			fmt.Fprintf(&buf, ".Pop()")
+		}
+		fmt.Fprintf(&buf, "\n")
+	}
+	fmt.Fprintf(&buf, "\treturn b\n}\n")
+	return format.Source(buf.Bytes())
+}
+
+// quote returns a Go string literal for s, preferring a raw string.
+func quote(s string) string {
+	if strings.ContainsAny(s, "`\r") {
+		return fmt.Sprintf("%q", s)
+	}
+	return "`" + s + "`"
+}
+
+func quoteList(names []string) string {
+	q := make([]string, len(names))
+	for i, name := range names {
+		q[i] = fmt.Sprintf("%q", cond(name))
+	}
+	return strings.Join(q, ", ")
+}
@@ -0,0 +1,154 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package flex
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+const calc = `%{
+#include "calc.tab.h"
+%}
+%option noyywrap
+DIGIT    [0-9]
+ID       [a-z][a-z0-9]*
+%x COMMENT STR
+%%
+{DIGIT}+("."{DIGIT}+)?  { return NUMBER; }
+"if"                    return IF;
+{ID}                    {
+                            return ID;
+                        }
+"+"                     |
+"-"                     return OP;
+"/*"                    BEGIN(COMMENT);
+<COMMENT>"*/"           BEGIN(INITIAL);
+<COMMENT>[^*]+|"*"      ;
+\"                      { yy_push_state(STR); return QUOTE; }
+<STR>\"                 { yy_pop_state(); return QUOTE; }
+<STR>[^"\n]+            return TEXT;
+[ \t\n]+                /* skip */ ;
+%%
+int main() { return yylex(); }
+`
+
+func lexAll(input string) []string {
+	spec, err := Parse(strings.NewReader(calc))
+	if err != nil {
+		panic(err)
+	}
+	b, err := spec.Builder(nil)
+	if err != nil {
+		panic(err)
+	}
+	start, err := b.Build()
+	if err != nil {
+		panic(err)
+	}
+	lex := lexer.New(start, input)
+	var items []string
+	for {
+		item := lex.Next()
+		if item.Type == lexer.ItemEOF {
+			return items
+		}
+		items = append(items, fmt.Sprintf("%d:%d:%q", item.Type, item.Pos, item.Value))
+	}
+}
+
+func TestParse(t *testing.T) {
+	spec, err := Parse(strings.NewReader(calc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tokens := spec.Tokens(); !reflect.DeepEqual(tokens, []string{"NUMBER", "IF", "ID", "OP", "QUOTE", "TEXT"}) {
+		t.Errorf("tokens %q", tokens)
+	}
+	if len(spec.Rules) != 12 {
+		t.Fatalf("%d rules", len(spec.Rules))
+	}
+	for i, expect := range []Rule{
+		{Line: 9, Pattern: `(?:[0-9])+((?:\.)(?:[0-9])+)?`, Token: "NUMBER"},
+		{Line: 14, Pattern: `(?:\+)`, Token: "OP"},
+		{Line: 16, Pattern: `(?:/\*)`, Begin: "COMMENT"},
+		{Line: 18, Conds: []string{"COMMENT"}, Pattern: `[^*]+|(?:\*)`},
+		{Line: 19, Pattern: `\"`, Token: "QUOTE", Push: "STR"},
+		{Line: 20, Conds: []string{"STR"}, Pattern: `\"`, Token: "QUOTE", Pop: true},
+	} {
+		var r Rule
+		for _, r = range spec.Rules {
+			if r.Line == expect.Line {
+				break
+			}
+		}
+		if !reflect.DeepEqual(r, expect) {
+			t.Errorf("%d: rule %+v (expected %+v)", i, r, expect)
+		}
+	}
+	items := lexAll(`if x1 + 2.5 /* c * d */ - "a b"`)
+	expect := []string{
+		`2:0:"if"`, `3:3:"x1"`, `4:6:"+"`, `1:8:"2.5"`, `4:24:"-"`,
+		`5:26:"\""`, `6:27:"a b"`, `5:30:"\""`,
+	}
+	if !reflect.DeepEqual(items, expect) {
+		t.Errorf("items\n\t%q\n(expected)\n\t%q", items, expect)
+	}
+}
+
+func TestGenerate(t *testing.T) {
+	spec, err := Parse(strings.NewReader(calc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	src, err := spec.Generate("calc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"\tNUMBER lexer.ItemType = iota + 1\n",
+		"\tb.Exclusive(\"COMMENT\", \"STR\")\n",
+		"\tb.Pattern(ID, `(?:[a-z][a-z0-9]*)`).Priority(10)\n",
+		"\tb.Skip(`(?:\\*/)`).Priority(6).In(\"COMMENT\").Begin(\"INITIAL\")\n",
+		"\tb.Pattern(QUOTE, `\\\"`).Priority(3).In(\"STR\").Pop()\n",
+	} {
+		if !strings.Contains(string(src), line) {
+			t.Errorf("missing %q in\n%s", line, src)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, test := range []struct {
+		input string
+		err   string
+	}{
+		{"a [a]\n", "line 1: missing %% separating the rules"},
+		{"%%\n{X}+ return X;\n", `line 2: undefined name "X"`},
+		{"%%\nab/c return X;\n", "line 2: trailing context is not supported"},
+		{"%%\n<<EOF>> return 0;\n", "line 2: <<EOF>> rules are not supported"},
+		{"%%\na ECHO;\n", `line 2: unsupported action "ECHO"`},
+		{"%%\na {\nreturn X;\n", "line 2: unterminated action"},
+		{"%%\n\"ab return X;\n", "line 2: unterminated string in pattern"},
+		{"%%\n[ab return X;\n", "line 2: unterminated character class in pattern"},
+		{"%%\na |\n", `line 2: the last rule has the action "|"`},
+	} {
+		_, err := Parse(strings.NewReader(test.input))
+		if err == nil || err.Error() != test.err {
+			t.Errorf("%q: error %v (expected %q)", test.input, err, test.err)
+		}
+	}
+	spec, err := Parse(strings.NewReader("%%\na return A;\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := spec.Builder(map[string]lexer.ItemType{"B": 1}); err == nil || err.Error() != "line 2: no item type for token A" {
+		t.Errorf("error %v", err)
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"strconv"
+)
+
+// Generate validates the rules of b and returns the source of a Go file in
+// package pkg implementing the lexer built from them, which needs no
+// compilation of the rules when the program runs.  The automata of the
+// start conditions are written as static tables and the effects of the
+// rules as a switch statement.  The file declares the start state Start and
+// unexported identifiers beginning with "lex".  Rules with an Action cannot
+// be generated.  The error, if any, is a *BuildError.
+func (b *Builder) Generate(pkg string) ([]byte, error) {
+	for _, r := range b.rules {
+		if r.action != nil {
+			return nil, &BuildError{[]string{fmt.Sprintf("%v has an action, which cannot be generated", r)}}
+		}
+	}
+	machines, rules, err := b.build()
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "// Code generated by rules.Builder.Generate. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	fmt.Fprintf(&buf, "import (\n\t\"sort\"\n\t\"unicode/utf8\"\n\n\t\"github.com/bmatsuo/go-lexer\"\n)\n\n")
+	fmt.Fprintf(&buf, `// Start is the start state of the lexer.
+func Start(l *lexer.Lexer) lexer.StateFn {
+	s := new(lexScanner)
+	return s.scan
+}
+
+// lexConds holds the names of the start conditions.
+var lexConds = [...]string{`)
+	for _, m := range machines {
+		fmt.Fprintf(&buf, "%q, ", m.name)
+	}
+	fmt.Fprintf(&buf, "}\n\n// lexClasses holds the lower bounds of the rune classes of each start\n// condition.\nvar lexClasses = [...][]rune{\n")
+	for _, m := range machines {
+		fmt.Fprintf(&buf, "\t{")
+		for _, r := range m.d.classes {
+			fmt.Fprintf(&buf, "%d, ", r)
+		}
+		fmt.Fprintf(&buf, "},\n")
+	}
+	fmt.Fprintf(&buf, "}\n\n// lexNext holds the transitions of each start condition, indexed by state\n// and rune class.\nvar lexNext = [...][][]int16{\n")
+	for _, m := range machines {
+		fmt.Fprintf(&buf, "\t{\n")
+		for _, s := range m.d.states {
+			fmt.Fprintf(&buf, "\t\t{")
+			for _, q := range s.next {
+				fmt.Fprintf(&buf, "%d, ", q)
+			}
+			fmt.Fprintf(&buf, "},\n")
+		}
+		fmt.Fprintf(&buf, "\t},\n")
+	}
+	fmt.Fprintf(&buf, "}\n\n// lexWin holds the rule winning a match ending in each state of each start\n// condition, or -1.\nvar lexWin = [...][]int{\n")
+	for _, m := range machines {
+		fmt.Fprintf(&buf, "\t{")
+		for _, s := range m.d.states {
+			w := -1
+			if s.win >= 0 {
+				w = m.rules[s.win].index
+			}
+			fmt.Fprintf(&buf, "%d, ", w)
+		}
+		fmt.Fprintf(&buf, "},\n")
+	}
+	fmt.Fprintf(&buf, `}
+
+type lexScanner struct {
+	cond  int
+	stack []int
+}
+
+func (s *lexScanner) scan(l *lexer.Lexer) lexer.StateFn {
+	rest := l.Input()[l.Pos():]
+	if rest == "" {
+		return nil
+	}
+	classes, next, win := lexClasses[s.cond], lexNext[s.cond], lexWin[s.cond]
+	best, n := -1, 0
+	for i, q := 0, 0; i < len(rest); {
+		r, size := utf8.DecodeRuneInString(rest[i:])
+		c := sort.Search(len(classes), func(j int) bool { return classes[j] > r }) - 1
+		if q = int(next[q][c]); q < 0 {
+			break
+		}
+		i += size
+		if w := win[q]; w >= 0 {
+			best, n = w, i
+		}
+	}
+	if best < 0 {
+		_, size := utf8.DecodeRuneInString(rest)
+		l.AcceptBytes(size)
+		l.Errorf("unexpected %%q", l.Current())
+		l.Ignore()
+		return s.scan
+	}
+	l.AcceptBytes(n)
+	switch best {
+`)
+	for _, r := range rules {
+		fmt.Fprintf(&buf, "\tcase %d: // %s\n", r.index, r)
+		if r.skip {
+			fmt.Fprintf(&buf, "\t\tl.Ignore()\n")
+		} else {
+			fmt.Fprintf(&buf, "\t\tl.Emit(%d)\n", r.typ)
+		}
+		switch r.op {
+		case opBegin:
+			fmt.Fprintf(&buf, "\t\ts.cond = %d // %s\n", r.next, strconv.Quote(r.target))
+		case opPush:
+			fmt.Fprintf(&buf, "\t\ts.stack = append(s.stack, s.cond)\n\t\ts.cond = %d // %s\n", r.next, strconv.Quote(r.target))
+		case opPop:
+			fmt.Fprintf(&buf, "\t\ts.pop(l)\n")
+		}
+	}
+	fmt.Fprintf(&buf, `	}
+	return s.scan
+}
+
+func (s *lexScanner) pop(l *lexer.Lexer) {
+	if len(s.stack) == 0 {
+		l.Errorf("%%s: start condition stack is empty", lexConds[s.cond])
+		return
+	}
+	s.cond = s.stack[len(s.stack)-1]
+	s.stack = s.stack[:len(s.stack)-1]
+}
+`)
+	return format.Source(buf.Bytes())
+}
@@ -0,0 +1,63 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"strings"
+	"testing"
+)
+
+func TestGenerate(t *testing.T) {
+	b := NewBuilder()
+	b.Exclusive("str")
+	b.Literal(itemIf, "if").Priority(1)
+	b.Pattern(itemIdent, `[a-z]+`)
+	b.Literal(itemOp, `"`).Push("str")
+	b.Pattern(itemNumber, `[^"]+`).In("str")
+	b.Literal(itemOp, `"`).In("str").Pop()
+	b.Skip(` +`)
+	src, err := b.Generate("calc")
+	if err != nil {
+		t.Fatal(err)
+	}
+	fset := token.NewFileSet()
+	file, err := parser.ParseFile(fset, "calc.go", src, parser.AllErrors)
+	if err != nil {
+		t.Fatalf("generated source does not parse: %v\n%s", err, src)
+	}
+	conf := types.Config{Importer: importer.ForCompiler(fset, "source", nil)}
+	if _, err := conf.Check("calc", fset, []*ast.File{file}, nil); err != nil {
+		t.Errorf("generated source does not type check: %v\n%s", err, src)
+	}
+	for _, s := range []string{
+		"package calc\n",
+		"func Start(l *lexer.Lexer) lexer.StateFn {\n",
+		`var lexConds = [...]string{"INITIAL", "str"}`,
+		"\t{0, 32, 33, 34, 35, 97, 102, 103, 105, 106, 123},\n",
+		"\tcase 0: // rule 0 (literal \"if\")\n\t\tl.Emit(1)\n",
+		"\tcase 2: // rule 2 (literal \"\\\"\")\n\t\tl.Emit(4)\n\t\ts.stack = append(s.stack, s.cond)\n\t\ts.cond = 1 // \"str\"\n",
+		"\tcase 4: // rule 4 (literal \"\\\"\")\n\t\tl.Emit(4)\n\t\ts.pop(l)\n",
+		"\tcase 5: // rule 5 (skip \" +\")\n\t\tl.Ignore()\n",
+	} {
+		if !strings.Contains(string(src), s) {
+			t.Errorf("missing %q in\n%s", s, src)
+		}
+	}
+
+	b.Pattern(itemNumber, `[0-9]+`).Do(func(g []string) (string, error) { return g[0], nil })
+	if _, err := b.Generate("calc"); err == nil || !strings.Contains(err.Error(), "rule 6 (pattern \"[0-9]+\") has an action, which cannot be generated") {
+		t.Errorf("error %v", err)
+	}
+	b = NewBuilder()
+	b.Pattern(itemIdent, `a*`)
+	if _, err := b.Generate("calc"); err == nil {
+		t.Errorf("no error")
+	}
+}
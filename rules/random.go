@@ -0,0 +1,257 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"math/rand"
+	"regexp/syntax"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Sample is a token of input produced by a Generator.
+type Sample struct {
+	Type lexer.ItemType // type of the rule matching Text
+	Text string
+	Skip bool // Text is matched by a skip rule and emits no item
+}
+
+// Generator produces random input for the lexer built from a set of rules,
+// for stress testing parsers and seeding fuzz tests of a language.  The
+// input is determined by the seed, so that failures can be reproduced.
+type Generator struct {
+	rng      *rand.Rand
+	start    lexer.StateFn
+	machines []*machine
+	exprs    map[*Rule]*syntax.Regexp
+}
+
+// maxRepeat bounds the repetitions of unbounded operators such as * and +.
+const maxRepeat = 4
+
+// Generator validates the rules of b and returns a generator of input for
+// the lexer built from them, seeded by seed.  The error, if any, is a
+// *BuildError.
+func (b *Builder) Generator(seed int64) (*Generator, error) {
+	machines, rules, err := b.build()
+	if err != nil {
+		return nil, err
+	}
+	g := &Generator{
+		rng: rand.New(rand.NewSource(seed)),
+		start: func(l *lexer.Lexer) lexer.StateFn {
+			s := &scanner{machines: machines}
+			return s.scan
+		},
+		machines: machines,
+		exprs:    make(map[*Rule]*syntax.Regexp),
+	}
+	for _, r := range rules {
+		re, err := syntax.Parse(r.source(), syntax.Perl)
+		if err != nil {
+			return nil, err
+		}
+		g.exprs[r] = re.Simplify()
+	}
+	return g, nil
+}
+
+// Valid returns input of about n tokens which the lexer splits into the
+// returned samples without error.  Each token is generated from a rule
+// active in the start condition the lexer is in, and the text matched by a
+// skip rule separates tokens which would otherwise merge.  Fewer tokens are
+// returned if no more can be found that the lexer reads back as generated.
+func (g *Generator) Valid(n int) (string, []Sample) {
+	var text strings.Builder
+	var samples []Sample
+	cond, stack := 0, []int(nil)
+	for tries := 0; len(samples) < n && tries < 20*n+20; tries++ {
+		m := g.machines[cond]
+		r := m.rules[g.rng.Intn(len(m.rules))]
+		if r.op == opPop && len(stack) == 0 {
+			continue
+		}
+		tok := Sample{r.typ, g.sample(g.exprs[r]), r.skip}
+		next := append(samples[:len(samples):len(samples)], tok)
+		if !g.check(text.String()+tok.Text, next) {
+			sep, ok := g.separator(m)
+			if !ok {
+				continue
+			}
+			next = append(append(samples[:len(samples):len(samples)], sep), tok)
+			if !g.check(text.String()+sep.Text+tok.Text, next) {
+				continue
+			}
+			text.WriteString(sep.Text)
+		}
+		text.WriteString(tok.Text)
+		samples = next
+		switch r.op {
+		case opBegin:
+			cond = r.next
+		case opPush:
+			stack = append(stack, cond)
+			cond = r.next
+		case opPop:
+			cond, stack = stack[len(stack)-1], stack[:len(stack)-1]
+		}
+	}
+	return text.String(), samples
+}
+
+// Invalid returns input of about n tokens which is valid except for a
+// small random change, such as a deleted, inserted or replaced rune, which
+// makes the lexer report an error.  If no such change is found among many
+// tries the last change tried is returned, which may be valid.
+func (g *Generator) Invalid(n int) string {
+	valid, _ := g.Valid(n)
+	in := []rune(valid)
+	var text string
+	for tries := 0; tries < 100; tries++ {
+		mutated := append([]rune(nil), in...)
+		i := 0
+		if len(mutated) > 0 {
+			i = g.rng.Intn(len(mutated))
+		}
+		switch k := g.rng.Intn(3); {
+		case k == 0 && len(mutated) > 0:
+			mutated = append(mutated[:i], mutated[i+1:]...)
+		case k == 1 && len(mutated) > 0:
+			mutated[i] = g.anyRune()
+		default:
+			mutated = append(mutated[:i], append([]rune{g.anyRune()}, mutated[i:]...)...)
+		}
+		text = string(mutated)
+		lex := lexer.New(g.start, text)
+		for item := lex.Next(); item.Type != lexer.ItemEOF; item = lex.Next() {
+			if item.Type == lexer.ItemError {
+				return text
+			}
+		}
+	}
+	return text
+}
+
+// separator returns text matched by a skip rule active in m.
+func (g *Generator) separator(m *machine) (Sample, bool) {
+	var skips []*Rule
+	for _, r := range m.rules {
+		if r.skip && r.op == opNone {
+			skips = append(skips, r)
+		}
+	}
+	if len(skips) == 0 {
+		return Sample{}, false
+	}
+	r := skips[g.rng.Intn(len(skips))]
+	return Sample{r.typ, g.sample(g.exprs[r]), true}, true
+}
+
+// check reports whether the lexer splits text into the items of samples.
+func (g *Generator) check(text string, samples []Sample) bool {
+	lex := lexer.New(g.start, text)
+	pos := 0
+	for _, s := range samples {
+		if s.Skip {
+			pos += len(s.Text)
+			continue
+		}
+		item := lex.Next()
+		if item.Type != s.Type || item.Pos != pos {
+			return false
+		}
+		pos += len(s.Text)
+	}
+	return lex.Next().Type == lexer.ItemEOF
+}
+
+// sample returns random text matched by re.
+func (g *Generator) sample(re *syntax.Regexp) string {
+	var b strings.Builder
+	g.write(&b, re)
+	return b.String()
+}
+
+func (g *Generator) write(b *strings.Builder, re *syntax.Regexp) {
+	switch re.Op {
+	case syntax.OpLiteral:
+		for _, r := range re.Rune {
+			if re.Flags&syntax.FoldCase != 0 && g.rng.Intn(2) == 0 {
+				r = unicode.SimpleFold(r)
+			}
+			b.WriteRune(r)
+		}
+	case syntax.OpCharClass:
+		b.WriteRune(g.classRune(re.Rune))
+	case syntax.OpAnyCharNotNL:
+		b.WriteRune(rune(' ' + g.rng.Intn('~'-' '+1)))
+	case syntax.OpAnyChar:
+		b.WriteRune(g.anyRune())
+	case syntax.OpCapture:
+		g.write(b, re.Sub[0])
+	case syntax.OpConcat:
+		for _, sub := range re.Sub {
+			g.write(b, sub)
+		}
+	case syntax.OpAlternate:
+		g.write(b, re.Sub[g.rng.Intn(len(re.Sub))])
+	case syntax.OpStar, syntax.OpPlus, syntax.OpQuest, syntax.OpRepeat:
+		min, max := re.Min, re.Max
+		switch re.Op {
+		case syntax.OpStar:
+			min, max = 0, -1
+		case syntax.OpPlus:
+			min, max = 1, -1
+		case syntax.OpQuest:
+			min, max = 0, 1
+		}
+		if max < 0 {
+			max = min + maxRepeat
+		}
+		for n := min + g.rng.Intn(max-min+1); n > 0; n-- {
+			g.write(b, re.Sub[0])
+		}
+	}
+}
+
+// classRune returns a random rune of the class given as ranges, preferring
+// printable ASCII characters.
+func (g *Generator) classRune(ranges []rune) rune {
+	var ascii []rune
+	for i := 0; i < len(ranges); i += 2 {
+		lo, hi := ranges[i], ranges[i+1]
+		if lo < ' ' {
+			lo = ' '
+		}
+		if hi > '~' {
+			hi = '~'
+		}
+		if lo <= hi {
+			ascii = append(ascii, lo, hi)
+		}
+	}
+	if len(ascii) > 0 && g.rng.Intn(8) > 0 {
+		ranges = ascii
+	}
+	for {
+		i := 2 * g.rng.Intn(len(ranges)/2)
+		lo, hi := ranges[i], ranges[i+1]
+		r := lo + rune(g.rng.Int63n(int64(hi-lo)+1))
+		if utf8.ValidRune(r) {
+			return r
+		}
+	}
+}
+
+// anyRune returns a random rune, usually printable ASCII.
+func (g *Generator) anyRune() rune {
+	if g.rng.Intn(8) > 0 {
+		return rune(' ' + g.rng.Intn('~'-' '+1))
+	}
+	return g.classRune([]rune{0, unicode.MaxRune})
+}
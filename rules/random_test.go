@@ -0,0 +1,109 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+func generatorRules() *Builder {
+	b := NewBuilder()
+	b.Exclusive("str")
+	b.Literal(itemIf, "if").Priority(1)
+	b.Pattern(itemIdent, `(?i)[a-z_][a-z0-9_]*`)
+	b.Pattern(itemNumber, `[0-9]+(\.[0-9]+)?`)
+	b.Literal(itemOp, "=")
+	b.Literal(itemOp, "==")
+	b.Literal(itemOp, `"`).Push("str")
+	b.Pattern(itemNumber, `[^"\\]+|\\.`).In("str")
+	b.Literal(itemOp, `"`).In("str").Pop()
+	b.Skip(`[ \t\n]+`)
+	return b
+}
+
+func TestGeneratorValid(t *testing.T) {
+	for seed := int64(0); seed < 20; seed++ {
+		g, err := generatorRules().Generator(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text, samples := g.Valid(30)
+		if len(samples) < 30 {
+			t.Errorf("seed %d: %d samples", seed, len(samples))
+		}
+		var joined strings.Builder
+		var expect []lexer.ItemType
+		for _, s := range samples {
+			joined.WriteString(s.Text)
+			if !s.Skip {
+				expect = append(expect, s.Type)
+			}
+		}
+		if joined.String() != text {
+			t.Errorf("seed %d: samples %v do not join to %q", seed, samples, text)
+		}
+		start, _ := generatorRules().Build()
+		lex := lexer.New(start, text)
+		for i := 0; ; i++ {
+			item := lex.Next()
+			if item.Type == lexer.ItemEOF {
+				if i != len(expect) {
+					t.Errorf("seed %d: %d items (expected %d)", seed, i, len(expect))
+				}
+				break
+			}
+			if i >= len(expect) || item.Type != expect[i] {
+				t.Errorf("seed %d: item %d %v in %q", seed, i, item, text)
+				break
+			}
+		}
+	}
+}
+
+func TestGeneratorDeterministic(t *testing.T) {
+	g1, _ := generatorRules().Generator(7)
+	g2, _ := generatorRules().Generator(7)
+	for i := 0; i < 3; i++ {
+		a, _ := g1.Valid(10)
+		b, _ := g2.Valid(10)
+		if a != b {
+			t.Errorf("same seed generated %q and %q", a, b)
+		}
+		if a, b := g1.Invalid(10), g2.Invalid(10); a != b {
+			t.Errorf("same seed generated invalid %q and %q", a, b)
+		}
+	}
+}
+
+func TestGeneratorInvalid(t *testing.T) {
+	start, _ := generatorRules().Build()
+	for seed := int64(0); seed < 20; seed++ {
+		g, err := generatorRules().Generator(seed)
+		if err != nil {
+			t.Fatal(err)
+		}
+		text := g.Invalid(10)
+		found := false
+		for _, item := range lexAll(start, text) {
+			if strings.HasPrefix(item, "65534:") {
+				found = true
+			}
+		}
+		if !found {
+			t.Errorf("seed %d: %q lexes without error", seed, text)
+		}
+	}
+}
+
+func TestGeneratorBuildError(t *testing.T) {
+	b := NewBuilder()
+	b.Pattern(1, `a*`)
+	if _, err := b.Generator(1); err == nil {
+		t.Errorf("no error")
+	}
+}
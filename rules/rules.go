@@ -0,0 +1,482 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package rules builds lexers from declarative rules instead of handwritten
+state functions.  Each rule pairs an item type with a literal string or a
+regular expression (in the syntax of package regexp).
+
+	b := rules.NewBuilder()
+	b.Literal(itemIf, "if").Priority(1)
+	b.Pattern(itemIdent, `[a-zA-Z_][a-zA-Z0-9_]*`)
+	b.Pattern(itemNumber, `[0-9]+`)
+	b.Skip(`[ \t\n]+`)
+	start, err := b.Build()
+
+The lexer built from the rules repeatedly emits the longest match at the
+current position.  When several rules match the same longest text the rule
+with the highest priority wins.  Rules have priority zero unless given
+another by Priority.
+
+The patterns of all rules are compiled into a single deterministic automaton,
+so the time taken to find a match is linear in its length regardless of the
+number of rules.
+
+Build validates the rules.  Besides malformed patterns it rejects rules
+that match the empty string, rules that can never win a match (shadowed
+rules, such as a keyword of lower priority than an identifier pattern), and
+rules of equal priority which match the same text (ambiguous rules).  The
+errors name an example of the offending text.
+
+Input that no rule matches produces an error item, after which the lexer
+skips a rune and continues.
+
+A rule emits the text it matches unless given an Action by Do, which
+computes the value of the item from the capture groups of the match.
+
+Ident adds a rule matching the identifiers described by a lexer.IdentSpec,
+so that rules and handwritten states using Lexer.ScanIdentifier agree on
+what an identifier is.
+
+# Start conditions
+
+As in lex and flex, rules may be restricted to start conditions so that text
+in different modes (string literals, comments, embedded languages) is
+matched by different rules.  A lexer begins in the condition Initial.  Other
+conditions are declared with Inclusive or Exclusive.  A rule tagged with
+conditions by In is active only in those conditions; an untagged rule is
+active in Initial and in every inclusive condition but in no exclusive one.
+
+A rule switches conditions after its match with Begin, or with Push and Pop,
+which maintain a stack of conditions for modes that nest.
+
+	b.Exclusive("str")
+	b.Literal(itemQuote, `"`).Begin("str")
+	b.Pattern(itemText, `[^"\\]+|\\.`).In("str")
+	b.Literal(itemQuote, `"`).In("str").Begin(rules.Initial)
+
+Each condition has its own automaton and is validated separately.  A rule is
+shadowed only if it wins no match in any of its conditions.
+*/
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Initial is the start condition in which a lexer begins.
+const Initial = "INITIAL"
+
+// Builder collects rules and builds a lexer from them.
+type Builder struct {
+	rules []*Rule
+	conds []string        // declared start conditions, in order
+	excl  map[string]bool // exclusivity of each declared condition
+}
+
+// Rule is a rule added to a Builder.
+type Rule struct {
+	typ      lexer.ItemType
+	expr     string
+	literal  bool
+	skip     bool
+	priority int
+	index    int
+	conds    []string // start conditions in which the rule is active
+	op       condOp   // change of condition following a match
+	target   string   // condition entered by op
+	next     int      // index of target in the built lexer
+	action   Action
+	re       *regexp.Regexp // matches the text of the rule, for action
+	label    string         // the description of the rule, for coverage
+}
+
+// condOp is a change of start condition made by a rule.
+type condOp int
+
+const (
+	opNone condOp = iota
+	opBegin
+	opPush
+	opPop
+)
+
+// Action computes the value of an item from the text matched by a rule.
+// groups holds the matched text followed by the text of each capture group
+// of the rule's pattern, as returned by regexp.FindStringSubmatch.  A
+// non-nil error produces an error item in place of the item.
+type Action func(groups []string) (string, error)
+
+// NewBuilder returns a Builder without rules.
+func NewBuilder() *Builder {
+	return &Builder{conds: []string{Initial}, excl: map[string]bool{Initial: false}}
+}
+
+// Inclusive declares inclusive start conditions, in which untagged rules are
+// active along with the rules tagged with the condition.
+func (b *Builder) Inclusive(names ...string) {
+	b.declare(names, false)
+}
+
+// Exclusive declares exclusive start conditions, in which only the rules
+// tagged with the condition are active.
+func (b *Builder) Exclusive(names ...string) {
+	b.declare(names, true)
+}
+
+func (b *Builder) declare(names []string, exclusive bool) {
+	for _, name := range names {
+		if _, ok := b.excl[name]; !ok {
+			b.conds = append(b.conds, name)
+		}
+		b.excl[name] = exclusive
+	}
+}
+
+func (b *Builder) add(r *Rule) *Rule {
+	r.index = len(b.rules)
+	b.rules = append(b.rules, r)
+	return r
+}
+
+// Literal adds a rule emitting the string s as an item of type t.
+func (b *Builder) Literal(t lexer.ItemType, s string) *Rule {
+	return b.add(&Rule{typ: t, expr: s, literal: true})
+}
+
+// Pattern adds a rule emitting text matching the regular expression expr as
+// an item of type t.
+func (b *Builder) Pattern(t lexer.ItemType, expr string) *Rule {
+	return b.add(&Rule{typ: t, expr: expr})
+}
+
+// Skip adds a rule discarding text matching the regular expression expr.
+func (b *Builder) Skip(expr string) *Rule {
+	return b.add(&Rule{expr: expr, skip: true})
+}
+
+// Ident adds a rule emitting the identifiers described by spec as items of
+// type t.  If spec normalizes identifiers the rule emits their values, as
+// if given an Action by Do.
+func (b *Builder) Ident(t lexer.ItemType, spec *lexer.IdentSpec) *Rule {
+	cont := classOf(spec.Start)
+	if spec.Continue != nil {
+		cont = classOf(spec.Continue)
+	}
+	expr := classOf(spec.Start) + cont + "*"
+	if spec.Connectors != "" {
+		expr += "(?:" + classOf(lexer.RunesTable(spec.Connectors)) + cont + "+)*"
+	}
+	r := b.add(&Rule{typ: t, expr: expr})
+	if spec.Normalize != nil {
+		r.action = func(g []string) (string, error) { return spec.Value(g[0]), nil }
+	}
+	return r
+}
+
+// classOf returns a regular expression character class matching the runes
+// of tab.
+func classOf(tab *unicode.RangeTable) string {
+	var buf strings.Builder
+	buf.WriteByte('[')
+	add := func(lo, hi, stride rune) {
+		if stride != 1 {
+			for r := lo; r <= hi; r += stride {
+				fmt.Fprintf(&buf, `\x{%x}`, r)
+			}
+			return
+		}
+		fmt.Fprintf(&buf, `\x{%x}-\x{%x}`, lo, hi)
+	}
+	for _, r := range tab.R16 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	for _, r := range tab.R32 {
+		add(rune(r.Lo), rune(r.Hi), rune(r.Stride))
+	}
+	buf.WriteByte(']')
+	return buf.String()
+}
+
+// Priority sets the priority of r to p and returns r.  When rules match the
+// same text the rule with the highest priority wins.
+func (r *Rule) Priority(p int) *Rule {
+	r.priority = p
+	return r
+}
+
+// In restricts r to the start conditions conds and returns r.  The name "*"
+// denotes every condition.
+func (r *Rule) In(conds ...string) *Rule {
+	r.conds = append(r.conds, conds...)
+	return r
+}
+
+// Begin makes r enter the start condition cond after each match and returns
+// r.
+func (r *Rule) Begin(cond string) *Rule {
+	r.op, r.target = opBegin, cond
+	return r
+}
+
+// Push makes r save the current start condition on a stack and enter cond
+// after each match.  Push returns r.
+func (r *Rule) Push(cond string) *Rule {
+	r.op, r.target = opPush, cond
+	return r
+}
+
+// Pop makes r return to the start condition saved by the last Push after
+// each match.  Pop returns r.  A Pop with an empty stack produces an error
+// item and leaves the condition unchanged.
+func (r *Rule) Pop() *Rule {
+	r.op, r.target = opPop, ""
+	return r
+}
+
+// Do makes r emit items with the value computed by fn from the text of
+// each match and returns r.
+//
+//	b.Pattern(itemHex, `0x([0-9a-f]+)`).Do(func(g []string) (string, error) {
+//		return g[1], nil
+//	})
+func (r *Rule) Do(fn Action) *Rule {
+	r.action = fn
+	return r
+}
+
+// active returns true if r is active in the condition cond.
+func (r *Rule) active(cond string, exclusive bool) bool {
+	if len(r.conds) == 0 {
+		return !exclusive
+	}
+	for _, c := range r.conds {
+		if c == cond || c == "*" {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *Rule) String() string {
+	switch {
+	case r.literal:
+		return fmt.Sprintf("rule %d (literal %q)", r.index, r.expr)
+	case r.skip:
+		return fmt.Sprintf("rule %d (skip %q)", r.index, r.expr)
+	}
+	return fmt.Sprintf("rule %d (pattern %q)", r.index, r.expr)
+}
+
+// source returns the regular expression matched by r.
+func (r *Rule) source() string {
+	if r.literal {
+		return regexp.QuoteMeta(r.expr)
+	}
+	return r.expr
+}
+
+// beats returns true if r wins a match of the same text over s.
+func (r *Rule) beats(s *Rule) bool {
+	return r.priority > s.priority
+}
+
+// BuildError lists the problems found in a set of rules.
+type BuildError struct {
+	Problems []string
+}
+
+func (err *BuildError) Error() string {
+	return "rules: " + strings.Join(err.Problems, "; ")
+}
+
+// Build validates the rules of b and returns the start state of a lexer
+// applying them.  The automata are compiled once by Build and are never
+// modified, so the start state may be shared by any number of lexers,
+// including lexers running concurrently.  Each match marks the description
+// of the winning rule, such as "rule 2 (literal \"if\")", for lexers created
+// with lexer.WithCoverage.  The error, if any, is a *BuildError.
+func (b *Builder) Build() (lexer.StateFn, error) {
+	machines, _, err := b.build()
+	if err != nil {
+		return nil, err
+	}
+	return func(l *lexer.Lexer) lexer.StateFn {
+		s := &scanner{machines: machines}
+		return s.scan
+	}, nil
+}
+
+// DeclareCoverage declares the descriptions of the rules of b as marks in c,
+// so that the coverage report lists the rules no input matched.
+func (b *Builder) DeclareCoverage(c *lexer.Coverage) {
+	for _, r := range b.rules {
+		c.DeclareMarks(r.String())
+	}
+}
+
+// build validates the rules of b and compiles the automaton of each start
+// condition.  It returns the automata, indexed like b.conds, and the
+// validated copies of the rules.
+func (b *Builder) build() ([]*machine, []*Rule, error) {
+	err := new(BuildError)
+	if len(b.rules) == 0 {
+		err.Problems = append(err.Problems, "no rules")
+	}
+	index := make(map[string]int)
+	for i, name := range b.conds {
+		index[name] = i
+	}
+	rules := make([]*Rule, len(b.rules))
+	for i, r := range b.rules {
+		c := *r
+		c.label = c.String()
+		rules[i] = &c
+		for _, cond := range c.conds {
+			if _, ok := index[cond]; !ok && cond != "*" {
+				err.Problems = append(err.Problems, fmt.Sprintf("%v: undeclared start condition %q", r, cond))
+			}
+		}
+		if c.action != nil {
+			if c.skip {
+				err.Problems = append(err.Problems, fmt.Sprintf("%v: skip rules have no action", r))
+			} else if re, e := regexp.Compile(`^(?:` + c.source() + `)$`); e == nil {
+				c.re = re
+			}
+		}
+		if c.op == opBegin || c.op == opPush {
+			var ok bool
+			if c.next, ok = index[c.target]; !ok {
+				err.Problems = append(err.Problems, fmt.Sprintf("%v: undeclared start condition %q", r, c.target))
+			}
+		}
+	}
+	if len(err.Problems) > 0 {
+		return nil, nil, err
+	}
+
+	seen := make(map[string]bool)
+	report := func(cond string, problems []string) {
+		for _, p := range problems {
+			if cond != Initial {
+				p = fmt.Sprintf("in condition %s: %s", cond, p)
+			}
+			if !seen[p] {
+				seen[p] = true
+				err.Problems = append(err.Problems, p)
+			}
+		}
+	}
+	wins := make(map[*Rule]bool)
+	examples := make(map[*Rule]string)
+	machines := make([]*machine, len(b.conds))
+	for i, cond := range b.conds {
+		m := &machine{name: cond}
+		for _, r := range rules {
+			if r.active(cond, b.excl[cond]) {
+				m.rules = append(m.rules, r)
+			}
+		}
+		if len(m.rules) == 0 {
+			report(Initial, []string{fmt.Sprintf("start condition %q has no rules", cond)})
+			continue
+		}
+		d, problems := compile(m.rules)
+		if problems == nil {
+			problems = validate(d, m.rules, wins, examples)
+		}
+		report(cond, problems)
+		m.d = d
+		machines[i] = m
+	}
+	for _, r := range rules {
+		if ex, ok := examples[r]; ok && !wins[r] {
+			err.Problems = append(err.Problems, fmt.Sprintf("%v is shadowed: it never wins a match, e.g. %q", r, ex))
+		}
+	}
+	if len(err.Problems) > 0 {
+		return nil, nil, err
+	}
+	return machines, rules, nil
+}
+
+// machine is the automaton for the rules active in a start condition.
+type machine struct {
+	name  string
+	d     *dfa
+	rules []*Rule
+}
+
+// scanner holds the start conditions of a lexer built from rules.
+type scanner struct {
+	machines []*machine
+	cond     int   // current condition
+	stack    []int // conditions saved by Push
+}
+
+// scan emits the longest match at the current position of l using the
+// automaton of the current start condition.
+func (s *scanner) scan(l *lexer.Lexer) lexer.StateFn {
+	rest := l.Input()[l.Pos():]
+	if rest == "" {
+		return nil
+	}
+	m := s.machines[s.cond]
+	var best *Rule
+	n := 0
+	for i, q := 0, 0; i < len(rest); {
+		r, size := utf8.DecodeRuneInString(rest[i:])
+		if q = m.d.states[q].next[m.d.class(r)]; q < 0 {
+			break
+		}
+		i += size
+		if w := m.d.states[q].win; w >= 0 {
+			best, n = m.rules[w], i
+		}
+	}
+	if best == nil {
+		_, size := utf8.DecodeRuneInString(rest)
+		l.AcceptBytes(size)
+		l.Errorf("unexpected %q", l.Current())
+		l.Ignore()
+		return s.scan
+	}
+	l.AcceptBytes(n)
+	l.Mark(best.label)
+	switch {
+	case best.skip:
+		l.Ignore()
+	case best.action != nil:
+		v, err := best.action(best.re.FindStringSubmatch(l.Current()))
+		if err != nil {
+			l.Errorf("%v", err)
+			l.Ignore()
+			break
+		}
+		l.EmitValue(best.typ, v)
+	default:
+		l.Emit(best.typ)
+	}
+	switch best.op {
+	case opBegin:
+		s.cond = best.next
+	case opPush:
+		s.stack = append(s.stack, s.cond)
+		s.cond = best.next
+	case opPop:
+		if len(s.stack) == 0 {
+			l.Errorf("%s: start condition stack is empty", m.name)
+			break
+		}
+		s.cond = s.stack[len(s.stack)-1]
+		s.stack = s.stack[:len(s.stack)-1]
+	}
+	return s.scan
+}
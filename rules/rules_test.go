@@ -0,0 +1,294 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package rules
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+const (
+	itemIf lexer.ItemType = iota + 1
+	itemIdent
+	itemNumber
+	itemOp
+)
+
+func lexAll(start lexer.StateFn, input string) []string {
+	lex := lexer.New(start, input)
+	var items []string
+	for {
+		item := lex.Next()
+		if item.Type == lexer.ItemEOF {
+			return items
+		}
+		items = append(items, fmt.Sprintf("%d:%d:%q", item.Type, item.Pos, item.Value))
+	}
+}
+
+func TestBuild(t *testing.T) {
+	b := NewBuilder()
+	b.Literal(itemIf, "if").Priority(1)
+	b.Pattern(itemIdent, `[a-zA-Z_][a-zA-Z0-9_]*`)
+	b.Pattern(itemNumber, `[0-9]+(\.[0-9]+)?`)
+	b.Literal(itemOp, "=")
+	b.Literal(itemOp, "==")
+	b.Skip(`[ \t\n]+`)
+	start, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := lexAll(start, "if iffy==1.5 $x\xff")
+	expect := []string{
+		`1:0:"if"`, `2:3:"iffy"`, `4:7:"=="`, `3:9:"1.5"`,
+		`65534:13:"unexpected \"$\""`, `2:14:"x"`, `65534:15:"unexpected \"\\xff\""`,
+	}
+	if !reflect.DeepEqual(items, expect) {
+		t.Errorf("items\n\t%q\n(expected)\n\t%q", items, expect)
+	}
+}
+
+func TestBuildErrors(t *testing.T) {
+	for _, test := range []struct {
+		build func(b *Builder)
+		err   []string
+	}{
+		{func(b *Builder) {}, []string{"no rules"}},
+		{func(b *Builder) { b.Pattern(1, `[a-`) }, []string{`rule 0 (pattern "[a-"): error parsing regexp`}},
+		{func(b *Builder) { b.Pattern(1, `a*`) }, []string{`rule 0 (pattern "a*") matches the empty string`}},
+		{func(b *Builder) { b.Pattern(1, `^a`) }, []string{"empty-width assertions are not supported"}},
+		{func(b *Builder) {
+			b.Pattern(itemIdent, `[a-z]+`)
+			b.Literal(itemIf, "if")
+		}, []string{`rule 0 (pattern "[a-z]+") and rule 1 (literal "if") are ambiguous: both match "if" with priority 0`}},
+		{func(b *Builder) {
+			b.Pattern(itemIdent, `[a-z]+`).Priority(2)
+			b.Literal(itemIf, "if").Priority(1)
+		}, []string{`rule 1 (literal "if") is shadowed: it never wins a match, e.g. "if"`}},
+		{func(b *Builder) {
+			b.Pattern(itemIdent, `(?i)[a-z]+`).Priority(1)
+			b.Pattern(itemNumber, `X|Y`)
+		}, []string{`rule 1 (pattern "X|Y") is shadowed: it never wins a match, e.g. "X"`}},
+		{func(b *Builder) {
+			b.Skip(`a`).Do(func([]string) (string, error) { return "", nil })
+		}, []string{`rule 0 (skip "a"): skip rules have no action`}},
+		{func(b *Builder) { b.Pattern(1, `a`).In("str") }, []string{`rule 0 (pattern "a"): undeclared start condition "str"`}},
+		{func(b *Builder) { b.Pattern(1, `a`).Begin("str") }, []string{`rule 0 (pattern "a"): undeclared start condition "str"`}},
+		{func(b *Builder) {
+			b.Exclusive("str")
+			b.Pattern(1, `a`)
+		}, []string{`start condition "str" has no rules`}},
+		{func(b *Builder) {
+			b.Inclusive("str")
+			b.Pattern(itemIdent, `[a-z]+`)
+			b.Literal(itemIf, "if").In("str")
+		}, []string{`in condition str: rule 0 (pattern "[a-z]+") and rule 1 (literal "if") are ambiguous`}},
+		{func(b *Builder) {
+			b.Exclusive("str")
+			b.Pattern(itemIdent, `[a-z]+`).In("*").Priority(1)
+			b.Literal(itemIf, "if").In("str")
+		}, []string{`rule 1 (literal "if") is shadowed`}},
+	} {
+		b := NewBuilder()
+		test.build(b)
+		_, err := b.Build()
+		if err == nil {
+			t.Errorf("%q: no error", test.err)
+			continue
+		}
+		for _, e := range test.err {
+			if !strings.Contains(err.Error(), e) {
+				t.Errorf("error %q (expected %q)", err, e)
+			}
+		}
+	}
+}
+
+func TestStartConditions(t *testing.T) {
+	b := NewBuilder()
+	b.Exclusive("str", "comment")
+	b.Inclusive("raw")
+	b.Pattern(itemIdent, `[a-z]+`)
+	b.Literal(itemOp, `"`).Begin("str")
+	b.Pattern(itemNumber, `[^"\\]+|\\.`).In("str")
+	b.Literal(itemOp, `"`).In("str").Begin(Initial)
+	b.Literal(itemIf, "/*").In(Initial, "comment").Push("comment")
+	b.Literal(itemIf, "*/").In("comment").Pop()
+	b.Skip(`[^/*]+|/|\*`).In("comment")
+	b.Literal(itemOp, "`").Begin("raw")
+	b.Pattern(itemNumber, "[0-9]+").In("raw")
+	b.Literal(itemOp, "'").In("raw").Pop()
+	b.Skip(` +`)
+	start, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := lexAll(start, `a "b\"c" /* x /* y */ */ d `+"`e 12'")
+	expect := []string{
+		`2:0:"a"`, `4:2:"\""`, `3:3:"b"`, `3:4:"\\\""`, `3:6:"c"`, `4:7:"\""`,
+		`1:9:"/*"`, `1:14:"/*"`, `1:19:"*/"`, `1:22:"*/"`, `2:25:"d"`,
+		`4:27:"` + "`" + `"`, `2:28:"e"`, `3:30:"12"`, `4:32:"'"`,
+		`65534:33:"raw: start condition stack is empty"`,
+	}
+	if !reflect.DeepEqual(items, expect) {
+		t.Errorf("items\n\t%q\n(expected)\n\t%q", items, expect)
+	}
+}
+
+// TestBuildConcurrent shares a start state with start conditions between
+// goroutines.  Run it with the race detector to check that lexers share no
+// mutable state.
+func TestBuildConcurrent(t *testing.T) {
+	b := NewBuilder()
+	b.Exclusive("str")
+	b.Pattern(itemIdent, `[a-z]+`)
+	b.Literal(itemOp, `"`).Begin("str")
+	b.Pattern(itemNumber, `[^"]+`).In("str")
+	b.Literal(itemOp, `"`).In("str").Begin(Initial)
+	b.Skip(` +`)
+	start, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expect := lexAll(start, `a "b c" d`)
+	var wg sync.WaitGroup
+	fail := make(chan []string, 8)
+	for g := 0; g < 8; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < 50; i++ {
+				if items := lexAll(start, `a "b c" d`); !reflect.DeepEqual(items, expect) {
+					fail <- items
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(fail)
+	for items := range fail {
+		t.Errorf("items\n\t%q\n(expected)\n\t%q", items, expect)
+	}
+}
+
+func TestActions(t *testing.T) {
+	b := NewBuilder()
+	b.Pattern(itemNumber, `0x([0-9a-f]+)`).Do(func(g []string) (string, error) {
+		n, err := strconv.ParseUint(g[1], 16, 8)
+		return strconv.FormatUint(n, 10), err
+	})
+	b.Pattern(itemIdent, `([a-z]+)\.([a-z]+)`).Do(func(g []string) (string, error) {
+		return g[2] + "/" + g[1], nil
+	})
+	b.Skip(` +`)
+	start, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := lexAll(start, "0xff a.bc 0x100")
+	expect := []string{
+		`3:0:"255"`, `2:5:"bc/a"`,
+		`65534:10:"strconv.ParseUint: parsing \"100\": value out of range"`,
+	}
+	if !reflect.DeepEqual(items, expect) {
+		t.Errorf("items\n\t%q\n(expected)\n\t%q", items, expect)
+	}
+}
+
+func TestIdent(t *testing.T) {
+	b := NewBuilder()
+	b.Literal(itemIf, "if").Priority(1)
+	b.Ident(itemIdent, &lexer.GoIdent)
+	b.Skip(` +`)
+	start, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := lexAll(start, "if héllo_1 _")
+	expect := []string{`1:0:"if"`, `2:3:"héllo_1"`, `2:12:"_"`}
+	if !reflect.DeepEqual(items, expect) {
+		t.Errorf("items\n\t%q\n(expected)\n\t%q", items, expect)
+	}
+
+	b = NewBuilder()
+	b.Ident(itemIdent, &lexer.IdentSpec{
+		Start:      lexer.RunesTable("abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ"),
+		Connectors: "-",
+		Normalize:  strings.ToLower,
+	})
+	b.Literal(itemOp, "-")
+	b.Skip(` +`)
+	start, err = b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	items = lexAll(start, "Font-Size a- -b")
+	expect = []string{`2:0:"font-size"`, `2:10:"a"`, `4:11:"-"`, `4:13:"-"`, `2:14:"b"`}
+	if !reflect.DeepEqual(items, expect) {
+		t.Errorf("items\n\t%q\n(expected)\n\t%q", items, expect)
+	}
+}
+
+func TestManyRules(t *testing.T) {
+	keywords := strings.Fields(`break case chan const continue default defer else
+		fallthrough for func go goto if import interface map package range return
+		select struct switch type var`)
+	b := NewBuilder()
+	for i, kw := range keywords {
+		b.Literal(lexer.ItemType(100+i), kw).Priority(1)
+	}
+	b.Pattern(itemIdent, `[\pL_][\pL\pN_]*`)
+	b.Pattern(itemNumber, `0[xX][0-9a-fA-F]+|[0-9]+`)
+	for _, op := range strings.Fields(`+ - * / % & | ^ << >> &^ += -= == != < <= > >= && || <- ++ -- := ...`) {
+		b.Literal(itemOp, op)
+	}
+	b.Skip(`[ \t\n]+|//[^\n]*`)
+	start, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	items := lexAll(start, "for i := 0x1F; i <= π; i++ { goto forward } // done")
+	expect := []string{
+		`109:0:"for"`, `2:4:"i"`, `4:6:":="`, `3:9:"0x1F"`, `65534:13:"unexpected \";\""`,
+		`2:15:"i"`, `4:17:"<="`, `2:20:"π"`, `65534:22:"unexpected \";\""`, `2:24:"i"`, `4:25:"++"`,
+		`65534:28:"unexpected \"{\""`, `112:30:"goto"`, `2:35:"forward"`, `65534:43:"unexpected \"}\""`,
+	}
+	if !reflect.DeepEqual(items, expect) {
+		t.Errorf("items\n\t%q\n(expected)\n\t%q", items, expect)
+	}
+}
+
+func TestCoverage(t *testing.T) {
+	b := NewBuilder()
+	b.Literal(itemIf, "if").Priority(1)
+	b.Pattern(itemIdent, `[a-z]+`)
+	b.Pattern(itemNumber, `[0-9]+`)
+	b.Skip(` +`)
+	start, err := b.Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := new(lexer.Coverage)
+	b.DeclareCoverage(c)
+	lex := lexer.New(start, "if x y", lexer.WithCoverage(c))
+	for lex.Next().Type != lexer.ItemEOF {
+	}
+	expect := []lexer.MarkCoverage{
+		{Label: `rule 0 (literal "if")`, Hits: 1},
+		{Label: `rule 1 (pattern "[a-z]+")`, Hits: 2},
+		{Label: `rule 2 (pattern "[0-9]+")`, Hits: 0},
+		{Label: `rule 3 (skip " +")`, Hits: 2},
+	}
+	if marks := c.Report().Marks; !reflect.DeepEqual(marks, expect) {
+		t.Errorf("marks %v (expected %v)", marks, expect)
+	}
+}
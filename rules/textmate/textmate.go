@@ -0,0 +1,295 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package textmate converts TextMate grammars, as used by many editors for
+syntax highlighting, into rules for package rules.
+
+	g, err := textmate.Import(file) // a .tmLanguage.json file
+	start, err := g.Builder().Build()
+	lex := lexer.New(start, src)
+	// g.Scope(item.Type) is the scope name of an item, e.g. "keyword.control"
+
+Each scope name of the grammar becomes an item type.  Match patterns become
+rules emitting the scope named by the pattern.  Begin and end patterns
+become start conditions entered by the begin pattern and left by the end
+pattern, in which the nested patterns apply and other text is emitted as
+the content scope of the pattern.  Patterns without a name are skipped, as
+is text that no pattern matches.
+
+TextMate grammars are written for the regular expressions of Oniguruma,
+which are matched differently than rules: a grammar applies the first
+pattern matching at the earliest position while rules apply the longest
+match, breaking ties in favor of patterns listed earlier.  Word boundaries
+(\b) at either end of a pattern are dropped, as the longest match usually
+has the same effect.  Constructs that cannot be converted are listed in
+Skipped:  patterns using lookaround, backreferences, anchors or other
+syntax unknown to package regexp, captures, while patterns and includes of
+other grammars.
+*/
+package textmate
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"regexp"
+	"regexp/syntax"
+	"strings"
+
+	"github.com/bmatsuo/go-lexer"
+	"github.com/bmatsuo/go-lexer/rules"
+)
+
+// Priorities of end patterns, which TextMate tries before nested patterns,
+// and of the rules matching text no pattern matches.
+const (
+	endPriority      = 1 << 20
+	fallbackPriority = -1 << 20
+)
+
+// Grammar is a TextMate grammar converted to rules.
+type Grammar struct {
+	Name      string
+	ScopeName string
+	Scopes    []string // scope names; the item type of Scopes[i] is i+1
+	Skipped   []string // constructs of the grammar which were not converted
+
+	b        *rules.Builder
+	types    map[string]lexer.ItemType
+	repo     map[string]*pattern
+	conds    map[*pattern]string
+	added    map[added]bool
+	priority int
+}
+
+// added identifies a pattern added to a condition.
+type added struct {
+	cond string
+	p    *pattern
+}
+
+type pattern struct {
+	Name          string              `json:"name"`
+	ContentName   string              `json:"contentName"`
+	Match         string              `json:"match"`
+	Begin         string              `json:"begin"`
+	End           string              `json:"end"`
+	While         string              `json:"while"`
+	Include       string              `json:"include"`
+	Patterns      []*pattern          `json:"patterns"`
+	Captures      map[string]*pattern `json:"captures"`
+	BeginCaptures map[string]*pattern `json:"beginCaptures"`
+	EndCaptures   map[string]*pattern `json:"endCaptures"`
+	Repository    map[string]*pattern `json:"repository"`
+	ScopeName     string              `json:"scopeName"`
+}
+
+// Import reads a TextMate grammar in JSON format from r and converts it.
+func Import(r io.Reader) (*Grammar, error) {
+	var top struct {
+		pattern
+		GrammarName string `json:"name"`
+	}
+	if err := json.NewDecoder(r).Decode(&top); err != nil {
+		return nil, err
+	}
+	g := &Grammar{
+		Name:      top.GrammarName,
+		ScopeName: top.ScopeName,
+		b:         rules.NewBuilder(),
+		types:     make(map[string]lexer.ItemType),
+		repo:      top.Repository,
+		conds:     make(map[*pattern]string),
+		added:     make(map[added]bool),
+	}
+	g.add(rules.Initial, top.Patterns, top.Patterns, []string{"$self"})
+	g.rule(rules.Initial, "", `(?s:.)`, fallbackPriority)
+	return g, nil
+}
+
+// Builder returns a Builder holding the rules converted from g.
+func (g *Grammar) Builder() *rules.Builder {
+	return g.b
+}
+
+// Scope returns the scope name of items of type t, or "" if t is not the
+// type of a scope of g.
+func (g *Grammar) Scope(t lexer.ItemType) string {
+	if t < 1 || int(t) > len(g.Scopes) {
+		return ""
+	}
+	return g.Scopes[t-1]
+}
+
+func (g *Grammar) skip(format string, v ...interface{}) {
+	msg := fmt.Sprintf(format, v...)
+	for _, s := range g.Skipped {
+		if s == msg {
+			return
+		}
+	}
+	g.Skipped = append(g.Skipped, msg)
+}
+
+// rule adds a rule in cond emitting text matching expr as the scope name,
+// or skipping it if name is empty.
+func (g *Grammar) rule(cond, name, expr string, priority int) *rules.Rule {
+	var r *rules.Rule
+	if name == "" {
+		r = g.b.Skip(expr)
+	} else {
+		t, ok := g.types[name]
+		if !ok {
+			g.Scopes = append(g.Scopes, name)
+			t = lexer.ItemType(len(g.Scopes))
+			g.types[name] = t
+		}
+		r = g.b.Pattern(t, expr)
+	}
+	return r.In(cond).Priority(priority)
+}
+
+// next returns the priority of the next pattern added, which is below the
+// priority of the patterns added before it.
+func (g *Grammar) next() int {
+	g.priority--
+	return g.priority
+}
+
+// add adds rules for pats in the condition cond.  self holds the top-level
+// patterns of the grammar and including lists the repository entries being
+// included, to break include cycles.
+func (g *Grammar) add(cond string, self, pats []*pattern, including []string) {
+	for _, p := range pats {
+		if p.Match != "" || p.Begin != "" {
+			if g.added[added{cond, p}] {
+				continue
+			}
+			g.added[added{cond, p}] = true
+		}
+		switch {
+		case p.Include != "":
+			g.include(cond, self, p.Include, including)
+		case p.Match != "":
+			expr, err := convert(p.Match)
+			if err != nil {
+				g.skip("match %q: %v", p.Match, err)
+				continue
+			}
+			if p.Captures != nil {
+				g.skip("captures of match %q", p.Match)
+			}
+			g.rule(cond, p.Name, expr, g.next())
+		case p.Begin != "":
+			g.block(cond, self, p)
+		case p.While != "":
+			g.skip("while pattern %q", p.While)
+		default:
+			g.add(cond, self, p.Patterns, including)
+		}
+	}
+}
+
+// include adds the rules of the patterns named by ref.
+func (g *Grammar) include(cond string, self []*pattern, ref string, including []string) {
+	if ref == "$base" {
+		ref = "$self"
+	}
+	for _, n := range including {
+		if n == ref {
+			g.skip("recursive include of %s", ref)
+			return
+		}
+	}
+	switch {
+	case ref == "$self":
+		g.add(cond, self, self, append(including, ref))
+	case strings.HasPrefix(ref, "#"):
+		name := ref[1:]
+		p, ok := g.repo[name]
+		if !ok {
+			g.skip("include of missing repository entry %s", ref)
+			return
+		}
+		g.add(cond, self, []*pattern{p}, append(including, ref))
+	default:
+		g.skip("include of grammar %q", ref)
+	}
+}
+
+// block adds the rules of a begin/end pattern p to cond.  The patterns
+// nested in p are added to a condition of their own the first time p is
+// added.
+func (g *Grammar) block(cond string, self []*pattern, p *pattern) {
+	begin, err := convert(p.Begin)
+	if err != nil {
+		g.skip("begin %q: %v", p.Begin, err)
+		return
+	}
+	end, err := convert(p.End)
+	if err != nil {
+		g.skip("end %q: %v", p.End, err)
+		return
+	}
+	if p.BeginCaptures != nil || p.EndCaptures != nil || p.Captures != nil {
+		g.skip("captures of begin %q", p.Begin)
+	}
+	inner, ok := g.conds[p]
+	if !ok {
+		inner = fmt.Sprintf("block%d", len(g.conds)+1)
+		g.conds[p] = inner
+		g.b.Exclusive(inner)
+		g.rule(inner, p.Name, end, endPriority).Pop()
+		g.add(inner, self, p.Patterns, nil)
+		content := p.ContentName
+		if content == "" {
+			content = p.Name
+		}
+		g.rule(inner, content, `(?s:.)`, fallbackPriority)
+	}
+	g.rule(cond, p.Name, begin, g.next()).Push(inner)
+}
+
+var namedGroup = regexp.MustCompile(`\(\?<([A-Za-z_][A-Za-z0-9_]*)>`)
+
+// convert translates the Oniguruma expression expr to the syntax of package
+// regexp, or returns an error describing why it cannot be.
+func convert(expr string) (string, error) {
+	expr = strings.TrimPrefix(expr, `\b`)
+	if strings.HasSuffix(expr, `\b`) && !strings.HasSuffix(expr, `\\b`) {
+		expr = expr[:len(expr)-2]
+	}
+	expr = namedGroup.ReplaceAllString(expr, `(?P<$1>`)
+	expr = strings.Replace(expr, `\h`, `[0-9a-fA-F]`, -1)
+	re, err := syntax.Parse(expr, syntax.Perl)
+	if err != nil {
+		if e, ok := err.(*syntax.Error); ok {
+			return "", fmt.Errorf("%v %q", e.Code, e.Expr)
+		}
+		return "", err
+	}
+	if emptyWidth(re) {
+		return "", fmt.Errorf("anchors and word boundaries are not supported")
+	}
+	if regexp.MustCompile(`^(?:` + expr + `)$`).MatchString("") {
+		return "", fmt.Errorf("matches the empty string")
+	}
+	return expr, nil
+}
+
+// emptyWidth returns true if re contains an empty-width assertion.
+func emptyWidth(re *syntax.Regexp) bool {
+	switch re.Op {
+	case syntax.OpBeginLine, syntax.OpEndLine, syntax.OpBeginText, syntax.OpEndText,
+		syntax.OpWordBoundary, syntax.OpNoWordBoundary:
+		return true
+	}
+	for _, sub := range re.Sub {
+		if emptyWidth(sub) {
+			return true
+		}
+	}
+	return false
+}
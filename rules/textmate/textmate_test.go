@@ -0,0 +1,114 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package textmate
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+const grammar = `{
+	"name": "Toy",
+	"scopeName": "source.toy",
+	"patterns": [
+		{"include": "#comments"},
+		{"include": "#expression"},
+		{"match": "(?<=\\.)[a-z]+", "name": "variable.other.property"}
+	],
+	"repository": {
+		"comments": {
+			"patterns": [
+				{"match": "#[^\\n]*", "name": "comment.line"},
+				{"begin": "/\\*", "end": "\\*/", "name": "comment.block"}
+			]
+		},
+		"expression": {
+			"patterns": [
+				{"match": "\\b(if|else|while)\\b", "name": "keyword.control"},
+				{"match": "\\b(?<name>[a-z_][a-z0-9_]*)\\b", "name": "variable"},
+				{"match": "\\b\\h+h\\b", "name": "constant.numeric"},
+				{"match": "\\s+"},
+				{"begin": "\"", "end": "\"", "name": "string.quoted", "contentName": "string.content",
+				 "patterns": [
+					{"match": "\\\\.", "name": "constant.character.escape"},
+					{"begin": "\\$\\(", "end": "\\)", "name": "meta.embedded", "patterns": [{"include": "$self"}]}
+				 ]},
+				{"include": "#expression"},
+				{"include": "source.other"}
+			]
+		}
+	}
+}`
+
+func TestImport(t *testing.T) {
+	g, err := Import(strings.NewReader(grammar))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if g.Name != "Toy" || g.ScopeName != "source.toy" {
+		t.Errorf("name %q scope %q", g.Name, g.ScopeName)
+	}
+	skipped := []string{
+		"recursive include of #expression",
+		`include of grammar "source.other"`,
+		`match "(?<=\\.)[a-z]+": invalid named capture "(?<=\\.)[a-z]+"`,
+	}
+	if !reflect.DeepEqual(g.Skipped, skipped) {
+		t.Errorf("skipped\n\t%q\n(expected)\n\t%q", g.Skipped, skipped)
+	}
+	start, err := g.Builder().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	lex := lexer.New(start, `if x1 "a\"$(0fh /*c*/)" .`)
+	var items []string
+	for {
+		item := lex.Next()
+		if item.Type == lexer.ItemEOF {
+			break
+		}
+		items = append(items, fmt.Sprintf("%s:%d:%q", g.Scope(item.Type), item.Pos, item.Value))
+	}
+	expect := []string{
+		`keyword.control:0:"if"`, `variable:3:"x1"`,
+		`string.quoted:6:"\""`, `string.content:7:"a"`, `constant.character.escape:8:"\\\""`,
+		`meta.embedded:10:"$("`, `constant.numeric:12:"0fh"`,
+		`comment.block:16:"/*"`, `comment.block:18:"c"`, `comment.block:19:"*/"`,
+		`meta.embedded:21:")"`, `string.quoted:22:"\""`,
+	}
+	if !reflect.DeepEqual(items, expect) {
+		t.Errorf("items\n\t%q\n(expected)\n\t%q", items, expect)
+	}
+}
+
+func TestConvert(t *testing.T) {
+	for _, test := range []struct {
+		in, out, err string
+	}{
+		{`\bfoo\b`, `foo`, ""},
+		{`(?<n>a)\h`, `(?P<n>a)[0-9a-fA-F]`, ""},
+		{`a\\b`, `a\\b`, ""},
+		{`^a`, "", "anchors and word boundaries are not supported"},
+		{`a\bb`, "", "anchors and word boundaries are not supported"},
+		{`a*`, "", "matches the empty string"},
+		{`(a)\1`, "", `invalid escape sequence "\\1"`},
+		{`a(?=b)`, "", `invalid or unsupported Perl syntax "(?="`},
+	} {
+		out, err := convert(test.in)
+		if test.err != "" {
+			if err == nil || err.Error() != test.err {
+				t.Errorf("%s: error %v (expected %q)", test.in, err, test.err)
+			}
+			continue
+		}
+		if err != nil || out != test.out {
+			t.Errorf("%s: %q %v (expected %q)", test.in, out, err, test.out)
+		}
+	}
+}
@@ -0,0 +1,148 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+/*
+Package sarif exports lexer diagnostics in the Static Analysis Results
+Interchange Format (SARIF) version 2.1.0, which is understood by GitHub code
+scanning and other tools.
+
+	var diags lexer.DiagnosticList
+	lex := lexer.New(start, input, lexer.WithName("config.ini"), lexer.WithDiagnostics(&diags))
+	// ... lex the input ...
+	err := sarif.Write(w, sarif.Tool{Name: "inilint"}, diags)
+
+Columns are reported as counts of Unicode code points, so inputs should be
+lexed without WithTabWidth.
+*/
+package sarif
+
+import (
+	"encoding/json"
+	"io"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+// Version and Schema identify the SARIF format written by Write.
+const (
+	Version = "2.1.0"
+	Schema  = "https://json.schemastore.org/sarif-2.1.0.json"
+)
+
+// Tool describes the program which produced the diagnostics.
+type Tool struct {
+	Name           string
+	Version        string
+	InformationURI string
+}
+
+// Write writes a SARIF log containing a single run of tool with a result
+// for each of diags.  The Name of a diagnostic is used as the URI of the
+// artifact it refers to.
+func Write(w io.Writer, tool Tool, diags []lexer.Diagnostic) error {
+	results := make([]result, 0, len(diags))
+	for _, d := range diags {
+		results = append(results, newResult(d))
+	}
+	log := sarifLog{
+		Schema:  Schema,
+		Version: Version,
+		Runs: []run{{
+			Tool: runTool{Driver: driver{
+				Name:           tool.Name,
+				Version:        tool.Version,
+				InformationURI: tool.InformationURI,
+			}},
+			ColumnKind: "unicodeCodePoints",
+			Results:    results,
+		}},
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(log)
+}
+
+func newResult(d lexer.Diagnostic) result {
+	r := region{
+		StartLine:   d.Position.Line,
+		StartColumn: d.Position.Column,
+		EndLine:     d.Position.Line,
+		EndColumn:   d.Position.Column,
+		ByteOffset:  d.Err.Pos,
+		ByteLength:  d.Err.End - d.Err.Pos,
+	}
+	if i := strings.LastIndexByte(d.Err.Lexeme, '\n'); i >= 0 {
+		r.EndLine += strings.Count(d.Err.Lexeme, "\n")
+		r.EndColumn = 1 + utf8.RuneCountInString(d.Err.Lexeme[i+1:])
+	} else {
+		r.EndColumn += utf8.RuneCountInString(d.Err.Lexeme)
+	}
+	res := result{
+		Level:   d.Severity.String(),
+		Message: message{Text: d.Err.Msg},
+	}
+	if d.Position.Line > 0 {
+		res.Locations = []location{{PhysicalLocation: physicalLocation{
+			ArtifactLocation: artifactLocation{URI: d.Name},
+			Region:           r,
+		}}}
+	}
+	return res
+}
+
+type sarifLog struct {
+	Schema  string `json:"$schema"`
+	Version string `json:"version"`
+	Runs    []run  `json:"runs"`
+}
+
+type run struct {
+	Tool       runTool  `json:"tool"`
+	ColumnKind string   `json:"columnKind"`
+	Results    []result `json:"results"`
+}
+
+type runTool struct {
+	Driver driver `json:"driver"`
+}
+
+type driver struct {
+	Name           string `json:"name"`
+	Version        string `json:"version,omitempty"`
+	InformationURI string `json:"informationUri,omitempty"`
+}
+
+type result struct {
+	Level     string     `json:"level"`
+	Message   message    `json:"message"`
+	Locations []location `json:"locations,omitempty"`
+}
+
+type message struct {
+	Text string `json:"text"`
+}
+
+type location struct {
+	PhysicalLocation physicalLocation `json:"physicalLocation"`
+}
+
+type physicalLocation struct {
+	ArtifactLocation artifactLocation `json:"artifactLocation"`
+	Region           region           `json:"region"`
+}
+
+type artifactLocation struct {
+	URI string `json:"uri,omitempty"`
+}
+
+type region struct {
+	StartLine   int `json:"startLine"`
+	StartColumn int `json:"startColumn"`
+	EndLine     int `json:"endLine"`
+	EndColumn   int `json:"endColumn"`
+	ByteOffset  int `json:"byteOffset"`
+	ByteLength  int `json:"byteLength"`
+}
@@ -0,0 +1,50 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package sarif
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"testing"
+
+	"github.com/bmatsuo/go-lexer"
+)
+
+func TestWrite(t *testing.T) {
+	var diags lexer.DiagnosticList
+	lex := lexer.New(func(l *lexer.Lexer) lexer.StateFn {
+		l.Warnf("empty")
+		l.AcceptString("ab\n")
+		l.Ignore()
+		l.AcceptString("\"é\nx")
+		return l.Errorf("unterminated string")
+	}, "ab\n\"é\nx", lexer.WithName("in.txt"), lexer.WithDiagnostics(&diags))
+	for lex.Next().Type != lexer.ItemEOF {
+	}
+	var buf bytes.Buffer
+	if err := Write(&buf, Tool{Name: "test", Version: "1.0"}, diags); err != nil {
+		t.Fatal(err)
+	}
+	var log sarifLog
+	if err := json.Unmarshal(buf.Bytes(), &log); err != nil {
+		t.Fatal(err)
+	}
+	if log.Version != Version || len(log.Runs) != 1 || log.Runs[0].Tool.Driver.Name != "test" {
+		t.Fatalf("log %+v", log)
+	}
+	results := log.Runs[0].Results
+	if len(results) != 2 || results[0].Level != "warning" || results[1].Level != "error" {
+		t.Fatalf("results %+v", results)
+	}
+	loc := results[1].Locations[0].PhysicalLocation
+	want := region{StartLine: 2, StartColumn: 1, EndLine: 3, EndColumn: 2, ByteOffset: 3, ByteLength: 5}
+	if loc.ArtifactLocation.URI != "in.txt" || !reflect.DeepEqual(loc.Region, want) {
+		t.Errorf("location %+v", loc)
+	}
+	if results[1].Message.Text != "unterminated string" {
+		t.Errorf("message %q", results[1].Message.Text)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+// Sink receives the items of a stream pushed to it by Drain, such as a
+// database writer or a network client.  Write is called once for each item
+// before the item of type ItemEOF, which is not written.  A Sink applies
+// backpressure by blocking in Write and stops the stream by returning an
+// error.
+//
+// A Sink which also implements
+//
+//	Flush() error
+//
+// has Flush called after the last item has been written, so that sinks
+// writing in batches can write the last batch.
+type Sink interface {
+	Write(item Item) error
+}
+
+// SinkFunc adapts a function to the Sink interface.
+type SinkFunc func(item Item) error
+
+// Write calls fn(item).
+func (fn SinkFunc) Write(item Item) error {
+	return fn(item)
+}
+
+type flusher interface {
+	Flush() error
+}
+
+// Drain writes the items of l to sink until the input is exhausted or sink
+// returns an error, which Drain returns.
+func (l *Lexer) Drain(sink Sink) error {
+	return drain(l, sink)
+}
+
+// Drain writes the items leaving p to sink until the stream ends or an error
+// occurs.  It returns the first error of a stage or of sink.
+func (p *Pipeline) Drain(sink Sink) error {
+	if err := drain(p, sink); err != nil {
+		return err
+	}
+	return p.err
+}
+
+func drain(src Stream, sink Sink) error {
+	for {
+		item := src.Next()
+		if item.Type == ItemEOF {
+			break
+		}
+		if err := sink.Write(*item); err != nil {
+			return err
+		}
+	}
+	if f, ok := sink.(flusher); ok {
+		return f.Flush()
+	}
+	return nil
+}
@@ -0,0 +1,67 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+)
+
+// batchSink records items in batches of two.
+type batchSink struct {
+	batch   []string
+	batches [][]string
+	err     error
+}
+
+func (s *batchSink) Write(item Item) error {
+	s.batch = append(s.batch, item.Value)
+	if len(s.batch) == 2 {
+		return s.Flush()
+	}
+	return nil
+}
+
+func (s *batchSink) Flush() error {
+	if len(s.batch) > 0 {
+		s.batches = append(s.batches, s.batch)
+		s.batch = nil
+	}
+	return s.err
+}
+
+func TestDrain(t *testing.T) {
+	sink := new(batchSink)
+	if err := New(lexWords, "ab cd ef").Drain(sink); err != nil {
+		t.Fatal(err)
+	}
+	if expect := [][]string{{"ab", "cd"}, {"ef"}}; !reflect.DeepEqual(sink.batches, expect) {
+		t.Errorf("batches %q (expected %q)", sink.batches, expect)
+	}
+
+	full := errors.New("full")
+	n := 0
+	lex := New(lexWords, "ab cd ef")
+	err := lex.Drain(SinkFunc(func(item Item) error {
+		n++
+		if item.Value == "cd" {
+			return full
+		}
+		return nil
+	}))
+	if err != full || n != 2 {
+		t.Errorf("error %v after %d items", err, n)
+	}
+	if item := lex.Next(); item.Value != "ef" {
+		t.Errorf("unexpected item %v", item)
+	}
+
+	bad := errors.New("bad")
+	p := NewPipeline(New(lexWords, "ab cd")).Stage("fail", func(*Item, func(*Item)) error { return bad })
+	if err := p.Drain(new(batchSink)); err == nil || err.(*StageError).Err != bad {
+		t.Errorf("error %v", err)
+	}
+}
@@ -0,0 +1,140 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+)
+
+// PushMode pushes mode onto the mode stack of l.  Modes name the contexts a
+// lexer may nest, such as the strings, comments or embedded expressions of a
+// template language, and are saved by Save along with the position of the
+// lexer.  States which keep such contexts in the mode stack rather than in
+// closures can be resumed by Load.
+func (l *Lexer) PushMode(mode string) {
+	l.modes = append(l.modes, mode)
+}
+
+// PopMode removes the top of the mode stack of l and returns it, or returns
+// the empty string if the stack is empty.
+func (l *Lexer) PopMode() string {
+	if len(l.modes) == 0 {
+		return ""
+	}
+	mode := l.modes[len(l.modes)-1]
+	l.modes = l.modes[:len(l.modes)-1]
+	return mode
+}
+
+// Mode returns the top of the mode stack of l, or the empty string if the
+// stack is empty.
+func (l *Lexer) Mode() string {
+	if len(l.modes) == 0 {
+		return ""
+	}
+	return l.modes[len(l.modes)-1]
+}
+
+// Snapshot is the state of a lexer saved by Save.
+type Snapshot struct {
+	Start        int      // offset in the source of the current lexeme
+	Pos          int      // offset in the source of the next rune
+	Line, Column int      // position of Start
+	State        string   // name of the current state, empty once stopped
+	Modes        []string // the mode stack, bottom first
+	Items        []Item   // items emitted but not yet returned by Next
+	Steps        int
+	Count        int
+	Errors       int
+}
+
+// ErrNotSaveable is returned by Save for lexers whose input is transformed,
+// as offsets in the input cannot be resumed from.
+var ErrNotSaveable = errors.New("lexer: lexers with input transforms cannot be saved")
+
+// Save writes the state of l to w as JSON, so that lexing a very long input
+// can be checkpointed and resumed by Load in another process.  Save must be
+// called between calls to Next.  The state saved is the position of l, its
+// current state function, its mode stack and its pending items, without
+// their payloads.  State functions are identified by name, so a state
+// function whose closure holds information other than its identity cannot
+// be resumed.
+func (l *Lexer) Save(w io.Writer) error {
+	if len(l.xforms) > 0 {
+		return ErrNotSaveable
+	}
+	start := l.offset(l.start)
+	p := l.Position(start)
+	snap := &Snapshot{
+		Start:  start,
+		Pos:    l.offset(l.pos),
+		Line:   p.Line,
+		Column: p.Column,
+		Modes:  l.modes,
+		Items:  make([]Item, 0, l.items.Len()),
+		Steps:  l.steps,
+		Count:  l.count,
+		Errors: l.errors,
+	}
+	if l.state != nil {
+		snap.State = stateName(l.state)
+	}
+	for e := l.items.Front(); e != nil; e = e.Next() {
+		item := *e.Value.(*Item)
+		item.Payload = nil
+		snap.Items = append(snap.Items, item)
+	}
+	return json.NewEncoder(w).Encode(snap)
+}
+
+// Load reads a snapshot written by Save from r and returns a lexer resuming
+// it.  The input of the lexer is the source from the offset Start of the
+// snapshot, which may be followed by more input given to Append.  The
+// current state of the snapshot must be one of states, which are matched by
+// name.  Items are positioned by their offset in the whole source, and
+// opts should be those of the saved lexer.
+func Load(r io.Reader, input string, states []StateFn, opts ...Option) (*Lexer, error) {
+	var snap Snapshot
+	if err := json.NewDecoder(r).Decode(&snap); err != nil {
+		return nil, err
+	}
+	if snap.Start > maxOffset-len(input) {
+		return nil, fmt.Errorf("lexer: snapshot offset %d exceeds the maximum offset of %d bytes", snap.Start, maxOffset)
+	}
+	if snap.Start < 0 || snap.Pos < snap.Start || snap.Pos-snap.Start > len(input) || snap.Line < 1 || snap.Column < 1 {
+		return nil, fmt.Errorf("lexer: invalid snapshot position")
+	}
+	var state StateFn
+	if snap.State != "" {
+		for _, fn := range states {
+			if fn != nil && stateName(fn) == snap.State {
+				state = fn
+				break
+			}
+		}
+		if state == nil {
+			return nil, fmt.Errorf("lexer: snapshot state %s is not among the states given to Load", snap.State)
+		}
+	}
+	l := New(func(*Lexer) StateFn { return nil }, input, opts...)
+	if len(l.xforms) > 0 {
+		return nil, ErrNotSaveable
+	}
+	l.state, l.begin = state, state
+	l.pos = snap.Pos - snap.Start
+	l.dropped = snap.Start
+	l.rbase = runeCursor{off: snap.Start}
+	l.lineBase, l.dropLine, l.dropCol = snap.Line-1, snap.Line-1, snap.Column-1
+	l.modes = snap.Modes
+	l.steps, l.count, l.errors = snap.Steps, snap.Count, snap.Errors
+	for i := range snap.Items {
+		snap.Items[i].format = l.format
+		l.items.PushBack(&snap.Items[i])
+	}
+	return l, nil
+}
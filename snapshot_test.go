@@ -0,0 +1,84 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// lexNested lexes words, which are of type 1 outside parentheses and of
+// type 2 inside them, keeping the parentheses open in the mode stack.
+func lexNested(l *Lexer) StateFn {
+	l.AcceptRun(" \n")
+	l.Ignore()
+	switch {
+	case l.Accept("("):
+		l.PushMode("paren")
+		l.Ignore()
+	case l.Accept(")"):
+		if l.PopMode() == "" {
+			return l.Errorf("unbalanced %q", l.Current())
+		}
+		l.Ignore()
+	case l.AcceptRun("abcdefghijklmnopqrstuvwxyz") > 0:
+		if l.Mode() == "paren" {
+			l.Emit(2)
+		} else {
+			l.Emit(1)
+		}
+	default:
+		return nil
+	}
+	return lexNested
+}
+
+func describe(item *Item) string {
+	return fmt.Sprintf("%d %d %d:%d %q", item.Type, item.Pos, item.Line, item.Column, item.Value)
+}
+
+func TestSaveLoad(t *testing.T) {
+	input := "a (b\n(c) d) e\n(f) )"
+	var want []string
+	lex := New(lexNested, input, WithLineTracking())
+	for item := lex.Next(); item.Type != ItemEOF; item = lex.Next() {
+		want = append(want, describe(item))
+	}
+
+	for n := 0; n < len(want); n++ {
+		lex := New(lexNested, input, WithLineTracking())
+		var got []string
+		for i := 0; i < n; i++ {
+			got = append(got, describe(lex.Next()))
+		}
+		var buf bytes.Buffer
+		if err := lex.Save(&buf); err != nil {
+			t.Fatal(err)
+		}
+		var snap Snapshot
+		if err := json.Unmarshal(buf.Bytes(), &snap); err != nil {
+			t.Fatal(err)
+		}
+		resumed, err := Load(&buf, input[snap.Start:], []StateFn{lexWords, lexNested}, WithLineTracking())
+		if err != nil {
+			t.Fatal(err)
+		}
+		for item := resumed.Next(); item.Type != ItemEOF; item = resumed.Next() {
+			got = append(got, describe(item))
+		}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("saved after %d items: got %q, want %q", n, got, want)
+		}
+	}
+
+	var buf bytes.Buffer
+	New(lexNested, input).Save(&buf)
+	if _, err := Load(&buf, input, []StateFn{lexWords}); err == nil {
+		t.Errorf("loaded a snapshot without its state")
+	}
+}
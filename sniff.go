@@ -0,0 +1,106 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"path"
+	"strings"
+)
+
+// Sniff returns the registered language of the file with the given name and
+// content, or nil if the language is not recognized.  Either argument may
+// be empty, and content may be a prefix of the file.
+//
+// The language is chosen by the base name of the file, then by the
+// interpreter named on a #! line at the beginning of content, then by the
+// confidence reported by the Analyze function of each language.  When
+// several languages match a name or an interpreter the one whose Analyze
+// function reports the greatest confidence is chosen.
+func Sniff(filename, content string) *Language {
+	langs := Languages()
+	if filename != "" {
+		base := filename[strings.LastIndexAny(filename, `/\`)+1:]
+		var match []*Language
+		for _, lang := range langs {
+			for _, pattern := range lang.Filenames {
+				if matchName(pattern, base) {
+					match = append(match, lang)
+					break
+				}
+			}
+		}
+		if len(match) > 0 {
+			return mostLikely(match, content, true)
+		}
+	}
+	if prog := interpreter(content); prog != "" {
+		var match []*Language
+		for _, lang := range langs {
+			for _, name := range lang.Interpreters {
+				if name == prog || name == strings.TrimRight(prog, "0123456789.") {
+					match = append(match, lang)
+					break
+				}
+			}
+		}
+		if len(match) > 0 {
+			return mostLikely(match, content, true)
+		}
+	}
+	return mostLikely(langs, content, false)
+}
+
+func matchName(pattern, name string) bool {
+	ok, _ := path.Match(pattern, name)
+	if !ok {
+		ok, _ = path.Match(pattern, strings.ToLower(name))
+	}
+	return ok
+}
+
+// mostLikely returns the language of langs whose Analyze function reports
+// the greatest confidence in content, preferring earlier languages on ties.
+// Unless any is true languages reporting no confidence are not returned.
+func mostLikely(langs []*Language, content string, any bool) *Language {
+	var best *Language
+	max := 0.0
+	for _, lang := range langs {
+		score := 0.0
+		if lang.Analyze != nil && content != "" {
+			score = lang.Analyze(content)
+		}
+		if score > max || best == nil && any {
+			best, max = lang, score
+		}
+	}
+	return best
+}
+
+// interpreter returns the base name of the program named on the #! line
+// beginning text, skipping env and its arguments.
+func interpreter(text string) string {
+	if !strings.HasPrefix(text, "#!") {
+		return ""
+	}
+	line := text[2:]
+	if i := strings.IndexByte(line, '\n'); i >= 0 {
+		line = line[:i]
+	}
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ""
+	}
+	prog := path.Base(fields[0])
+	if prog == "env" {
+		prog = ""
+		for _, f := range fields[1:] {
+			if !strings.HasPrefix(f, "-") && !strings.Contains(f, "=") {
+				prog = path.Base(f)
+				break
+			}
+		}
+	}
+	return prog
+}
@@ -0,0 +1,56 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSniff(t *testing.T) {
+	start := func() StateFn { return lexWords }
+	RegisterLanguage(&Language{
+		Name:      "sniff-a",
+		Start:     start,
+		Filenames: []string{"*.sa", "Safile"},
+		Analyze: func(text string) float64 {
+			if strings.HasPrefix(text, "a") {
+				return 0.5
+			}
+			return 0
+		},
+	})
+	RegisterLanguage(&Language{
+		Name:         "sniff-b",
+		Start:        start,
+		Filenames:    []string{"*.sa"},
+		Interpreters: []string{"sb"},
+		Analyze: func(text string) float64 {
+			if strings.Contains(text, "b") {
+				return 0.3
+			}
+			return 0
+		},
+	})
+	for _, test := range []struct {
+		filename, content, lang string
+	}{
+		{"dir/x.sa", "", "sniff-a"},
+		{"X.SA", "", "sniff-a"},
+		{"x.sa", "b", "sniff-b"},
+		{"x.sa", "ab", "sniff-a"},
+		{`C:\dir\Safile`, "", "sniff-a"},
+		{"x.txt", "#!/usr/bin/sb\nxyz", "sniff-b"},
+		{"", "#!/usr/bin/env -S VAR=1 sb2.7 -x\n", "sniff-b"},
+		{"", "#!/bin/zz\nb", "sniff-b"},
+		{"", "xyz", ""},
+		{"", "", ""},
+	} {
+		lang := Sniff(test.filename, test.content)
+		if lang == nil && test.lang != "" || lang != nil && lang.Name != test.lang {
+			t.Errorf("%q %q: language %v (expected %q)", test.filename, test.content, lang, test.lang)
+		}
+	}
+}
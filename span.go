@@ -0,0 +1,96 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+)
+
+// Span is a range of byte offsets in a source, from Start up to but not
+// including End.  Spans answer the range queries of editor tooling, such as
+// finding the token under the cursor or the tokens in a selection.
+type Span struct {
+	Start, End int
+}
+
+// Span returns the span of the source lexed as i.  The span of an error or
+// a warning is that of the offending text.  The span of an item emitted by
+// EmitValue is that of its lexeme, whatever its value.  Items not returned
+// by a lexer, such as decoded items, span their value from their position.
+func (i *Item) Span() Span {
+	if err, ok := i.Payload.(*LexError); ok && (i.Type == ItemError || i.Type == ItemWarning) {
+		return Span{err.Pos, err.End}
+	}
+	if i.end > 0 {
+		return Span{i.Pos, i.end - 1}
+	}
+	return Span{i.Pos, i.Pos + len(i.Value)}
+}
+
+// Len returns the number of bytes in s.
+func (s Span) Len() int {
+	return s.End - s.Start
+}
+
+// Empty returns true if s contains no bytes.
+func (s Span) Empty() bool {
+	return s.End <= s.Start
+}
+
+// Contains returns true if the byte at offset is in s.  An empty span
+// contains no offset, so that the offset between two adjacent tokens is
+// contained by the second only.
+func (s Span) Contains(offset int) bool {
+	return s.Start <= offset && offset < s.End
+}
+
+// ContainsSpan returns true if every byte of t is in s.  Every span contains
+// an empty span located within it or at one of its ends.
+func (s Span) ContainsSpan(t Span) bool {
+	if t.Empty() {
+		return s.Start <= t.Start && t.Start <= s.End
+	}
+	return s.Start <= t.Start && t.End <= s.End
+}
+
+// Intersects returns true if s and t share at least one byte.  Adjacent
+// spans, such as those of consecutive tokens, do not intersect, and empty
+// spans intersect nothing.
+func (s Span) Intersects(t Span) bool {
+	return s.Start < t.End && t.Start < s.End && !s.Empty() && !t.Empty()
+}
+
+// Intersect returns the bytes shared by s and t, and false if they share
+// none.
+func (s Span) Intersect(t Span) (Span, bool) {
+	if !s.Intersects(t) {
+		return Span{}, false
+	}
+	u := s
+	if t.Start > u.Start {
+		u.Start = t.Start
+	}
+	if t.End < u.End {
+		u.End = t.End
+	}
+	return u, true
+}
+
+// Union returns the smallest span containing both s and t, including the
+// bytes between them if they are disjoint.
+func (s Span) Union(t Span) Span {
+	u := s
+	if t.Start < u.Start {
+		u.Start = t.Start
+	}
+	if t.End > u.End {
+		u.End = t.End
+	}
+	return u
+}
+
+func (s Span) String() string {
+	return fmt.Sprintf("[%d:%d]", s.Start, s.End)
+}
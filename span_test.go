@@ -0,0 +1,73 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func TestSpan(t *testing.T) {
+	a, b, c := Span{2, 5}, Span{5, 8}, Span{3, 4}
+	empty := Span{5, 5}
+	for _, test := range []struct {
+		name string
+		got  bool
+		want bool
+	}{
+		{"a contains 2", a.Contains(2), true},
+		{"a contains 5", a.Contains(5), false},
+		{"empty contains 5", empty.Contains(5), false},
+		{"a contains c", a.ContainsSpan(c), true},
+		{"a contains b", a.ContainsSpan(b), false},
+		{"a contains empty", a.ContainsSpan(empty), true},
+		{"c contains empty", c.ContainsSpan(empty), false},
+		{"a intersects b", a.Intersects(b), false},
+		{"a intersects c", a.Intersects(c), true},
+		{"c intersects a", c.Intersects(a), true},
+		{"a intersects empty", a.Intersects(empty), false},
+		{"a intersects a union b", a.Intersects(a.Union(b)), true},
+		{"empty is empty", empty.Empty(), true},
+		{"inverted is empty", Span{4, 3}.Empty(), true},
+	} {
+		if test.got != test.want {
+			t.Errorf("%s: %v", test.name, test.got)
+		}
+	}
+	if u := a.Union(b); u != (Span{2, 8}) || u.Len() != 6 {
+		t.Errorf("union %v", u)
+	}
+	if u := c.Union(Span{6, 7}); u != (Span{3, 7}) {
+		t.Errorf("union of disjoint spans %v", u)
+	}
+	if s, ok := (Span{1, 4}).Intersect(a); !ok || s != (Span{2, 4}) {
+		t.Errorf("intersection %v %v", s, ok)
+	}
+	if s, ok := a.Intersect(b); ok {
+		t.Errorf("intersection of adjacent spans %v", s)
+	}
+	if s := a.String(); s != "[2:5]" {
+		t.Errorf("string %q", s)
+	}
+}
+
+func TestItemSpan(t *testing.T) {
+	lex := New(func(l *Lexer) StateFn {
+		l.AcceptString(`"a\"b"`)
+		l.EmitValue(1, `a"b`)
+		l.AcceptString("xy")
+		l.Emit(2)
+		l.AcceptString("!")
+		return l.Errorf("bang")
+	}, `"a\"b"xy!`)
+	for _, want := range []Span{{0, 6}, {6, 8}, {8, 9}} {
+		if span := lex.Next().Span(); span != want {
+			t.Errorf("span %v (expected %v)", span, want)
+		}
+	}
+	decoded := Item{Type: 1, Pos: 3, Value: "abc"}
+	if span := decoded.Span(); span != (Span{3, 6}) {
+		t.Errorf("span of a decoded item %v", span)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+)
+
+// WithLineSplicing causes the lexer to remove each backslash immediately
+// followed by a newline, or by a carriage return and a newline, from its
+// input before lexing, joining physical lines into logical lines as the C
+// preprocessor, Python and the shell do.  It is an input transform, so the
+// positions of items and errors refer to the physical lines, as described
+// by WithInputTransform.
+func WithLineSplicing() Option {
+	return WithInputTransform(spliceLines)
+}
+
+// spliceLines removes backslash-newline sequences from src.
+func spliceLines(src string) (string, *TransformMap) {
+	b := NewInputBuilder(src)
+	for i := 0; i < len(src); i++ {
+		if src[i] != '\\' {
+			continue
+		}
+		switch {
+		case strings.HasPrefix(src[i+1:], "\n"):
+			b.Replace(i, i+2, "")
+			i++
+		case strings.HasPrefix(src[i+1:], "\r\n"):
+			b.Replace(i, i+3, "")
+			i += 2
+		}
+	}
+	return b.Finish()
+}
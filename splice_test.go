@@ -0,0 +1,54 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func TestLineSplicing(t *testing.T) {
+	src := "ab\\\ncd \\\r\nef x\\\n\\\nyz 1"
+	l := New(lexWords, src, WithLineSplicing(), WithLineTracking())
+	if l.Input() != "abcd ef xyz 1" || l.Source() != src {
+		t.Fatalf("input %q source %q", l.Input(), l.Source())
+	}
+	want := []struct {
+		value     string
+		pos, line int
+	}{
+		{"abcd", 0, 1},
+		{"ef", 10, 3},
+		{"xyz", 13, 3},
+		{`unexpected "1"`, 21, 5},
+	}
+	for _, w := range want {
+		item := l.Next()
+		if item.Value != w.value || item.Pos != w.pos || item.Line != w.line {
+			t.Errorf("item %+v, want %q at %d line %d", item, w.value, w.pos, w.line)
+		}
+	}
+	if item := l.Next(); item.Type != ItemEOF || item.Pos != len(src) {
+		t.Errorf("eof %+v", item)
+	}
+}
+
+func TestLineSplicingError(t *testing.T) {
+	l := New(func(l *Lexer) StateFn {
+		l.AcceptString("ab")
+		return l.Errorf("bad")
+	}, "a\\\nb", WithLineSplicing())
+	err := l.Next().Err().(*LexError)
+	if err.Pos != 0 || err.End != 4 || err.Lexeme != "a\\\nb" {
+		t.Errorf("error %+v", err)
+	}
+}
+
+func TestLineSplicingAppend(t *testing.T) {
+	l := New(lexWords, "ab\\\nc", WithLineSplicing())
+	l.Append("d\\\nef")
+	if l.Input() != "abcdef" || l.Source() != "ab\\\ncd\\\nef" {
+		t.Errorf("input %q source %q", l.Input(), l.Source())
+	}
+}
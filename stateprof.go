@@ -0,0 +1,158 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"fmt"
+	"runtime/metrics"
+	"sort"
+	"text/tabwriter"
+	"time"
+)
+
+// StateCost describes the work done by one state in a StateProfile.
+type StateCost struct {
+	Name   string
+	Calls  int           // number of calls of the state
+	Time   time.Duration // wall time spent in the state
+	Items  int           // items emitted, including errors and warnings
+	Bytes  int           // input consumed
+	Allocs uint64        // estimated heap allocations, if measured
+}
+
+// StateProfiler attributes the cost of lexing to the states of lexers, so
+// that the hot or pathological states of a complex lexer can be found.  A
+// StateProfiler is given to lexers with WithStateProfiler and may be shared
+// by several lexers, one at a time, to profile a corpus.
+//
+//	p := lexer.NewStateProfiler(false)
+//	lex := lexer.New(lexText, input, lexer.WithStateProfiler(p))
+//	...
+//	fmt.Print(p)
+//
+// States are named as by Named, or else by the names of their functions.
+type StateProfiler struct {
+	allocs bool
+	costs  map[string]*StateCost
+	names  funcNames
+	sample []metrics.Sample
+}
+
+// stateMark holds the counters of a lexer when a state is entered.
+type stateMark struct {
+	cost   *StateCost
+	start  time.Time
+	count  int
+	pos    int
+	allocs uint64
+}
+
+// NewStateProfiler returns an empty state profiler.  If allocs is true the
+// profiler estimates the heap allocations made by each state from the
+// runtime's counters, which slows lexing down.  The estimate includes the
+// allocations of all goroutines running while the state does, and small
+// allocations may be attributed to a later state.
+func NewStateProfiler(allocs bool) *StateProfiler {
+	p := &StateProfiler{allocs: allocs, costs: make(map[string]*StateCost)}
+	if allocs {
+		p.sample = []metrics.Sample{{Name: "/gc/heap/allocs:objects"}}
+	}
+	return p
+}
+
+// WithStateProfiler attributes the cost of the states of the lexer to p.
+func WithStateProfiler(p *StateProfiler) Option {
+	return func(l *Lexer) { l.sprof = p }
+}
+
+func (p *StateProfiler) enter(l *Lexer) stateMark {
+	name := p.names.name(l.state)
+	c := p.costs[name]
+	if c == nil {
+		c = &StateCost{Name: name}
+		p.costs[name] = c
+	}
+	m := stateMark{cost: c, count: l.count, pos: l.offset(l.pos)}
+	m.allocs = p.allocations()
+	m.start = time.Now()
+	return m
+}
+
+func (p *StateProfiler) exit(l *Lexer, m stateMark) {
+	elapsed := time.Since(m.start)
+	c := m.cost
+	c.Calls++
+	c.Time += elapsed
+	c.Items += l.count - m.count
+	c.Bytes += l.offset(l.pos) - m.pos
+	if p.allocs {
+		c.Allocs += p.allocations() - m.allocs
+	}
+}
+
+func (p *StateProfiler) allocations() uint64 {
+	if !p.allocs {
+		return 0
+	}
+	metrics.Read(p.sample)
+	if p.sample[0].Value.Kind() != metrics.KindUint64 {
+		return 0
+	}
+	return p.sample[0].Value.Uint64()
+}
+
+// Costs returns the costs of the states profiled so far, by decreasing
+// time.
+func (p *StateProfiler) Costs() []StateCost {
+	costs := make([]StateCost, 0, len(p.costs))
+	for _, c := range p.costs {
+		costs = append(costs, *c)
+	}
+	sort.Slice(costs, func(i, j int) bool {
+		a, b := costs[i], costs[j]
+		if a.Time != b.Time {
+			return a.Time > b.Time
+		}
+		return a.Name < b.Name
+	})
+	return costs
+}
+
+// String returns the costs of p as a table for display, by decreasing time.
+//
+//	STATE            CALLS  TIME    %     ITEMS  BYTES  NS/BYTE
+//	main.lexString   120    1.2ms   61.3  120    5400   222.2
+//	...
+func (p *StateProfiler) String() string {
+	costs := p.Costs()
+	var total time.Duration
+	for _, c := range costs {
+		total += c.Time
+	}
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintf(w, "STATE\tCALLS\tTIME\t%%\tITEMS\tBYTES\tNS/BYTE")
+	if p.allocs {
+		fmt.Fprintf(w, "\tALLOCS")
+	}
+	fmt.Fprintf(w, "\n")
+	for _, c := range costs {
+		share, perByte := 0.0, 0.0
+		if total > 0 {
+			share = 100 * float64(c.Time) / float64(total)
+		}
+		if c.Bytes > 0 {
+			perByte = float64(c.Time.Nanoseconds()) / float64(c.Bytes)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%v\t%.1f\t%d\t%d\t%.1f", c.Name, c.Calls, c.Time, share, c.Items, c.Bytes, perByte)
+		if p.allocs {
+			fmt.Fprintf(w, "\t%d", c.Allocs)
+		}
+		fmt.Fprintf(w, "\n")
+	}
+	w.Flush()
+	return buf.String()
+}
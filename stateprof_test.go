@@ -0,0 +1,42 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStateProfiler(t *testing.T) {
+	p := NewStateProfiler(true)
+	space := Named("space", func(l *Lexer) StateFn {
+		l.AcceptRun(" ")
+		l.Ignore()
+		return lexWords
+	})
+	collect(New(space, "  ab cd 1", WithStateProfiler(p)))
+	collect(New(space, "ef", WithStateProfiler(p)))
+	costs := p.Costs()
+	if len(costs) != 2 {
+		t.Fatalf("costs %+v", costs)
+	}
+	byName := make(map[string]StateCost)
+	for _, c := range costs {
+		byName[c.Name] = c
+	}
+	if c := byName["space"]; c.Calls != 2 || c.Items != 0 || c.Bytes != 2 {
+		t.Errorf("space %+v", c)
+	}
+	if c := byName["go-lexer.lexWords"]; c.Calls != 5 || c.Items != 4 || c.Bytes != 9 {
+		t.Errorf("lexWords %+v", c)
+	}
+	if costs[0].Time < costs[1].Time {
+		t.Errorf("costs not sorted by time: %+v", costs)
+	}
+	s := p.String()
+	if !strings.HasPrefix(s, "STATE ") || !strings.Contains(s, "ALLOCS") || !strings.Contains(s, "\nspace ") {
+		t.Errorf("report\n%s", s)
+	}
+}
@@ -0,0 +1,111 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+// StateTable is an optional execution mode for small states which are
+// entered very often, such as those skipping white space or scanning
+// identifiers.  States registered with a table are identified by integer
+// ids, and a state moves to another with Goto instead of returning it
+// directly.  Transitions made through the table that emit no item are
+// dispatched in a loop, bypassing the bookkeeping the lexer does between
+// state calls, and the calls themselves may be made directly by a switch
+// statement given as Dispatch rather than through function values.
+//
+//	const (
+//		stSpace = iota
+//		stWord
+//	)
+//
+//	var table *lexer.StateTable
+//
+//	func init() {
+//		table = lexer.NewStateTable(lexSpace, lexWord)
+//		table.Dispatch = func(l *lexer.Lexer, id int) lexer.StateFn {
+//			switch id {
+//			case stSpace:
+//				return lexSpace(l)
+//			default:
+//				return lexWord(l)
+//			}
+//		}
+//	}
+//
+//	func lexSpace(l *lexer.Lexer) lexer.StateFn {
+//		l.AcceptRun(" ")
+//		l.Ignore()
+//		return table.Goto(l, stWord)
+//	}
+//
+// States remain ordinary StateFns, and Goto returns a StateFn, so tables
+// change neither the items lexed nor the way states are written.  The loop
+// is not used while the lexer traces, records or graphs its states, or has a
+// step limit, so that every state call is observed.  A StateTable may be
+// shared by any number of lexers.
+type StateTable struct {
+	states []StateFn
+	gotos  []StateFn
+
+	// Dispatch, if not nil, calls the state with the given id and returns
+	// its result.  It is typically a switch statement calling each state
+	// directly.  When Dispatch is nil states are called through the table.
+	Dispatch func(l *Lexer, id int) StateFn
+}
+
+// NewStateTable returns a table of states whose ids are their indices in
+// states.  As states usually refer to the table, it is typically created in
+// an init function.
+func NewStateTable(states ...StateFn) *StateTable {
+	t := &StateTable{states: states, gotos: make([]StateFn, len(states))}
+	for id := range states {
+		id := id
+		t.gotos[id] = func(l *Lexer) StateFn { return t.run(l, id) }
+	}
+	return t
+}
+
+// State returns the StateFn entering the state with the given id, which may
+// be used as the start state of a lexer.
+func (t *StateTable) State(id int) StateFn {
+	return t.gotos[id]
+}
+
+// Goto returns the StateFn entering the state with the given id.  A state
+// of t moving to another state of t must return the result of Goto
+// immediately.
+func (t *StateTable) Goto(l *Lexer, id int) StateFn {
+	l.jump = id + 1
+	return t.gotos[id]
+}
+
+// run calls the state id and those it moves to with Goto until a state
+// emits an item or leaves the table.
+func (t *StateTable) run(l *Lexer, id int) StateFn {
+	observed := l.observed()
+	for {
+		l.jump = 0
+		count, errors := l.count, l.errors
+		var next StateFn
+		if t.Dispatch != nil {
+			next = t.Dispatch(l, id)
+		} else {
+			next = t.states[id](l)
+		}
+		if observed || l.jump == 0 || l.count != count || l.errors != errors || l.halted {
+			l.jump = 0
+			return next
+		}
+		id = l.jump - 1
+		l.steps++
+	}
+}
+
+// observed returns true if the calls of l's states are observed, by tracing,
+// recording, hooks, profiling, the watchdog or the MaxSteps limit, so that each state must be called
+// by step.
+func (l *Lexer) observed() bool {
+	return l.trace != nil || l.rec != nil || l.graph != nil || l.cover != nil ||
+		l.onEnter != nil || l.onExit != nil || l.sprof != nil || l.watch != nil ||
+		l.limits.MaxSteps > 0
+}
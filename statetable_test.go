@@ -0,0 +1,92 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const (
+	stSpace = iota
+	stWord
+	stPunct
+)
+
+var table *StateTable
+
+func init() {
+	table = NewStateTable(tableSpace, tableWord, tablePunct)
+}
+
+// tableSpace, tableWord and tablePunct lex words like lexWords, except that
+// dashes join words.
+func tableSpace(l *Lexer) StateFn {
+	l.AcceptRun(" ")
+	l.Ignore()
+	if _, n := l.Peek(); n == 0 {
+		return nil
+	}
+	return table.Goto(l, stWord)
+}
+
+func tableWord(l *Lexer) StateFn {
+	if l.AcceptRun("abcdefghijklmnopqrstuvwxyz") == 0 {
+		if _, n := l.Advance(); n == 0 {
+			return nil
+		}
+		return l.Errorf("unexpected %q", l.Current())
+	}
+	if r, _ := l.Peek(); r == '-' {
+		return table.Goto(l, stPunct)
+	}
+	l.Emit(1)
+	return table.Goto(l, stSpace)
+}
+
+func tablePunct(l *Lexer) StateFn {
+	l.Accept("-")
+	return table.Goto(l, stWord)
+}
+
+func TestStateTable(t *testing.T) {
+	input := "ab  cd-ef-gh i 9 j"
+	lex := New(table.State(stSpace), input)
+	want := collect(lex)
+	if !reflect.DeepEqual(want, []string{"ab", "cd-ef-gh", "i", `unexpected "9"`}) {
+		t.Fatalf("items %q", want)
+	}
+
+	var trace bytes.Buffer
+	traced := New(table.State(stSpace), input, WithTrace(&trace))
+	if got := collect(traced); !reflect.DeepEqual(got, want) {
+		t.Errorf("traced items %q", got)
+	}
+	if n := strings.Count(trace.String(), "state "); n != traced.steps || n != lex.steps {
+		t.Errorf("traced %d states in %d steps, want %d steps", n, traced.steps, lex.steps)
+	}
+
+	dispatched := 0
+	table.Dispatch = func(l *Lexer, id int) StateFn {
+		dispatched++
+		switch id {
+		case stSpace:
+			return tableSpace(l)
+		case stWord:
+			return tableWord(l)
+		default:
+			return tablePunct(l)
+		}
+	}
+	defer func() { table.Dispatch = nil }()
+	if got := collect(New(table.State(stSpace), input)); !reflect.DeepEqual(got, want) {
+		t.Errorf("dispatched items %q", got)
+	}
+	if dispatched != lex.steps {
+		t.Errorf("Dispatch called %d times, want %d", dispatched, lex.steps)
+	}
+}
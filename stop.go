@@ -0,0 +1,79 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+// StopReason describes why a lexer stopped.
+type StopReason int
+
+const (
+	// NotStopped is the reason of a lexer which has not stopped.
+	NotStopped StopReason = iota
+	// StopCompleted means a state returned nil after the whole input was
+	// consumed and every lexeme emitted or ignored.
+	StopCompleted
+	// StopEarly means a state returned nil without an error while input
+	// remained unread or the current lexeme was neither emitted nor
+	// ignored, which is usually a bug in the state.
+	StopEarly
+	// StopError means the lexer stopped after a state emitted an error, as
+	// by returning the result of Errorf or under ErrorHalt.
+	StopError
+	// StopHalted means the lexer was halted by a limit given to WithLimits
+	// or by a violation of the rules checked by WithStrict.
+	StopHalted
+)
+
+var stopReasons = [...]string{
+	NotStopped:    "not stopped",
+	StopCompleted: "completed",
+	StopEarly:     "stopped early",
+	StopError:     "stopped after an error",
+	StopHalted:    "halted",
+}
+
+func (r StopReason) String() string {
+	if r < 0 || int(r) >= len(stopReasons) {
+		return "StopReason(?)"
+	}
+	return stopReasons[r]
+}
+
+// WithStopCheck makes the lexer emit an error when it stops early, so that
+// a state returning nil by mistake does not end the stream silently.  The
+// error names the state which returned nil and covers the lexeme it left
+// pending, if any.
+func WithStopCheck() Option {
+	return func(l *Lexer) { l.stopCheck = true }
+}
+
+// StopReason returns the reason l stopped, or NotStopped if l's state is not
+// nil.  After Continue the reason is that of the next stop.
+func (l *Lexer) StopReason() StopReason {
+	switch {
+	case l.state != nil:
+		return NotStopped
+	case l.halted:
+		return StopHalted
+	}
+	return l.stop
+}
+
+// setStopReason records why l stopped after the state prev returned nil, given
+// the number of errors emitted before it was called.
+func (l *Lexer) setStopReason(prev StateFn, errors int) {
+	switch {
+	case l.halted:
+		l.stop = StopHalted
+	case l.errors > errors:
+		l.stop = StopError
+	case l.pos < len(l.input) || l.start < l.pos:
+		l.stop = StopEarly
+		if l.stopCheck {
+			l.Errorf("state %s stopped at offset %d before the end of input", graphName(prev), l.offset(l.pos))
+		}
+	default:
+		l.stop = StopCompleted
+	}
+}
@@ -0,0 +1,60 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStopReason(t *testing.T) {
+	pending := func(l *Lexer) StateFn {
+		l.Advance()
+		return nil
+	}
+	for _, test := range []struct {
+		start StateFn
+		input string
+		opts  []Option
+		why   StopReason
+	}{
+		{lexWords, "ab cd", nil, StopCompleted},
+		{lexWords, "", nil, StopCompleted},
+		{nilState, "ab", nil, StopEarly},
+		{pending, "a", nil, StopEarly},
+		{lexWords, "ab 1", nil, StopError},
+		{lexWords, "ab cd", []Option{WithLimits(Limits{MaxItems: 1})}, StopHalted},
+	} {
+		lex := New(test.start, test.input, test.opts...)
+		if why := lex.StopReason(); why != NotStopped {
+			t.Errorf("%q: reason %v before lexing", test.input, why)
+		}
+		collect(lex)
+		if why := lex.StopReason(); why != test.why {
+			t.Errorf("%q: reason %v (expected %v)", test.input, why, test.why)
+		}
+	}
+}
+
+func TestStopCheck(t *testing.T) {
+	early := func(l *Lexer) StateFn {
+		l.AcceptRun("ab")
+		return nil
+	}
+	lex := New(early, "abc", WithStopCheck())
+	item := lex.Next()
+	if item.Type != ItemError || !strings.Contains(item.Value, "stopped at offset 2 before the end of input") {
+		t.Fatalf("item %v", item)
+	}
+	if err := item.Err().(*LexError); err.Pos != 0 || err.Lexeme != "ab" {
+		t.Errorf("error %+v", err)
+	}
+	if why := lex.StopReason(); why != StopEarly {
+		t.Errorf("reason %v", why)
+	}
+	if items := collect(New(lexWords, "ab", WithStopCheck())); len(items) != 1 {
+		t.Errorf("items %q", items)
+	}
+}
@@ -0,0 +1,129 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+// TokenStream is the stream of items consumed by a parser.  Parsers which
+// depend on a TokenStream rather than a *Lexer can be tested with items
+// given by FromItems, without running a lexer.
+//
+// Next returns the next item, and an item of type ItemEOF after the stream
+// ends.  Peek returns the item Next will return without consuming it.  Err
+// returns the error of the first item of type ItemError returned by Next,
+// or the error which stopped the source of the stream, such as a stage of a
+// Pipeline.
+type TokenStream interface {
+	Stream
+	Peek() *Item
+	Err() error
+}
+
+// Tokens returns a TokenStream of the items of src, such as a *Lexer, a
+// *SyncLexer or a *Pipeline.  If src has an Err method its error is reported
+// after the stream ends.
+//
+//	p := parser.New(lexer.Tokens(lex))
+func Tokens(src Stream) TokenStream {
+	return &tokens{src: src}
+}
+
+// FromItems returns a TokenStream serving items, such as items recorded for
+// a parser test, cached from an earlier run or loaded from a dump.  The
+// stream ends after the last item, or at the first item of type ItemEOF.
+// Next returns copies of the items, so items is not modified by consumers
+// of the stream and may be served again.
+//
+//	ts := lexer.FromItems([]lexer.Item{
+//		{Type: itemIdent, Pos: 0, Value: "x"},
+//		{Type: itemPlus, Pos: 2, Value: "+"},
+//	})
+func FromItems(items []Item) TokenStream {
+	return &tokens{src: &itemSlice{items: items}}
+}
+
+type tokens struct {
+	src  Stream
+	next *Item
+	err  error
+}
+
+func (ts *tokens) Next() *Item {
+	item := ts.Peek()
+	if item.Type != ItemEOF {
+		ts.next = nil
+	}
+	if ts.err == nil {
+		ts.err = item.Err()
+	}
+	return item
+}
+
+func (ts *tokens) Peek() *Item {
+	if ts.next == nil {
+		ts.next = ts.src.Next()
+	}
+	return ts.next
+}
+
+func (ts *tokens) Err() error {
+	if ts.err != nil {
+		return ts.err
+	}
+	if e, ok := ts.src.(interface{ Err() error }); ok && ts.next != nil && ts.next.Type == ItemEOF {
+		return e.Err()
+	}
+	return nil
+}
+
+// itemSlice is a Stream of the items in a slice.
+type itemSlice struct {
+	items []Item
+	pos   int
+}
+
+func (s *itemSlice) Next() *Item {
+	if len(s.items) == 0 {
+		return &Item{Type: ItemEOF, Pos: s.pos}
+	}
+	item := s.items[0]
+	if item.Type != ItemEOF {
+		s.items = s.items[1:]
+		s.pos = item.Pos + len(item.Value)
+	}
+	return &item
+}
+
+// SeekType reads items from s until one of type t, and returns it.  Items
+// of other types are discarded.  SeekType returns nil if s ends first, so
+// that a TokenStream seeking past its end reports any error in Err.
+//
+//	for item := lexer.SeekType(ts, itemString); item != nil; item = lexer.SeekType(ts, itemString) {
+//		strs = append(strs, item.Value)
+//	}
+func SeekType(s Stream, t ItemType) *Item {
+	for {
+		item := s.Next()
+		if item == nil {
+			return nil
+		}
+		if item.Type == t {
+			return item
+		}
+		if item.Type == ItemEOF {
+			return nil
+		}
+	}
+}
+
+// FindAll returns the items for which pred returns true, in order, such as
+// the comments of items collected from a lexer containing "TODO".
+func FindAll(items []Item, pred func(*Item) bool) []Item {
+	var found []Item
+	for i := range items {
+		if pred(&items[i]) {
+			found = append(found, items[i])
+		}
+	}
+	return found
+}
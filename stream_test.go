@@ -0,0 +1,99 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestTokens(t *testing.T) {
+	ts := Tokens(New(lexWords, "ab cd 1 ef"))
+	if item := ts.Peek(); item.Value != "ab" || ts.Peek() != item || ts.Next() != item {
+		t.Errorf("peek %+v", item)
+	}
+	if item := ts.Next(); item.Value != "cd" || ts.Err() != nil {
+		t.Errorf("next %+v err %v", item, ts.Err())
+	}
+	if item := ts.Next(); item.Type != ItemError || ts.Err() == nil || ts.Err().Error() != `unexpected "1"` {
+		t.Errorf("error %+v err %v", item, ts.Err())
+	}
+	if item := ts.Next(); item.Type != ItemEOF || ts.Peek().Type != ItemEOF {
+		t.Errorf("eof %+v", item)
+	}
+}
+
+func TestTokensErr(t *testing.T) {
+	stop := errors.New("stop")
+	p := NewPipeline(New(lexWords, "ab cd")).Stage("stop", func(item *Item, emit func(*Item)) error {
+		if item.Value == "cd" {
+			return stop
+		}
+		emit(item)
+		return nil
+	})
+	ts := Tokens(p)
+	if ts.Next().Value != "ab" || ts.Err() != nil {
+		t.Fatalf("error before the end of the stream: %v", ts.Err())
+	}
+	if ts.Next().Type != ItemEOF || ts.Err() == nil {
+		t.Errorf("pipeline error %v", ts.Err())
+	}
+}
+
+func TestFromItems(t *testing.T) {
+	ts := FromItems([]Item{{Type: 1, Pos: 0, Value: "ab"}, {Type: 1, Pos: 3, Value: "cd"}})
+	if a, b := ts.Next(), ts.Next(); a.Value != "ab" || b.Value != "cd" {
+		t.Errorf("items %+v %+v", a, b)
+	}
+	if eof := ts.Next(); eof.Type != ItemEOF || eof.Pos != 5 || ts.Next().Type != ItemEOF {
+		t.Errorf("eof %+v", eof)
+	}
+	items := []Item{{Type: 1, Value: "x"}, {Type: ItemEOF, Pos: 7}, {Type: 1, Value: "y"}}
+	ts = FromItems(items)
+	ts.Next().Value = "z"
+	if items[0].Value != "x" {
+		t.Errorf("item modified through the stream")
+	}
+	if eof := ts.Next(); eof.Type != ItemEOF || eof.Pos != 7 || ts.Next().Pos != 7 {
+		t.Errorf("explicit eof %+v", eof)
+	}
+}
+
+func TestSeekType(t *testing.T) {
+	ts := Tokens(New(lexWords, "ab 1 cd"))
+	if item := SeekType(ts, ItemError); item == nil || item.Value != `unexpected "1"` {
+		t.Fatalf("seek error %+v", item)
+	}
+	if item := SeekType(ts, ItemError); item != nil {
+		t.Errorf("seek past the end %+v", item)
+	}
+	if item := ts.Next(); item.Type != ItemEOF || ts.Err() == nil {
+		t.Errorf("after seeking %+v err %v", item, ts.Err())
+	}
+
+	ts = FromItems([]Item{{Type: 1, Value: "a"}, {Type: 2, Value: "b"}, {Type: 1, Value: "c"}})
+	var vals []string
+	for item := SeekType(ts, 1); item != nil; item = SeekType(ts, 1) {
+		vals = append(vals, item.Value)
+	}
+	if len(vals) != 2 || vals[0] != "a" || vals[1] != "c" {
+		t.Errorf("seek %q", vals)
+	}
+}
+
+func TestFindAll(t *testing.T) {
+	items := []Item{{Type: 1, Value: "a"}, {Type: 2, Value: "TODO b"}, {Type: 2, Value: "c"}, {Type: 2, Value: "TODO d"}}
+	found := FindAll(items, func(item *Item) bool {
+		return item.Type == 2 && strings.Contains(item.Value, "TODO")
+	})
+	if vals := values(found); len(vals) != 2 || vals[0] != "TODO b" || vals[1] != "TODO d" {
+		t.Errorf("found %q", vals)
+	}
+	if found := FindAll(items, func(*Item) bool { return false }); found != nil {
+		t.Errorf("found %v", found)
+	}
+}
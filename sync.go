@@ -0,0 +1,58 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"sync"
+)
+
+// SyncLexer allows several goroutines to pull items from one Lexer.  A Lexer
+// itself must not be used concurrently.  Each item is returned to exactly one
+// caller of SyncLexer.Next.  Once the input is exhausted every caller
+// receives an item of type ItemEOF, even under the EOFPolicy EOFOnce.
+type SyncLexer struct {
+	mu  sync.Mutex
+	lex *Lexer
+}
+
+// NewSync returns a SyncLexer pulling items from l.  After calling NewSync l
+// must only be used through the SyncLexer.
+func NewSync(l *Lexer) *SyncLexer {
+	return &SyncLexer{lex: l}
+}
+
+// Next returns the next item from the underlying lexer.
+func (s *SyncLexer) Next() *Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.next()
+}
+
+// NextN returns up to n items from the underlying lexer, which are
+// consecutive in the stream.  Fewer than n items are returned only when the
+// stream ends, in which case the last item has type ItemEOF.
+func (s *SyncLexer) NextN(n int) []*Item {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	items := make([]*Item, 0, n)
+	for len(items) < n {
+		item := s.next()
+		items = append(items, item)
+		if item.Type == ItemEOF {
+			break
+		}
+	}
+	return items
+}
+
+// next returns the next item of the underlying lexer, or another item of
+// type ItemEOF where a lexer with the EOFPolicy EOFOnce returns nil.
+func (s *SyncLexer) next() *Item {
+	if item := s.lex.Next(); item != nil {
+		return item
+	}
+	l := s.lex
+	return l.stamp(&Item{Type: ItemEOF, Pos: l.offset(l.pos)})
+}
@@ -0,0 +1,71 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+)
+
+func TestSyncLexer(t *testing.T) {
+	words := strings.Repeat("ab cd ef gh ", 250)
+	s := NewSync(New(lexWords, words))
+	var (
+		mu    sync.Mutex
+		wg    sync.WaitGroup
+		items []*Item
+	)
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for {
+				var batch []*Item
+				if i%2 == 0 {
+					batch = s.NextN(3)
+				} else {
+					batch = []*Item{s.Next()}
+				}
+				mu.Lock()
+				for _, item := range batch {
+					if item.Type != ItemEOF {
+						items = append(items, item)
+					}
+				}
+				mu.Unlock()
+				if batch[len(batch)-1].Type == ItemEOF {
+					return
+				}
+			}
+		}(i)
+	}
+	wg.Wait()
+	if len(items) != 1000 {
+		t.Fatalf("received %d items", len(items))
+	}
+	sort.Slice(items, func(i, j int) bool { return items[i].Pos < items[j].Pos })
+	for i, item := range items {
+		if item.Pos != 3*i {
+			t.Fatalf("item %d at position %d", i, item.Pos)
+		}
+	}
+}
+
+func TestSyncLexerEOFOnce(t *testing.T) {
+	s := NewSync(New(lexWords, "ab cd", WithEOFPolicy(EOFOnce)))
+	if items := s.NextN(3); len(items) != 3 || items[2].Type != ItemEOF {
+		t.Fatalf("items %v", items)
+	}
+	for i := 0; i < 2; i++ {
+		if items := s.NextN(3); len(items) != 1 || items[0].Type != ItemEOF || items[0].Pos != 5 {
+			t.Errorf("items %v after the end", items)
+		}
+		if item := s.Next(); item == nil || item.Type != ItemEOF {
+			t.Errorf("item %v after the end", item)
+		}
+	}
+}
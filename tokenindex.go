@@ -0,0 +1,119 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"sort"
+)
+
+// TokenIndex finds the items of a source by offset, answering the queries
+// of editor tooling such as hover, go-to-definition and the expansion of
+// selections.
+//
+//	items := ... // the items of a document, collected once
+//	ix := lexer.NewTokenIndex(items)
+//	if item, ok := ix.TokenAt(cursor); ok {
+//		...
+//	}
+//
+// Items are located by their spans (see Item.Span).  Queries take
+// logarithmic time in the number of items, plus the number of items
+// returned, as long as few items overlap; error items overlapping tokens
+// are found as well.
+type TokenIndex struct {
+	items  []Item
+	spans  []Span
+	maxEnd []int // the greatest End of the spans up to each index
+}
+
+// NewTokenIndex indexes items, which are copied.  Items of type ItemEOF are
+// not indexed.
+func NewTokenIndex(items []Item) *TokenIndex {
+	ix := new(TokenIndex)
+	for i := range items {
+		if items[i].Type != ItemEOF {
+			ix.items = append(ix.items, items[i])
+		}
+	}
+	ix.spans = make([]Span, len(ix.items))
+	for i := range ix.items {
+		ix.spans[i] = ix.items[i].Span()
+	}
+	if !sort.SliceIsSorted(ix.spans, func(i, j int) bool { return ix.spans[i].Start < ix.spans[j].Start }) {
+		sort.Stable(byStart{ix})
+	}
+	ix.maxEnd = make([]int, len(ix.spans))
+	max := 0
+	for i, s := range ix.spans {
+		if i == 0 || s.End > max {
+			max = s.End
+		}
+		ix.maxEnd[i] = max
+	}
+	return ix
+}
+
+type byStart struct{ ix *TokenIndex }
+
+func (s byStart) Len() int           { return len(s.ix.spans) }
+func (s byStart) Less(i, j int) bool { return s.ix.spans[i].Start < s.ix.spans[j].Start }
+func (s byStart) Swap(i, j int) {
+	s.ix.spans[i], s.ix.spans[j] = s.ix.spans[j], s.ix.spans[i]
+	s.ix.items[i], s.ix.items[j] = s.ix.items[j], s.ix.items[i]
+}
+
+// Len returns the number of items indexed.
+func (ix *TokenIndex) Len() int {
+	return len(ix.items)
+}
+
+// Items returns the items indexed, ordered by the start of their spans.
+// The slice belongs to ix.
+func (ix *TokenIndex) Items() []Item {
+	return ix.items
+}
+
+// TokenAt returns the item whose span contains offset.  If several do, as
+// when an error overlaps a token, the one starting last is returned.  Empty
+// items contain no offset.  The item belongs to ix.
+func (ix *TokenIndex) TokenAt(offset int) (*Item, bool) {
+	if i := ix.at(offset); i >= 0 {
+		return &ix.items[i], true
+	}
+	return nil, false
+}
+
+// at returns the index of the item TokenAt returns, or -1.
+func (ix *TokenIndex) at(offset int) int {
+	j := sort.Search(len(ix.spans), func(i int) bool { return ix.spans[i].Start > offset })
+	for i := j - 1; i >= 0 && ix.maxEnd[i] > offset; i-- {
+		if ix.spans[i].Contains(offset) {
+			return i
+		}
+	}
+	return -1
+}
+
+// TokensInRange returns copies of the items whose spans intersect s,
+// ordered by the start of their spans.  An empty span selects the item
+// containing its offset, if any, as an empty selection in an editor selects
+// the token under the cursor.
+func (ix *TokenIndex) TokensInRange(s Span) []Item {
+	if s.Empty() {
+		if i := ix.at(s.Start); i >= 0 {
+			return []Item{ix.items[i]}
+		}
+		return nil
+	}
+	lo := sort.Search(len(ix.maxEnd), func(i int) bool { return ix.maxEnd[i] > s.Start })
+	hi := sort.Search(len(ix.spans), func(i int) bool { return ix.spans[i].Start >= s.End })
+	var found []Item
+	for i := lo; i < hi; i++ {
+		if ix.spans[i].Intersects(s) {
+			found = append(found, ix.items[i])
+		}
+	}
+	return found
+}
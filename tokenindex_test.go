@@ -0,0 +1,96 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func values(items []Item) []string {
+	var v []string
+	for _, item := range items {
+		v = append(v, item.Value)
+	}
+	return v
+}
+
+func TestTokenIndex(t *testing.T) {
+	lex := New(lexWords, "ab cd 1 ef")
+	var items []Item
+	for {
+		item := lex.Next()
+		items = append(items, *item)
+		if item.Type == ItemEOF {
+			break
+		}
+	}
+	// lexWords stops at the error, so lex the rest separately.
+	rest := New(lexWords, "ef")
+	item := rest.Next()
+	item.Pos += 8
+	items = append(items, *item)
+	ix := NewTokenIndex(items)
+	if ix.Len() != 4 {
+		t.Fatalf("%d items", ix.Len())
+	}
+	for offset, want := range []string{"ab", "ab", "", "cd", "cd", "", "unexpected \"1\"", "", "ef", "ef", ""} {
+		switch item, ok := ix.TokenAt(offset); {
+		case !ok && want != "":
+			t.Errorf("offset %d: no item (expected %q)", offset, want)
+		case ok && item.Value != want:
+			t.Errorf("offset %d: %q (expected %q)", offset, item.Value, want)
+		}
+	}
+	for _, test := range []struct {
+		span Span
+		want []string
+	}{
+		{Span{0, 10}, []string{"ab", "cd", "unexpected \"1\"", "ef"}},
+		{Span{1, 4}, []string{"ab", "cd"}},
+		{Span{2, 3}, nil},
+		{Span{4, 4}, []string{"cd"}},
+		{Span{5, 5}, nil},
+		{Span{9, 20}, []string{"ef"}},
+	} {
+		if got := values(ix.TokensInRange(test.span)); !reflect.DeepEqual(got, test.want) {
+			t.Errorf("range %v: %q (expected %q)", test.span, got, test.want)
+		}
+	}
+}
+
+func TestTokenIndexOverlap(t *testing.T) {
+	items := []Item{
+		{Type: 2, Pos: 4, Value: "inner"},
+		{Type: 1, Pos: 0, Value: strings.Repeat("x", 20)},
+		{Type: 3, Pos: 22, Value: "after"},
+	}
+	ix := NewTokenIndex(items)
+	if got := values(ix.Items()); !reflect.DeepEqual(got, []string{items[1].Value, "inner", "after"}) {
+		t.Errorf("items %q", got)
+	}
+	if item, _ := ix.TokenAt(5); item.Value != "inner" {
+		t.Errorf("item at 5 %q", item.Value)
+	}
+	if item, _ := ix.TokenAt(12); item.Type != 1 {
+		t.Errorf("item at 12 %v", item)
+	}
+	if got := values(ix.TokensInRange(Span{10, 23})); !reflect.DeepEqual(got, []string{items[1].Value, "after"}) {
+		t.Errorf("range %q", got)
+	}
+}
+
+func BenchmarkTokenAt(b *testing.B) {
+	items := make([]Item, 100000)
+	for i := range items {
+		items[i] = Item{Type: 1, Pos: 4 * i, Value: "abc"}
+	}
+	ix := NewTokenIndex(items)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		ix.TokenAt(i % 400000)
+	}
+}
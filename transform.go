@@ -0,0 +1,229 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"sort"
+	"strings"
+)
+
+// An InputTransform derives the input of a lexer from its source text, such
+// as by replacing C trigraphs, decoding HTML entities or normalizing
+// escapes ahead of lexing.  It returns the input and a map from offsets in
+// the input to offsets in src, usually built with an InputBuilder.  A nil
+// map means that offsets are unchanged.
+type InputTransform func(src string) (input string, m *TransformMap)
+
+// WithInputTransform causes the lexer to apply t to its input before
+// lexing.  The state functions see only the transformed input, returned by
+// Input, while the positions of items and errors are offsets in the source
+// text, returned by Source, so that errors point at the original bytes.
+// Several transforms are applied in the order they are given.
+//
+// Text given to Append is transformed with the rest of the source, and must
+// not change how the earlier input is transformed.
+func WithInputTransform(t InputTransform) Option {
+	return func(l *Lexer) {
+		if !l.inRecord {
+			l.xforms = append(l.xforms, t)
+		}
+	}
+}
+
+// TransformMap maps offsets in the output of a preprocessing step, such as
+// an InputTransform, to offsets in its input, so that steps compose while
+// diagnostics keep pointing at the original source.  The output is made of
+// runs of text copied from the input and of replacements of spans of the
+// input.  A nil *TransformMap maps each offset to itself.
+type TransformMap struct {
+	logical  []int // offsets in the output of the runs
+	physical []int // offsets in the input of the runs
+	limit    []int // lengths of replaced spans, or -1 for copied runs
+
+	chain []*TransformMap // maps composed by Compose, in order
+}
+
+// Compose returns the map of the steps mapped by maps applied in order,
+// from offsets in the output of the last step to offsets in the input of
+// the first.  Nil maps are ignored.
+func Compose(maps ...*TransformMap) *TransformMap {
+	var chain []*TransformMap
+	for _, m := range maps {
+		switch {
+		case m == nil:
+		case m.chain != nil:
+			chain = append(chain, m.chain...)
+		default:
+			chain = append(chain, m)
+		}
+	}
+	switch len(chain) {
+	case 0:
+		return nil
+	case 1:
+		return chain[0]
+	}
+	return &TransformMap{chain: chain}
+}
+
+// MapToOriginal returns the offset in the original input of the byte at
+// offset pos in the output.  Offsets in a replacement map into the replaced
+// span, to its start if the span is empty.  An offset at the boundary of
+// two runs maps to the start of the later run.
+func (m *TransformMap) MapToOriginal(pos int) int {
+	if m == nil {
+		return pos
+	}
+	if m.chain != nil {
+		for i := len(m.chain) - 1; i >= 0; i-- {
+			pos = m.chain[i].MapToOriginal(pos)
+		}
+		return pos
+	}
+	i := sort.SearchInts(m.logical, pos+1) - 1
+	if i < 0 {
+		return pos
+	}
+	d := pos - m.logical[i]
+	if max := m.limit[i]; max >= 0 && d > max {
+		d = max
+	}
+	return m.physical[i] + d
+}
+
+func (m *TransformMap) add(logical, physical, limit int) {
+	m.logical = append(m.logical, logical)
+	m.physical = append(m.physical, physical)
+	m.limit = append(m.limit, limit)
+}
+
+// InputBuilder builds the input of an InputTransform and its TransformMap by
+// replacing spans of the source, in order, and copying the text between
+// them.
+//
+//	// dropCR removes the carriage returns of CRLF line endings.
+//	func dropCR(src string) (string, *lexer.TransformMap) {
+//		b := lexer.NewInputBuilder(src)
+//		for i := 0; i+1 < len(src); i++ {
+//			if src[i] == '\r' && src[i+1] == '\n' {
+//				b.Replace(i, i+1, "")
+//			}
+//		}
+//		return b.Finish()
+//	}
+type InputBuilder struct {
+	src  string
+	buf  strings.Builder
+	m    TransformMap
+	last int
+}
+
+// NewInputBuilder returns a builder of input derived from src.
+func NewInputBuilder(src string) *InputBuilder {
+	b := &InputBuilder{src: src}
+	b.m.add(0, 0, -1)
+	return b
+}
+
+// Replace replaces src[pos:end] with text, after copying the source since
+// the previous replacement.  Replace panics if the span precedes the end
+// of the previous replacement.
+func (b *InputBuilder) Replace(pos, end int, text string) {
+	if pos < b.last || end < pos || end > len(b.src) {
+		panic("lexer: InputBuilder replacement out of order")
+	}
+	b.buf.WriteString(b.src[b.last:pos])
+	b.m.add(b.buf.Len(), pos, end-pos)
+	b.buf.WriteString(text)
+	b.m.add(b.buf.Len(), end, -1)
+	b.last = end
+}
+
+// Finish copies the rest of the source and returns the input and its map.
+func (b *InputBuilder) Finish() (string, *TransformMap) {
+	if b.last == 0 && len(b.m.logical) == 1 {
+		return b.src, &b.m
+	}
+	b.buf.WriteString(b.src[b.last:])
+	return b.buf.String(), &b.m
+}
+
+// ReplaceInput returns a transform replacing each occurrence of the strings
+// in oldnew, which alternate between old and new strings as for
+// strings.NewReplacer, with the following new string.  At each position
+// the longest old string matching is replaced.  The C trigraphs, for
+// example, are replaced by
+//
+//	lexer.ReplaceInput("??=", "#", "??/", `\`, "??'", "^", "??(", "[",
+//		"??)", "]", "??!", "|", "??<", "{", "??>", "}", "??-", "~")
+func ReplaceInput(oldnew ...string) InputTransform {
+	if len(oldnew)%2 != 0 {
+		panic("lexer: ReplaceInput with odd argument count")
+	}
+	return func(src string) (string, *TransformMap) {
+		b := NewInputBuilder(src)
+		for i := 0; i < len(src); {
+			n, repl := 0, ""
+			for j := 0; j < len(oldnew); j += 2 {
+				old := oldnew[j]
+				if len(old) > n && strings.HasPrefix(src[i:], old) {
+					n, repl = len(old), oldnew[j+1]
+				}
+			}
+			if n == 0 {
+				i++
+				continue
+			}
+			b.Replace(i, i+n, repl)
+			i += n
+		}
+		return b.Finish()
+	}
+}
+
+// transform applies the transforms of l to src.
+func (l *Lexer) transform(src string) (string, *TransformMap) {
+	maps := make([]*TransformMap, len(l.xforms))
+	for i, t := range l.xforms {
+		src, maps[i] = t(src)
+	}
+	return src, Compose(maps...)
+}
+
+// transformInput derives the input of l from its source text, which is
+// l.input when the lexer is created.
+func (l *Lexer) transformInput() {
+	if len(l.xforms) == 0 {
+		return
+	}
+	l.src = l.input
+	l.input, l.tmap = l.transform(l.src)
+	if l.rec != nil {
+		l.rec.Input = l.input
+	}
+}
+
+// Source returns the text from which the input of l was derived.  It is
+// the same as Input unless an option such as WithInputTransform transforms
+// the input.  The positions of items and errors are offsets in the source.
+// If l discards input (see WithRetention) only the retained source is
+// returned.
+func (l *Lexer) Source() string {
+	if len(l.xforms) > 0 {
+		return l.src
+	}
+	return l.input
+}
+
+// TransformMap returns the map from offsets in the input of l to offsets in
+// its source, or nil if the input is not transformed.
+func (l *Lexer) TransformMap() *TransformMap {
+	return l.tmap
+}
+
+// offset returns the offset in the source of the input offset pos.
+func (l *Lexer) offset(pos int) int {
+	return l.tmap.MapToOriginal(pos) + l.dropped
+}
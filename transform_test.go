@@ -0,0 +1,86 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"testing"
+)
+
+func TestReplaceInput(t *testing.T) {
+	entities := ReplaceInput("&lt;", "<", "&amp;", "&", "&a;", "a")
+	input, m := entities("x&lt;y &amp;&a;b")
+	if input != "x<y &ab" {
+		t.Fatalf("input %q", input)
+	}
+	for pos, want := range []int{0, 1, 5, 6, 7, 12, 15, 16} {
+		if got := m.MapToOriginal(pos); got != want {
+			t.Errorf("offset %d: %d, want %d", pos, got, want)
+		}
+	}
+}
+
+func TestInputTransforms(t *testing.T) {
+	lower := ReplaceInput("A", "a", "B", "b", "C", "c")
+	src := "A\\\nB cd 1"
+	l := New(lexWords, src, WithLineSplicing(), WithInputTransform(lower), WithLineTracking())
+	if l.Input() != "ab cd 1" || l.Source() != src {
+		t.Fatalf("input %q source %q", l.Input(), l.Source())
+	}
+	for _, want := range []struct {
+		Item
+		span Span
+	}{
+		{Item{Type: 1, Pos: 0, Value: "ab", Line: 1, Column: 1}, Span{0, 4}},
+		{Item{Type: 1, Pos: 5, Value: "cd", Line: 2, Column: 3}, Span{5, 7}},
+	} {
+		item := l.Next()
+		if item.Type != want.Type || item.Pos != want.Pos || item.Value != want.Value ||
+			item.Line != want.Line || item.Column != want.Column {
+			t.Errorf("item %+v, want %+v", item, &want.Item)
+		}
+		if span := item.Span(); span != want.span {
+			t.Errorf("item %+v: span %v, want %v", item, span, want.span)
+		}
+	}
+	err := l.Next().Err().(*LexError)
+	if err.Pos != 8 || err.Lexeme != "1" {
+		t.Errorf("error %+v", err)
+	}
+}
+
+func TestInputBuilderOrder(t *testing.T) {
+	defer func() {
+		if recover() == nil {
+			t.Errorf("no panic")
+		}
+	}()
+	b := NewInputBuilder("abcdef")
+	b.Replace(2, 4, "x")
+	b.Replace(3, 5, "y")
+}
+
+func TestCompose(t *testing.T) {
+	spliced, splice := spliceLines("ab\\\nc&amp;d")
+	_, entities := ReplaceInput("&amp;", "&")(spliced)
+	m := Compose(splice, nil, entities)
+	for pos, want := range []int{0, 1, 4, 5, 10, 11} {
+		if got := m.MapToOriginal(pos); got != want {
+			t.Errorf("offset %d: %d, want %d", pos, got, want)
+		}
+	}
+	if Compose(nil, nil) != nil || Compose(splice) != splice {
+		t.Errorf("trivial compositions")
+	}
+	if got := (*TransformMap)(nil).MapToOriginal(3); got != 3 {
+		t.Errorf("nil map: %d", got)
+	}
+	l := New(lexWords, "a\\\nb", WithLineSplicing())
+	if got := l.TransformMap().MapToOriginal(1); got != 3 {
+		t.Errorf("lexer map: %d", got)
+	}
+	if New(lexWords, "ab").TransformMap() != nil {
+		t.Errorf("map without transforms")
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"unicode/utf8"
+)
+
+// ColumnUnit is the unit in which a lexer counts the columns of positions.
+type ColumnUnit int
+
+const (
+	// ColumnRunes counts characters, with tabs advancing to the next tab
+	// stop set by WithTabWidth.
+	ColumnRunes ColumnUnit = iota
+	// ColumnBytes counts bytes of UTF-8.
+	ColumnBytes
+	// ColumnUTF16 counts UTF-16 code units, as the Language Server Protocol
+	// and editors built on JavaScript do.  Characters outside the Basic
+	// Multilingual Plane count twice.
+	ColumnUTF16
+)
+
+// WithColumnUnit sets the unit of the columns computed by Position and
+// stamped on items by WithLineTracking.  Tabs count as one unit except in
+// ColumnRunes, the default.
+func WithColumnUnit(u ColumnUnit) Option {
+	return func(l *Lexer) { l.unit = u }
+}
+
+// columns returns the number of columns taken at column col by the rune r
+// encoded in n bytes.
+func (l *Lexer) columns(r rune, n, col int) int {
+	switch l.unit {
+	case ColumnBytes:
+		return n
+	case ColumnUTF16:
+		if r >= 0x10000 && n == 4 {
+			return 2
+		}
+		return 1
+	}
+	if r == '\t' {
+		return l.tabs - col%l.tabs
+	}
+	return 1
+}
+
+// WithRuneOffsets causes the lexer to set the RuneOffset and UTF16Offset of
+// each item, including the final ItemEOF, for tools which count positions
+// in characters rather than bytes.  Offsets are counted incrementally as
+// items are emitted, so tracking them costs little more than scanning.
+func WithRuneOffsets() Option {
+	return func(l *Lexer) { l.runes = true }
+}
+
+// runeCursor counts the runes and UTF-16 code units of a source up to a byte
+// offset.
+type runeCursor struct {
+	off, runes, units int
+}
+
+// RuneOffset returns the number of runes and of UTF-16 code units in l's
+// source before the byte offset.  Invalid UTF-8 bytes count as one rune and
+// one code unit each.  Offsets in input discarded by the lexer (see
+// WithRetention) are clamped, and the offsets of a lexer created by Load
+// are counted from the Start of the snapshot.
+func (l *Lexer) RuneOffset(offset int) (runes, units int) {
+	src := l.Source()
+	if offset < l.dropped {
+		offset = l.dropped
+	}
+	if offset > l.dropped+len(src) {
+		offset = l.dropped + len(src)
+	}
+	c := &l.rcur
+	if c.off < l.rbase.off || offset < c.off {
+		*c = l.rbase
+	}
+	for c.off < offset {
+		r, n := utf8.DecodeRuneInString(src[c.off-l.dropped:])
+		c.off += n
+		c.runes++
+		c.units++
+		if r >= 0x10000 && n == 4 {
+			c.units++
+		}
+	}
+	return c.runes, c.units
+}
+
+// dropRunes moves the base of the rune offsets of l to the offset of the
+// first retained byte before input up to it is discarded.
+func (l *Lexer) dropRunes(offset int) {
+	if l.runes {
+		l.RuneOffset(offset)
+		l.rbase = l.rcur
+	}
+}
@@ -0,0 +1,87 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+// lexFields emits runs of characters other than spaces, tabs and newlines.
+func lexFields(l *Lexer) StateFn {
+	l.AcceptRun(" \t\n")
+	l.Ignore()
+	if l.AcceptRunFunc(func(r rune) bool { return r != ' ' && r != '\t' && r != '\n' }) > 0 {
+		l.Emit(1)
+		return lexFields
+	}
+	return nil
+}
+
+func TestColumnUnit(t *testing.T) {
+	input := "é😀\tx\n\t😀y"
+	for _, test := range []struct {
+		unit ColumnUnit
+		cols []int
+	}{
+		{ColumnRunes, []int{1, 5, 6}},
+		{ColumnBytes, []int{1, 8, 6}},
+		{ColumnUTF16, []int{1, 5, 4}},
+	} {
+		lex := New(lexFields, input, WithLineTracking(), WithTabWidth(4), WithColumnUnit(test.unit))
+		var cols []int
+		for item := lex.Next(); item.Type != ItemEOF; item = lex.Next() {
+			cols = append(cols, item.Column)
+		}
+		// The last field begins after the emoji of the second line.
+		cols[2] = lex.Position(len(input) - 1).Column
+		if !reflect.DeepEqual(cols, test.cols) {
+			t.Errorf("unit %d: columns %v (expected %v)", test.unit, cols, test.cols)
+		}
+	}
+}
+
+func TestRuneOffsets(t *testing.T) {
+	input := "é😀 x 😀y z"
+	lex := New(lexFields, input, WithRuneOffsets())
+	var offsets []string
+	for {
+		item := lex.Next()
+		offsets = append(offsets, fmt.Sprintf("%d:%d:%d", item.Pos, item.RuneOffset, item.UTF16Offset))
+		if item.Type == ItemEOF {
+			break
+		}
+	}
+	expect := []string{"0:0:0", "7:3:4", "9:5:6", "15:8:10", "16:9:11"}
+	if !reflect.DeepEqual(offsets, expect) {
+		t.Errorf("offsets %q (expected %q)", offsets, expect)
+	}
+	if r, u := lex.RuneOffset(2); r != 1 || u != 1 {
+		t.Errorf("rune offset %d %d after moving back", r, u)
+	}
+	if r, u := New(nilState, "a\xff😀b").RuneOffset(6); r != 3 || u != 4 {
+		t.Errorf("rune offset %d %d after invalid UTF-8", r, u)
+	}
+}
+
+func TestRuneOffsetsRetention(t *testing.T) {
+	lex := New(lexFields, "", WithRetention(4), WithRuneOffsets())
+	lex.Next()
+	var last *Item
+	for i := 0; i < 50; i++ {
+		lex.Append("añb\n")
+		lex.Continue(lexFields)
+		for item := lex.Next(); item.Type != ItemEOF; item = lex.Next() {
+			last = item
+		}
+	}
+	if lex.Discarded() == 0 {
+		t.Fatalf("nothing discarded")
+	}
+	if last.Pos != 49*5 || last.RuneOffset != 49*4 || last.UTF16Offset != 49*4 {
+		t.Errorf("last item %d %d %d", last.Pos, last.RuneOffset, last.UTF16Offset)
+	}
+}
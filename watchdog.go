@@ -0,0 +1,58 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"time"
+)
+
+// watchdog measures the progress of a lexer, see WithWatchdog.
+type watchdog struct {
+	window time.Duration // lexing time between checks
+	min    int           // bytes to consume in each window
+	busy   time.Duration // lexing time since the last check
+	pos    int           // offset at the last check
+}
+
+// WithWatchdog halts the lexer with an error if it consumes fewer than min
+// bytes of input in window of lexing time, protecting services from crafted
+// inputs which trigger quadratic behavior in state functions.  Only the
+// time spent in state functions is measured, so a parser taking its time
+// between calls to Next does not trip the watchdog.  Progress is checked
+// between calls of states, so a state which never returns is not stopped.
+//
+//	lex := lexer.New(lexText, input, lexer.WithWatchdog(100*time.Millisecond, 64<<10))
+func WithWatchdog(window time.Duration, min int) Option {
+	return func(l *Lexer) {
+		l.watch = &watchdog{window: window, min: min}
+	}
+}
+
+// call calls the current state of l, under the watchdog if there is one,
+// and returns the state it returns.
+func (l *Lexer) call() StateFn {
+	if l.watch != nil {
+		return l.watch.call(l)
+	}
+	return l.state(l)
+}
+
+// call calls the current state of l and returns the state it returns.  It
+// halts l if l progressed too slowly.
+func (w *watchdog) call(l *Lexer) StateFn {
+	state := l.state
+	start := time.Now()
+	next := state(l)
+	w.busy += time.Since(start)
+	if w.busy < w.window || l.halted {
+		return next
+	}
+	pos := l.offset(l.pos)
+	if n := pos - w.pos; n < w.min {
+		return l.halt("lexer consumed %d bytes in %v of lexing in state %s, below the watchdog minimum of %d bytes", n, w.busy.Round(time.Millisecond), graphName(state), w.min)
+	}
+	w.busy, w.pos = 0, pos
+	return next
+}
@@ -0,0 +1,50 @@
+// Copyright 2012, Bryan Matsuo. All rights reserved.
+// Use of this source code is governed by a BSD-style
+// license that can be found in the LICENSE file.
+
+package lexer
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWatchdog(t *testing.T) {
+	slow := Named("slow", func(l *Lexer) StateFn {
+		time.Sleep(time.Millisecond)
+		if _, n := l.Advance(); n == 0 {
+			return nil
+		}
+		l.Ignore()
+		return nil
+	})
+	var crawl StateFn
+	crawl = Named("crawl", func(l *Lexer) StateFn {
+		if slow(l) == nil && l.pos == len(l.input) {
+			return nil
+		}
+		return crawl
+	})
+	items := collect(New(crawl, strings.Repeat("x", 1000), WithWatchdog(10*time.Millisecond, 100)))
+	if len(items) != 1 || !strings.Contains(items[0], "below the watchdog minimum of 100 bytes") ||
+		!strings.Contains(items[0], "in state crawl") {
+		t.Errorf("items %q", items)
+	}
+
+	lex := New(lexWords, strings.Repeat("ab ", 1000), WithWatchdog(time.Millisecond, 1))
+	if items := collect(lex); len(items) != 1000 {
+		t.Errorf("%d items", len(items))
+	}
+}
+
+// TestWatchdogIdle checks that time spent outside the lexer does not count.
+func TestWatchdogIdle(t *testing.T) {
+	lex := New(lexWords, "ab cd ef", WithWatchdog(time.Millisecond, 1000))
+	for i := 0; i < 3; i++ {
+		time.Sleep(2 * time.Millisecond)
+		if item := lex.Next(); item.Type != 1 {
+			t.Fatalf("item %v", item)
+		}
+	}
+}